@@ -0,0 +1,120 @@
+// Command embed_books computes the vectors used to find similar books.
+//
+// By default it embeds every book whose vectors are missing, were made by
+// another embedding model, or were made from chapter text that has since
+// changed, so it can run on a schedule after imports. With -book it embeds
+// a single book.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"go.uber.org/zap"
+
+	"github.com/ponyo877/roudoku/server/domain"
+	"github.com/ponyo877/roudoku/server/internal/database"
+	"github.com/ponyo877/roudoku/server/pkg/config"
+	"github.com/ponyo877/roudoku/server/pkg/logger"
+	"github.com/ponyo877/roudoku/server/repository"
+	"github.com/ponyo877/roudoku/server/services"
+)
+
+func main() {
+	bookID := flag.Int64("book", 0, "embed only this book")
+	force := flag.Bool("force", false, "re-embed the book even if its vectors are current")
+	refit := flag.Bool("refit", false, "fit the local embedder to the books again before a stale run")
+	embedderName := flag.String("embedder", "", "embedder to use instead of the configured one (local or vertex)")
+	flag.Parse()
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	if *embedderName != "" {
+		cfg.Embedding.Embedder = *embedderName
+	}
+
+	appLogger, err := logger.New(cfg.Logging)
+	if err != nil {
+		log.Fatalf("Failed to initialize logger: %v", err)
+	}
+	defer appLogger.Sync()
+
+	db, err := database.Connect(database.Config{
+		Host:     cfg.Database.Host,
+		Port:     cfg.Database.Port,
+		Database: cfg.Database.Name,
+		Username: cfg.Database.User,
+		Password: cfg.Database.Password,
+		SSLMode:  cfg.Database.SSLMode,
+	})
+	if err != nil {
+		appLogger.Fatal("Failed to connect to database")
+	}
+	defer db.Close()
+
+	embedder, err := newEmbedder(cfg)
+	if err != nil {
+		appLogger.WithError(err).Fatal("Failed to create embedder")
+	}
+
+	embeddingService := services.NewBookEmbeddingService(
+		repository.NewPostgresBookRepository(db),
+		repository.NewPostgresBookVectorRepository(db),
+		repository.NewPostgresRecommendationCacheRepository(db),
+		embedder,
+		services.BookEmbeddingConfig{
+			ChunkRunes:   cfg.Embedding.ChunkRunes,
+			ChunkOverlap: cfg.Embedding.ChunkOverlap,
+			BatchSize:    cfg.Embedding.BatchSize,
+			Refit:        *refit,
+			RefitDrift:   cfg.Embedding.RefitDrift,
+		},
+		appLogger,
+	)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if *bookID != 0 {
+		embedded, err := embeddingService.EmbedBook(ctx, *bookID, *force)
+		if err != nil {
+			appLogger.WithError(err).Fatal("Failed to embed book")
+		}
+		appLogger.WithFields(zap.Int64("book_id", *bookID), zap.Bool("embedded", embedded)).Info("Book embedding finished")
+		return
+	}
+
+	run, err := embeddingService.EmbedStaleBooks(ctx)
+	if err != nil {
+		appLogger.WithError(err).Fatal("Book embedding run stopped")
+	}
+	if run.Failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// newEmbedder creates the configured embedder, producing vectors of the
+// size stored in book_vectors
+func newEmbedder(cfg *config.Config) (services.Embedder, error) {
+	switch cfg.Embedding.Embedder {
+	case services.EmbedderVertex:
+		return services.NewVertexEmbedder(services.VertexEmbedderConfig{
+			ProjectID:       cfg.ExternalServices.GoogleCloud.ProjectID,
+			Location:        cfg.Embedding.Location,
+			Model:           cfg.Embedding.Model,
+			CredentialsPath: cfg.ExternalServices.GoogleCloud.CredentialsPath,
+			Dimensions:      domain.BookContentVectorDims,
+		})
+	case services.EmbedderLocal, "":
+		return services.NewLocalEmbedder(domain.BookContentVectorDims), nil
+	default:
+		return nil, fmt.Errorf("unknown embedder in configuration: %s", cfg.Embedding.Embedder)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
@@ -14,10 +15,13 @@ import (
 
 	"github.com/ponyo877/roudoku/server/handlers"
 	"github.com/ponyo877/roudoku/server/internal/database"
+	"github.com/ponyo877/roudoku/server/pkg/audio"
 	"github.com/ponyo877/roudoku/server/pkg/config"
 	"github.com/ponyo877/roudoku/server/pkg/logger"
 	"github.com/ponyo877/roudoku/server/pkg/middleware"
+	"github.com/ponyo877/roudoku/server/pkg/storage"
 	"github.com/ponyo877/roudoku/server/pkg/utils"
+	"github.com/ponyo877/roudoku/server/pkg/voicepolicy"
 	"github.com/ponyo877/roudoku/server/repository"
 	"github.com/ponyo877/roudoku/server/services"
 )
@@ -73,6 +77,7 @@ func main() {
 	preferencesRepo := repository.NewPostgresUserPreferencesRepository(db)
 	interactionRepo := repository.NewPostgresUserInteractionRepository(db)
 	similarityRepo := repository.NewPostgresUserSimilarityRepository(db)
+	cfRepo := repository.NewPostgresCollaborativeFilteringRepository(db)
 	cacheRepo := repository.NewPostgresRecommendationCacheRepository(db)
 	feedbackRepo := repository.NewPostgresRecommendationFeedbackRepository(db)
 	vectorRepo := repository.NewPostgresBookVectorRepository(db)
@@ -82,6 +87,17 @@ func main() {
 	subscriptionRepo := repository.NewPostgresUserSubscriptionRepository(db)
 	usageRepo := repository.NewPostgresUsageTrackingRepository(db)
 
+	// Initialize audio repositories
+	audioFileRepo := repository.NewPostgresAudioFileRepository(db)
+	audioAlignmentRepo := repository.NewPostgresAudioAlignmentRepository(db)
+	audiobookJobRepo := repository.NewPostgresAudiobookJobRepository(db)
+	playbackSessionRepo := repository.NewPostgresAudioPlaybackSessionRepository(db)
+	voiceCastRepo := repository.NewPostgresVoiceCastRepository(db)
+	pronunciationRepo := repository.NewPostgresPronunciationRepository(db)
+	audioLifecycleRepo := repository.NewPostgresAudioLifecycleRepository(db)
+	offlineDownloadRepo := repository.NewPostgresOfflineDownloadRepository(db)
+	podcastFeedRepo := repository.NewPostgresPodcastFeedRepository(db)
+
 	// Initialize services
 	validationService := services.NewBusinessValidationService(appLogger)
 	bookService := services.NewBookService(bookRepo, appLogger)
@@ -90,8 +106,58 @@ func main() {
 	sessionService := services.NewSessionService(sessionRepo, validationService, appLogger)
 	ratingService := services.NewRatingService(ratingRepo, appLogger)
 
+	// Initialize blob storage
+	storageConfig := storage.Config{
+		Backend:         cfg.ExternalServices.Storage.Backend,
+		BucketName:      cfg.ExternalServices.Storage.BucketName,
+		CredentialsPath: cfg.ExternalServices.Storage.CredentialsPath,
+		LocalPath:       cfg.ExternalServices.Storage.LocalPath,
+		BaseURL:         cfg.ExternalServices.Storage.BaseURL,
+		SigningKey:      cfg.ExternalServices.Storage.SigningKey,
+	}
+
+	blobStore, err := storage.New(context.Background(), storageConfig)
+	if err != nil {
+		appLogger.Fatal("Failed to initialize blob storage")
+	}
+
+	// Initialize TTS engines
+	ttsFallback := services.TTSEngineFallback{
+		Default:  cfg.TTS.Engines,
+		PerVoice: cfg.TTS.VoiceEngines,
+	}
+	if len(ttsFallback.Default) == 0 {
+		ttsFallback.Default = []string{services.TTSEngineGoogle}
+	}
+	ttsEngines := newTTSEngines(cfg.TTS, ttsFallback, appLogger)
+
+	// Initialize subscription service
+	subscriptionService := services.NewSubscriptionService(
+		planRepo, subscriptionRepo, usageRepo, offlineDownloadRepo, appLogger)
+
+	// Load the voice selection policy
+	var voicePolicy *voicepolicy.Policy
+	if cfg.TTS.VoicePolicyPath != "" {
+		voicePolicy, err = voicepolicy.Load(cfg.TTS.VoicePolicyPath)
+		if err != nil {
+			appLogger.WithError(err).Fatal("Failed to load voice policy")
+		}
+	}
+
+	// Initialize pronunciation service
+	pronunciationService := services.NewPronunciationService(pronunciationRepo, bookRepo, appLogger)
+
 	// Initialize TTS service
-	ttsService, err := services.NewTTSService(cfg.TTS.CredentialsPath, appLogger)
+	ttsService, err := services.NewTTSService(
+		ttsEngines, ttsFallback,
+		services.TTSVoiceDefaults{
+			Language:      cfg.TTS.VoiceLanguage,
+			Voice:         cfg.TTS.VoiceName,
+			GenderVoices:  cfg.TTS.GenderVoices,
+			AudioEncoding: cfg.TTS.AudioEncoding,
+			Policy:        voicePolicy,
+		},
+		audioFileRepo, audioAlignmentRepo, userRepo, bookRepo, blobStore, subscriptionService, pronunciationService, appLogger)
 	if err != nil {
 		appLogger.Fatal("Failed to initialize TTS service")
 	}
@@ -103,19 +169,120 @@ func main() {
 		appLogger.Fatal("Failed to initialize notification service")
 	}
 
+	// Initialize audiobook service
+	audiobookService := services.NewAudiobookService(
+		audiobookJobRepo, voiceCastRepo, bookRepo, ttsService, notificationService,
+		services.AudiobookWorkerConfig{
+			Workers:      cfg.Audiobook.Workers,
+			PollInterval: cfg.Audiobook.PollInterval,
+			MaxAttempts:  cfg.Audiobook.MaxAttempts,
+			LockTimeout:  cfg.Audiobook.LockTimeout,
+		},
+		appLogger)
+
+	// Initialize audio delivery service
+	audioDeliveryService := services.NewAudioDeliveryService(
+		audioFileRepo, audiobookJobRepo, bookRepo, blobStore, appLogger)
+
+	// Initialize ambient mixing service
+	ambientService := services.NewAmbientService(
+		audioFileRepo, bookRepo, blobStore, ttsService,
+		services.AmbientConfig{
+			LibraryKey:      cfg.Ambient.LibraryKey,
+			RefreshInterval: cfg.Ambient.RefreshInterval,
+			Mix: audio.MixOptions{
+				BedGainDB: cfg.Ambient.BedVolumeDB,
+				DuckDB:    cfg.Ambient.DuckDB,
+				FadeIn:    cfg.Ambient.FadeIn,
+				FadeOut:   cfg.Ambient.FadeOut,
+			},
+		},
+		appLogger)
+
+	// Initialize audio lifecycle service
+	audioLifecycleService := services.NewAudioLifecycleService(
+		audioFileRepo, audioLifecycleRepo, subscriptionRepo, planRepo, blobStore,
+		services.AudioLifecycleConfig{
+			Interval:  cfg.AudioLifecycle.Interval,
+			IdleTTL:   cfg.AudioLifecycle.IdleTTL,
+			BatchSize: cfg.AudioLifecycle.BatchSize,
+		},
+		appLogger)
+
+	// Initialize offline download service
+	offlineDownloadService, err := services.NewOfflineDownloadService(
+		offlineDownloadRepo, bookRepo, audiobookJobRepo, audioFileRepo, subscriptionRepo,
+		subscriptionService, pronunciationService, blobStore,
+		services.OfflineDownloadConfig{
+			SigningKey:     cfg.Downloads.SigningKey,
+			URLExpiry:      cfg.ExternalServices.Storage.SignedURLExpiry,
+			RevokeInterval: cfg.Downloads.RevokeInterval,
+			PollInterval:   cfg.Downloads.PollInterval,
+		},
+		appLogger)
+	if err != nil {
+		// Offline downloads need a signing key; the rest of the API runs
+		// without them
+		appLogger.WithError(err).Warn("Offline downloads are disabled")
+	}
+
 	// Initialize analytics service
 	analyticsService := services.NewAnalyticsService(
 		analyticsRepo, streakRepo, goalRepo, achievementRepo, userAchievementRepo,
 		progressRepo, contextRepo, insightRepo, bookRepo, appLogger)
 
 	// Initialize recommendation service
-	recommendationService := services.NewRecommendationService(
-		preferencesRepo, interactionRepo, similarityRepo, cacheRepo, feedbackRepo,
-		vectorRepo, bookRepo, progressRepo, appLogger)
+	cfService := services.NewCollaborativeFilteringService(
+		interactionRepo, cfRepo,
+		services.CollaborativeFilteringConfig{
+			Factors:         cfg.CollaborativeFiltering.Factors,
+			Iterations:      cfg.CollaborativeFiltering.Iterations,
+			Regularization:  cfg.CollaborativeFiltering.Regularization,
+			Alpha:           cfg.CollaborativeFiltering.Alpha,
+			Neighbours:      cfg.CollaborativeFiltering.Neighbours,
+			MinCommonUsers:  cfg.CollaborativeFiltering.MinCommonUsers,
+			MaxBooksPerUser: cfg.CollaborativeFiltering.MaxBooksPerUser,
+		},
+		appLogger)
+	recommendationCacheService := services.NewRecommendationCacheService(cacheRepo, services.RecommendationCacheConfig{
+		LocalEntries:    cfg.RecommendationCache.LocalEntries,
+		LocalTTL:        cfg.RecommendationCache.LocalTTL,
+		TTL:             cfg.RecommendationCache.TTL,
+		TypeTTLs:        cfg.RecommendationCache.TypeTTLs,
+		CleanupInterval: cfg.RecommendationCache.CleanupInterval,
+	}, appLogger)
+	recommendationPipelines := make(map[string]services.RecommendationPipelineConfig, len(cfg.RecommendationPipelines))
+	for name, pipeline := range cfg.RecommendationPipelines {
+		recommendationPipelines[name] = services.RecommendationPipelineConfig{
+			Sources:            pipeline.Sources,
+			Weights:            pipeline.Weights,
+			PersonalizeWeights: pipeline.PersonalizeWeights,
+			PostProcessors:     pipeline.PostProcessors,
+			Fallback:           pipeline.Fallback,
+			Description:        pipeline.Description,
+		}
+	}
+	recommendationService, err := services.NewRecommendationService(
+		preferencesRepo, interactionRepo, similarityRepo, recommendationCacheService, feedbackRepo,
+		vectorRepo, bookRepo, progressRepo, swipeRepo, cfService, recommendationPipelines, appLogger)
+	if err != nil {
+		appLogger.WithError(err).Fatal("Failed to initialize recommendation pipelines")
+	}
 
-	// Initialize subscription service
-	subscriptionService := services.NewSubscriptionService(
-		planRepo, subscriptionRepo, usageRepo, appLogger)
+	// Initialize playback service
+	playbackService := services.NewPlaybackService(
+		playbackSessionRepo, audioFileRepo, analyticsRepo, subscriptionService, appLogger)
+
+	// Initialize reading/listening position service
+	positionService := services.NewPositionService(
+		bookRepo, audiobookJobRepo, audioFileRepo, audioAlignmentRepo, sessionRepo, playbackSessionRepo, appLogger)
+
+	// Initialize podcast feed service
+	podcastService := services.NewPodcastService(
+		podcastFeedRepo, audiobookJobRepo, interactionRepo, audioFileRepo, bookRepo,
+		audioDeliveryService, playbackService, subscriptionService,
+		services.PodcastConfig{MaxBooks: cfg.Podcast.MaxBooks},
+		appLogger)
 
 	// Initialize advanced recommendation service
 	advancedRecommendationService := services.NewAdvancedRecommendationService(
@@ -129,7 +296,8 @@ func main() {
 
 	// Initialize admin dashboard service
 	adminDashboardService := services.NewAdminDashboardService(
-		userRepo, bookRepo, subscriptionRepo, analyticsRepo, interactionRepo, feedbackRepo, nil, subscriptionService, appLogger)
+		userRepo, bookRepo, subscriptionRepo, analyticsRepo, interactionRepo, feedbackRepo,
+		audioFileRepo, audioLifecycleRepo, nil, subscriptionService, appLogger)
 
 	// Initialize authentication middleware
 	authMiddleware, err := middleware.NewAuthMiddleware(cfg.Firebase.CredentialsPath, appLogger)
@@ -146,9 +314,16 @@ func main() {
 	recommendationHandler := handlers.NewRecommendationHandler(recommendationService, appLogger)
 	subscriptionHandler := handlers.NewSubscriptionHandler(subscriptionService, appLogger)
 	ttsHandler := handlers.NewTTSHandler(ttsService, appLogger)
+	audiobookHandler := handlers.NewAudiobookHandler(audiobookService, appLogger)
+	audioHandler := handlers.NewAudioHandler(audioDeliveryService, appLogger)
+	ambientHandler := handlers.NewAmbientHandler(ambientService, appLogger)
+	playbackHandler := handlers.NewPlaybackHandler(playbackService, appLogger)
+	podcastHandler := handlers.NewPodcastHandler(podcastService, appLogger)
+	positionHandler := handlers.NewPositionHandler(positionService, appLogger)
 	notificationHandler := handlers.NewNotificationHandler(notificationService, appLogger)
 	analyticsHandler := handlers.NewAnalyticsHandler(analyticsService, appLogger)
 	adminDashboardHandler := handlers.NewAdminDashboardHandler(adminDashboardService, appLogger)
+	pronunciationHandler := handlers.NewPronunciationHandler(pronunciationService, appLogger)
 	advancedRecommendationHandler := handlers.NewAdvancedRecommendationHandler(advancedRecommendationService, appLogger)
 	
 	// Initialize webhook handler
@@ -161,8 +336,15 @@ func main() {
 	router.Use(middleware.CORS())
 	router.Use(middleware.Logging(appLogger))
 	router.Use(middleware.Recovery(appLogger))
-	router.Use(middleware.Timeout(cfg.Server.Timeout, appLogger))
+	// Audio streams and exports may take longer than the request timeout
+	untimedRoutes := middleware.UntimedRoutes{}
+	router.Use(middleware.Timeout(cfg.Server.Timeout, appLogger, untimedRoutes))
 	
+	// Serve signed URLs for the local blob store
+	if localStore, ok := blobStore.(*storage.LocalStore); ok {
+		untimedRoutes.Add(router.PathPrefix("/storage/").Handler(http.StripPrefix("/storage", localStore.Handler())))
+	}
+
 	api := router.PathPrefix("/api/v1").Subrouter()
 
 	// Book routes
@@ -217,6 +399,73 @@ func main() {
 	ttsRoutes.HandleFunc("/synthesize", ttsHandler.SynthesizeText).Methods("POST")
 	ttsRoutes.HandleFunc("/voices", ttsHandler.GetVoices).Methods("GET")
 	ttsRoutes.HandleFunc("/preview", ttsHandler.PreviewVoice).Methods("POST")
+	ttsRoutes.HandleFunc("/voice-preset", ttsHandler.SaveVoicePreset).Methods("PUT")
+	ttsRoutes.HandleFunc("/voice-selection", ttsHandler.SelectVoice).Methods("GET")
+
+	// Audio routes (require authentication)
+	audioRoutes := api.PathPrefix("/audio").Subrouter()
+	audioRoutes.Use(authMiddleware.RequireAuth())
+	audioRoutes.HandleFunc("/{id}/alignment", ttsHandler.GetAlignment).Methods("GET")
+	untimedRoutes.Add(audioRoutes.HandleFunc("/{id}/stream", audioHandler.StreamAudio).Methods("GET", "HEAD"))
+	audioRoutes.HandleFunc("/{id}/segments/{index:[0-9]+}.mp3", audioHandler.GetAudioSegment).Methods("GET")
+	audioRoutes.HandleFunc("/{id}/ambient", ambientHandler.MixAudio).Methods("POST")
+
+	// Ambient library routes (require authentication)
+	ambientRoutes := api.PathPrefix("/ambient").Subrouter()
+	ambientRoutes.Use(authMiddleware.RequireAuth())
+	ambientRoutes.HandleFunc("/tracks", ambientHandler.ListTracks).Methods("GET")
+
+	// Playback routes (require authentication)
+	playbackRoutes := api.PathPrefix("/playback").Subrouter()
+	playbackRoutes.Use(authMiddleware.RequireAuth())
+	playbackRoutes.HandleFunc("/sessions", playbackHandler.StartSession).Methods("POST")
+	playbackRoutes.HandleFunc("/sessions/{session_id}/heartbeat", playbackHandler.Heartbeat).Methods("POST")
+	playbackRoutes.HandleFunc("/sessions/{session_id}/end", playbackHandler.EndSession).Methods("POST")
+	playbackRoutes.HandleFunc("/books/{book_id}/resume", playbackHandler.GetResumePosition).Methods("GET")
+
+	// Reading/listening position routes (require authentication)
+	positionRoutes := api.PathPrefix("/books/{id}/position").Subrouter()
+	positionRoutes.Use(authMiddleware.RequireAuth())
+	positionRoutes.HandleFunc("", positionHandler.GetHandoffPosition).Methods("GET")
+	positionRoutes.HandleFunc("/to-audio", positionHandler.TextToAudio).Methods("POST")
+	positionRoutes.HandleFunc("/to-text", positionHandler.AudioToText).Methods("POST")
+
+	// Podcast feed routes. Managing the feed requires authentication; the
+	// feed and its episodes are fetched by podcast apps, which authenticate
+	// with the token in the URL instead.
+	requireAuth := authMiddleware.RequireAuth()
+	api.Handle("/podcast/feed", requireAuth(http.HandlerFunc(podcastHandler.CreateFeed))).Methods("POST")
+	api.Handle("/podcast/feed", requireAuth(http.HandlerFunc(podcastHandler.GetFeed))).Methods("GET")
+	api.Handle("/podcast/feed", requireAuth(http.HandlerFunc(podcastHandler.RevokeFeed))).Methods("DELETE")
+	api.HandleFunc("/podcast/{token}/feed.xml", podcastHandler.GetFeedXML).Methods("GET", "HEAD")
+	untimedRoutes.Add(api.HandleFunc("/podcast/{token}/episodes/{id}", podcastHandler.StreamEpisode).Methods("GET", "HEAD"))
+
+	// Audiobook routes (require authentication)
+	bookAudiobookRoutes := api.PathPrefix("/books/{id}/audiobook").Subrouter()
+	bookAudiobookRoutes.Use(authMiddleware.RequireAuth())
+	bookAudiobookRoutes.HandleFunc("", audiobookHandler.CreateJob).Methods("POST")
+	bookAudiobookRoutes.HandleFunc("", audiobookHandler.GetLatestJob).Methods("GET")
+	bookAudiobookRoutes.HandleFunc("/playlist.m3u8", audioHandler.GetBookPlaylist).Methods("GET")
+	bookAudiobookRoutes.HandleFunc("/cast", audiobookHandler.GetCast).Methods("GET")
+	bookAudiobookRoutes.HandleFunc("/cast", audiobookHandler.SaveCast).Methods("PUT")
+	bookAudiobookRoutes.HandleFunc("/cast", audiobookHandler.DeleteCast).Methods("DELETE")
+	untimedRoutes.Add(api.Handle("/books/{id}/export.m4b", requireAuth(http.HandlerFunc(audioHandler.ExportBookM4B))).Methods("GET", "HEAD"))
+
+	audiobookJobRoutes := api.PathPrefix("/audiobook-jobs").Subrouter()
+	audiobookJobRoutes.Use(authMiddleware.RequireAuth())
+	audiobookJobRoutes.HandleFunc("/{job_id}", audiobookHandler.GetJob).Methods("GET")
+
+	// Offline download routes (require authentication)
+	if offlineDownloadService != nil {
+		downloadHandler := handlers.NewDownloadHandler(offlineDownloadService, appLogger)
+		downloadRoutes := api.PathPrefix("/downloads").Subrouter()
+		downloadRoutes.Use(authMiddleware.RequireAuth())
+		downloadRoutes.HandleFunc("", downloadHandler.CreateDownload).Methods("POST")
+		downloadRoutes.HandleFunc("", downloadHandler.ListDownloads).Methods("GET")
+		downloadRoutes.HandleFunc("/signing-key", downloadHandler.GetSigningKey).Methods("GET")
+		downloadRoutes.HandleFunc("/{id}", downloadHandler.GetDownload).Methods("GET")
+		downloadRoutes.HandleFunc("/{id}", downloadHandler.DeleteDownload).Methods("DELETE")
+	}
 
 	// Notification routes (require authentication)
 	notificationRoutes := api.PathPrefix("/notifications").Subrouter()
@@ -282,6 +531,8 @@ func main() {
 	subscriptionRoutes.HandleFunc("/me", subscriptionHandler.UpdateSubscription).Methods("PUT")
 	subscriptionRoutes.HandleFunc("/me", subscriptionHandler.CancelSubscription).Methods("DELETE")
 	subscriptionRoutes.HandleFunc("/usage", subscriptionHandler.GetUsageStats).Methods("GET")
+	subscriptionRoutes.HandleFunc("/usage/tts", subscriptionHandler.GetTTSQuota).Methods("GET")
+	subscriptionRoutes.HandleFunc("/usage/downloads", subscriptionHandler.GetOfflineDownloadQuota).Methods("GET")
 	subscriptionRoutes.HandleFunc("/features/{feature}/access", subscriptionHandler.CheckFeatureAccess).Methods("GET")
 
 	// Webhook routes (no authentication required for external services)
@@ -307,6 +558,16 @@ func main() {
 	adminDashboardRoutes.HandleFunc("/api/metrics", adminDashboardHandler.GetAPIMetrics).Methods("GET")
 	adminDashboardRoutes.HandleFunc("/errors/analysis", adminDashboardHandler.GetErrorAnalysis).Methods("GET")
 	adminDashboardRoutes.HandleFunc("/ml/performance", adminDashboardHandler.GetModelPerformance).Methods("GET")
+	adminDashboardRoutes.HandleFunc("/audio/storage", adminDashboardHandler.GetAudioStorage).Methods("GET")
+
+	// Pronunciation lexicon routes (require authentication)
+	pronunciationRoutes := adminRoutes.PathPrefix("/pronunciations").Subrouter()
+	pronunciationRoutes.HandleFunc("", pronunciationHandler.ListEntries).Methods("GET")
+	pronunciationRoutes.HandleFunc("", pronunciationHandler.CreateEntry).Methods("POST")
+	pronunciationRoutes.HandleFunc("/preview", pronunciationHandler.Preview).Methods("POST")
+	pronunciationRoutes.HandleFunc("/{id}", pronunciationHandler.GetEntry).Methods("GET")
+	pronunciationRoutes.HandleFunc("/{id}", pronunciationHandler.UpdateEntry).Methods("PUT")
+	pronunciationRoutes.HandleFunc("/{id}", pronunciationHandler.DeleteEntry).Methods("DELETE")
 
 	// Advanced Recommendation routes (require authentication)
 	advancedRecRoutes := recommendationRoutes.PathPrefix("/advanced").Subrouter()
@@ -343,6 +604,34 @@ func main() {
 		IdleTimeout:  cfg.Server.Timeout * 2,
 	}
 
+	// Start background workers
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	workers.Add(3)
+	go func() {
+		defer workers.Done()
+		audiobookService.RunWorkers(workerCtx)
+	}()
+	go func() {
+		defer workers.Done()
+		audioLifecycleService.RunWorker(workerCtx)
+	}()
+	go func() {
+		defer workers.Done()
+		recommendationCacheService.RunWorker(workerCtx)
+	}()
+	if offlineDownloadService != nil {
+		workers.Add(2)
+		go func() {
+			defer workers.Done()
+			offlineDownloadService.RunRevoker(workerCtx)
+		}()
+		go func() {
+			defer workers.Done()
+			offlineDownloadService.RunPackager(workerCtx)
+		}()
+	}
+
 	go func() {
 		appLogger.Info(fmt.Sprintf("Server starting on port %s", cfg.Server.Port))
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -364,5 +653,43 @@ func main() {
 		appLogger.Fatal("Server forced to shutdown")
 	}
 
+	stopWorkers()
+	workers.Wait()
+
 	appLogger.Info("Server exited")
 }
+
+// newTTSEngines creates every TTS engine referenced by the fallback order.
+// Engines that cannot be created are skipped so the server can still start
+// with the remaining ones.
+func newTTSEngines(cfg config.TTSConfig, fallback services.TTSEngineFallback, appLogger *logger.Logger) []services.TTSEngine {
+	names := append([]string{}, fallback.Default...)
+	for _, voiceEngines := range fallback.PerVoice {
+		names = append(names, voiceEngines...)
+	}
+
+	var engines []services.TTSEngine
+	created := make(map[string]bool)
+	for _, name := range names {
+		if created[name] {
+			continue
+		}
+		created[name] = true
+
+		switch name {
+		case services.TTSEngineGoogle:
+			engine, err := services.NewGoogleTTSEngine(cfg.CredentialsPath)
+			if err != nil {
+				appLogger.WithError(err).Warn("Google TTS engine is unavailable")
+				continue
+			}
+			engines = append(engines, engine)
+		case services.TTSEngineLocal:
+			engines = append(engines, services.NewLocalTTSEngine())
+		default:
+			appLogger.Warn("Unknown TTS engine in configuration: " + name)
+		}
+	}
+
+	return engines
+}
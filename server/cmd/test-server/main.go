@@ -20,8 +20,19 @@ func (m *mockBookRepository) Create(ctx context.Context, book *domain.Book) erro
 func (m *mockBookRepository) GetByID(ctx context.Context, id int64) (*domain.Book, error) {
 	return &domain.Book{ID: id, Title: "Test Book", Author: "Test Author"}, nil
 }
+func (m *mockBookRepository) GetByIDs(ctx context.Context, ids []int64) ([]*domain.Book, error) {
+	books := make([]*domain.Book, 0, len(ids))
+	for _, id := range ids {
+		book, _ := m.GetByID(ctx, id)
+		books = append(books, book)
+	}
+	return books, nil
+}
 func (m *mockBookRepository) Update(ctx context.Context, book *domain.Book) error { return nil }
 func (m *mockBookRepository) Delete(ctx context.Context, id int64) error          { return nil }
+func (m *mockBookRepository) UpdateEmbedding(ctx context.Context, bookID int64, embedding []float64) error {
+	return nil
+}
 func (m *mockBookRepository) List(ctx context.Context, req *domain.BookSearchRequest) ([]*domain.Book, int, error) {
 	books := []*domain.Book{
 		{ID: 1, Title: "テスト本1", Author: "著者1"},
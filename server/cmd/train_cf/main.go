@@ -0,0 +1,69 @@
+// Command train_cf trains the collaborative filtering model from the
+// implicit feedback of all users.
+//
+// It replaces the stored user and book factors and the item–item book
+// similarities in one transaction, so it can run on a schedule while the
+// server keeps serving the previous model.
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/ponyo877/roudoku/server/internal/database"
+	"github.com/ponyo877/roudoku/server/pkg/config"
+	"github.com/ponyo877/roudoku/server/pkg/logger"
+	"github.com/ponyo877/roudoku/server/repository"
+	"github.com/ponyo877/roudoku/server/services"
+)
+
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	appLogger, err := logger.New(cfg.Logging)
+	if err != nil {
+		log.Fatalf("Failed to initialize logger: %v", err)
+	}
+	defer appLogger.Sync()
+
+	db, err := database.Connect(database.Config{
+		Host:     cfg.Database.Host,
+		Port:     cfg.Database.Port,
+		Database: cfg.Database.Name,
+		Username: cfg.Database.User,
+		Password: cfg.Database.Password,
+		SSLMode:  cfg.Database.SSLMode,
+	})
+	if err != nil {
+		appLogger.Fatal("Failed to connect to database")
+	}
+	defer db.Close()
+
+	cfService := services.NewCollaborativeFilteringService(
+		repository.NewPostgresUserInteractionRepository(db),
+		repository.NewPostgresCollaborativeFilteringRepository(db),
+		services.CollaborativeFilteringConfig{
+			Factors:         cfg.CollaborativeFiltering.Factors,
+			Iterations:      cfg.CollaborativeFiltering.Iterations,
+			Regularization:  cfg.CollaborativeFiltering.Regularization,
+			Alpha:           cfg.CollaborativeFiltering.Alpha,
+			Neighbours:      cfg.CollaborativeFiltering.Neighbours,
+			MinCommonUsers:  cfg.CollaborativeFiltering.MinCommonUsers,
+			MaxBooksPerUser: cfg.CollaborativeFiltering.MaxBooksPerUser,
+		},
+		appLogger,
+	)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if _, err := cfService.Train(ctx); err != nil {
+		appLogger.WithError(err).Fatal("Collaborative filtering training failed")
+	}
+}
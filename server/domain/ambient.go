@@ -0,0 +1,30 @@
+package domain
+
+// Ambient track selection sources
+const (
+	AmbientSelectionUser    = "user"
+	AmbientSelectionScene   = "scene"
+	AmbientSelectionGenre   = "genre"
+	AmbientSelectionDefault = "default"
+)
+
+// AmbientLibrary is the curated set of background tracks, stored as a JSON
+// manifest next to the tracks in blob storage
+type AmbientLibrary struct {
+	Tracks []AmbientTrack `json:"tracks"`
+}
+
+// AmbientTrack is a loopable 16-bit WAV background track. Keywords found in
+// the narrated text select the track for its scene; Genres match the book.
+type AmbientTrack struct {
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	Key      string   `json:"key"`
+	Scene    string   `json:"scene,omitempty"`
+	Keywords []string `json:"keywords,omitempty"`
+	Genres   []string `json:"genres,omitempty"`
+	// GainDB trims the track's level relative to the rest of the library
+	GainDB float64 `json:"gain_db,omitempty"`
+	// Default marks the track used when nothing else matches
+	Default bool `json:"default,omitempty"`
+}
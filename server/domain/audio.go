@@ -30,19 +30,81 @@ type AudioFile struct {
 	UpdatedAt        time.Time  `json:"updated_at" db:"updated_at"`
 }
 
+// AudioAlignment maps playback time in an audio file to sentences of the
+// text it was synthesized from
+type AudioAlignment struct {
+	AudioFileID uuid.UUID          `json:"audio_file_id" db:"audio_file_id"`
+	DurationMs  int                `json:"duration_ms" db:"duration_ms"`
+	Segments    []AlignmentSegment `json:"segments" db:"segments"`
+	CreatedAt   time.Time          `json:"created_at" db:"created_at"`
+}
+
+// AlignmentSegment is the playback interval of one sentence. Character
+// offsets count runes in the source text.
+type AlignmentSegment struct {
+	StartMs         int `json:"start_ms"`
+	EndMs           int `json:"end_ms"`
+	Sentence        int `json:"sentence"`
+	Paragraph       int `json:"paragraph"`
+	ParagraphOffset int `json:"paragraph_offset"`
+	CharOffset      int `json:"char_offset"`
+	CharLength      int `json:"char_length"`
+}
+
+// SegmentForOffset returns the segment of the sentence containing a
+// character offset, or of the next sentence when the offset falls between
+// sentences
+func (a *AudioAlignment) SegmentForOffset(offset int) (AlignmentSegment, bool) {
+	for _, segment := range a.Segments {
+		if offset < segment.CharOffset+segment.CharLength {
+			return segment, true
+		}
+	}
+	return AlignmentSegment{}, false
+}
+
+// SegmentAt returns the segment of the sentence playing at positionMs, or of
+// the last sentence once playback has passed it
+func (a *AudioAlignment) SegmentAt(positionMs int) (AlignmentSegment, bool) {
+	if len(a.Segments) == 0 {
+		return AlignmentSegment{}, false
+	}
+	for _, segment := range a.Segments {
+		if positionMs < segment.EndMs {
+			return segment, true
+		}
+	}
+	return a.Segments[len(a.Segments)-1], true
+}
+
 // AudioPlaybackSession represents a user's audio playback session
 type AudioPlaybackSession struct {
-	ID              uuid.UUID `json:"id" db:"id"`
-	UserID          uuid.UUID `json:"user_id" db:"user_id"`
-	AudioFileID     uuid.UUID `json:"audio_file_id" db:"audio_file_id"`
-	StartPositionMs int       `json:"start_position_ms" db:"start_position_ms"`
-	EndPositionMs   *int      `json:"end_position_ms,omitempty" db:"end_position_ms"`
-	DurationMs      int       `json:"duration_ms" db:"duration_ms"`
-	PlaybackSpeed   float64   `json:"playback_speed" db:"playback_speed"`
-	Completed       bool      `json:"completed" db:"completed"`
-	DeviceType      *string   `json:"device_type,omitempty" db:"device_type"`
-	CreatedAt       time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
+	ID              uuid.UUID  `json:"id" db:"id"`
+	UserID          uuid.UUID  `json:"user_id" db:"user_id"`
+	AudioFileID     uuid.UUID  `json:"audio_file_id" db:"audio_file_id"`
+	StartPositionMs int        `json:"start_position_ms" db:"start_position_ms"`
+	EndPositionMs   *int       `json:"end_position_ms,omitempty" db:"end_position_ms"`
+	DurationMs      int        `json:"duration_ms" db:"duration_ms"`
+	PlaybackSpeed   float64    `json:"playback_speed" db:"playback_speed"`
+	Completed       bool       `json:"completed" db:"completed"`
+	DeviceType      *string    `json:"device_type,omitempty" db:"device_type"`
+	RecordedMs      int        `json:"recorded_ms" db:"recorded_ms"`
+	EndedAt         *time.Time `json:"ended_at,omitempty" db:"ended_at"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// IsEnded returns true if the playback session has been ended
+func (s *AudioPlaybackSession) IsEnded() bool {
+	return s.EndedAt != nil
+}
+
+// CurrentPositionMs returns the last reported playback position
+func (s *AudioPlaybackSession) CurrentPositionMs() int {
+	if s.EndPositionMs != nil {
+		return *s.EndPositionMs
+	}
+	return s.StartPositionMs
 }
 
 // NotificationPreferences represents user notification settings
@@ -125,4 +187,48 @@ type TTSConfig struct {
 	Speed      float32 `json:"speed"`
 	Pitch      float32 `json:"pitch"`
 	VolumeGain float32 `json:"volume_gain"`
-}
\ No newline at end of file
+}
+
+// VoiceCast assigns voices to the narration and dialogue of a book.
+// Character voices with an empty language or zero speed use the narrator's.
+type VoiceCast struct {
+	Narrator   TTSConfig       `json:"narrator"`
+	Characters []CastCharacter `json:"characters"`
+}
+
+// CastCharacter is a voice for dialogue. Lines are given to the character
+// whose name appears in the surrounding narration; unnamed lines alternate
+// between the characters.
+type CastCharacter struct {
+	Name  string    `json:"name"`
+	Voice TTSConfig `json:"voice"`
+}
+
+// BookVoiceCast is the cast a user has chosen for a book
+type BookVoiceCast struct {
+	UserID    uuid.UUID `json:"user_id" db:"user_id"`
+	BookID    int64     `json:"book_id" db:"book_id"`
+	Cast      VoiceCast `json:"cast" db:"cast"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// AudioLifecycleRun records one pass of the audio cache lifecycle worker
+type AudioLifecycleRun struct {
+	ID             uuid.UUID `json:"id" db:"id"`
+	StartedAt      time.Time `json:"started_at" db:"started_at"`
+	FinishedAt     time.Time `json:"finished_at" db:"finished_at"`
+	ExpiredFiles   int       `json:"expired_files" db:"expired_files"`
+	EvictedFiles   int       `json:"evicted_files" db:"evicted_files"`
+	ReclaimedBytes int64     `json:"reclaimed_bytes" db:"reclaimed_bytes"`
+	FailedFiles    int       `json:"failed_files" db:"failed_files"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+}
+
+// AudioStorageUsage is the amount of synthesized audio kept for a user, or
+// for all users when UserID is uuid.Nil
+type AudioStorageUsage struct {
+	UserID uuid.UUID `json:"user_id" db:"user_id"`
+	Files  int       `json:"files" db:"files"`
+	Bytes  int64     `json:"bytes" db:"bytes"`
+}
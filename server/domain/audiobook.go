@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Audiobook job and chapter task statuses
+const (
+	AudiobookStatusQueued     = "queued"
+	AudiobookStatusProcessing = "processing"
+	AudiobookStatusCompleted  = "completed"
+	AudiobookStatusFailed     = "failed"
+)
+
+// AudiobookJob represents a request to narrate every chapter of a book
+type AudiobookJob struct {
+	ID                uuid.UUID  `json:"id" db:"id"`
+	UserID            uuid.UUID  `json:"user_id" db:"user_id"`
+	BookID            int64      `json:"book_id" db:"book_id"`
+	VoiceConfig       string     `json:"voice_config" db:"voice_config"` // JSONB stored as string
+	Status            string     `json:"status" db:"status"`
+	TotalChapters     int        `json:"total_chapters" db:"total_chapters"`
+	CompletedChapters int        `json:"completed_chapters" db:"completed_chapters"`
+	FailedChapters    int        `json:"failed_chapters" db:"failed_chapters"`
+	ErrorMessage      *string    `json:"error_message,omitempty" db:"error_message"`
+	CompletedAt       *time.Time `json:"completed_at,omitempty" db:"completed_at"`
+	CreatedAt         time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// IsFinished reports whether every chapter has either completed or failed
+func (j *AudiobookJob) IsFinished() bool {
+	return j.CompletedChapters+j.FailedChapters >= j.TotalChapters
+}
+
+// AudiobookChapterTask is a queued synthesis of a single chapter
+type AudiobookChapterTask struct {
+	ID            uuid.UUID  `json:"id" db:"id"`
+	JobID         uuid.UUID  `json:"job_id" db:"job_id"`
+	ChapterID     uuid.UUID  `json:"chapter_id" db:"chapter_id"`
+	Position      int        `json:"position" db:"position"`
+	Status        string     `json:"status" db:"status"`
+	Attempts      int        `json:"attempts" db:"attempts"`
+	MaxAttempts   int        `json:"max_attempts" db:"max_attempts"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" db:"next_attempt_at"`
+	LockedBy      *string    `json:"locked_by,omitempty" db:"locked_by"`
+	LockedAt      *time.Time `json:"locked_at,omitempty" db:"locked_at"`
+	AudioFileID   *uuid.UUID `json:"audio_file_id,omitempty" db:"audio_file_id"`
+	ErrorMessage  *string    `json:"error_message,omitempty" db:"error_message"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
+}
@@ -0,0 +1,103 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Offline download statuses. Pending packages are waiting to be built and
+// hold a slot like active ones.
+const (
+	OfflineDownloadStatusPending = "pending"
+	OfflineDownloadStatusActive  = "active"
+	OfflineDownloadStatusFailed  = "failed"
+	OfflineDownloadStatusRevoked = "revoked"
+	OfflineDownloadStatusDeleted = "deleted"
+)
+
+// OfflinePackageVersion is the layout version written to package manifests
+const OfflinePackageVersion = 2
+
+// OfflineDownload is a book package a user has downloaded for offline reading
+type OfflineDownload struct {
+	ID                uuid.UUID  `json:"id" db:"id"`
+	UserID            uuid.UUID  `json:"user_id" db:"user_id"`
+	BookID            int64      `json:"book_id" db:"book_id"`
+	Status            string     `json:"status" db:"status"`
+	PackagePath       string     `json:"package_path" db:"package_path"`
+	PackageSizeBytes  int64      `json:"package_size_bytes" db:"package_size_bytes"`
+	ManifestSHA256    string     `json:"manifest_sha256" db:"manifest_sha256"`
+	ChapterCount      int        `json:"chapter_count" db:"chapter_count"`
+	AudioChapterCount int        `json:"audio_chapter_count" db:"audio_chapter_count"`
+	SkipAudio         bool       `json:"skip_audio" db:"skip_audio"`
+	Attempts          int        `json:"attempts" db:"attempts"`
+	LockedAt          *time.Time `json:"locked_at,omitempty" db:"locked_at"`
+	ErrorMessage      *string    `json:"error_message,omitempty" db:"error_message"`
+	ExpiresAt         time.Time  `json:"expires_at" db:"expires_at"`
+	RevokedAt         *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	RevokeReason      *string    `json:"revoke_reason,omitempty" db:"revoke_reason"`
+	CreatedAt         time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// IsActive reports whether the package may still be used offline
+func (d *OfflineDownload) IsActive() bool {
+	return d.Status == OfflineDownloadStatusActive
+}
+
+// IsHeld reports whether the package uses one of the user's download slots
+func (d *OfflineDownload) IsHeld() bool {
+	return d.Status == OfflineDownloadStatusPending || d.Status == OfflineDownloadStatusActive
+}
+
+// OfflinePackageManifest is manifest.json at the root of a download package.
+// manifest.sig holds the hex Ed25519 signature of the manifest bytes, made
+// with the key whose public half GET /downloads/signing-key returns.
+type OfflinePackageManifest struct {
+	Version    int                     `json:"version"`
+	KeyID      string                  `json:"key_id"`
+	DownloadID uuid.UUID               `json:"download_id"`
+	UserID     uuid.UUID               `json:"user_id"`
+	Book       OfflinePackageBook      `json:"book"`
+	Chapters   []OfflinePackageChapter `json:"chapters"`
+	Files      []OfflinePackageFile    `json:"files"`
+	IssuedAt   time.Time               `json:"issued_at"`
+	ExpiresAt  time.Time               `json:"expires_at"`
+}
+
+// OfflinePackageBook describes the packaged book
+type OfflinePackageBook struct {
+	ID     int64   `json:"id"`
+	Title  string  `json:"title"`
+	Author string  `json:"author"`
+	Genre  *string `json:"genre,omitempty"`
+}
+
+// OfflinePackageChapter points at a chapter's files within the package
+type OfflinePackageChapter struct {
+	ID              uuid.UUID `json:"id"`
+	Position        int       `json:"position"`
+	Title           string    `json:"title"`
+	TextPath        string    `json:"text_path"`
+	RubyPath        string    `json:"ruby_path"`
+	AudioPath       string    `json:"audio_path,omitempty"`
+	AudioFormat     string    `json:"audio_format,omitempty"`
+	DurationSeconds float64   `json:"duration_seconds,omitempty"`
+}
+
+// OfflinePackageFile is the size and checksum of a file in the package
+type OfflinePackageFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// OfflinePackageRuby is the reading of a word in a chapter, in character
+// offsets into the chapter text
+type OfflinePackageRuby struct {
+	Start   int    `json:"start"`
+	End     int    `json:"end"`
+	Base    string `json:"base"`
+	Reading string `json:"reading"`
+}
@@ -0,0 +1,23 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PodcastFeed is a user's private podcast feed. The feed URL carries a
+// secret token of which only the hash is stored.
+type PodcastFeed struct {
+	ID             uuid.UUID  `json:"id" db:"id"`
+	UserID         uuid.UUID  `json:"user_id" db:"user_id"`
+	TokenHash      string     `json:"-" db:"token_hash"`
+	LastAccessedAt *time.Time `json:"last_accessed_at,omitempty" db:"last_accessed_at"`
+	RevokedAt      *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+}
+
+// IsActive reports whether the feed still answers
+func (f *PodcastFeed) IsActive() bool {
+	return f.RevokedAt == nil
+}
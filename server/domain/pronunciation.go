@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Pronunciation entry scopes, from the most general to the most specific
+const (
+	PronunciationScopeGlobal = "global"
+	PronunciationScopeAuthor = "author"
+	PronunciationScopeBook   = "book"
+)
+
+// PronunciationEntry tells narration how to read a surface form, either
+// everywhere or only in the books of one author or in one book
+type PronunciationEntry struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	Scope     string    `json:"scope" db:"scope"`
+	BookID    *int64    `json:"book_id,omitempty" db:"book_id"`
+	Author    *string   `json:"author,omitempty" db:"author"`
+	Surface   string    `json:"surface" db:"surface"`
+	Reading   string    `json:"reading" db:"reading"`
+	Note      *string   `json:"note,omitempty" db:"note"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// PronunciationFilter narrows a listing of pronunciation entries
+type PronunciationFilter struct {
+	Scope   string
+	BookID  *int64
+	Author  string
+	Surface string
+	Limit   int
+	Offset  int
+}
@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"context"
 	"time"
 
 	"github.com/google/uuid"
@@ -24,6 +25,13 @@ type UserPreferences struct {
 	UpdatedAt              time.Time `json:"updated_at" db:"updated_at"`
 }
 
+// Dimensions of the book vectors stored in book_vectors
+const (
+	BookContentVectorDims = 768
+	BookGenreVectorDims   = 50
+	BookStyleVectorDims   = 100
+)
+
 // BookVector represents ML features for a book
 type BookVector struct {
 	BookID          int64     `json:"book_id" db:"book_id"`
@@ -34,7 +42,24 @@ type BookVector struct {
 	PopularityScore float64   `json:"popularity_score" db:"popularity_score"`
 	QualityScore    float64   `json:"quality_score" db:"quality_score"`
 	NoveltyScore    float64   `json:"novelty_score" db:"novelty_score"`
-	LastUpdated     time.Time `json:"last_updated" db:"last_updated"`
+	// EmbeddingModel identifies the embedder that produced ContentVector;
+	// vectors of different models are not comparable
+	EmbeddingModel string `json:"embedding_model" db:"embedding_model"`
+	// ContentHash is the SHA-256 of the chapter text that was embedded
+	ContentHash string    `json:"content_hash" db:"content_hash"`
+	LastUpdated time.Time `json:"last_updated" db:"last_updated"`
+}
+
+// EmbedderSnapshot holds the corpus statistics an embedder was fitted to
+type EmbedderSnapshot struct {
+	// Family names the embedder features the statistics apply to
+	Family string `json:"family" db:"family"`
+	// Version numbers the snapshots of a family from 1
+	Version int `json:"version" db:"version"`
+	// Documents is how many books the statistics were computed from
+	Documents  int       `json:"documents" db:"documents"`
+	Statistics []byte    `json:"-" db:"statistics"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
 }
 
 // UserInteraction represents user's interaction with books
@@ -61,19 +86,67 @@ type UserSimilarity struct {
 	LastCalculated    time.Time `json:"last_calculated" db:"last_calculated"`
 }
 
+// BookSimilarity is the item–item similarity of two books, the cosine of
+// the implicit preferences of the users who interacted with them
+type BookSimilarity struct {
+	BookID        int64     `json:"book_id" db:"book_id"`
+	SimilarBookID int64     `json:"similar_book_id" db:"similar_book_id"`
+	Similarity    float64   `json:"similarity" db:"similarity"`
+	CommonUsers   int       `json:"common_users" db:"common_users"`
+	CalculatedAt  time.Time `json:"calculated_at" db:"calculated_at"`
+}
+
+// CFModel describes a trained collaborative filtering model. Its user and
+// book factors are stored with it and replaced by the next training run.
+type CFModel struct {
+	ID             uuid.UUID `json:"id" db:"id"`
+	Factors        int       `json:"factors" db:"factors"`
+	Iterations     int       `json:"iterations" db:"iterations"`
+	Regularization float64   `json:"regularization" db:"regularization"`
+	Alpha          float64   `json:"alpha" db:"alpha"`
+	Users          int       `json:"users" db:"users"`
+	Books          int       `json:"books" db:"books"`
+	Interactions   int       `json:"interactions" db:"interactions"`
+	Loss           float64   `json:"loss" db:"loss"`
+	TrainedAt      time.Time `json:"trained_at" db:"trained_at"`
+}
+
+// CF score sources
+const (
+	CFSourceFactorization = "factorization"
+	CFSourceItemItem      = "item_item"
+)
+
+// CFScore is a collaborative filtering score of a book for a user
+type CFScore struct {
+	BookID int64   `json:"book_id"`
+	Score  float64 `json:"score"`
+	// Source is the model that produced the score
+	Source string `json:"source"`
+}
+
 // RecommendationCache represents cached recommendations
 type RecommendationCache struct {
 	ID                 uuid.UUID              `json:"id" db:"id"`
 	UserID             uuid.UUID              `json:"user_id" db:"user_id"`
 	RecommendationType string                 `json:"recommendation_type" db:"recommendation_type"`
+	ParamsHash         string                 `json:"params_hash" db:"params_hash"`
 	BookIDs            []int64                `json:"book_ids" db:"book_ids"`
 	Scores             []float64              `json:"scores" db:"scores"`
 	Reasoning          map[string]interface{} `json:"reasoning,omitempty" db:"reasoning"`
+	Response           []byte                 `json:"-" db:"response"` // JSON encoded response as served
 	ContextFilters     map[string]interface{} `json:"context_filters,omitempty" db:"context_filters"`
 	ExpiresAt          time.Time              `json:"expires_at" db:"expires_at"`
 	CreatedAt          time.Time              `json:"created_at" db:"created_at"`
 }
 
+// RecommendationCacheInvalidation describes cached recommendations that were
+// removed. A nil UserID covers all users and empty Types cover all types.
+type RecommendationCacheInvalidation struct {
+	UserID *uuid.UUID `json:"user_id"`
+	Types  []string   `json:"types"`
+}
+
 // RecommendationFeedback represents user feedback on recommendations
 type RecommendationFeedback struct {
 	ID                 uuid.UUID              `json:"id" db:"id"`
@@ -101,6 +174,7 @@ type SubscriptionPlan struct {
 	MaxPremiumBooks         int                    `json:"max_premium_books" db:"max_premium_books"`
 	MaxTTSMinutesPerDay     int                    `json:"max_tts_minutes_per_day" db:"max_tts_minutes_per_day"`
 	MaxOfflineDownloads     int                    `json:"max_offline_downloads" db:"max_offline_downloads"`
+	MaxAudioStorageMB       int                    `json:"max_audio_storage_mb" db:"max_audio_storage_mb"`
 	HasAdvancedAnalytics    bool                   `json:"has_advanced_analytics" db:"has_advanced_analytics"`
 	HasAIRecommendations    bool                   `json:"has_ai_recommendations" db:"has_ai_recommendations"`
 	HasPrioritySupport      bool                   `json:"has_priority_support" db:"has_priority_support"`
@@ -173,6 +247,8 @@ func (s *UserSubscription) CanAccessFeature(feature string) bool {
 		return s.Plan.MaxPremiumBooks == -1 || s.Plan.MaxPremiumBooks > 0
 	case "unlimited_tts":
 		return s.Plan.MaxTTSMinutesPerDay == -1
+	case "offline_downloads":
+		return s.Plan.MaxOfflineDownloads == -1 || s.Plan.MaxOfflineDownloads > 0
 	case "advanced_analytics":
 		return s.Plan.HasAdvancedAnalytics
 	case "ai_recommendations":
@@ -238,8 +314,12 @@ type ExperimentInteraction struct {
 
 // RecommendationAlgorithm represents the interface for recommendation algorithms
 type RecommendationAlgorithm interface {
-	GenerateRecommendations(userID uuid.UUID, count int, filters map[string]interface{}) ([]*BookRecommendation, error)
+	// GenerateRecommendations returns at most count recommendations, best
+	// first. filters holds the request filters keyed by their JSON names.
+	GenerateRecommendations(ctx context.Context, userID uuid.UUID, count int, filters map[string]interface{}) ([]*BookRecommendation, error)
 	GetAlgorithmName() string
+	// SupportsRealtime reports whether new interactions change the
+	// recommendations without waiting for a batch job
 	SupportsRealtime() bool
 }
 
@@ -259,6 +339,8 @@ type RecommendationConfig struct {
 	CollaborativeWeight float64 `json:"collaborative_weight"`
 	PopularityWeight   float64 `json:"popularity_weight"`
 	NoveltyWeight      float64 `json:"novelty_weight"`
+	SequentialWeight   float64 `json:"sequential_weight"`
+	SwipeAffinityWeight float64 `json:"swipe_affinity_weight"`
 	DiversityThreshold float64 `json:"diversity_threshold"`
 	MinScore           float64 `json:"min_score"`
 	UseRealtime        bool    `json:"use_realtime"`
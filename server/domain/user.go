@@ -19,11 +19,15 @@ type User struct {
 	UpdatedAt             time.Time
 }
 
-// VoicePreset represents user's voice preferences
+// VoicePreset represents user's voice preferences. Pitch runs from 0 to 1
+// with 0.5 as the voice's natural pitch. Language and Voice are set when the
+// user saved a specific voice; otherwise Gender selects one.
 type VoicePreset struct {
-	Gender string
-	Pitch  float64
-	Speed  float64
+	Gender   string
+	Pitch    float64
+	Speed    float64
+	Language string
+	Voice    string
 }
 
 // SubscriptionStatus represents user's subscription status
@@ -762,4 +762,34 @@ type ABTestOverallImpact struct {
 	SignificantTests      int     `json:"significant_tests"`
 	TotalTests            int     `json:"total_tests"`
 	EstimatedRevenueLift  float64 `json:"estimated_revenue_lift"`
-}
\ No newline at end of file
+}
+
+// AudioStorageResponse represents synthesized audio storage and cache eviction
+type AudioStorageResponse struct {
+	TotalFiles  int                    `json:"total_files"`
+	TotalBytes  int64                  `json:"total_bytes"`
+	Reclaimed   []AudioReclaimedPeriod `json:"reclaimed"`
+	RecentRuns  []AudioLifecycleRun    `json:"recent_runs"`
+	GeneratedAt time.Time              `json:"generated_at"`
+}
+
+// AudioReclaimedPeriod represents the space freed by the lifecycle worker over a period
+type AudioReclaimedPeriod struct {
+	Period         string `json:"period"`
+	Runs           int    `json:"runs"`
+	ExpiredFiles   int    `json:"expired_files"`
+	EvictedFiles   int    `json:"evicted_files"`
+	ReclaimedBytes int64  `json:"reclaimed_bytes"`
+	FailedFiles    int    `json:"failed_files"`
+}
+
+// AudioLifecycleRun represents one run of the audio lifecycle worker
+type AudioLifecycleRun struct {
+	ID             uuid.UUID `json:"id"`
+	StartedAt      time.Time `json:"started_at"`
+	FinishedAt     time.Time `json:"finished_at"`
+	ExpiredFiles   int       `json:"expired_files"`
+	EvictedFiles   int       `json:"evicted_files"`
+	ReclaimedBytes int64     `json:"reclaimed_bytes"`
+	FailedFiles    int       `json:"failed_files"`
+}
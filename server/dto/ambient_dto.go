@@ -0,0 +1,38 @@
+package dto
+
+import (
+	"github.com/google/uuid"
+)
+
+// AmbientMixRequest represents a request to lay an ambient track under
+// narration. Without TrackID the track is chosen from the text and book.
+type AmbientMixRequest struct {
+	TrackID        string   `json:"track_id,omitempty" validate:"omitempty,max=100"`
+	BedVolumeDB    *float64 `json:"bed_volume_db,omitempty" validate:"omitempty,min=-40,max=0"`
+	DuckDB         *float64 `json:"duck_db,omitempty" validate:"omitempty,min=0,max=30"`
+	FadeInSeconds  *float64 `json:"fade_in_seconds,omitempty" validate:"omitempty,min=0,max=30"`
+	FadeOutSeconds *float64 `json:"fade_out_seconds,omitempty" validate:"omitempty,min=0,max=30"`
+}
+
+// AmbientMixResponse represents narration mixed with an ambient track
+type AmbientMixResponse struct {
+	AudioFileID       uuid.UUID            `json:"audio_file_id"`
+	SourceAudioFileID uuid.UUID            `json:"source_audio_file_id"`
+	Track             AmbientTrackResponse `json:"track"`
+	Selection         string               `json:"selection"`
+	Duration          float64              `json:"duration"`
+	Cached            bool                 `json:"cached"`
+}
+
+// AmbientTrackResponse represents a track of the ambient library
+type AmbientTrackResponse struct {
+	ID     string   `json:"id"`
+	Name   string   `json:"name"`
+	Scene  string   `json:"scene,omitempty"`
+	Genres []string `json:"genres,omitempty"`
+}
+
+// AmbientTrackListResponse represents the ambient library
+type AmbientTrackListResponse struct {
+	Tracks []AmbientTrackResponse `json:"tracks"`
+}
@@ -0,0 +1,74 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CreateAudiobookJobRequest represents a request to narrate a whole book
+type CreateAudiobookJobRequest struct {
+	Language   string  `json:"language" validate:"omitempty"`
+	Voice      string  `json:"voice" validate:"omitempty"`
+	Speed      float32 `json:"speed" validate:"omitempty,min=0.25,max=4.0"`
+	Pitch      float32 `json:"pitch" validate:"omitempty,min=-20.0,max=20.0"`
+	VolumeGain float32 `json:"volume_gain" validate:"omitempty,min=-96.0,max=16.0"`
+	Timezone   string  `json:"timezone" validate:"omitempty"` // IANA zone of the listener
+}
+
+// AudiobookJobResponse represents the progress of an audiobook job
+type AudiobookJobResponse struct {
+	ID                uuid.UUID                  `json:"id"`
+	BookID            int64                      `json:"book_id"`
+	Status            string                     `json:"status"`
+	TotalChapters     int                        `json:"total_chapters"`
+	CompletedChapters int                        `json:"completed_chapters"`
+	FailedChapters    int                        `json:"failed_chapters"`
+	Progress          float64                    `json:"progress"` // 0.0 - 1.0
+	Chapters          []AudiobookChapterProgress `json:"chapters,omitempty"`
+	VoiceRule         *VoiceRule                 `json:"voice_rule,omitempty"` // Policy rule that chose the voice
+	ErrorMessage      *string                    `json:"error_message,omitempty"`
+	CompletedAt       *time.Time                 `json:"completed_at,omitempty"`
+	CreatedAt         time.Time                  `json:"created_at"`
+	UpdatedAt         time.Time                  `json:"updated_at"`
+}
+
+// AudiobookChapterProgress represents the synthesis state of one chapter
+type AudiobookChapterProgress struct {
+	ChapterID    uuid.UUID  `json:"chapter_id"`
+	Position     int        `json:"position"`
+	Status       string     `json:"status"`
+	Attempts     int        `json:"attempts"`
+	AudioFileID  *uuid.UUID `json:"audio_file_id,omitempty"`
+	ErrorMessage *string    `json:"error_message,omitempty"`
+}
+
+// CastVoice represents the voice settings of a cast member
+type CastVoice struct {
+	Language   string  `json:"language" validate:"omitempty"`
+	Voice      string  `json:"voice" validate:"omitempty"`
+	Speed      float32 `json:"speed" validate:"omitempty,min=0.25,max=4.0"`
+	Pitch      float32 `json:"pitch" validate:"omitempty,min=-20.0,max=20.0"`
+	VolumeGain float32 `json:"volume_gain" validate:"omitempty,min=-96.0,max=16.0"`
+}
+
+// CastCharacter represents a character voice for dialogue
+type CastCharacter struct {
+	Name  string    `json:"name" validate:"omitempty,max=50"`
+	Voice CastVoice `json:"voice"`
+}
+
+// SaveVoiceCastRequest represents a request to choose the voices of a book.
+// Dialogue in 「」 is read by the characters and everything else by the narrator.
+type SaveVoiceCastRequest struct {
+	Narrator   CastVoice       `json:"narrator"`
+	Characters []CastCharacter `json:"characters" validate:"max=8,dive"`
+}
+
+// VoiceCastResponse represents the voices chosen for a book
+type VoiceCastResponse struct {
+	BookID     int64           `json:"book_id"`
+	Narrator   CastVoice       `json:"narrator"`
+	Characters []CastCharacter `json:"characters"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+}
@@ -0,0 +1,45 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CreateOfflineDownloadRequest represents a request to package a book for offline reading
+type CreateOfflineDownloadRequest struct {
+	BookID int64 `json:"book_id" validate:"required,min=1"`
+	// SkipAudio leaves narration out of the package to keep it small
+	SkipAudio bool `json:"skip_audio"`
+}
+
+// OfflineDownloadResponse represents a downloaded book package
+type OfflineDownloadResponse struct {
+	ID                uuid.UUID  `json:"id"`
+	BookID            int64      `json:"book_id"`
+	Status            string     `json:"status"`
+	DownloadURL       string     `json:"download_url,omitempty"` // Only while the package is active
+	PackageSizeBytes  int64      `json:"package_size_bytes"`
+	ManifestSHA256    string     `json:"manifest_sha256"`
+	ChapterCount      int        `json:"chapter_count"`
+	AudioChapterCount int        `json:"audio_chapter_count"`
+	ErrorMessage      *string    `json:"error_message,omitempty"` // Why packaging failed
+	ExpiresAt         time.Time  `json:"expires_at"`              // Clients should check the status again by this time
+	RevokedAt         *time.Time `json:"revoked_at,omitempty"`
+	RevokeReason      *string    `json:"revoke_reason,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// OfflineDownloadListResponse represents a user's active packages and their allowance
+type OfflineDownloadListResponse struct {
+	Downloads []OfflineDownloadResponse    `json:"downloads"`
+	Quota     OfflineDownloadQuotaResponse `json:"quota"`
+}
+
+// OfflineSigningKeyResponse is the public key that verifies package manifests
+type OfflineSigningKeyResponse struct {
+	Algorithm string `json:"algorithm"`  // Always "Ed25519"
+	KeyID     string `json:"key_id"`     // Matches key_id in manifest.json
+	PublicKey string `json:"public_key"` // Base64 (standard encoding) of the 32 byte key
+}
@@ -0,0 +1,53 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// StartPlaybackRequest represents a request to start listening to an audio file
+type StartPlaybackRequest struct {
+	AudioFileID   uuid.UUID `json:"audio_file_id" validate:"required"`
+	PositionMs    int       `json:"position_ms" validate:"min=0"`
+	PlaybackSpeed float64   `json:"playback_speed" validate:"omitempty,min=0.25,max=4.0"`
+	DeviceType    *string   `json:"device_type,omitempty" validate:"omitempty,oneof=ios android web"`
+}
+
+// PlaybackHeartbeatRequest reports the player state while audio is playing
+type PlaybackHeartbeatRequest struct {
+	PositionMs    int     `json:"position_ms" validate:"min=0"`
+	PlaybackSpeed float64 `json:"playback_speed" validate:"omitempty,min=0.25,max=4.0"`
+}
+
+// EndPlaybackRequest represents a request to end a playback session
+type EndPlaybackRequest struct {
+	PositionMs int  `json:"position_ms" validate:"min=0"`
+	Completed  bool `json:"completed"`
+}
+
+// PlaybackSessionResponse represents a playback session
+type PlaybackSessionResponse struct {
+	ID              uuid.UUID  `json:"id"`
+	AudioFileID     uuid.UUID  `json:"audio_file_id"`
+	StartPositionMs int        `json:"start_position_ms"`
+	PositionMs      int        `json:"position_ms"`
+	ListenedMs      int        `json:"listened_ms"`
+	PlaybackSpeed   float64    `json:"playback_speed"`
+	Completed       bool       `json:"completed"`
+	DeviceType      *string    `json:"device_type,omitempty"`
+	EndedAt         *time.Time `json:"ended_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// ResumePositionResponse tells the player where to continue a book
+type ResumePositionResponse struct {
+	BookID        int64      `json:"book_id"`
+	ChapterID     *uuid.UUID `json:"chapter_id,omitempty"`
+	AudioFileID   uuid.UUID  `json:"audio_file_id"`
+	PositionMs    int        `json:"position_ms"`
+	PlaybackSpeed float64    `json:"playback_speed"`
+	Completed     bool       `json:"completed"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,15 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PodcastFeedResponse represents a user's private podcast feed
+type PodcastFeedResponse struct {
+	ID             uuid.UUID  `json:"id"`
+	FeedURL        string     `json:"feed_url,omitempty"` // Only returned when the feed is created
+	LastAccessedAt *time.Time `json:"last_accessed_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
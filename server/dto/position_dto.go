@@ -0,0 +1,46 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Position sources of a handoff position
+const (
+	PositionSourceReading   = "reading"
+	PositionSourceListening = "listening"
+)
+
+// TextToAudioRequest asks for the audio position narrating a text position.
+// TextOffset counts characters (runes) of the book's chapters in reading
+// order, as ReadingSession.CurrentPos does.
+type TextToAudioRequest struct {
+	TextOffset int `json:"text_offset" validate:"min=0"`
+}
+
+// AudioToTextRequest asks for the text position narrated at a playback
+// position of a chapter's audio
+type AudioToTextRequest struct {
+	AudioFileID uuid.UUID `json:"audio_file_id" validate:"required"`
+	PositionMs  int       `json:"position_ms" validate:"min=0"`
+}
+
+// BookPositionResponse is a position in a book both as a text offset and as
+// a playback position in the chapter's audio. Audio fields are omitted when
+// the chapter has not been narrated.
+type BookPositionResponse struct {
+	BookID        int64      `json:"book_id"`
+	ChapterID     uuid.UUID  `json:"chapter_id"`
+	TextOffset    int        `json:"text_offset"`
+	ChapterOffset int        `json:"chapter_offset"`
+	AudioFileID   *uuid.UUID `json:"audio_file_id,omitempty"`
+	PositionMs    *int       `json:"position_ms,omitempty"`
+	// Aligned is false when the position was estimated from the chapter's
+	// length and audio duration because no sentence timings are stored
+	Aligned bool `json:"aligned"`
+	// Source and UpdatedAt tell which mode a handoff position was last
+	// reported by
+	Source    string     `json:"source,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
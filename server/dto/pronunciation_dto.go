@@ -0,0 +1,74 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PronunciationEntryRequest represents a request to create or replace a
+// pronunciation lexicon entry. BookID is required for the book scope and
+// Author for the author scope.
+type PronunciationEntryRequest struct {
+	Scope   string  `json:"scope" validate:"required,oneof=global author book"`
+	BookID  *int64  `json:"book_id,omitempty" validate:"omitempty,min=1"`
+	Author  *string `json:"author,omitempty" validate:"omitempty,min=1,max=255"`
+	Surface string  `json:"surface" validate:"required,max=100"`
+	Reading string  `json:"reading" validate:"required,max=200"`
+	Note    *string `json:"note,omitempty" validate:"omitempty,max=500"`
+}
+
+// PronunciationListRequest represents a search of the pronunciation lexicon
+type PronunciationListRequest struct {
+	Scope  string `json:"scope,omitempty" validate:"omitempty,oneof=global author book"`
+	BookID *int64 `json:"book_id,omitempty"`
+	Author string `json:"author,omitempty"`
+	Query  string `json:"query,omitempty"`
+	Limit  int    `json:"limit,omitempty" validate:"omitempty,min=1,max=100"`
+	Offset int    `json:"offset,omitempty" validate:"omitempty,min=0"`
+}
+
+// PronunciationEntryResponse represents a pronunciation lexicon entry
+type PronunciationEntryResponse struct {
+	ID        uuid.UUID `json:"id"`
+	Scope     string    `json:"scope"`
+	BookID    *int64    `json:"book_id,omitempty"`
+	Author    *string   `json:"author,omitempty"`
+	Surface   string    `json:"surface"`
+	Reading   string    `json:"reading"`
+	Note      *string   `json:"note,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// PronunciationListResponse represents a paginated list of lexicon entries
+type PronunciationListResponse struct {
+	Entries []*PronunciationEntryResponse `json:"entries"`
+	Total   int                           `json:"total"`
+	Limit   int                           `json:"limit"`
+	Offset  int                           `json:"offset"`
+	HasMore bool                          `json:"has_more"`
+}
+
+// PronunciationPreviewRequest asks how a text will be read, optionally with
+// the author and book entries of a book applied
+type PronunciationPreviewRequest struct {
+	Text   string `json:"text" validate:"required,max=5000"`
+	BookID *int64 `json:"book_id,omitempty" validate:"omitempty,min=1"`
+}
+
+// PronunciationMatch is a lexicon entry applied to the previewed text
+type PronunciationMatch struct {
+	Surface string `json:"surface"`
+	Reading string `json:"reading"`
+	Scope   string `json:"scope"`
+}
+
+// PronunciationPreviewResponse shows the SSML sent to engines and the text
+// as it will be spoken
+type PronunciationPreviewResponse struct {
+	Text    string               `json:"text"`
+	SSML    string               `json:"ssml"`
+	Reading string               `json:"reading"`
+	Matches []PronunciationMatch `json:"matches"`
+}
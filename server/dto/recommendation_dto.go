@@ -10,7 +10,7 @@ import (
 
 // RecommendationRequest represents a request for book recommendations
 type RecommendationRequest struct {
-	RecommendationType string   `json:"recommendation_type" validate:"required,oneof=content_based collaborative hybrid trending similar_users personalized"`
+	RecommendationType string   `json:"recommendation_type" validate:"required"`
 	Count              int      `json:"count" validate:"min=1,max=50"`
 	Filters            *RecommendationFilters `json:"filters,omitempty"`
 	Context            *RecommendationContext `json:"context,omitempty"`
@@ -121,8 +121,9 @@ type RecommendationFeedbackRequest struct {
 // SimilarBooksRequest represents request for similar books
 type SimilarBooksRequest struct {
 	BookID         int64                  `json:"book_id" validate:"required"`
+	UserID         uuid.UUID              `json:"-"`
 	Count          int                    `json:"count" validate:"min=1,max=20"`
-	SimilarityType string                 `json:"similarity_type" validate:"oneof=content collaborative hybrid"`
+	SimilarityType string                 `json:"similarity_type" validate:"oneof=content genre style hybrid"`
 	Filters        *RecommendationFilters `json:"filters,omitempty"`
 }
 
@@ -139,6 +140,7 @@ type SubscriptionPlanResponse struct {
 	MaxPremiumBooks         int                    `json:"max_premium_books"` // -1 for unlimited
 	MaxTTSMinutesPerDay     int                    `json:"max_tts_minutes_per_day"` // -1 for unlimited
 	MaxOfflineDownloads     int                    `json:"max_offline_downloads"` // -1 for unlimited
+	MaxAudioStorageMB       int                    `json:"max_audio_storage_mb"` // -1 for unlimited
 	HasAdvancedAnalytics    bool                   `json:"has_advanced_analytics"`
 	HasAIRecommendations    bool                   `json:"has_ai_recommendations"`
 	HasPrioritySupport      bool                   `json:"has_priority_support"`
@@ -203,6 +205,24 @@ type UsageStatsResponse struct {
 	UsageBreakdown     map[string]UsageBreakdown  `json:"usage_breakdown"`
 }
 
+// TTSQuotaResponse represents the user's daily text-to-speech allowance
+type TTSQuotaResponse struct {
+	Unlimited        bool      `json:"unlimited"`
+	LimitMinutes     int       `json:"limit_minutes"` // -1 for unlimited
+	UsedMinutes      float64   `json:"used_minutes"`
+	RemainingMinutes float64   `json:"remaining_minutes"`
+	ResetAt          time.Time `json:"reset_at"`
+}
+
+// OfflineDownloadQuotaResponse represents how many books the user may keep offline
+type OfflineDownloadQuotaResponse struct {
+	Available bool `json:"available"` // false when the plan has no offline downloads
+	Unlimited bool `json:"unlimited"`
+	Limit     int  `json:"limit"` // -1 for unlimited
+	Active    int  `json:"active"`
+	Remaining int  `json:"remaining"` // -1 for unlimited
+}
+
 // UsageBreakdown represents daily usage breakdown
 type UsageBreakdown struct {
 	Date  string `json:"date"`
@@ -1,25 +1,63 @@
 package dto
 
-import "time"
+import (
+	"time"
 
-// TTSSynthesizeRequest represents a text-to-speech synthesis request
+	"github.com/google/uuid"
+)
+
+// TTSSynthesizeRequest represents a text-to-speech synthesis request.
+// Omitted voice parameters are chosen by the voice policy from the book and
+// the listener's local time, starting from the user's voice preset.
+// Format and Quality default to the server's audio encoding at standard quality.
 type TTSSynthesizeRequest struct {
-	Text       string  `json:"text" validate:"required,min=1,max=5000"`
-	Language   string  `json:"language" validate:"required"`
-	Voice      string  `json:"voice" validate:"required"`
-	Speed      float32 `json:"speed" validate:"omitempty,min=0.25,max=4.0"`
-	Pitch      float32 `json:"pitch" validate:"omitempty,min=-20.0,max=20.0"`
-	VolumeGain float32 `json:"volume_gain" validate:"omitempty,min=-96.0,max=16.0"`
+	Text       string   `json:"text" validate:"required,min=1,max=5000"`
+	Language   string   `json:"language,omitempty"`
+	Voice      string   `json:"voice,omitempty"`
+	Speed      *float32 `json:"speed,omitempty" validate:"omitempty,min=0.25,max=4.0"`
+	Pitch      *float32 `json:"pitch,omitempty" validate:"omitempty,min=-20.0,max=20.0"`
+	VolumeGain float32  `json:"volume_gain" validate:"omitempty,min=-96.0,max=16.0"`
+	BookID     *int64   `json:"book_id,omitempty" validate:"omitempty,min=1"`
+	Timezone   string   `json:"timezone,omitempty"` // IANA zone of the listener, e.g. Asia/Tokyo
+	Format     string   `json:"format,omitempty" validate:"omitempty,oneof=mp3 wav ogg"`
+	Quality    string   `json:"quality,omitempty" validate:"omitempty,oneof=low standard high"`
+	// IncludeAudio also returns the audio as base64 in audio_content
+	IncludeAudio bool `json:"include_audio,omitempty"`
 }
 
 // TTSSynthesizeResponse represents a text-to-speech synthesis response
 type TTSSynthesizeResponse struct {
-	AudioContent string    `json:"audio_content"`
-	ContentType  string    `json:"content_type"`
-	Duration     int       `json:"duration"` // Duration in seconds
-	Language     string    `json:"language"`
-	Voice        string    `json:"voice"`
-	CreatedAt    time.Time `json:"created_at"`
+	AudioFileID  *uuid.UUID `json:"audio_file_id,omitempty"`
+	StreamURL    string     `json:"stream_url,omitempty"`
+	AudioContent string     `json:"audio_content,omitempty"` // Base64 audio, with include_audio or when the audio could not be stored
+	ContentType  string     `json:"content_type"`
+	Duration     int        `json:"duration"` // Duration in seconds
+	Language     string     `json:"language"`
+	Voice        string     `json:"voice"`
+	Speed        float32    `json:"speed"`
+	Pitch        float32    `json:"pitch"`
+	VoiceRule    *VoiceRule `json:"voice_rule,omitempty"` // Policy rule that chose the voice
+	Cached       bool       `json:"cached"`               // True when served from the audio cache
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// AudioAlignmentResponse maps playback time to sentences of the source text
+type AudioAlignmentResponse struct {
+	AudioFileID uuid.UUID               `json:"audio_file_id"`
+	DurationMs  int                     `json:"duration_ms"`
+	Segments    []AudioAlignmentSegment `json:"segments"`
+}
+
+// AudioAlignmentSegment is the playback interval of one sentence.
+// Character offsets count characters (not bytes) in the source text.
+type AudioAlignmentSegment struct {
+	StartMs         int `json:"start_ms"`
+	EndMs           int `json:"end_ms"`
+	Sentence        int `json:"sentence"`
+	Paragraph       int `json:"paragraph"`
+	ParagraphOffset int `json:"paragraph_offset"`
+	CharOffset      int `json:"char_offset"`
+	CharLength      int `json:"char_length"`
 }
 
 // TTSVoicesResponse represents available voices response
@@ -35,22 +73,63 @@ type TTSVoice struct {
 	NaturalSampleRateHertz int32    `json:"natural_sample_rate_hertz"`
 }
 
-// TTSPreviewRequest represents a voice preview request
+// TTSPreviewRequest represents a voice preview request.
+// Omitted voice parameters are taken from the user's voice preset.
 type TTSPreviewRequest struct {
-	Language    string  `json:"language" validate:"required"`
-	Voice       string  `json:"voice" validate:"required"`
-	PreviewText string  `json:"preview_text,omitempty"`
-	Speed       float32 `json:"speed" validate:"omitempty,min=0.25,max=4.0"`
-	Pitch       float32 `json:"pitch" validate:"omitempty,min=-20.0,max=20.0"`
-	VolumeGain  float32 `json:"volume_gain" validate:"omitempty,min=-96.0,max=16.0"`
+	Language    string   `json:"language,omitempty"`
+	Voice       string   `json:"voice,omitempty"`
+	PreviewText string   `json:"preview_text,omitempty"`
+	Speed       *float32 `json:"speed,omitempty" validate:"omitempty,min=0.25,max=4.0"`
+	Pitch       *float32 `json:"pitch,omitempty" validate:"omitempty,min=-20.0,max=20.0"`
+	VolumeGain  float32  `json:"volume_gain" validate:"omitempty,min=-96.0,max=16.0"`
+	Format      string   `json:"format,omitempty" validate:"omitempty,oneof=mp3 wav ogg"`
+	Quality     string   `json:"quality,omitempty" validate:"omitempty,oneof=low standard high"`
 }
 
-// TTSPreviewResponse represents a voice preview response
+// TTSPreviewResponse represents a voice preview response. The voice
+// parameters are the resolved ones, ready to be saved as a preset.
 type TTSPreviewResponse struct {
-	AudioContent string `json:"audio_content"`
-	ContentType  string `json:"content_type"`
-	Duration     int    `json:"duration"`
-	PreviewText  string `json:"preview_text"`
+	AudioContent string     `json:"audio_content"`
+	ContentType  string     `json:"content_type"`
+	Duration     int        `json:"duration"`
+	PreviewText  string     `json:"preview_text"`
+	Language     string     `json:"language"`
+	Voice        string     `json:"voice"`
+	Speed        float32    `json:"speed"`
+	Pitch        float32    `json:"pitch"`
+	VoiceRule    *VoiceRule `json:"voice_rule,omitempty"`
+}
+
+// VoiceSelectionRequest asks which voice would narrate a book for the user
+type VoiceSelectionRequest struct {
+	BookID   *int64 `json:"book_id,omitempty" validate:"omitempty,min=1"`
+	Timezone string `json:"timezone,omitempty"`
+	Language string `json:"language,omitempty"`
+}
+
+// VoiceSelectionResponse is the voice chosen for a request and the policy
+// rule that chose it
+type VoiceSelectionResponse struct {
+	Language  string     `json:"language"`
+	Voice     string     `json:"voice"`
+	Speed     float32    `json:"speed"`
+	Pitch     float32    `json:"pitch"`
+	VoiceRule *VoiceRule `json:"voice_rule,omitempty"`
+	LocalTime time.Time  `json:"local_time"`
+}
+
+// VoiceRule identifies a voice policy rule
+type VoiceRule struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// SaveVoicePresetRequest saves a previewed voice as the user's voice preset
+type SaveVoicePresetRequest struct {
+	Language string   `json:"language" validate:"required"`
+	Voice    string   `json:"voice" validate:"required"`
+	Speed    *float32 `json:"speed,omitempty" validate:"omitempty,min=0.5,max=2.0"`
+	Pitch    *float32 `json:"pitch,omitempty" validate:"omitempty,min=-10.0,max=10.0"`
 }
 
 // AudioFileUploadRequest represents an audio file upload request
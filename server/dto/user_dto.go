@@ -21,9 +21,11 @@ type UserResponse struct {
 
 // VoicePresetResponse represents voice preset in API responses
 type VoicePresetResponse struct {
-	Gender string  `json:"gender"`
-	Pitch  float64 `json:"pitch"`
-	Speed  float64 `json:"speed"`
+	Gender   string  `json:"gender"`
+	Pitch    float64 `json:"pitch"`
+	Speed    float64 `json:"speed"`
+	Language string  `json:"language,omitempty"`
+	Voice    string  `json:"voice,omitempty"`
 }
 
 // CreateUserRequest represents the request to create a user
@@ -45,7 +47,9 @@ type UpdateUserRequest struct {
 
 // VoicePresetRequest represents voice preset in API requests
 type VoicePresetRequest struct {
-	Gender string  `json:"gender" validate:"required,oneof=male female neutral"`
-	Pitch  float64 `json:"pitch" validate:"required,min=0.0,max=1.0"`
-	Speed  float64 `json:"speed" validate:"required,min=0.5,max=2.0"`
+	Gender   string  `json:"gender" validate:"required,oneof=male female neutral"`
+	Pitch    float64 `json:"pitch" validate:"required,min=0.0,max=1.0"`
+	Speed    float64 `json:"speed" validate:"required,min=0.5,max=2.0"`
+	Language string  `json:"language,omitempty" validate:"required_with=Voice"`
+	Voice    string  `json:"voice,omitempty"`
 }
\ No newline at end of file
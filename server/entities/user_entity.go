@@ -23,9 +23,11 @@ type UserEntity struct {
 
 // VoicePresetDB represents voice preset for database storage
 type VoicePresetDB struct {
-	Gender string  `json:"gender"`
-	Pitch  float64 `json:"pitch"`
-	Speed  float64 `json:"speed"`
+	Gender   string  `json:"gender"`
+	Pitch    float64 `json:"pitch"`
+	Speed    float64 `json:"speed"`
+	Language string  `json:"language,omitempty"`
+	Voice    string  `json:"voice,omitempty"`
 }
 
 // Value implements driver.Valuer interface for database storage
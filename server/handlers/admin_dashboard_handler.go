@@ -244,4 +244,17 @@ func (h *AdminDashboardHandler) GetRecommendationQuality(w http.ResponseWriter,
 	}
 
 	utils.WriteSuccess(w, quality)
-}
\ No newline at end of file
+}
+
+// GetAudioStorage handles GET /api/v1/admin/dashboard/audio/storage
+func (h *AdminDashboardHandler) GetAudioStorage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	storage, err := h.adminDashboardService.GetAudioStorage(ctx)
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	utils.WriteSuccess(w, storage)
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/ponyo877/roudoku/server/dto"
+	"github.com/ponyo877/roudoku/server/pkg/logger"
+	"github.com/ponyo877/roudoku/server/pkg/utils"
+	"github.com/ponyo877/roudoku/server/services"
+)
+
+// AmbientHandler handles ambient track HTTP requests
+type AmbientHandler struct {
+	*BaseHandler
+	ambientService services.AmbientService
+}
+
+// NewAmbientHandler creates a new ambient handler
+func NewAmbientHandler(ambientService services.AmbientService, log *logger.Logger) *AmbientHandler {
+	return &AmbientHandler{
+		BaseHandler:    NewBaseHandler(log),
+		ambientService: ambientService,
+	}
+}
+
+// ListTracks handles GET /ambient/tracks
+func (h *AmbientHandler) ListTracks(w http.ResponseWriter, r *http.Request) {
+	response, err := h.ambientService.ListTracks(r.Context())
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	utils.WriteSuccess(w, response)
+}
+
+// MixAudio handles POST /audio/{id}/ambient
+func (h *AmbientHandler) MixAudio(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authenticatedUserID(w, r)
+	if !ok {
+		return
+	}
+
+	audioFileID, err := utils.ParseUUIDParam(r, "id")
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	var req dto.AmbientMixRequest
+	if err := utils.DecodeJSON(r, &req); err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	if err := h.validator.ValidateStruct(&req); err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	response, err := h.ambientService.MixAudio(r.Context(), userID, audioFileID, &req)
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	utils.WriteSuccess(w, response)
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/ponyo877/roudoku/server/pkg/logger"
+	"github.com/ponyo877/roudoku/server/pkg/utils"
+	"github.com/ponyo877/roudoku/server/services"
+)
+
+// AudioHandler handles audio delivery HTTP requests
+type AudioHandler struct {
+	*BaseHandler
+	audioDeliveryService services.AudioDeliveryService
+}
+
+// NewAudioHandler creates a new audio handler
+func NewAudioHandler(audioDeliveryService services.AudioDeliveryService, log *logger.Logger) *AudioHandler {
+	return &AudioHandler{
+		BaseHandler:          NewBaseHandler(log),
+		audioDeliveryService: audioDeliveryService,
+	}
+}
+
+// StreamAudio handles GET /audio/{id}/stream. Range, If-Range and
+// If-None-Match requests are handled by http.ServeContent. The Accept header
+// selects among the formats the audio has been synthesized in; when another
+// file is served, Content-Location points at its stream.
+func (h *AudioHandler) StreamAudio(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authenticatedUserID(w, r)
+	if !ok {
+		return
+	}
+
+	audioFileID, err := utils.ParseUUIDParam(r, "id")
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	w.Header().Set("Vary", "Accept")
+	stream, err := h.audioDeliveryService.OpenAudio(r.Context(), userID, audioFileID, r.Header.Get("Accept"))
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+	defer stream.Content.Close()
+
+	if stream.AudioFileID != audioFileID {
+		w.Header().Set("Content-Location", strings.Replace(r.URL.Path, audioFileID.String(), stream.AudioFileID.String(), 1))
+	}
+	w.Header().Set("Content-Type", stream.ContentType)
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	if stream.ETag != "" {
+		w.Header().Set("ETag", `"`+stream.ETag+`"`)
+	}
+
+	http.ServeContent(w, r, stream.Name, stream.ModTime, stream.Content)
+}
+
+// GetBookPlaylist handles GET /books/{id}/audiobook/playlist.m3u8
+func (h *AudioHandler) GetBookPlaylist(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authenticatedUserID(w, r)
+	if !ok {
+		return
+	}
+
+	bookID, err := utils.ParseInt64Param(r, "id")
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	// The audio routes share the API prefix of the book routes
+	apiPath := strings.TrimSuffix(r.URL.Path, fmt.Sprintf("/books/%d/audiobook/playlist.m3u8", bookID))
+	audioBaseURL := utils.RequestBaseURL(r) + apiPath + "/audio"
+
+	playlist, err := h.audioDeliveryService.BuildBookPlaylist(r.Context(), userID, bookID, audioBaseURL)
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
+	// Running audiobooks gain chapters, so players must not cache the playlist
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(playlist))
+}
+
+// GetAudioSegment handles GET /audio/{id}/segments/{index}.mp3, the
+// segments of book playlists
+func (h *AudioHandler) GetAudioSegment(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authenticatedUserID(w, r)
+	if !ok {
+		return
+	}
+
+	audioFileID, err := utils.ParseUUIDParam(r, "id")
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+	index, err := utils.ParseIntParam(r, "index")
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	segment, err := h.audioDeliveryService.OpenAudioSegment(r.Context(), userID, audioFileID, index)
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "audio/mpeg")
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	w.WriteHeader(http.StatusOK)
+	w.Write(segment)
+}
+
+// ExportBookM4B handles GET /books/{id}/export.m4b. The file is served with
+// range support so large audiobooks can be downloaded in parts.
+func (h *AudioHandler) ExportBookM4B(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authenticatedUserID(w, r)
+	if !ok {
+		return
+	}
+
+	bookID, err := utils.ParseInt64Param(r, "id")
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	stream, err := h.audioDeliveryService.ExportBookM4B(r.Context(), userID, bookID)
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+	defer stream.Content.Close()
+
+	w.Header().Set("Content-Type", stream.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": stream.Name}))
+	w.Header().Set("Cache-Control", "private, no-cache")
+	if stream.ETag != "" {
+		w.Header().Set("ETag", `"`+stream.ETag+`"`)
+	}
+
+	http.ServeContent(w, r, stream.Name, stream.ModTime, stream.Content)
+}
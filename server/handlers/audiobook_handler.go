@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/ponyo877/roudoku/server/dto"
+	"github.com/ponyo877/roudoku/server/pkg/logger"
+	"github.com/ponyo877/roudoku/server/pkg/utils"
+	"github.com/ponyo877/roudoku/server/services"
+)
+
+// AudiobookHandler handles audiobook generation HTTP requests
+type AudiobookHandler struct {
+	*BaseHandler
+	audiobookService services.AudiobookService
+}
+
+// NewAudiobookHandler creates a new audiobook handler
+func NewAudiobookHandler(audiobookService services.AudiobookService, log *logger.Logger) *AudiobookHandler {
+	return &AudiobookHandler{
+		BaseHandler:      NewBaseHandler(log),
+		audiobookService: audiobookService,
+	}
+}
+
+// CreateJob handles POST /books/{id}/audiobook
+func (h *AudiobookHandler) CreateJob(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authenticatedUserID(w, r)
+	if !ok {
+		return
+	}
+
+	bookID, err := utils.ParseInt64Param(r, "id")
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	var req dto.CreateAudiobookJobRequest
+	if r.ContentLength != 0 {
+		if err := utils.DecodeJSON(r, &req); err != nil {
+			utils.WriteError(w, r, h.logger, err)
+			return
+		}
+	}
+
+	if err := h.validator.ValidateStruct(&req); err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	response, err := h.audiobookService.CreateJob(r.Context(), userID, bookID, &req)
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	utils.WriteAccepted(w, response)
+}
+
+// GetLatestJob handles GET /books/{id}/audiobook
+func (h *AudiobookHandler) GetLatestJob(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authenticatedUserID(w, r)
+	if !ok {
+		return
+	}
+
+	bookID, err := utils.ParseInt64Param(r, "id")
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	response, err := h.audiobookService.GetLatestJob(r.Context(), userID, bookID)
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	utils.WriteSuccess(w, response)
+}
+
+// GetJob handles GET /audiobook-jobs/{job_id}
+func (h *AudiobookHandler) GetJob(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authenticatedUserID(w, r)
+	if !ok {
+		return
+	}
+
+	jobID, err := utils.ParseUUIDParam(r, "job_id")
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	response, err := h.audiobookService.GetJob(r.Context(), userID, jobID)
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	utils.WriteSuccess(w, response)
+}
+
+// GetCast handles GET /books/{id}/audiobook/cast
+func (h *AudiobookHandler) GetCast(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authenticatedUserID(w, r)
+	if !ok {
+		return
+	}
+
+	bookID, err := utils.ParseInt64Param(r, "id")
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	response, err := h.audiobookService.GetCast(r.Context(), userID, bookID)
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	utils.WriteSuccess(w, response)
+}
+
+// SaveCast handles PUT /books/{id}/audiobook/cast
+func (h *AudiobookHandler) SaveCast(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authenticatedUserID(w, r)
+	if !ok {
+		return
+	}
+
+	bookID, err := utils.ParseInt64Param(r, "id")
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	var req dto.SaveVoiceCastRequest
+	if err := utils.DecodeJSON(r, &req); err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	response, err := h.audiobookService.SaveCast(r.Context(), userID, bookID, &req)
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	utils.WriteSuccess(w, response)
+}
+
+// DeleteCast handles DELETE /books/{id}/audiobook/cast
+func (h *AudiobookHandler) DeleteCast(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authenticatedUserID(w, r)
+	if !ok {
+		return
+	}
+
+	bookID, err := utils.ParseInt64Param(r, "id")
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	if err := h.audiobookService.DeleteCast(r.Context(), userID, bookID); err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	utils.WriteNoContent(w)
+}
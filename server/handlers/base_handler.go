@@ -1,7 +1,13 @@
 package handlers
 
 import (
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/ponyo877/roudoku/server/pkg/errors"
 	"github.com/ponyo877/roudoku/server/pkg/logger"
+	"github.com/ponyo877/roudoku/server/pkg/middleware"
 	"github.com/ponyo877/roudoku/server/pkg/utils"
 )
 
@@ -15,4 +21,21 @@ func NewBaseHandler(log *logger.Logger) *BaseHandler {
 		logger:    log,
 		validator: utils.NewValidator(),
 	}
-}
\ No newline at end of file
+}
+
+// authenticatedUserID extracts the authenticated user's ID, writing an error response on failure
+func (h *BaseHandler) authenticatedUserID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userIDStr, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.WriteError(w, r, h.logger, errors.Unauthorized("User not authenticated", nil))
+		return uuid.Nil, false
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		utils.WriteError(w, r, h.logger, errors.BadRequest("Invalid user ID", err))
+		return uuid.Nil, false
+	}
+
+	return userID, true
+}
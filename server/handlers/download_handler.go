@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/ponyo877/roudoku/server/dto"
+	"github.com/ponyo877/roudoku/server/pkg/logger"
+	"github.com/ponyo877/roudoku/server/pkg/utils"
+	"github.com/ponyo877/roudoku/server/services"
+)
+
+// DownloadHandler handles offline download HTTP requests
+type DownloadHandler struct {
+	*BaseHandler
+	downloadService services.OfflineDownloadService
+}
+
+// NewDownloadHandler creates a new offline download handler
+func NewDownloadHandler(downloadService services.OfflineDownloadService, log *logger.Logger) *DownloadHandler {
+	return &DownloadHandler{
+		BaseHandler:     NewBaseHandler(log),
+		downloadService: downloadService,
+	}
+}
+
+// CreateDownload handles POST /downloads. The package is built in the
+// background; clients poll GET /downloads/{id} until it is active.
+func (h *DownloadHandler) CreateDownload(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authenticatedUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req dto.CreateOfflineDownloadRequest
+	if err := utils.DecodeJSON(r, &req); err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	if err := h.validator.ValidateStruct(&req); err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	response, err := h.downloadService.CreateDownload(r.Context(), userID, &req)
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	utils.WriteAccepted(w, response)
+}
+
+// GetSigningKey handles GET /downloads/signing-key
+func (h *DownloadHandler) GetSigningKey(w http.ResponseWriter, r *http.Request) {
+	utils.WriteSuccess(w, h.downloadService.SigningKey(r.Context()))
+}
+
+// ListDownloads handles GET /downloads
+func (h *DownloadHandler) ListDownloads(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authenticatedUserID(w, r)
+	if !ok {
+		return
+	}
+
+	response, err := h.downloadService.ListDownloads(r.Context(), userID)
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	utils.WriteSuccess(w, response)
+}
+
+// GetDownload handles GET /downloads/{id}
+func (h *DownloadHandler) GetDownload(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authenticatedUserID(w, r)
+	if !ok {
+		return
+	}
+
+	downloadID, err := utils.ParseUUIDParam(r, "id")
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	response, err := h.downloadService.GetDownload(r.Context(), userID, downloadID)
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	utils.WriteSuccess(w, response)
+}
+
+// DeleteDownload handles DELETE /downloads/{id}
+func (h *DownloadHandler) DeleteDownload(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authenticatedUserID(w, r)
+	if !ok {
+		return
+	}
+
+	downloadID, err := utils.ParseUUIDParam(r, "id")
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	if err := h.downloadService.DeleteDownload(r.Context(), userID, downloadID); err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	utils.WriteNoContent(w)
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/ponyo877/roudoku/server/dto"
+	"github.com/ponyo877/roudoku/server/pkg/logger"
+	"github.com/ponyo877/roudoku/server/pkg/utils"
+	"github.com/ponyo877/roudoku/server/services"
+)
+
+// PlaybackHandler handles audio playback session HTTP requests
+type PlaybackHandler struct {
+	*BaseHandler
+	playbackService services.PlaybackService
+}
+
+// NewPlaybackHandler creates a new playback handler
+func NewPlaybackHandler(playbackService services.PlaybackService, log *logger.Logger) *PlaybackHandler {
+	return &PlaybackHandler{
+		BaseHandler:     NewBaseHandler(log),
+		playbackService: playbackService,
+	}
+}
+
+// StartSession handles POST /playback/sessions
+func (h *PlaybackHandler) StartSession(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authenticatedUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req dto.StartPlaybackRequest
+	if err := utils.DecodeJSON(r, &req); err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	response, err := h.playbackService.StartSession(r.Context(), userID, &req)
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	utils.WriteCreated(w, response)
+}
+
+// Heartbeat handles POST /playback/sessions/{session_id}/heartbeat
+func (h *PlaybackHandler) Heartbeat(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authenticatedUserID(w, r)
+	if !ok {
+		return
+	}
+
+	sessionID, err := utils.ParseUUIDParam(r, "session_id")
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	var req dto.PlaybackHeartbeatRequest
+	if err := utils.DecodeJSON(r, &req); err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	response, err := h.playbackService.Heartbeat(r.Context(), userID, sessionID, &req)
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	utils.WriteSuccess(w, response)
+}
+
+// EndSession handles POST /playback/sessions/{session_id}/end
+func (h *PlaybackHandler) EndSession(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authenticatedUserID(w, r)
+	if !ok {
+		return
+	}
+
+	sessionID, err := utils.ParseUUIDParam(r, "session_id")
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	var req dto.EndPlaybackRequest
+	if err := utils.DecodeJSON(r, &req); err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	response, err := h.playbackService.EndSession(r.Context(), userID, sessionID, &req)
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	utils.WriteSuccess(w, response)
+}
+
+// GetResumePosition handles GET /playback/books/{book_id}/resume
+func (h *PlaybackHandler) GetResumePosition(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authenticatedUserID(w, r)
+	if !ok {
+		return
+	}
+
+	bookID, err := utils.ParseInt64Param(r, "book_id")
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	response, err := h.playbackService.GetResumePosition(r.Context(), userID, bookID)
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	utils.WriteSuccess(w, response)
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/ponyo877/roudoku/server/pkg/logger"
+	"github.com/ponyo877/roudoku/server/pkg/utils"
+	"github.com/ponyo877/roudoku/server/services"
+)
+
+// PodcastHandler handles private podcast feed HTTP requests
+type PodcastHandler struct {
+	*BaseHandler
+	podcastService services.PodcastService
+}
+
+// NewPodcastHandler creates a new podcast handler
+func NewPodcastHandler(podcastService services.PodcastService, log *logger.Logger) *PodcastHandler {
+	return &PodcastHandler{
+		BaseHandler:    NewBaseHandler(log),
+		podcastService: podcastService,
+	}
+}
+
+// CreateFeed handles POST /podcast/feed. Creating a feed revokes the
+// previous feed URL.
+func (h *PodcastHandler) CreateFeed(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authenticatedUserID(w, r)
+	if !ok {
+		return
+	}
+
+	baseURL := utils.RequestBaseURL(r) + strings.TrimSuffix(r.URL.Path, "/feed")
+	response, err := h.podcastService.CreateFeed(r.Context(), userID, baseURL)
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	utils.WriteCreated(w, response)
+}
+
+// GetFeed handles GET /podcast/feed
+func (h *PodcastHandler) GetFeed(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authenticatedUserID(w, r)
+	if !ok {
+		return
+	}
+
+	response, err := h.podcastService.GetFeed(r.Context(), userID)
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	utils.WriteSuccess(w, response)
+}
+
+// RevokeFeed handles DELETE /podcast/feed
+func (h *PodcastHandler) RevokeFeed(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authenticatedUserID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.podcastService.RevokeFeed(r.Context(), userID); err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	utils.WriteNoContent(w)
+}
+
+// GetFeedXML handles GET /podcast/{token}/feed.xml
+func (h *PodcastHandler) GetFeedXML(w http.ResponseWriter, r *http.Request) {
+	token := mux.Vars(r)["token"]
+	baseURL := utils.RequestBaseURL(r) + strings.TrimSuffix(r.URL.Path, "/"+token+"/feed.xml")
+
+	feed, err := h.podcastService.BuildFeed(r.Context(), token, baseURL)
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+	w.Header().Set("Cache-Control", "private, no-cache")
+	// Keep the tokenized URL out of search engines
+	w.Header().Set("X-Robots-Tag", "noindex")
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		w.Write(feed)
+	}
+}
+
+// StreamEpisode handles GET /podcast/{token}/episodes/{id}. Podcast apps
+// fetch episodes in ranges while buffering and resuming, so only a request
+// for the start of the file counts as a play.
+func (h *PodcastHandler) StreamEpisode(w http.ResponseWriter, r *http.Request) {
+	token := mux.Vars(r)["token"]
+	audioFileID, err := utils.ParseUUIDParam(r, "id")
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	rangeHeader := r.Header.Get("Range")
+	countPlay := r.Method == http.MethodGet && (rangeHeader == "" || strings.HasPrefix(rangeHeader, "bytes=0-"))
+
+	stream, err := h.podcastService.OpenEpisode(r.Context(), token, audioFileID, countPlay)
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+	defer stream.Content.Close()
+
+	w.Header().Set("Content-Type", stream.ContentType)
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	w.Header().Set("X-Robots-Tag", "noindex")
+	if stream.ETag != "" {
+		w.Header().Set("ETag", `"`+stream.ETag+`"`)
+	}
+
+	http.ServeContent(w, r, stream.Name, stream.ModTime, stream.Content)
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/ponyo877/roudoku/server/dto"
+	"github.com/ponyo877/roudoku/server/pkg/logger"
+	"github.com/ponyo877/roudoku/server/pkg/utils"
+	"github.com/ponyo877/roudoku/server/services"
+)
+
+// PositionHandler handles conversions between reading and listening positions
+type PositionHandler struct {
+	*BaseHandler
+	positionService services.PositionService
+}
+
+// NewPositionHandler creates a new position handler
+func NewPositionHandler(positionService services.PositionService, log *logger.Logger) *PositionHandler {
+	return &PositionHandler{
+		BaseHandler:     NewBaseHandler(log),
+		positionService: positionService,
+	}
+}
+
+// GetHandoffPosition handles GET /books/{id}/position
+func (h *PositionHandler) GetHandoffPosition(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authenticatedUserID(w, r)
+	if !ok {
+		return
+	}
+
+	bookID, err := utils.ParseInt64Param(r, "id")
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	response, err := h.positionService.GetHandoffPosition(r.Context(), userID, bookID)
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	utils.WriteSuccess(w, response)
+}
+
+// TextToAudio handles POST /books/{id}/position/to-audio
+func (h *PositionHandler) TextToAudio(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authenticatedUserID(w, r)
+	if !ok {
+		return
+	}
+
+	bookID, err := utils.ParseInt64Param(r, "id")
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	var req dto.TextToAudioRequest
+	if err := utils.DecodeJSON(r, &req); err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	response, err := h.positionService.TextToAudio(r.Context(), userID, bookID, &req)
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	utils.WriteSuccess(w, response)
+}
+
+// AudioToText handles POST /books/{id}/position/to-text
+func (h *PositionHandler) AudioToText(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authenticatedUserID(w, r)
+	if !ok {
+		return
+	}
+
+	bookID, err := utils.ParseInt64Param(r, "id")
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	var req dto.AudioToTextRequest
+	if err := utils.DecodeJSON(r, &req); err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	response, err := h.positionService.AudioToText(r.Context(), userID, bookID, &req)
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	utils.WriteSuccess(w, response)
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/ponyo877/roudoku/server/dto"
+	"github.com/ponyo877/roudoku/server/pkg/errors"
+	"github.com/ponyo877/roudoku/server/pkg/logger"
+	"github.com/ponyo877/roudoku/server/pkg/utils"
+	"github.com/ponyo877/roudoku/server/services"
+)
+
+// PronunciationHandler handles admin HTTP requests for the narration pronunciation lexicon
+type PronunciationHandler struct {
+	*BaseHandler
+	pronunciationService services.PronunciationService
+}
+
+// NewPronunciationHandler creates a new pronunciation handler
+func NewPronunciationHandler(pronunciationService services.PronunciationService, log *logger.Logger) *PronunciationHandler {
+	return &PronunciationHandler{
+		BaseHandler:          NewBaseHandler(log),
+		pronunciationService: pronunciationService,
+	}
+}
+
+// ListEntries handles GET /admin/pronunciations
+func (h *PronunciationHandler) ListEntries(w http.ResponseWriter, r *http.Request) {
+	req := dto.PronunciationListRequest{
+		Scope:  r.URL.Query().Get("scope"),
+		Author: r.URL.Query().Get("author"),
+		Query:  r.URL.Query().Get("query"),
+		Limit:  utils.ParseQueryInt(r, "limit", 50),
+		Offset: utils.ParseQueryInt(r, "offset", 0),
+	}
+	if bookIDStr := r.URL.Query().Get("book_id"); bookIDStr != "" {
+		bookID, err := strconv.ParseInt(bookIDStr, 10, 64)
+		if err != nil {
+			utils.WriteError(w, r, h.logger, errors.BadRequest("Invalid book_id", err))
+			return
+		}
+		req.BookID = &bookID
+	}
+
+	response, err := h.pronunciationService.ListEntries(r.Context(), &req)
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	utils.WriteSuccess(w, response)
+}
+
+// CreateEntry handles POST /admin/pronunciations
+func (h *PronunciationHandler) CreateEntry(w http.ResponseWriter, r *http.Request) {
+	var req dto.PronunciationEntryRequest
+	if err := utils.DecodeJSON(r, &req); err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	response, err := h.pronunciationService.CreateEntry(r.Context(), &req)
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	utils.WriteCreated(w, response)
+}
+
+// GetEntry handles GET /admin/pronunciations/{id}
+func (h *PronunciationHandler) GetEntry(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ParseUUIDParam(r, "id")
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	response, err := h.pronunciationService.GetEntry(r.Context(), id)
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	utils.WriteSuccess(w, response)
+}
+
+// UpdateEntry handles PUT /admin/pronunciations/{id}
+func (h *PronunciationHandler) UpdateEntry(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ParseUUIDParam(r, "id")
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	var req dto.PronunciationEntryRequest
+	if err := utils.DecodeJSON(r, &req); err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	response, err := h.pronunciationService.UpdateEntry(r.Context(), id, &req)
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	utils.WriteSuccess(w, response)
+}
+
+// DeleteEntry handles DELETE /admin/pronunciations/{id}
+func (h *PronunciationHandler) DeleteEntry(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ParseUUIDParam(r, "id")
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	if err := h.pronunciationService.DeleteEntry(r.Context(), id); err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	utils.WriteNoContent(w)
+}
+
+// Preview handles POST /admin/pronunciations/preview
+func (h *PronunciationHandler) Preview(w http.ResponseWriter, r *http.Request) {
+	var req dto.PronunciationPreviewRequest
+	if err := utils.DecodeJSON(r, &req); err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	response, err := h.pronunciationService.Preview(r.Context(), &req)
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	utils.WriteSuccess(w, response)
+}
@@ -93,6 +93,18 @@ func (h *RecommendationHandler) GetRecommendations(w http.ResponseWriter, r *htt
 
 // GetSimilarBooks handles GET /recommendations/similar/{bookId}
 func (h *RecommendationHandler) GetSimilarBooks(w http.ResponseWriter, r *http.Request) {
+	userIDStr, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.WriteError(w, r, h.logger, errors.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		utils.WriteError(w, r, h.logger, errors.BadRequest("Invalid user ID", err))
+		return
+	}
+
 	vars := mux.Vars(r)
 	bookIDStr, exists := vars["bookId"]
 	if !exists {
@@ -123,6 +135,7 @@ func (h *RecommendationHandler) GetSimilarBooks(w http.ResponseWriter, r *http.R
 
 	req := &dto.SimilarBooksRequest{
 		BookID:         bookID,
+		UserID:         userID,
 		Count:          count,
 		SimilarityType: similarityType,
 	}
@@ -137,7 +150,7 @@ func (h *RecommendationHandler) GetSimilarBooks(w http.ResponseWriter, r *http.R
 
 	response, err := h.recommendationService.GetSimilarBooks(r.Context(), req)
 	if err != nil {
-		utils.WriteError(w, r, h.logger, errors.InternalServerError("Failed to get similar books", err))
+		utils.WriteError(w, r, h.logger, err)
 		return
 	}
 
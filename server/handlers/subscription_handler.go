@@ -206,6 +206,52 @@ func (h *SubscriptionHandler) GetUsageStats(w http.ResponseWriter, r *http.Reque
 	utils.WriteSuccess(w, response)
 }
 
+// GetTTSQuota handles GET /subscriptions/usage/tts
+func (h *SubscriptionHandler) GetTTSQuota(w http.ResponseWriter, r *http.Request) {
+	userIDStr, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.WriteError(w, r, h.logger, errors.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		utils.WriteError(w, r, h.logger, errors.BadRequest("Invalid user ID", err))
+		return
+	}
+
+	response, err := h.subscriptionService.GetTTSQuota(r.Context(), userID)
+	if err != nil {
+		utils.WriteError(w, r, h.logger, errors.InternalServerError("Failed to get TTS quota", err))
+		return
+	}
+
+	utils.WriteSuccess(w, response)
+}
+
+// GetOfflineDownloadQuota handles GET /subscriptions/usage/downloads
+func (h *SubscriptionHandler) GetOfflineDownloadQuota(w http.ResponseWriter, r *http.Request) {
+	userIDStr, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.WriteError(w, r, h.logger, errors.Unauthorized("User not authenticated", nil))
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		utils.WriteError(w, r, h.logger, errors.BadRequest("Invalid user ID", err))
+		return
+	}
+
+	response, err := h.subscriptionService.GetOfflineDownloadQuota(r.Context(), userID)
+	if err != nil {
+		utils.WriteError(w, r, h.logger, errors.InternalServerError("Failed to get offline download quota", err))
+		return
+	}
+
+	utils.WriteSuccess(w, response)
+}
+
 // CheckFeatureAccess handles GET /subscriptions/features/{feature}/access
 func (h *SubscriptionHandler) CheckFeatureAccess(w http.ResponseWriter, r *http.Request) {
 	userIDStr, ok := middleware.GetUserIDFromContext(r.Context())
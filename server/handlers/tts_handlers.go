@@ -2,8 +2,11 @@ package handlers
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/ponyo877/roudoku/server/dto"
+	"github.com/ponyo877/roudoku/server/pkg/errors"
 	"github.com/ponyo877/roudoku/server/pkg/logger"
 	"github.com/ponyo877/roudoku/server/pkg/utils"
 	"github.com/ponyo877/roudoku/server/services"
@@ -23,8 +26,14 @@ func NewTTSHandler(ttsService services.TTSService, log *logger.Logger) *TTSHandl
 	}
 }
 
-// SynthesizeText handles POST /tts/synthesize
+// SynthesizeText handles POST /tts/synthesize. Stored audio is returned as
+// the URL of its stream, which shares the API prefix of this route.
 func (h *TTSHandler) SynthesizeText(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authenticatedUserID(w, r)
+	if !ok {
+		return
+	}
+
 	var req dto.TTSSynthesizeRequest
 	if err := utils.DecodeJSON(r, &req); err != nil {
 		utils.WriteError(w, r, h.logger, err)
@@ -36,22 +45,15 @@ func (h *TTSHandler) SynthesizeText(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Set default values if not provided
-	if req.Speed == 0 {
-		req.Speed = 1.0
-	}
-	if req.Pitch == 0 {
-		req.Pitch = 0.0
-	}
-	if req.VolumeGain == 0 {
-		req.VolumeGain = 0.0
-	}
-
-	response, err := h.ttsService.SynthesizeText(r.Context(), &req)
+	response, err := h.ttsService.SynthesizeText(r.Context(), userID, &req)
 	if err != nil {
 		utils.WriteError(w, r, h.logger, err)
 		return
 	}
+	if response.AudioFileID != nil {
+		apiPath := strings.TrimSuffix(r.URL.Path, "/tts/synthesize")
+		response.StreamURL = utils.RequestBaseURL(r) + apiPath + "/audio/" + response.AudioFileID.String() + "/stream"
+	}
 
 	utils.WriteSuccess(w, response)
 }
@@ -74,6 +76,11 @@ func (h *TTSHandler) GetVoices(w http.ResponseWriter, r *http.Request) {
 
 // PreviewVoice handles POST /tts/preview
 func (h *TTSHandler) PreviewVoice(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authenticatedUserID(w, r)
+	if !ok {
+		return
+	}
+
 	var req dto.TTSPreviewRequest
 	if err := utils.DecodeJSON(r, &req); err != nil {
 		utils.WriteError(w, r, h.logger, err)
@@ -85,22 +92,89 @@ func (h *TTSHandler) PreviewVoice(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Set default values if not provided
-	if req.Speed == 0 {
-		req.Speed = 1.0
+	response, err := h.ttsService.PreviewVoice(r.Context(), userID, &req)
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	utils.WriteSuccess(w, response)
+}
+
+// SaveVoicePreset handles PUT /tts/voice-preset
+func (h *TTSHandler) SaveVoicePreset(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authenticatedUserID(w, r)
+	if !ok {
+		return
 	}
-	if req.Pitch == 0 {
-		req.Pitch = 0.0
+
+	var req dto.SaveVoicePresetRequest
+	if err := utils.DecodeJSON(r, &req); err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
 	}
-	if req.VolumeGain == 0 {
-		req.VolumeGain = 0.0
+
+	if err := h.validator.ValidateStruct(&req); err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
 	}
 
-	response, err := h.ttsService.PreviewVoice(r.Context(), &req)
+	response, err := h.ttsService.SaveVoicePreset(r.Context(), userID, &req)
 	if err != nil {
 		utils.WriteError(w, r, h.logger, err)
 		return
 	}
 
 	utils.WriteSuccess(w, response)
-}
\ No newline at end of file
+}
+
+// GetAlignment handles GET /audio/{id}/alignment
+func (h *TTSHandler) GetAlignment(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authenticatedUserID(w, r)
+	if !ok {
+		return
+	}
+
+	audioFileID, err := utils.ParseUUIDParam(r, "id")
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	response, err := h.ttsService.GetAlignment(r.Context(), userID, audioFileID)
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	utils.WriteSuccess(w, response)
+}
+
+// SelectVoice handles GET /tts/voice-selection
+func (h *TTSHandler) SelectVoice(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.authenticatedUserID(w, r)
+	if !ok {
+		return
+	}
+
+	req := dto.VoiceSelectionRequest{
+		Timezone: r.URL.Query().Get("timezone"),
+		Language: r.URL.Query().Get("language"),
+	}
+	if bookIDStr := r.URL.Query().Get("book_id"); bookIDStr != "" {
+		bookID, err := strconv.ParseInt(bookIDStr, 10, 64)
+		if err != nil {
+			utils.WriteError(w, r, h.logger, errors.BadRequest("Invalid book_id", err))
+			return
+		}
+		req.BookID = &bookID
+	}
+
+	response, err := h.ttsService.SelectVoice(r.Context(), userID, &req)
+	if err != nil {
+		utils.WriteError(w, r, h.logger, err)
+		return
+	}
+
+	utils.WriteSuccess(w, response)
+}
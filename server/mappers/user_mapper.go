@@ -26,9 +26,11 @@ func (m *UserMapper) DomainToDTO(user *domain.User) *dto.UserResponse {
 		DisplayName: user.DisplayName,
 		Email:       user.Email,
 		VoicePreset: dto.VoicePresetResponse{
-			Gender: user.VoicePreset.Gender,
-			Pitch:  user.VoicePreset.Pitch,
-			Speed:  user.VoicePreset.Speed,
+			Gender:   user.VoicePreset.Gender,
+			Pitch:    user.VoicePreset.Pitch,
+			Speed:    user.VoicePreset.Speed,
+			Language: user.VoicePreset.Language,
+			Voice:    user.VoicePreset.Voice,
 		},
 		SubscriptionStatus:    string(user.SubscriptionStatus),
 		SubscriptionExpiresAt: user.SubscriptionExpiresAt,
@@ -43,9 +45,11 @@ func (m *UserMapper) CreateRequestToDomain(req *dto.CreateUserRequest) *domain.U
 	
 	if req.VoicePreset != nil {
 		user.VoicePreset = domain.VoicePreset{
-			Gender: req.VoicePreset.Gender,
-			Pitch:  req.VoicePreset.Pitch,
-			Speed:  req.VoicePreset.Speed,
+			Gender:   req.VoicePreset.Gender,
+			Pitch:    req.VoicePreset.Pitch,
+			Speed:    req.VoicePreset.Speed,
+			Language: req.VoicePreset.Language,
+			Voice:    req.VoicePreset.Voice,
 		}
 	}
 	
@@ -64,9 +68,11 @@ func (m *UserMapper) DomainToEntity(user *domain.User) *entities.UserEntity {
 		DisplayName: user.DisplayName,
 		Email:       user.Email,
 		VoicePreset: entities.VoicePresetDB{
-			Gender: user.VoicePreset.Gender,
-			Pitch:  user.VoicePreset.Pitch,
-			Speed:  user.VoicePreset.Speed,
+			Gender:   user.VoicePreset.Gender,
+			Pitch:    user.VoicePreset.Pitch,
+			Speed:    user.VoicePreset.Speed,
+			Language: user.VoicePreset.Language,
+			Voice:    user.VoicePreset.Voice,
 		},
 		SubscriptionStatus:    string(user.SubscriptionStatus),
 		SubscriptionExpiresAt: user.SubscriptionExpiresAt,
@@ -87,9 +93,11 @@ func (m *UserMapper) EntityToDomain(entity *entities.UserEntity) *domain.User {
 		DisplayName: entity.DisplayName,
 		Email:       entity.Email,
 		VoicePreset: domain.VoicePreset{
-			Gender: entity.VoicePreset.Gender,
-			Pitch:  entity.VoicePreset.Pitch,
-			Speed:  entity.VoicePreset.Speed,
+			Gender:   entity.VoicePreset.Gender,
+			Pitch:    entity.VoicePreset.Pitch,
+			Speed:    entity.VoicePreset.Speed,
+			Language: entity.VoicePreset.Language,
+			Voice:    entity.VoicePreset.Voice,
 		},
 		SubscriptionStatus:    domain.SubscriptionStatus(entity.SubscriptionStatus),
 		SubscriptionExpiresAt: entity.SubscriptionExpiresAt,
@@ -117,9 +125,11 @@ func (m *UserMapper) UpdateRequestApplyToDomain(user *domain.User, req *dto.Upda
 	}
 	if req.VoicePreset != nil {
 		user.VoicePreset = domain.VoicePreset{
-			Gender: req.VoicePreset.Gender,
-			Pitch:  req.VoicePreset.Pitch,
-			Speed:  req.VoicePreset.Speed,
+			Gender:   req.VoicePreset.Gender,
+			Pitch:    req.VoicePreset.Pitch,
+			Speed:    req.VoicePreset.Speed,
+			Language: req.VoicePreset.Language,
+			Voice:    req.VoicePreset.Voice,
 		}
 	}
 	if req.SubscriptionStatus != nil {
@@ -1,5 +1,8 @@
 -- Add tables for AI recommendation engine and premium features
 
+-- Book vectors are stored with pgvector
+CREATE EXTENSION IF NOT EXISTS vector;
+
 -- User preferences for recommendation engine
 CREATE TABLE IF NOT EXISTS user_preferences (
     id UUID PRIMARY KEY,
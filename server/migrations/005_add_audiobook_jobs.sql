@@ -0,0 +1,44 @@
+-- Add tables for asynchronous audiobook generation
+
+-- Audiobook jobs table (one per book narration request)
+CREATE TABLE IF NOT EXISTS audiobook_jobs (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    book_id BIGINT NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+    voice_config JSONB NOT NULL, -- Voice settings used for every chapter
+    status TEXT NOT NULL DEFAULT 'queued' CHECK (status IN ('queued', 'processing', 'completed', 'failed')),
+    total_chapters INTEGER NOT NULL DEFAULT 0,
+    completed_chapters INTEGER NOT NULL DEFAULT 0,
+    failed_chapters INTEGER NOT NULL DEFAULT 0,
+    error_message TEXT,
+    completed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Audiobook chapter tasks table (work queue consumed by the audiobook workers)
+CREATE TABLE IF NOT EXISTS audiobook_chapter_tasks (
+    id UUID PRIMARY KEY,
+    job_id UUID NOT NULL REFERENCES audiobook_jobs(id) ON DELETE CASCADE,
+    chapter_id UUID NOT NULL REFERENCES chapters(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    status TEXT NOT NULL DEFAULT 'queued' CHECK (status IN ('queued', 'processing', 'completed', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    max_attempts INTEGER NOT NULL DEFAULT 5,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    locked_by TEXT,
+    locked_at TIMESTAMP WITH TIME ZONE,
+    audio_file_id UUID REFERENCES audio_files(id) ON DELETE SET NULL,
+    error_message TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE(job_id, chapter_id)
+);
+
+-- Create indexes for performance
+CREATE INDEX IF NOT EXISTS idx_audiobook_jobs_user_book ON audiobook_jobs(user_id, book_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audiobook_jobs_status ON audiobook_jobs(status);
+CREATE INDEX IF NOT EXISTS idx_audiobook_chapter_tasks_job_id ON audiobook_chapter_tasks(job_id, position);
+CREATE INDEX IF NOT EXISTS idx_audiobook_chapter_tasks_queue ON audiobook_chapter_tasks(next_attempt_at) WHERE status = 'queued';
+CREATE INDEX IF NOT EXISTS idx_audiobook_chapter_tasks_locked ON audiobook_chapter_tasks(locked_at) WHERE status = 'processing';
+
@@ -0,0 +1,9 @@
+-- Add sentence timepoints for read-along highlighting
+
+-- Audio alignments table (one per synthesized audio file)
+CREATE TABLE IF NOT EXISTS audio_alignments (
+    audio_file_id UUID PRIMARY KEY REFERENCES audio_files(id) ON DELETE CASCADE,
+    duration_ms INTEGER NOT NULL DEFAULT 0,
+    segments JSONB NOT NULL DEFAULT '[]', -- [{start_ms, end_ms, sentence, paragraph, paragraph_offset, char_offset, char_length}]
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,7 @@
+-- Track playback session lifecycle for heartbeats and resume
+
+ALTER TABLE audio_playback_sessions ADD COLUMN IF NOT EXISTS ended_at TIMESTAMP WITH TIME ZONE;
+-- Listening time already credited to reading_analytics and usage_tracking
+ALTER TABLE audio_playback_sessions ADD COLUMN IF NOT EXISTS recorded_ms INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_audio_playback_sessions_user_updated ON audio_playback_sessions(user_id, updated_at DESC);
@@ -0,0 +1,12 @@
+-- Add per-book voice casts for multi-voice dialogue narration
+
+-- Book voice casts table (one cast per user and book)
+CREATE TABLE IF NOT EXISTS book_voice_casts (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    book_id BIGINT NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+    narrator JSONB NOT NULL, -- {language, voice, speed, pitch, volume_gain}
+    characters JSONB NOT NULL DEFAULT '[]', -- [{name, voice}]
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, book_id)
+);
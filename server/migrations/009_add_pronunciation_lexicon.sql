@@ -0,0 +1,26 @@
+-- Add an admin-managed pronunciation lexicon for narration
+
+-- Pronunciation entries map a surface form to the reading engines should speak.
+-- Global entries apply to every book; author and book entries override them.
+CREATE TABLE IF NOT EXISTS pronunciation_entries (
+    id UUID PRIMARY KEY,
+    scope VARCHAR(20) NOT NULL CHECK (scope IN ('global', 'author', 'book')),
+    book_id BIGINT REFERENCES books(id) ON DELETE CASCADE,
+    author VARCHAR(255),
+    surface TEXT NOT NULL CHECK (surface <> ''),
+    reading TEXT NOT NULL CHECK (reading <> ''),
+    note TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CHECK (
+        (scope = 'global' AND book_id IS NULL AND author IS NULL) OR
+        (scope = 'author' AND book_id IS NULL AND author IS NOT NULL) OR
+        (scope = 'book' AND book_id IS NOT NULL AND author IS NULL)
+    )
+);
+
+-- One reading per surface form within each scope
+CREATE UNIQUE INDEX IF NOT EXISTS idx_pronunciation_entries_surface
+    ON pronunciation_entries (scope, COALESCE(book_id, 0), COALESCE(author, ''), surface);
+CREATE INDEX IF NOT EXISTS idx_pronunciation_entries_book_id ON pronunciation_entries(book_id) WHERE book_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_pronunciation_entries_author ON pronunciation_entries(author) WHERE author IS NOT NULL;
@@ -0,0 +1,26 @@
+-- Add storage budgets for synthesized audio and a log of cache eviction runs
+
+-- Megabytes of synthesized audio a plan's users may keep; -1 for unlimited
+ALTER TABLE subscription_plans ADD COLUMN IF NOT EXISTS max_audio_storage_mb INTEGER NOT NULL DEFAULT 200;
+
+UPDATE subscription_plans SET max_audio_storage_mb = 200 WHERE name = 'Free';
+UPDATE subscription_plans SET max_audio_storage_mb = 5000 WHERE name = 'Premium';
+UPDATE subscription_plans SET max_audio_storage_mb = -1 WHERE name = 'Premium Plus';
+
+-- Eviction scans audio by last use and sums it per user
+CREATE INDEX IF NOT EXISTS idx_audio_files_last_used
+    ON audio_files ((COALESCE(last_played_at, created_at)));
+
+-- One row per run of the audio lifecycle worker
+CREATE TABLE IF NOT EXISTS audio_lifecycle_runs (
+    id UUID PRIMARY KEY,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    finished_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    expired_files INTEGER NOT NULL DEFAULT 0,  -- past expires_at or unused for the idle TTL
+    evicted_files INTEGER NOT NULL DEFAULT 0,  -- least recently used files over a storage budget
+    reclaimed_bytes BIGINT NOT NULL DEFAULT 0,
+    failed_files INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_audio_lifecycle_runs_started_at ON audio_lifecycle_runs(started_at DESC);
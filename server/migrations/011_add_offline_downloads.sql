@@ -0,0 +1,29 @@
+-- Add offline download packages for subscribers
+
+-- Subscribers may keep up to 10 books offline; free users may not download
+UPDATE subscription_plans SET max_offline_downloads = 0 WHERE name = 'Free';
+UPDATE subscription_plans SET max_offline_downloads = 10 WHERE name IN ('Premium', 'Premium Plus');
+
+-- One row per package a user has downloaded. Active packages count towards
+-- the plan's offline download limit.
+CREATE TABLE IF NOT EXISTS offline_downloads (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    book_id BIGINT NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'revoked', 'deleted')),
+    package_path TEXT NOT NULL,
+    package_size_bytes BIGINT NOT NULL DEFAULT 0,
+    manifest_sha256 VARCHAR(64) NOT NULL,
+    chapter_count INTEGER NOT NULL DEFAULT 0,
+    audio_chapter_count INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL, -- End of the subscription period the package was issued for
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    revoke_reason TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- A book is downloaded at most once at a time per user
+CREATE UNIQUE INDEX IF NOT EXISTS idx_offline_downloads_active_book
+    ON offline_downloads(user_id, book_id) WHERE status = 'active';
+CREATE INDEX IF NOT EXISTS idx_offline_downloads_user_id ON offline_downloads(user_id, created_at DESC);
@@ -0,0 +1,16 @@
+-- Add private podcast feeds of a user's audiobooks
+
+-- A feed is addressed by a secret token. Only the token's SHA-256 hash is
+-- kept, and a revoked feed stops answering.
+CREATE TABLE IF NOT EXISTS podcast_feeds (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    last_accessed_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- A user has at most one feed in use
+CREATE UNIQUE INDEX IF NOT EXISTS idx_podcast_feeds_active_user
+    ON podcast_feeds(user_id) WHERE revoked_at IS NULL;
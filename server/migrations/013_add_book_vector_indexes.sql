@@ -0,0 +1,19 @@
+-- Add approximate nearest-neighbour indexes for similar book searches
+
+-- HNSW indexes need no training data, so they can be built while the table
+-- is still empty and keep their recall as books are added. IVFFlat would
+-- cluster whatever rows exist when the index is created.
+CREATE INDEX IF NOT EXISTS idx_book_vectors_content_hnsw
+    ON book_vectors USING hnsw (content_vector vector_cosine_ops)
+    WITH (m = 16, ef_construction = 64);
+
+CREATE INDEX IF NOT EXISTS idx_book_vectors_genre_hnsw
+    ON book_vectors USING hnsw (genre_vector vector_cosine_ops)
+    WITH (m = 16, ef_construction = 64);
+
+CREATE INDEX IF NOT EXISTS idx_book_vectors_style_hnsw
+    ON book_vectors USING hnsw (style_vector vector_cosine_ops)
+    WITH (m = 16, ef_construction = 64);
+
+CREATE INDEX IF NOT EXISTS idx_book_vectors_popularity
+    ON book_vectors(popularity_score DESC);
@@ -0,0 +1,5 @@
+-- Record which model produced each book's vectors and from which text, so
+-- books are re-embedded when either changes
+
+ALTER TABLE book_vectors ADD COLUMN IF NOT EXISTS embedding_model TEXT;
+ALTER TABLE book_vectors ADD COLUMN IF NOT EXISTS content_hash VARCHAR(64);
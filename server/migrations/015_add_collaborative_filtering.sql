@@ -0,0 +1,44 @@
+-- Add collaborative filtering models trained on user_interactions.implicit_score
+
+-- Training runs; the factors below belong to the latest one
+CREATE TABLE IF NOT EXISTS cf_models (
+    id UUID PRIMARY KEY,
+    factors INTEGER NOT NULL,
+    iterations INTEGER NOT NULL,
+    regularization DOUBLE PRECISION NOT NULL,
+    alpha DOUBLE PRECISION NOT NULL,
+    users INTEGER NOT NULL,
+    books INTEGER NOT NULL,
+    interactions INTEGER NOT NULL,
+    loss DOUBLE PRECISION NOT NULL,
+    trained_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Matrix factorization factors. Book factors are pgvector vectors so that
+-- the books with the largest predicted preference are found with <#>.
+CREATE TABLE IF NOT EXISTS cf_user_factors (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    model_id UUID NOT NULL REFERENCES cf_models(id) ON DELETE CASCADE,
+    factors VECTOR NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS cf_book_factors (
+    book_id BIGINT PRIMARY KEY REFERENCES books(id) ON DELETE CASCADE,
+    model_id UUID NOT NULL REFERENCES cf_models(id) ON DELETE CASCADE,
+    factors VECTOR NOT NULL
+);
+
+-- Item–item neighbours, stored in both directions
+CREATE TABLE IF NOT EXISTS book_similarities (
+    book_id BIGINT NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+    similar_book_id BIGINT NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+    similarity DOUBLE PRECISION NOT NULL,
+    common_users INTEGER NOT NULL DEFAULT 0,
+    calculated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (book_id, similar_book_id),
+    CHECK (book_id != similar_book_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_cf_models_trained_at ON cf_models(trained_at DESC);
+CREATE INDEX IF NOT EXISTS idx_book_similarities_book_score ON book_similarities(book_id, similarity DESC);
+CREATE INDEX IF NOT EXISTS idx_user_similarities_user_score ON user_similarities(user_a_id, similarity_type, similarity_score DESC);
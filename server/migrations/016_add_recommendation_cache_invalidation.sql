@@ -0,0 +1,114 @@
+-- Key cached recommendations by request parameters and invalidate them when
+-- the data they were generated from changes
+
+ALTER TABLE recommendation_cache ADD COLUMN IF NOT EXISTS params_hash TEXT NOT NULL DEFAULT '';
+-- The response as served, so that a hit needs no book lookups
+ALTER TABLE recommendation_cache ADD COLUMN IF NOT EXISTS response JSONB;
+-- Hybrid scores are sums of weighted scores and may exceed NUMERIC(5,4)
+ALTER TABLE recommendation_cache ALTER COLUMN scores TYPE DOUBLE PRECISION[];
+
+DROP INDEX IF EXISTS idx_recommendation_cache_user_type;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_recommendation_cache_key
+    ON recommendation_cache(user_id, recommendation_type, params_hash);
+
+-- Every invalidation advances the generation of the user, or of the nil
+-- UUID for all users. Recommendations are cached only if no generation
+-- they depend on advanced while they were generated.
+CREATE SEQUENCE IF NOT EXISTS recommendation_cache_generation;
+
+CREATE TABLE IF NOT EXISTS recommendation_cache_generations (
+    user_id UUID PRIMARY KEY,
+    generation BIGINT NOT NULL
+);
+
+-- Deletes the cached recommendations of a user, or of all users when
+-- p_user_id is NULL, restricted to p_types unless it is NULL. Servers
+-- listening on recommendation_cache_invalidated drop their in-process
+-- copies of the same entries.
+CREATE OR REPLACE FUNCTION invalidate_recommendation_cache(
+    p_user_id UUID,
+    p_types TEXT[] DEFAULT NULL
+) RETURNS VOID AS $$
+BEGIN
+    -- Taken first, so that a concurrent write of the cache either waits
+    -- for this transaction or is deleted below
+    INSERT INTO recommendation_cache_generations (user_id, generation)
+    VALUES (COALESCE(p_user_id, '00000000-0000-0000-0000-000000000000'), nextval('recommendation_cache_generation'))
+    ON CONFLICT (user_id) DO UPDATE SET generation = EXCLUDED.generation;
+
+    DELETE FROM recommendation_cache
+    WHERE (p_user_id IS NULL OR user_id = p_user_id)
+      AND (p_types IS NULL OR recommendation_type = ANY(p_types));
+
+    PERFORM pg_notify(
+        'recommendation_cache_invalidated',
+        json_build_object('user_id', p_user_id, 'types', p_types)::text
+    );
+END;
+$$ LANGUAGE plpgsql;
+
+-- Feedback, ratings, swipes and preference changes invalidate every cached
+-- recommendation of the user
+CREATE OR REPLACE FUNCTION invalidate_user_recommendation_cache()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        PERFORM invalidate_recommendation_cache(OLD.user_id);
+        RETURN OLD;
+    END IF;
+    PERFORM invalidate_recommendation_cache(NEW.user_id);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trigger_invalidate_recommendations_on_feedback
+    AFTER INSERT ON recommendation_feedback
+    FOR EACH ROW
+    EXECUTE FUNCTION invalidate_user_recommendation_cache();
+
+CREATE TRIGGER trigger_invalidate_recommendations_on_rating
+    AFTER INSERT OR UPDATE OR DELETE ON ratings
+    FOR EACH ROW
+    EXECUTE FUNCTION invalidate_user_recommendation_cache();
+
+CREATE TRIGGER trigger_invalidate_recommendations_on_swipe
+    AFTER INSERT ON swipe_logs
+    FOR EACH ROW
+    EXECUTE FUNCTION invalidate_user_recommendation_cache();
+
+CREATE TRIGGER trigger_invalidate_recommendations_on_preferences
+    AFTER INSERT OR UPDATE ON user_preferences
+    FOR EACH ROW
+    EXECUTE FUNCTION invalidate_user_recommendation_cache();
+
+-- Reading position updates are frequent and do not change what is
+-- recommended; starting, finishing or abandoning a book does
+CREATE TRIGGER trigger_invalidate_recommendations_on_progress_start
+    AFTER INSERT OR DELETE ON book_progress
+    FOR EACH ROW
+    EXECUTE FUNCTION invalidate_user_recommendation_cache();
+
+CREATE TRIGGER trigger_invalidate_recommendations_on_progress_status
+    AFTER UPDATE OF is_completed, is_abandoned ON book_progress
+    FOR EACH ROW
+    WHEN (OLD.is_completed IS DISTINCT FROM NEW.is_completed OR OLD.is_abandoned IS DISTINCT FROM NEW.is_abandoned)
+    EXECUTE FUNCTION invalidate_user_recommendation_cache();
+
+-- A new collaborative filtering model changes the recommendations of every
+-- user built from it
+CREATE OR REPLACE FUNCTION invalidate_collaborative_recommendation_cache()
+RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM invalidate_recommendation_cache(NULL, ARRAY['collaborative', 'hybrid', 'personalized']);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trigger_invalidate_recommendations_on_cf_model
+    AFTER INSERT ON cf_models
+    FOR EACH STATEMENT
+    EXECUTE FUNCTION invalidate_collaborative_recommendation_cache();
+
+-- New book vectors change content-based similarities. They are written in
+-- batches by the embedding command, which invalidates the cache once at the
+-- end of a run instead of per statement.
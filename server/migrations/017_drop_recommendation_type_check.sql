@@ -0,0 +1,4 @@
+-- Recommendation types are pipelines defined in configuration, so the cache
+-- no longer restricts them to the built-in ones
+
+ALTER TABLE recommendation_cache DROP CONSTRAINT IF EXISTS recommendation_cache_recommendation_type_check;
@@ -0,0 +1,21 @@
+-- Build offline download packages in a background worker
+
+-- Packages wait as 'pending' until a worker has built them, and end as
+-- 'failed' when building gives up. Pending packages hold a slot.
+ALTER TABLE offline_downloads DROP CONSTRAINT IF EXISTS offline_downloads_status_check;
+ALTER TABLE offline_downloads ADD CONSTRAINT offline_downloads_status_check
+    CHECK (status IN ('pending', 'active', 'failed', 'revoked', 'deleted'));
+
+ALTER TABLE offline_downloads ALTER COLUMN manifest_sha256 SET DEFAULT '';
+ALTER TABLE offline_downloads ADD COLUMN IF NOT EXISTS skip_audio BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE offline_downloads ADD COLUMN IF NOT EXISTS attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE offline_downloads ADD COLUMN IF NOT EXISTS locked_at TIMESTAMP WITH TIME ZONE; -- When a worker claimed the package
+ALTER TABLE offline_downloads ADD COLUMN IF NOT EXISTS error_message TEXT;
+
+-- A book is downloaded at most once at a time per user, including while it is packaged
+DROP INDEX IF EXISTS idx_offline_downloads_active_book;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_offline_downloads_active_book
+    ON offline_downloads(user_id, book_id) WHERE status IN ('pending', 'active');
+
+CREATE INDEX IF NOT EXISTS idx_offline_downloads_pending
+    ON offline_downloads(updated_at) WHERE status = 'pending';
@@ -0,0 +1,28 @@
+-- Keep one completed audio file per user, text and voice configuration, so
+-- that serving cached audio to a user reuses their own record
+
+-- Merge existing duplicates into the newest record of each key. Sessions
+-- and audiobook tasks move to the kept record; the duplicates point at the
+-- same cached blob.
+CREATE TEMPORARY TABLE audio_file_duplicates AS
+SELECT id, keep_id FROM (
+    SELECT id, FIRST_VALUE(id) OVER (
+        PARTITION BY user_id, text_hash, voice_config
+        ORDER BY created_at DESC, id
+    ) AS keep_id
+    FROM audio_files
+    WHERE status = 'completed'
+) ranked
+WHERE id <> keep_id;
+
+UPDATE audio_playback_sessions s SET audio_file_id = d.keep_id
+FROM audio_file_duplicates d WHERE s.audio_file_id = d.id;
+
+UPDATE audiobook_chapter_tasks t SET audio_file_id = d.keep_id
+FROM audio_file_duplicates d WHERE t.audio_file_id = d.id;
+
+DELETE FROM audio_files f USING audio_file_duplicates d WHERE f.id = d.id;
+DROP TABLE audio_file_duplicates;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_audio_files_user_cache_key
+    ON audio_files(user_id, text_hash, voice_config) WHERE status = 'completed';
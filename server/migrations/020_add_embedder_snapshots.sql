@@ -0,0 +1,12 @@
+-- Corpus statistics that corpus-fitted embedders were fitted to. Book
+-- vectors record the snapshot version in their embedding model, so they
+-- stay current until the embedder is fitted again.
+
+CREATE TABLE IF NOT EXISTS embedder_snapshots (
+    family TEXT NOT NULL,      -- Embedder features the statistics apply to
+    version INTEGER NOT NULL,
+    documents INTEGER NOT NULL, -- Books the statistics were computed from
+    statistics BYTEA NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (family, version)
+);
@@ -0,0 +1,107 @@
+package audio
+
+import (
+	"fmt"
+)
+
+// Concat joins audio files of the same format into one file. MP3 streams are
+// joined frame-wise after removing ID3 tags from all but the first part and
+// the Xing/Info or VBRI header frame, which describes only its own part,
+// from every part; WAV files must share sample rate and channel count; Ogg
+// Opus streams are chained.
+func Concat(format string, parts [][]byte) ([]byte, error) {
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("no audio to concatenate")
+	}
+	if len(parts) == 1 {
+		return parts[0], nil
+	}
+
+	switch format {
+	case "mp3":
+		return concatMP3(parts), nil
+	case "wav":
+		return concatWAV(parts)
+	case "ogg":
+		return concatOgg(parts)
+	default:
+		return nil, fmt.Errorf("concatenation is not supported for %s audio", format)
+	}
+}
+
+func concatMP3(parts [][]byte) []byte {
+	var size int
+	for _, part := range parts {
+		size += len(part)
+	}
+
+	joined := make([]byte, 0, size)
+	for i, part := range parts {
+		if i > 0 {
+			part = stripID3v2(part)
+		}
+		if i < len(parts)-1 {
+			part = stripID3v1(part)
+		}
+		joined = appendWithoutMP3HeaderFrame(joined, part)
+	}
+	return joined
+}
+
+// appendWithoutMP3HeaderFrame appends an MP3 stream, leaving out a
+// Xing/Info or VBRI header frame at the start of its audio
+func appendWithoutMP3HeaderFrame(joined, part []byte) []byte {
+	audio := stripID3v2(part)
+	tag := len(part) - len(audio)
+	if frame, ok := parseMP3Frame(audio); ok {
+		if isMP3HeaderFrame(audio, frame) && frame.Size <= len(audio) {
+			joined = append(joined, part[:tag]...)
+			return append(joined, audio[frame.Size:]...)
+		}
+	}
+	return append(joined, part...)
+}
+
+// stripID3v2 removes a leading ID3v2 tag
+func stripID3v2(data []byte) []byte {
+	if len(data) < 10 || string(data[0:3]) != "ID3" {
+		return data
+	}
+	// The tag size is a 28-bit synchsafe integer excluding the 10 byte header
+	size := int(data[6]&0x7f)<<21 | int(data[7]&0x7f)<<14 | int(data[8]&0x7f)<<7 | int(data[9]&0x7f)
+	size += 10
+	if data[5]&0x10 != 0 {
+		size += 10 // footer present
+	}
+	if size > len(data) {
+		return data[:0]
+	}
+	return data[size:]
+}
+
+// stripID3v1 removes a trailing 128 byte ID3v1 tag
+func stripID3v1(data []byte) []byte {
+	if len(data) >= 128 && string(data[len(data)-128:len(data)-125]) == "TAG" {
+		return data[:len(data)-128]
+	}
+	return data
+}
+
+func concatWAV(parts [][]byte) ([]byte, error) {
+	var joined *PCM
+	for i, part := range parts {
+		pcm, err := DecodeWAV(part)
+		if err != nil {
+			return nil, fmt.Errorf("part %d: %w", i, err)
+		}
+		if joined == nil {
+			joined = pcm
+			continue
+		}
+		if pcm.SampleRate != joined.SampleRate || pcm.Channels != joined.Channels {
+			return nil, fmt.Errorf("part %d: sample rate or channel count differs", i)
+		}
+		joined.Samples = append(joined.Samples, pcm.Samples...)
+	}
+	return EncodeWAV(joined), nil
+}
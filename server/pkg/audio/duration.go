@@ -0,0 +1,77 @@
+package audio
+
+// Duration returns the playing time of an audio file in seconds
+func Duration(format string, data []byte) (float64, error) {
+	info, err := Probe(format, data)
+	if err != nil {
+		return 0, err
+	}
+	return info.Duration, nil
+}
+
+// MPEG audio version indexes as encoded in the frame header
+const (
+	mpegVersion25 = 0
+	mpegVersion2  = 2
+	mpegVersion1  = 3
+)
+
+// Layer III bitrates in kbit/s, indexed by [MPEG-1?][bitrate index]
+var mp3Bitrates = [2][16]int{
+	{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},
+	{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0},
+}
+
+var mp3SampleRates = map[int][3]int{
+	mpegVersion1:  {44100, 48000, 32000},
+	mpegVersion2:  {22050, 24000, 16000},
+	mpegVersion25: {11025, 12000, 8000},
+}
+
+// mp3Frame is a parsed MPEG Layer III frame header
+type mp3Frame struct {
+	SampleRate      int
+	SamplesPerFrame int
+	Size            int
+	Channels        int
+}
+
+// parseMP3Frame parses the four byte frame header at the start of data
+func parseMP3Frame(data []byte) (*mp3Frame, bool) {
+	if len(data) < 4 || data[0] != 0xff || data[1]&0xe0 != 0xe0 {
+		return nil, false
+	}
+
+	version := int(data[1]>>3) & 0x03
+	layer := int(data[1]>>1) & 0x03
+	bitrateIndex := int(data[2]>>4) & 0x0f
+	sampleRateIndex := int(data[2]>>2) & 0x03
+	padding := int(data[2]>>1) & 0x01
+	channelMode := int(data[3]>>6) & 0x03
+
+	// Only Layer III (encoded as 1) is supported
+	if version == 1 || layer != 1 || sampleRateIndex == 3 || bitrateIndex == 0 || bitrateIndex == 15 {
+		return nil, false
+	}
+
+	mpeg1 := 0
+	samplesPerFrame := 576
+	if version == mpegVersion1 {
+		mpeg1 = 1
+		samplesPerFrame = 1152
+	}
+	bitrate := mp3Bitrates[mpeg1][bitrateIndex] * 1000
+	sampleRate := mp3SampleRates[version][sampleRateIndex]
+
+	channels := 2
+	if channelMode == 3 {
+		channels = 1
+	}
+
+	return &mp3Frame{
+		SampleRate:      sampleRate,
+		SamplesPerFrame: samplesPerFrame,
+		Size:            samplesPerFrame/8*bitrate/sampleRate + padding,
+		Channels:        channels,
+	}, true
+}
@@ -0,0 +1,809 @@
+package audio
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"unicode/utf8"
+)
+
+// M4BChapter is one chapter of an M4B audiobook
+type M4BChapter struct {
+	Title string
+	// Audio returns the chapter's encoded audio. It is called twice, once
+	// to index the frames and once to write them, so only one chapter is
+	// held in memory at a time.
+	Audio func() ([]byte, error)
+}
+
+// M4BMetadata is the book information written to the iTunes metadata of an
+// M4B file
+type M4BMetadata struct {
+	Title  string
+	Author string
+	Genre  string
+}
+
+// Track IDs of an M4B file
+const (
+	m4bAudioTrack   = 1
+	m4bChapterTrack = 2
+)
+
+// chplMaxChapters is the most chapters a Nero chapter list can hold
+const chplMaxChapters = 255
+
+// errM4BChapterChanged is returned when a chapter's audio differs between
+// indexing and writing
+var errM4BChapterChanged = errors.New("audio changed while it was packaged")
+
+// m4bStream is the audio track of an M4B file. Samples are the encoded
+// frames or packets of every chapter in order, of which sizes and durations
+// are kept; durations are counted at timescale.
+type m4bStream struct {
+	format      string
+	timescale   uint32
+	channels    int
+	sampleEntry []byte
+	// delay is the number of samples at the start the decoder discards
+	delay uint32
+
+	sizes     []uint32
+	durations []uint32
+	// chapterSamples is the number of samples of each chapter
+	chapterSamples []int
+	// chapterDurations is the length of each chapter at timescale
+	chapterDurations []uint64
+}
+
+// WriteM4B packages MP3 or Ogg Opus chapters into an MP4 audiobook written
+// to w. The audio is copied into the container without re-encoding.
+// Chapters are marked both by a QuickTime chapter track and a Nero chapter
+// list, which between them cover the common audiobook players.
+func WriteM4B(w io.Writer, format string, meta M4BMetadata, chapters []M4BChapter) error {
+	if len(chapters) == 0 {
+		return errors.New("no chapters to package")
+	}
+	if format != "mp3" && format != "ogg" {
+		return fmt.Errorf("M4B packaging is not supported for %s audio", format)
+	}
+
+	// The sample tables in moov precede the audio, so every chapter is
+	// indexed before any audio is written
+	stream := &m4bStream{format: format}
+	for i, chapter := range chapters {
+		data, err := chapter.Audio()
+		if err != nil {
+			return fmt.Errorf("chapter %d: %w", i+1, err)
+		}
+		if err := stream.add(data, i == 0); err != nil {
+			return fmt.Errorf("chapter %d: %w", i+1, err)
+		}
+	}
+	stream.finishSampleEntry()
+
+	// Audio chunks hold one chapter each and are followed by one chunk per
+	// chapter title sample
+	titles := make([][]byte, len(chapters))
+	var payloadSize int64
+	for _, size := range stream.sizes {
+		payloadSize += int64(size)
+	}
+	for i, chapter := range chapters {
+		titles[i] = chapterTextSample(chapter.Title)
+		payloadSize += int64(len(titles[i]))
+	}
+
+	ftyp := mp4Box("ftyp", []byte("M4B "), be32(0x200), []byte("M4B M4A mp42isom"))
+
+	mdatHeader := mp4Box("mdat")
+	if payloadSize+8 > math.MaxUint32 {
+		// A size of 1 is followed by a 64-bit size
+		mdatHeader = append(be32(1), "mdat"...)
+		mdatHeader = binary.BigEndian.AppendUint64(mdatHeader, uint64(payloadSize+16))
+	} else {
+		binary.BigEndian.PutUint32(mdatHeader, uint32(payloadSize+8))
+	}
+
+	// The size of moov does not depend on the offsets it records, so a first
+	// pass finds where the audio starts. 64-bit offsets are needed when the
+	// file outgrows 4 GiB.
+	largeOffsets := false
+	moov := stream.moov(meta, chapters, titles, 0, largeOffsets)
+	if int64(len(ftyp)+len(moov)+len(mdatHeader))+payloadSize > math.MaxUint32 {
+		largeOffsets = true
+		moov = stream.moov(meta, chapters, titles, 0, largeOffsets)
+	}
+	base := int64(len(ftyp) + len(moov) + len(mdatHeader))
+	moov = stream.moov(meta, chapters, titles, base, largeOffsets)
+
+	for _, box := range [][]byte{ftyp, moov, mdatHeader} {
+		if _, err := w.Write(box); err != nil {
+			return err
+		}
+	}
+	first := 0
+	for i, chapter := range chapters {
+		data, err := chapter.Audio()
+		if err != nil {
+			return fmt.Errorf("chapter %d: %w", i+1, err)
+		}
+		if err := stream.writeChapter(w, first, stream.chapterSamples[i], data); err != nil {
+			return fmt.Errorf("chapter %d: %w", i+1, err)
+		}
+		first += stream.chapterSamples[i]
+	}
+	for _, title := range titles {
+		if _, err := w.Write(title); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// add indexes the samples of a chapter
+func (s *m4bStream) add(data []byte, first bool) error {
+	if s.format == "mp3" {
+		return s.addMP3(data, first)
+	}
+	return s.addOpus(data, first)
+}
+
+// writeChapter writes the count samples of a chapter starting at sample
+// first, checking them against the index
+func (s *m4bStream) writeChapter(w io.Writer, first, count int, data []byte) error {
+	next := first
+	write := func(sample []byte) error {
+		if next >= first+count || uint32(len(sample)) != s.sizes[next] {
+			return errM4BChapterChanged
+		}
+		next++
+		_, err := w.Write(sample)
+		return err
+	}
+
+	var err error
+	if s.format == "mp3" {
+		err = mp3AudioFrames(data, func(_ *mp3Frame, frame []byte) error {
+			return write(frame)
+		})
+	} else {
+		err = opusAudioPackets(data, func([]byte) error { return nil }, func(packet []byte, _ int) error {
+			return write(packet)
+		})
+	}
+	if err != nil {
+		return err
+	}
+	if next != first+count {
+		return errM4BChapterChanged
+	}
+	return nil
+}
+
+// mp3AudioFrames calls yield with every audio frame of an MP3 stream. A
+// Xing, Info or VBRI header frame carries no audio and is left out, as is a
+// truncated last frame.
+func mp3AudioFrames(data []byte, yield func(frame *mp3Frame, data []byte) error) error {
+	data = stripID3v1(stripID3v2(data))
+
+	first := true
+	for offset := 0; offset+4 <= len(data); {
+		frame, ok := parseMP3Frame(data[offset:])
+		if !ok {
+			offset++
+			continue
+		}
+		if offset+frame.Size > len(data) {
+			break
+		}
+
+		if first {
+			first = false
+			if isMP3HeaderFrame(data[offset:], frame) {
+				offset += frame.Size
+				continue
+			}
+		}
+
+		if err := yield(frame, data[offset:offset+frame.Size]); err != nil {
+			return err
+		}
+		offset += frame.Size
+	}
+	return nil
+}
+
+// opusAudioPackets calls head with the OpusHead packet of every logical
+// stream of an Ogg Opus file, which may be chained, and yield with every
+// audio packet and its sample count
+func opusAudioPackets(data []byte, head func(packet []byte) error, yield func(packet []byte, samples int) error) error {
+	var packets int
+	var tags bool
+	return oggPackets(data, func(packet []byte) error {
+		packets++
+		if len(packet) >= 19 && string(packet[0:8]) == "OpusHead" {
+			tags = true
+			return head(packet)
+		}
+		if packets == 1 {
+			return errors.New("M4B packaging is only supported for Ogg Opus")
+		}
+		if tags {
+			// OpusTags follows OpusHead
+			tags = false
+			return nil
+		}
+
+		samples, ok := opusPacketSamples(packet)
+		if !ok {
+			return fmt.Errorf("invalid Opus packet %d", packets)
+		}
+		return yield(packet, samples)
+	})
+}
+
+// addMP3 indexes the frames of an MP3 chapter. Every chapter must share the
+// sample rate and channel count of the first.
+func (s *m4bStream) addMP3(data []byte, first bool) error {
+	var samples int
+	var duration uint64
+	err := mp3AudioFrames(data, func(frame *mp3Frame, data []byte) error {
+		if samples == 0 {
+			if first {
+				s.timescale = uint32(frame.SampleRate)
+				s.channels = frame.Channels
+				s.sampleEntry = mp4AudioSampleEntry("mp4a", frame.Channels, frame.SampleRate)
+			} else if frame.SampleRate != int(s.timescale) || frame.Channels != s.channels {
+				return errors.New("sample rate or channel count differs from the first chapter")
+			}
+		}
+
+		s.sizes = append(s.sizes, uint32(len(data)))
+		s.durations = append(s.durations, uint32(frame.SamplesPerFrame))
+		samples++
+		duration += uint64(frame.SamplesPerFrame)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if samples == 0 {
+		return errors.New("no MP3 frames found")
+	}
+	s.chapterSamples = append(s.chapterSamples, samples)
+	s.chapterDurations = append(s.chapterDurations, duration)
+	return nil
+}
+
+// addOpus indexes the audio packets of an Ogg Opus chapter. The stream
+// takes its codec setup from the first logical stream of the first chapter;
+// an MP4 track has a single decoder setup, so the pre-skip of later streams
+// is played through.
+func (s *m4bStream) addOpus(data []byte, first bool) error {
+	var samples int
+	var duration uint64
+	head := func(packet []byte) error {
+		if first && s.sampleEntry == nil {
+			s.timescale = 48000
+			s.channels = int(packet[9])
+			s.delay = uint32(binary.LittleEndian.Uint16(packet[10:12]))
+			s.sampleEntry = mp4AudioSampleEntry("Opus", int(packet[9]), 48000, opusSpecificBox(packet))
+		} else if int(packet[9]) != s.channels {
+			return errors.New("channel count differs from the first chapter")
+		}
+		return nil
+	}
+	err := opusAudioPackets(data, head, func(packet []byte, frameSamples int) error {
+		s.sizes = append(s.sizes, uint32(len(packet)))
+		s.durations = append(s.durations, uint32(frameSamples))
+		samples++
+		duration += uint64(frameSamples)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if samples == 0 {
+		return errors.New("no Opus packets found")
+	}
+	s.chapterSamples = append(s.chapterSamples, samples)
+	s.chapterDurations = append(s.chapterDurations, duration)
+	return nil
+}
+
+// finishSampleEntry adds the MPEG-4 elementary stream descriptor to an MP3
+// sample entry, which needs buffer size and bit rates of the whole stream
+func (s *m4bStream) finishSampleEntry() {
+	if s.format != "mp3" {
+		return
+	}
+
+	var total, largest, maxBitRate uint64
+	for i, sampleSize := range s.sizes {
+		size := uint64(sampleSize)
+		total += size
+		largest = max(largest, size)
+		maxBitRate = max(maxBitRate, size*8*uint64(s.timescale)/uint64(s.durations[i]))
+	}
+	avgBitRate := total * 8 * uint64(s.timescale) / s.totalDuration()
+
+	// MPEG-1 audio runs at 32 kHz and above, MPEG-2 audio below
+	objectType := byte(0x6b)
+	if s.timescale < 32000 {
+		objectType = 0x69
+	}
+
+	decoderConfig := []byte{0x04, 13, objectType, 0x15} // audio stream
+	decoderConfig = append(decoderConfig, byte(largest>>16), byte(largest>>8), byte(largest))
+	decoderConfig = binary.BigEndian.AppendUint32(decoderConfig, uint32(min(maxBitRate, math.MaxUint32)))
+	decoderConfig = binary.BigEndian.AppendUint32(decoderConfig, uint32(min(avgBitRate, math.MaxUint32)))
+	slConfig := []byte{0x06, 1, 0x02}
+
+	descriptor := []byte{0x03, byte(3 + len(decoderConfig) + len(slConfig)), 0, 0, 0}
+	descriptor = append(descriptor, decoderConfig...)
+	descriptor = append(descriptor, slConfig...)
+
+	esds := mp4FullBox("esds", 0, 0, descriptor)
+	s.sampleEntry = append(s.sampleEntry, esds...)
+	binary.BigEndian.PutUint32(s.sampleEntry, uint32(len(s.sampleEntry)))
+}
+
+func (s *m4bStream) totalDuration() uint64 {
+	var total uint64
+	for _, duration := range s.chapterDurations {
+		total += duration
+	}
+	return total
+}
+
+// chapterStarts returns the presentation time at which each chapter starts,
+// at timescale. The decoder delay is cut from the first chapter.
+func (s *m4bStream) chapterStarts() []uint64 {
+	starts := make([]uint64, len(s.chapterDurations))
+	var position uint64
+	for i, duration := range s.chapterDurations {
+		if position > uint64(s.delay) {
+			starts[i] = position - uint64(s.delay)
+		}
+		position += duration
+	}
+	return starts
+}
+
+// moov builds the movie box. base is the file offset of the mdat payload.
+func (s *m4bStream) moov(meta M4BMetadata, chapters []M4BChapter, titles [][]byte, base int64, largeOffsets bool) []byte {
+	total := s.totalDuration()
+	presented := total - min(total, uint64(s.delay))
+
+	// Audio chunks
+	audioOffsets := make([]int64, len(s.chapterSamples))
+	offset := base
+	sample := 0
+	for i, count := range s.chapterSamples {
+		audioOffsets[i] = offset
+		for _, size := range s.sizes[sample : sample+count] {
+			offset += int64(size)
+		}
+		sample += count
+	}
+
+	// Chapter title chunks
+	starts := s.chapterStarts()
+	titleOffsets := make([]int64, len(titles))
+	titleSizes := make([]uint32, len(titles))
+	titleDurations := make([]uint32, len(titles))
+	for i, title := range titles {
+		titleOffsets[i] = offset
+		titleSizes[i] = uint32(len(title))
+		end := presented
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+		titleDurations[i] = uint32(end - min(end, starts[i]))
+		offset += int64(len(title))
+	}
+	titleChunkSamples := make([]int, len(titles))
+	for i := range titleChunkSamples {
+		titleChunkSamples[i] = 1
+	}
+
+	audioSTBL := mp4Box("stbl",
+		mp4FullBox("stsd", 0, 0, be32(1), s.sampleEntry),
+		mp4TimeToSample(s.durations),
+		mp4SampleToChunk(s.chapterSamples),
+		mp4SampleSizes(s.sizes),
+		mp4ChunkOffsets(audioOffsets, largeOffsets),
+	)
+	var audioEdits []byte
+	if s.delay > 0 {
+		audioEdits = mp4Box("edts", mp4EditList(presented, uint64(s.delay)))
+	}
+	audioTrak := mp4Box("trak",
+		mp4TrackHeader(m4bAudioTrack, 0x3, presented, 0x0100),
+		audioEdits,
+		mp4Box("tref", mp4Box("chap", be32(m4bChapterTrack))),
+		mp4Box("mdia",
+			mp4MediaHeader(s.timescale, total),
+			mp4Handler("soun", "SoundHandler"),
+			mp4Box("minf",
+				mp4FullBox("smhd", 0, 0, make([]byte, 4)),
+				mp4DataInformation(),
+				audioSTBL,
+			),
+		),
+	)
+
+	// QuickTime requires a text sample description with these display
+	// properties, all zero but a left justification, to list chapters
+	textProperties := make([]byte, 43)
+	textProperties[7] = 1
+	textEntry := mp4Box("text", make([]byte, 6), be16(1), textProperties)
+	chapterSTBL := mp4Box("stbl",
+		mp4FullBox("stsd", 0, 0, be32(1), textEntry),
+		mp4TimeToSample(titleDurations),
+		mp4SampleToChunk(titleChunkSamples),
+		mp4SampleSizes(titleSizes),
+		mp4ChunkOffsets(titleOffsets, largeOffsets),
+	)
+	chapterTrak := mp4Box("trak",
+		// The chapter track is in the movie but not enabled for playback
+		mp4TrackHeader(m4bChapterTrack, 0x2, presented, 0),
+		mp4Box("mdia",
+			mp4MediaHeader(s.timescale, presented),
+			mp4Handler("text", "ChapterHandler"),
+			mp4Box("minf",
+				mp4GenericMediaHeader(),
+				mp4DataInformation(),
+				chapterSTBL,
+			),
+		),
+	)
+
+	return mp4Box("moov",
+		mp4MovieHeader(s.timescale, presented, m4bChapterTrack+1),
+		audioTrak,
+		chapterTrak,
+		mp4Box("udta",
+			s.neroChapters(chapters, starts),
+			mp4ITunesMetadata(meta),
+		),
+	)
+}
+
+// neroChapters returns the chpl box listing chapter start times in 100ns
+// units. The list holds at most 255 chapters, so longer books rely on the
+// chapter track alone.
+func (s *m4bStream) neroChapters(chapters []M4BChapter, starts []uint64) []byte {
+	if len(chapters) > chplMaxChapters {
+		return nil
+	}
+
+	list := []byte{0, 0, 0, 0, byte(len(chapters))}
+	for i, chapter := range chapters {
+		list = binary.BigEndian.AppendUint64(list, starts[i]*10_000_000/uint64(s.timescale))
+		title := truncateUTF8(chapter.Title, 255)
+		list = append(list, byte(len(title)))
+		list = append(list, title...)
+	}
+	return mp4FullBox("chpl", 1, 0, list)
+}
+
+// chapterTextSample encodes a chapter title as a QuickTime text sample: the
+// UTF-8 text with a 16-bit length, followed by an encoding atom
+func chapterTextSample(title string) []byte {
+	title = truncateUTF8(title, math.MaxUint16)
+	sample := be16(uint16(len(title)))
+	sample = append(sample, title...)
+	return append(sample, mp4Box("encd", be32(0x00000100))...) // UTF-8
+}
+
+// truncateUTF8 shortens s to at most n bytes without splitting a character
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// opusSpecificBox converts an OpusHead packet to the dOps box of an MP4
+// Opus sample entry, which holds the same fields in big-endian order
+func opusSpecificBox(head []byte) []byte {
+	box := []byte{0, head[9]}
+	box = binary.BigEndian.AppendUint16(box, binary.LittleEndian.Uint16(head[10:12]))
+	box = binary.BigEndian.AppendUint32(box, binary.LittleEndian.Uint32(head[12:16]))
+	box = binary.BigEndian.AppendUint16(box, binary.LittleEndian.Uint16(head[16:18]))
+	box = append(box, head[18])
+	// Mapping families other than 0 carry a channel mapping table
+	if head[18] != 0 {
+		box = append(box, head[19:]...)
+	}
+	return mp4Box("dOps", box)
+}
+
+// opusPacketSamples returns the number of 48 kHz samples an Opus packet
+// decodes to, read from its table-of-contents byte
+func opusPacketSamples(packet []byte) (int, bool) {
+	if len(packet) == 0 {
+		return 0, false
+	}
+
+	config := packet[0] >> 3
+	var frameSize int
+	switch {
+	case config < 12: // SILK
+		frameSize = [4]int{480, 960, 1920, 2880}[config%4]
+	case config < 16: // Hybrid
+		frameSize = [2]int{480, 960}[config%2]
+	default: // CELT
+		frameSize = [4]int{120, 240, 480, 960}[config%4]
+	}
+
+	frames := 1
+	switch packet[0] & 0x03 {
+	case 1, 2:
+		frames = 2
+	case 3:
+		if len(packet) < 2 {
+			return 0, false
+		}
+		frames = int(packet[1] & 0x3f)
+	}
+	return frames * frameSize, true
+}
+
+// MP4 box writers
+
+func be16(v uint16) []byte { return binary.BigEndian.AppendUint16(nil, v) }
+func be32(v uint32) []byte { return binary.BigEndian.AppendUint32(nil, v) }
+
+// mp4Box returns a box of the given type holding the concatenated payloads
+func mp4Box(boxType string, payloads ...[]byte) []byte {
+	size := 8
+	for _, payload := range payloads {
+		size += len(payload)
+	}
+	box := make([]byte, 0, size)
+	box = binary.BigEndian.AppendUint32(box, uint32(size))
+	box = append(box, boxType...)
+	for _, payload := range payloads {
+		box = append(box, payload...)
+	}
+	return box
+}
+
+// mp4FullBox returns a box whose payload starts with a version and flags
+func mp4FullBox(boxType string, version byte, flags uint32, payloads ...[]byte) []byte {
+	header := []byte{version, byte(flags >> 16), byte(flags >> 8), byte(flags)}
+	return mp4Box(boxType, append([][]byte{header}, payloads...)...)
+}
+
+// mp4Matrix is the identity transformation matrix of movie and track headers
+var mp4Matrix = func() []byte {
+	matrix := make([]byte, 0, 36)
+	for _, v := range []uint32{0x00010000, 0, 0, 0, 0x00010000, 0, 0, 0, 0x40000000} {
+		matrix = binary.BigEndian.AppendUint32(matrix, v)
+	}
+	return matrix
+}()
+
+// mp4Times appends zero creation and modification times, the timescale when
+// it is given, and duration. Version 1 boxes are used for durations that
+// overflow 32 bits.
+func mp4Times(duration uint64, timescale uint32, withTimescale bool) (byte, []byte) {
+	var payload []byte
+	version := byte(0)
+	if duration > math.MaxUint32 {
+		version = 1
+		payload = make([]byte, 16)
+	} else {
+		payload = make([]byte, 8)
+	}
+	if withTimescale {
+		payload = binary.BigEndian.AppendUint32(payload, timescale)
+	}
+	if version == 1 {
+		payload = binary.BigEndian.AppendUint64(payload, duration)
+	} else {
+		payload = binary.BigEndian.AppendUint32(payload, uint32(duration))
+	}
+	return version, payload
+}
+
+func mp4MovieHeader(timescale uint32, duration uint64, nextTrackID uint32) []byte {
+	version, times := mp4Times(duration, timescale, true)
+	return mp4FullBox("mvhd", version, 0,
+		times,
+		be32(0x00010000), // rate 1.0
+		be16(0x0100),     // volume 1.0
+		make([]byte, 10),
+		mp4Matrix,
+		make([]byte, 24),
+		be32(nextTrackID),
+	)
+}
+
+func mp4TrackHeader(trackID uint32, flags uint32, duration uint64, volume uint16) []byte {
+	version, times := mp4Times(duration, 0, false)
+	// Track headers place the track ID between the times and the duration
+	durationSize := 4
+	if version == 1 {
+		durationSize = 8
+	}
+	split := len(times) - durationSize
+	return mp4FullBox("tkhd", version, flags,
+		times[:split],
+		be32(trackID),
+		make([]byte, 4),
+		times[split:],
+		make([]byte, 8),
+		be16(0), // layer
+		be16(0), // alternate group
+		be16(volume),
+		make([]byte, 2),
+		mp4Matrix,
+		be32(0), // width
+		be32(0), // height
+	)
+}
+
+func mp4MediaHeader(timescale uint32, duration uint64) []byte {
+	version, times := mp4Times(duration, timescale, true)
+	return mp4FullBox("mdhd", version, 0,
+		times,
+		be16(0x55c4), // "und" language
+		be16(0),
+	)
+}
+
+// mp4EditList starts presentation mediaTime samples into the media
+func mp4EditList(duration, mediaTime uint64) []byte {
+	if duration > math.MaxUint32 || mediaTime > math.MaxInt32 {
+		entry := binary.BigEndian.AppendUint64(nil, duration)
+		entry = binary.BigEndian.AppendUint64(entry, mediaTime)
+		return mp4FullBox("elst", 1, 0, be32(1), entry, be16(1), be16(0))
+	}
+	return mp4FullBox("elst", 0, 0, be32(1), be32(uint32(duration)), be32(uint32(mediaTime)), be16(1), be16(0))
+}
+
+func mp4Handler(handlerType, name string) []byte {
+	return mp4FullBox("hdlr", 0, 0,
+		make([]byte, 4),
+		[]byte(handlerType),
+		make([]byte, 12),
+		append([]byte(name), 0),
+	)
+}
+
+// mp4GenericMediaHeader is the media header of a QuickTime text track,
+// including the undocumented text atom that Apple players require of
+// chapter tracks
+func mp4GenericMediaHeader() []byte {
+	text := make([]byte, 0, 36)
+	text = append(text, be16(1)...)
+	for _, v := range []uint32{0, 0, 0, 1, 0, 0, 0, 0x00004000} {
+		text = binary.BigEndian.AppendUint32(text, v)
+	}
+	text = append(text, be16(0)...)
+
+	return mp4Box("gmhd",
+		mp4FullBox("gmin", 0, 0,
+			be16(0x40),                               // graphics mode
+			be16(0x8000), be16(0x8000), be16(0x8000), // op color
+			be16(0), // balance
+			be16(0),
+		),
+		mp4Box("text", text),
+	)
+}
+
+// mp4DataInformation declares that the media is in the file itself
+func mp4DataInformation() []byte {
+	return mp4Box("dinf", mp4FullBox("dref", 0, 0, be32(1), mp4FullBox("url ", 0, 1)))
+}
+
+// mp4AudioSampleEntry returns an audio sample entry of the given codec. Its
+// size is updated when more boxes are appended.
+func mp4AudioSampleEntry(codec string, channels, sampleRate int, boxes ...[]byte) []byte {
+	payloads := [][]byte{
+		make([]byte, 6),
+		be16(1), // data reference index
+		make([]byte, 8),
+		be16(uint16(channels)),
+		be16(16), // sample size
+		make([]byte, 4),
+		be32(uint32(sampleRate) << 16),
+	}
+	return mp4Box(codec, append(payloads, boxes...)...)
+}
+
+// mp4TimeToSample run-length encodes sample durations
+func mp4TimeToSample(durations []uint32) []byte {
+	var entries []byte
+	var count uint32
+	for i, duration := range durations {
+		count++
+		if i+1 < len(durations) && durations[i+1] == duration {
+			continue
+		}
+		entries = binary.BigEndian.AppendUint32(entries, count)
+		entries = binary.BigEndian.AppendUint32(entries, duration)
+		count = 0
+	}
+	return mp4FullBox("stts", 0, 0, be32(uint32(len(entries)/8)), entries)
+}
+
+// mp4SampleToChunk records the number of samples in each chunk, listing
+// only the chunks where it changes
+func mp4SampleToChunk(chunkSamples []int) []byte {
+	var entries []byte
+	for i, count := range chunkSamples {
+		if i > 0 && chunkSamples[i-1] == count {
+			continue
+		}
+		entries = binary.BigEndian.AppendUint32(entries, uint32(i+1))
+		entries = binary.BigEndian.AppendUint32(entries, uint32(count))
+		entries = binary.BigEndian.AppendUint32(entries, 1) // sample description index
+	}
+	return mp4FullBox("stsc", 0, 0, be32(uint32(len(entries)/12)), entries)
+}
+
+func mp4SampleSizes(sizes []uint32) []byte {
+	entries := make([]byte, 0, len(sizes)*4)
+	for _, size := range sizes {
+		entries = binary.BigEndian.AppendUint32(entries, size)
+	}
+	return mp4FullBox("stsz", 0, 0, be32(0), be32(uint32(len(sizes))), entries)
+}
+
+func mp4ChunkOffsets(offsets []int64, large bool) []byte {
+	var entries []byte
+	for _, offset := range offsets {
+		if large {
+			entries = binary.BigEndian.AppendUint64(entries, uint64(offset))
+		} else {
+			entries = binary.BigEndian.AppendUint32(entries, uint32(offset))
+		}
+	}
+	if large {
+		return mp4FullBox("co64", 0, 0, be32(uint32(len(offsets))), entries)
+	}
+	return mp4FullBox("stco", 0, 0, be32(uint32(len(offsets))), entries)
+}
+
+// mp4ITunesMetadata returns the meta box with the book's title, author and
+// genre, marking the file as an audiobook
+func mp4ITunesMetadata(meta M4BMetadata) []byte {
+	item := func(name string, dataType uint32, value []byte) []byte {
+		return mp4Box(name, mp4Box("data", be32(dataType), be32(0), value))
+	}
+
+	const utf8Type, integerType = 1, 21
+	items := [][]byte{
+		item("\xa9nam", utf8Type, []byte(meta.Title)),
+		item("\xa9alb", utf8Type, []byte(meta.Title)),
+		item("\xa9ART", utf8Type, []byte(meta.Author)),
+		item("aART", utf8Type, []byte(meta.Author)),
+	}
+	if meta.Genre != "" {
+		items = append(items, item("\xa9gen", utf8Type, []byte(meta.Genre)))
+	}
+	items = append(items, item("stik", integerType, []byte{2})) // audiobook
+
+	handler := mp4FullBox("hdlr", 0, 0,
+		make([]byte, 4),
+		[]byte("mdir"),
+		[]byte("appl"),
+		make([]byte, 8),
+		[]byte{0},
+	)
+	return mp4FullBox("meta", 0, 0, handler, mp4Box("ilst", items...))
+}
@@ -0,0 +1,153 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// testChapters returns numbered chapters holding the audio
+func testChapters(audio ...[]byte) []M4BChapter {
+	chapters := make([]M4BChapter, len(audio))
+	for i, data := range audio {
+		data := data
+		chapters[i] = M4BChapter{
+			Title: "第" + string(rune('1'+i)) + "章",
+			Audio: func() ([]byte, error) { return data, nil },
+		}
+	}
+	return chapters
+}
+
+// mp4TopLevelBoxes returns the types and sizes of the boxes of an MP4 file
+func mp4TopLevelBoxes(t *testing.T, data []byte) ([]string, []int) {
+	t.Helper()
+	var types []string
+	var sizes []int
+	for len(data) > 0 {
+		if len(data) < 8 {
+			t.Fatalf("%d bytes left after the last box", len(data))
+		}
+		size := int(binary.BigEndian.Uint32(data))
+		if size < 8 || size > len(data) {
+			t.Fatalf("box %q has size %d with %d bytes left", data[4:8], size, len(data))
+		}
+		types = append(types, string(data[4:8]))
+		sizes = append(sizes, size)
+		data = data[size:]
+	}
+	return types, sizes
+}
+
+func TestWriteM4B(t *testing.T) {
+	mp3 := testMP3(t, mp3StereoHeader, 20)
+	opus := testOpus(5, 10)
+	// Each Opus audio packet is three bytes
+	opusPayload := 10 * 3
+
+	tests := []struct {
+		name     string
+		format   string
+		chapters []M4BChapter
+		// audioSize is the size of the audio samples in mdat
+		audioSize int
+		wantErr   bool
+	}{
+		{
+			name:      "MP3",
+			format:    "mp3",
+			chapters:  testChapters(mp3, mp3, mp3),
+			audioSize: 3 * len(mp3),
+		},
+		{
+			name:      "MP3 with ID3v2 tag",
+			format:    "mp3",
+			chapters:  testChapters(append(testID3v2(100), mp3...)),
+			audioSize: len(mp3),
+		},
+		{
+			name:      "Opus",
+			format:    "ogg",
+			chapters:  testChapters(opus, opus),
+			audioSize: 2 * opusPayload,
+		},
+		{
+			name:      "chained Opus",
+			format:    "ogg",
+			chapters:  testChapters(append(testOpus(1, 10), testOpus(2, 10)...)),
+			audioSize: 2 * opusPayload,
+		},
+		{
+			name:    "no chapters",
+			format:  "mp3",
+			wantErr: true,
+		},
+		{
+			name:     "unsupported format",
+			format:   "wav",
+			chapters: testChapters([]byte("RIFF")),
+			wantErr:  true,
+		},
+		{
+			name:     "chapter without audio",
+			format:   "mp3",
+			chapters: testChapters([]byte("not audio at all")),
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := WriteM4B(&out, tt.format, M4BMetadata{Title: "Book", Author: "Author"}, tt.chapters)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("WriteM4B succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			data := out.Bytes()
+			types, sizes := mp4TopLevelBoxes(t, data)
+			if len(types) != 3 || types[0] != "ftyp" || types[1] != "moov" || types[2] != "mdat" {
+				t.Fatalf("top-level boxes %v, want [ftyp moov mdat]", types)
+			}
+
+			titleSize := 0
+			for _, chapter := range tt.chapters {
+				titleSize += len(chapterTextSample(chapter.Title))
+			}
+			if want := 8 + tt.audioSize + titleSize; sizes[2] != want {
+				t.Errorf("mdat size %d, want %d", sizes[2], want)
+			}
+
+			chpl := bytes.Index(data, []byte("chpl"))
+			if chpl < 0 {
+				t.Fatal("no Nero chapter list")
+			}
+			if count := int(data[chpl+8+4]); count != len(tt.chapters) {
+				t.Errorf("Nero chapter list has %d chapters, want %d", count, len(tt.chapters))
+			}
+		})
+	}
+}
+
+func TestWriteM4BChapterChanged(t *testing.T) {
+	calls := 0
+	chapters := []M4BChapter{{
+		Title: "Changing",
+		Audio: func() ([]byte, error) {
+			calls++
+			return testMP3(t, mp3StereoHeader, calls*2), nil
+		},
+	}}
+
+	var out bytes.Buffer
+	if err := WriteM4B(&out, "mp3", M4BMetadata{}, chapters); !errors.Is(err, errM4BChapterChanged) {
+		t.Fatalf("got error %v, want %v", err, errM4BChapterChanged)
+	}
+}
@@ -0,0 +1,291 @@
+package audio
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// duckBlock is the window over which narration loudness is measured for ducking
+const duckBlock = 10 * time.Millisecond
+
+// loopCrossfade is how long the end of a looped bed overlaps its start
+const loopCrossfade = 300 * time.Millisecond
+
+// resampleZeroCrossings is how many zero crossings of the interpolation
+// kernel lie on each side of an output sample. More give a steeper filter.
+const resampleZeroCrossings = 16
+
+// MixOptions controls how a background bed is laid under narration
+type MixOptions struct {
+	// BedGainDB is the level of the bed while narration is silent
+	BedGainDB float64
+	// DuckDB is the further attenuation applied while narration is audible
+	DuckDB float64
+	// DuckThreshold is the narration RMS, relative to full scale, above
+	// which the bed is ducked
+	DuckThreshold float64
+	// Attack and Release are how quickly the bed ducks and recovers
+	Attack  time.Duration
+	Release time.Duration
+	// FadeIn and FadeOut shape the start and end of the bed
+	FadeIn  time.Duration
+	FadeOut time.Duration
+}
+
+// DefaultMixOptions returns settings that keep an ambient bed clearly below speech
+func DefaultMixOptions() MixOptions {
+	return MixOptions{
+		BedGainDB:     -18,
+		DuckDB:        12,
+		DuckThreshold: 0.02,
+		Attack:        80 * time.Millisecond,
+		Release:       600 * time.Millisecond,
+		FadeIn:        2 * time.Second,
+		FadeOut:       3 * time.Second,
+	}
+}
+
+// Mix lays bed under voice. The bed is converted to the voice's sample rate
+// and channel count, looped to the length of the voice with a crossfade at
+// the seam, ducked while the voice is audible and faded in and out. The
+// result has the voice's format.
+func Mix(voice, bed *PCM, opts MixOptions) (*PCM, error) {
+	if voice.SampleRate <= 0 || voice.Channels <= 0 {
+		return nil, errors.New("narration has no audio format")
+	}
+	if bed.SampleRate <= 0 || bed.Channels <= 0 || len(bed.Samples) < bed.Channels {
+		return nil, errors.New("background track is empty")
+	}
+
+	bed = Resample(RemixChannels(bed, voice.Channels), voice.SampleRate)
+	frames := len(voice.Samples) / voice.Channels
+	bedFrames := len(bed.Samples) / bed.Channels
+	if bedFrames == 0 {
+		// A bed shorter than one frame at the voice's sample rate
+		return nil, errors.New("background track is too short")
+	}
+	loop := newBedLoop(bed, durationFrames(loopCrossfade, voice.SampleRate))
+	gains := duckGains(voice, opts)
+
+	bedGain := dbToGain(opts.BedGainDB)
+	fadeIn := durationFrames(opts.FadeIn, voice.SampleRate)
+	fadeOut := durationFrames(opts.FadeOut, voice.SampleRate)
+
+	mixed := &PCM{
+		SampleRate: voice.SampleRate,
+		Channels:   voice.Channels,
+		Samples:    make([]int16, frames*voice.Channels),
+	}
+	bedFrame := make([]float64, bed.Channels)
+	for frame := 0; frame < frames; frame++ {
+		gain := bedGain * gains[frame] * fadeGain(frame, frames, fadeIn, fadeOut)
+		loop.frame(frame, bedFrame)
+		for ch := 0; ch < voice.Channels; ch++ {
+			i := frame*voice.Channels + ch
+			value := float64(voice.Samples[i]) + gain*bedFrame[ch]
+			mixed.Samples[i] = clampSample(value)
+		}
+	}
+
+	return mixed, nil
+}
+
+// bedLoop plays a bed once from its start and then repeats it, with the
+// last overlap frames of the bed faded out over its first overlap frames
+type bedLoop struct {
+	bed      *PCM
+	channels int
+	// period is the length of one repetition after the first pass
+	period int
+	// seam holds the crossfaded frames that start every repetition
+	seam []float64
+}
+
+// newBedLoop crossfades at most half of the bed, with equal-power gains
+// since the ends of an ambient recording are uncorrelated
+func newBedLoop(bed *PCM, overlap int) *bedLoop {
+	frames := len(bed.Samples) / bed.Channels
+	overlap = min(overlap, frames/2)
+	loop := &bedLoop{bed: bed, channels: bed.Channels, period: frames - overlap}
+
+	loop.seam = make([]float64, overlap*bed.Channels)
+	for frame := 0; frame < overlap; frame++ {
+		t := (float64(frame) + 0.5) / float64(overlap) * math.Pi / 2
+		for ch := 0; ch < bed.Channels; ch++ {
+			head := float64(bed.Samples[frame*bed.Channels+ch])
+			tail := float64(bed.Samples[(loop.period+frame)*bed.Channels+ch])
+			loop.seam[frame*bed.Channels+ch] = head*math.Sin(t) + tail*math.Cos(t)
+		}
+	}
+	return loop
+}
+
+// frame reads the samples of the bed at a frame of the mix into samples
+func (l *bedLoop) frame(frame int, samples []float64) {
+	if frame >= l.period {
+		frame = (frame - l.period) % l.period
+		if start := frame * l.channels; start < len(l.seam) {
+			copy(samples, l.seam[start:start+l.channels])
+			return
+		}
+	}
+	for ch := range samples {
+		samples[ch] = float64(l.bed.Samples[frame*l.channels+ch])
+	}
+}
+
+// duckGains returns the bed gain for every voice frame. Loudness is measured
+// per block, and the gain follows it with separate attack and release times
+// so the bed does not pump between words.
+func duckGains(voice *PCM, opts MixOptions) []float64 {
+	frames := len(voice.Samples) / voice.Channels
+	gains := make([]float64, frames)
+
+	ducked := dbToGain(-math.Abs(opts.DuckDB))
+	attack := smoothingCoefficient(opts.Attack, voice.SampleRate)
+	release := smoothingCoefficient(opts.Release, voice.SampleRate)
+	block := durationFrames(duckBlock, voice.SampleRate)
+	if block < 1 {
+		block = 1
+	}
+
+	gain := 1.0
+	for start := 0; start < frames; start += block {
+		end := start + block
+		if end > frames {
+			end = frames
+		}
+
+		var sum float64
+		for _, sample := range voice.Samples[start*voice.Channels : end*voice.Channels] {
+			value := float64(sample) / math.MaxInt16
+			sum += value * value
+		}
+		rms := math.Sqrt(sum / float64((end-start)*voice.Channels))
+
+		target, coefficient := 1.0, release
+		if rms > opts.DuckThreshold {
+			target, coefficient = ducked, attack
+		}
+		for frame := start; frame < end; frame++ {
+			gain = target + (gain-target)*coefficient
+			gains[frame] = gain
+		}
+	}
+
+	return gains
+}
+
+// RemixChannels converts audio to the given channel count. Mono is copied to
+// every output channel; otherwise channels are averaged down to mono first.
+func RemixChannels(p *PCM, channels int) *PCM {
+	if p.Channels == channels {
+		return p
+	}
+
+	frames := len(p.Samples) / p.Channels
+	out := &PCM{SampleRate: p.SampleRate, Channels: channels, Samples: make([]int16, frames*channels)}
+	for frame := 0; frame < frames; frame++ {
+		var sum int
+		for ch := 0; ch < p.Channels; ch++ {
+			sum += int(p.Samples[frame*p.Channels+ch])
+		}
+		mono := int16(sum / p.Channels)
+		for ch := 0; ch < channels; ch++ {
+			out.Samples[frame*channels+ch] = mono
+		}
+	}
+	return out
+}
+
+// Resample converts audio to the given sample rate with a windowed sinc
+// interpolator. Its cutoff is the lower of the two Nyquist frequencies, so
+// content the new rate cannot represent is filtered out instead of aliasing.
+func Resample(p *PCM, sampleRate int) *PCM {
+	if p.SampleRate == sampleRate {
+		return p
+	}
+
+	frames := len(p.Samples) / p.Channels
+	outFrames := int(int64(frames) * int64(sampleRate) / int64(p.SampleRate))
+	out := &PCM{SampleRate: sampleRate, Channels: p.Channels, Samples: make([]int16, outFrames*p.Channels)}
+	step := float64(p.SampleRate) / float64(sampleRate)
+	// cutoff is relative to the input Nyquist frequency; the kernel widens
+	// as it narrows
+	cutoff := math.Min(1, 1/step)
+	halfWidth := resampleZeroCrossings / cutoff
+
+	sums := make([]float64, p.Channels)
+	for frame := 0; frame < outFrames; frame++ {
+		center := float64(frame) * step
+		first := max(0, int(math.Ceil(center-halfWidth)))
+		last := min(frames-1, int(math.Floor(center+halfWidth)))
+
+		clear(sums)
+		var total float64
+		for i := first; i <= last; i++ {
+			x := center - float64(i)
+			window := 0.5 + 0.5*math.Cos(math.Pi*x/halfWidth)
+			weight := cutoff * sinc(cutoff*x) * window
+			total += weight
+			for ch := 0; ch < p.Channels; ch++ {
+				sums[ch] += weight * float64(p.Samples[i*p.Channels+ch])
+			}
+		}
+		// Normalizing keeps the gain at DC exact near the ends, where the
+		// kernel is cut short
+		for ch := 0; ch < p.Channels; ch++ {
+			out.Samples[frame*p.Channels+ch] = clampSample(sums[ch] / total)
+		}
+	}
+	return out
+}
+
+// sinc is the normalized sinc function sin(πx)/(πx)
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// fadeGain returns the linear fade applied to a frame of the bed
+func fadeGain(frame, frames, fadeIn, fadeOut int) float64 {
+	gain := 1.0
+	if fadeIn > 0 && frame < fadeIn {
+		gain = float64(frame) / float64(fadeIn)
+	}
+	if remaining := frames - frame; fadeOut > 0 && remaining < fadeOut {
+		gain = math.Min(gain, float64(remaining)/float64(fadeOut))
+	}
+	return gain
+}
+
+// smoothingCoefficient returns the per-frame coefficient of a one-pole
+// smoother with the given time constant
+func smoothingCoefficient(d time.Duration, sampleRate int) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return math.Exp(-1 / (d.Seconds() * float64(sampleRate)))
+}
+
+func durationFrames(d time.Duration, sampleRate int) int {
+	return int(d.Seconds() * float64(sampleRate))
+}
+
+func dbToGain(db float64) float64 {
+	return math.Pow(10, db/20)
+}
+
+func clampSample(value float64) int16 {
+	switch {
+	case value > math.MaxInt16:
+		return math.MaxInt16
+	case value < math.MinInt16:
+		return math.MinInt16
+	default:
+		return int16(math.Round(value))
+	}
+}
@@ -0,0 +1,145 @@
+package audio
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Ogg page header flags
+const (
+	oggFlagBOS = 0x02
+	oggFlagEOS = 0x04
+)
+
+// oggCRCTable is the table of the Ogg page checksum, a CRC-32 with polynomial
+// 0x04c11db7 computed without bit reflection
+var oggCRCTable = func() [256]uint32 {
+	var table [256]uint32
+	for i := range table {
+		crc := uint32(i) << 24
+		for bit := 0; bit < 8; bit++ {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04c11db7
+			} else {
+				crc <<= 1
+			}
+		}
+		table[i] = crc
+	}
+	return table
+}()
+
+// oggChecksum returns the checksum of a page whose checksum field is zeroed
+func oggChecksum(page []byte) uint32 {
+	var crc uint32
+	for _, b := range page {
+		crc = crc<<8 ^ oggCRCTable[byte(crc>>24)^b]
+	}
+	return crc
+}
+
+// splitOggPages splits an Ogg file into its pages
+func splitOggPages(data []byte) ([][]byte, error) {
+	var pages [][]byte
+	for offset := 0; offset < len(data); {
+		page, ok := parseOggPage(data[offset:])
+		if !ok {
+			return nil, fmt.Errorf("invalid Ogg page at byte %d", offset)
+		}
+		pages = append(pages, data[offset:offset+page.Size])
+		offset += page.Size
+	}
+	if len(pages) == 0 {
+		return nil, errors.New("not an Ogg file")
+	}
+	return pages, nil
+}
+
+// concatOgg joins Ogg Opus files into a chained Ogg stream: the parts
+// follow one another as complete logical streams, each with its own header
+// pages, pre-skip and end trimming, so a player resets its decoder at the
+// start of every part. Parts are given distinct serial numbers, which a
+// chained stream requires.
+func concatOgg(parts [][]byte) ([]byte, error) {
+	var joined []byte
+	used := make(map[uint32]bool)
+
+	for i, part := range parts {
+		pages, err := splitOggPages(part)
+		if err != nil {
+			return nil, fmt.Errorf("part %d: %w", i, err)
+		}
+		head, _ := parseOggPage(pages[0])
+		if len(head.Packet) < 8 || string(head.Packet[0:8]) != "OpusHead" {
+			return nil, fmt.Errorf("part %d: concatenation is only supported for Ogg Opus", i)
+		}
+
+		// A part may itself be chained; each of its logical streams starts
+		// with a BOS page and gets a serial not used before
+		serials := make(map[uint32]uint32)
+		for j, page := range pages {
+			page = append([]byte(nil), page...)
+
+			original := binary.LittleEndian.Uint32(page[14:18])
+			serial, ok := serials[original]
+			if !ok || page[5]&oggFlagBOS != 0 {
+				serial = original
+				for used[serial] {
+					serial++
+				}
+				used[serial] = true
+				serials[original] = serial
+			}
+
+			if j == len(pages)-1 {
+				page[5] |= oggFlagEOS
+			}
+			binary.LittleEndian.PutUint32(page[14:18], serial)
+			binary.LittleEndian.PutUint32(page[22:26], 0)
+			binary.LittleEndian.PutUint32(page[22:26], oggChecksum(page))
+
+			joined = append(joined, page...)
+		}
+	}
+
+	return joined, nil
+}
+
+// oggPackets calls yield with every packet of an Ogg file in order. Packets
+// that continue across pages are joined.
+func oggPackets(data []byte, yield func(packet []byte) error) error {
+	var partial []byte
+	var continued bool
+	for offset := 0; offset < len(data); {
+		page, ok := parseOggPage(data[offset:])
+		if !ok {
+			return fmt.Errorf("invalid Ogg page at byte %d", offset)
+		}
+		lacing := data[offset+27 : offset+27+int(data[offset+26])]
+
+		start, end := 0, 0
+		for _, size := range lacing {
+			end += int(size)
+			// A lacing value below 255 ends the packet
+			if size < 255 {
+				packet := page.Packet[start:end]
+				if continued {
+					packet = append(partial, packet...)
+					partial, continued = nil, false
+				}
+				if err := yield(packet); err != nil {
+					return err
+				}
+				start = end
+			}
+		}
+		if start < end {
+			partial = append(partial, page.Packet[start:end]...)
+			continued = true
+		}
+
+		offset += page.Size
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package audio
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+// testOpusFrameSamples is the length of the 20 ms packets the tests write
+const testOpusFrameSamples = 960
+
+// testOggPage returns an Ogg page holding one packet shorter than 255 bytes
+func testOggPage(flags byte, granule int64, serial, sequence uint32, packet []byte) []byte {
+	page := []byte("OggS")
+	page = append(page, 0, flags)
+	page = binary.LittleEndian.AppendUint64(page, uint64(granule))
+	page = binary.LittleEndian.AppendUint32(page, serial)
+	page = binary.LittleEndian.AppendUint32(page, sequence)
+	page = append(page, 0, 0, 0, 0, 1, byte(len(packet)))
+	page = append(page, packet...)
+	binary.LittleEndian.PutUint32(page[22:26], oggChecksum(page))
+	return page
+}
+
+// testOpus returns an Ogg Opus stream of packets 20 ms packets, one per
+// page, with a pre-skip of 312 samples
+func testOpus(serial uint32, packets int) []byte {
+	head := append([]byte("OpusHead"), 1, 1, 0x38, 0x01, 0x80, 0xbb, 0, 0, 0, 0, 0)
+	data := testOggPage(oggFlagBOS, 0, serial, 0, head)
+	data = append(data, testOggPage(0, 0, serial, 1, []byte("OpusTags\x00\x00\x00\x00\x00\x00\x00\x00"))...)
+	for i := 0; i < packets; i++ {
+		var flags byte
+		if i == packets-1 {
+			flags = oggFlagEOS
+		}
+		granule := int64(312 + testOpusFrameSamples*(i+1))
+		// TOC byte of a single 20 ms hybrid frame
+		data = append(data, testOggPage(flags, granule, serial, uint32(2+i), []byte{0x78, 0, 0})...)
+	}
+	return data
+}
+
+func TestConcatOgg(t *testing.T) {
+	tests := []struct {
+		name  string
+		parts [][]byte
+		// duration is the playing time of the joined stream in seconds
+		duration float64
+		wantErr  bool
+	}{
+		{
+			name:     "parts with the same serial",
+			parts:    [][]byte{testOpus(7, 50), testOpus(7, 100)},
+			duration: 3,
+		},
+		{
+			name:     "three parts",
+			parts:    [][]byte{testOpus(1, 25), testOpus(2, 25), testOpus(1, 50)},
+			duration: 2,
+		},
+		{
+			name:     "chained part",
+			parts:    [][]byte{append(testOpus(3, 50), testOpus(4, 50)...), testOpus(3, 50)},
+			duration: 3,
+		},
+		{
+			name:    "not Opus",
+			parts:   [][]byte{testOpus(1, 10), testOggPage(oggFlagBOS, 0, 2, 0, []byte("\x01vorbis"))},
+			wantErr: true,
+		},
+		{
+			name:    "not Ogg",
+			parts:   [][]byte{testOpus(1, 10), []byte("RIFF")},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			joined, err := Concat("ogg", tt.parts)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Concat succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			pages, err := splitOggPages(joined)
+			if err != nil {
+				t.Fatal(err)
+			}
+			serials := make(map[uint32]bool)
+			var open bool
+			for i, page := range pages {
+				checked := append([]byte(nil), page...)
+				binary.LittleEndian.PutUint32(checked[22:26], 0)
+				if oggChecksum(checked) != binary.LittleEndian.Uint32(page[22:26]) {
+					t.Errorf("page %d has a wrong checksum", i)
+				}
+
+				// Every logical stream starts with BOS under a new serial
+				// and ends with EOS before the next one starts
+				serial := binary.LittleEndian.Uint32(page[14:18])
+				if page[5]&oggFlagBOS != 0 {
+					if open {
+						t.Errorf("page %d starts a stream before the previous one ended", i)
+					}
+					if serials[serial] {
+						t.Errorf("page %d reuses serial %d", i, serial)
+					}
+					serials[serial] = true
+					open = true
+				}
+				if page[5]&oggFlagEOS != 0 {
+					open = false
+				}
+			}
+			if open {
+				t.Error("the last stream does not end with EOS")
+			}
+
+			info, err := Probe("ogg", joined)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if math.Abs(info.Duration-tt.duration) > 1e-9 {
+				t.Errorf("duration %v, want %v", info.Duration, tt.duration)
+			}
+
+			var samples int
+			err = opusAudioPackets(joined, func([]byte) error { return nil }, func(_ []byte, packetSamples int) error {
+				samples += packetSamples
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := int(tt.duration * 48000); samples != want {
+				t.Errorf("%d audio samples, want %d", samples, want)
+			}
+		})
+	}
+}
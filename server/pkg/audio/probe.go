@@ -0,0 +1,267 @@
+package audio
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Info describes encoded audio as read from its headers and frames
+type Info struct {
+	// Duration is the playing time in seconds
+	Duration   float64
+	SampleRate int
+	Channels   int
+	// BitRate is the average bit rate in bits per second
+	BitRate int
+}
+
+// Probe reads the duration, sample rate, channel count and bit rate of an
+// encoded MP3, Ogg (Opus or Vorbis) or WAV file
+func Probe(format string, data []byte) (*Info, error) {
+	switch format {
+	case "wav":
+		return probeWAV(data)
+	case "mp3":
+		return probeMP3(data)
+	case "ogg":
+		return probeOgg(data)
+	default:
+		return nil, fmt.Errorf("probing is not supported for %s audio", format)
+	}
+}
+
+// probeWAV reads the format chunk and the size of the data chunk
+func probeWAV(data []byte) (*Info, error) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return nil, errors.New("not a WAV file")
+	}
+
+	var info *Info
+	var byteRate int
+	offset := 12
+	for offset+8 <= len(data) {
+		chunkID := string(data[offset : offset+4])
+		chunkSize := int(binary.LittleEndian.Uint32(data[offset+4 : offset+8]))
+		body := offset + 8
+		if body+chunkSize > len(data) {
+			chunkSize = len(data) - body
+		}
+
+		switch chunkID {
+		case "fmt ":
+			if chunkSize < 16 {
+				return nil, errors.New("invalid WAV format chunk")
+			}
+			info = &Info{
+				Channels:   int(binary.LittleEndian.Uint16(data[body+2 : body+4])),
+				SampleRate: int(binary.LittleEndian.Uint32(data[body+4 : body+8])),
+			}
+			byteRate = int(binary.LittleEndian.Uint32(data[body+8 : body+12]))
+			info.BitRate = byteRate * 8
+		case "data":
+			if info == nil {
+				return nil, errors.New("WAV data chunk precedes format chunk")
+			}
+			if byteRate > 0 {
+				info.Duration = float64(chunkSize) / float64(byteRate)
+			}
+			return info, nil
+		}
+
+		offset = body + chunkSize + chunkSize%2
+	}
+
+	return nil, errors.New("WAV file has no data chunk")
+}
+
+// probeMP3 walks every frame. The frame count in a Xing/Info or VBRI header
+// is not trusted, since files joined from several encoder outputs may carry
+// a header describing only the first of them; the header frame itself
+// carries no audio and is skipped.
+func probeMP3(data []byte) (*Info, error) {
+	data = stripID3v1(stripID3v2(data))
+
+	offset := 0
+	for ; offset+4 <= len(data); offset++ {
+		if _, ok := parseMP3Frame(data[offset:]); ok {
+			break
+		}
+	}
+	first, ok := parseMP3Frame(data[offset:])
+	if !ok {
+		return nil, errors.New("no MP3 frames found")
+	}
+
+	info := &Info{SampleRate: first.SampleRate, Channels: first.Channels}
+	if isMP3HeaderFrame(data[offset:], first) {
+		offset += first.Size
+	}
+
+	var audioBytes int
+	for offset+4 <= len(data) {
+		frame, ok := parseMP3Frame(data[offset:])
+		if !ok {
+			offset++
+			continue
+		}
+		info.Duration += float64(frame.SamplesPerFrame) / float64(frame.SampleRate)
+		audioBytes += frame.Size
+		offset += frame.Size
+	}
+	if info.Duration > 0 {
+		info.BitRate = int(float64(audioBytes) * 8 / info.Duration)
+	}
+
+	return info, nil
+}
+
+// isMP3HeaderFrame reports whether a frame holds a Xing, Info or VBRI
+// header instead of audio
+func isMP3HeaderFrame(frame []byte, header *mp3Frame) bool {
+	// The Xing tag follows the side information, whose size depends on the
+	// MPEG version and channel count
+	var sideInfo int
+	switch {
+	case header.SamplesPerFrame == 1152 && header.Channels == 1:
+		sideInfo = 17
+	case header.SamplesPerFrame == 1152:
+		sideInfo = 32
+	case header.Channels == 1:
+		sideInfo = 9
+	default:
+		sideInfo = 17
+	}
+
+	xing := 4 + sideInfo
+	if len(frame) >= xing+12 {
+		tag := string(frame[xing : xing+4])
+		flags := binary.BigEndian.Uint32(frame[xing+4 : xing+8])
+		if (tag == "Xing" || tag == "Info") && flags&0x1 != 0 {
+			return true
+		}
+	}
+
+	// VBRI headers sit at a fixed offset of 32 bytes after the frame header
+	const vbri = 4 + 32
+	return len(frame) >= vbri+18 && string(frame[vbri:vbri+4]) == "VBRI"
+}
+
+// oggPage is the part of an Ogg page header needed for probing
+type oggPage struct {
+	Flags           byte
+	GranulePosition int64
+	Packet          []byte
+	Size            int
+}
+
+// parseOggPage parses the page at the start of data
+func parseOggPage(data []byte) (*oggPage, bool) {
+	if len(data) < 27 || string(data[0:4]) != "OggS" {
+		return nil, false
+	}
+
+	segments := int(data[26])
+	if len(data) < 27+segments {
+		return nil, false
+	}
+	bodySize := 0
+	for _, lacing := range data[27 : 27+segments] {
+		bodySize += int(lacing)
+	}
+	body := 27 + segments
+	if len(data) < body+bodySize {
+		return nil, false
+	}
+
+	return &oggPage{
+		Flags:           data[5],
+		GranulePosition: int64(binary.LittleEndian.Uint64(data[6:14])),
+		Packet:          data[body : body+bodySize],
+		Size:            body + bodySize,
+	}, true
+}
+
+// oggLink describes a logical stream of an Ogg file from its codec header:
+// granule positions count samples at granuleRate after preSkip samples
+type oggLink struct {
+	channels    int
+	sampleRate  int
+	granuleRate int
+	preSkip     int64
+}
+
+// parseOggLink reads the codec header packet of a logical stream
+func parseOggLink(packet []byte) (*oggLink, error) {
+	link := &oggLink{}
+	switch {
+	case len(packet) >= 19 && string(packet[0:8]) == "OpusHead":
+		link.channels = int(packet[9])
+		link.preSkip = int64(binary.LittleEndian.Uint16(packet[10:12]))
+		link.sampleRate = int(binary.LittleEndian.Uint32(packet[12:16]))
+		// Opus always runs at 48 kHz; the header records the input rate
+		link.granuleRate = 48000
+		if link.sampleRate == 0 {
+			link.sampleRate = link.granuleRate
+		}
+	case len(packet) >= 16 && packet[0] == 1 && string(packet[1:7]) == "vorbis":
+		link.channels = int(packet[11])
+		link.sampleRate = int(binary.LittleEndian.Uint32(packet[12:16]))
+		link.granuleRate = link.sampleRate
+	default:
+		return nil, errors.New("unsupported Ogg codec")
+	}
+	if link.granuleRate == 0 {
+		return nil, errors.New("invalid Ogg codec header")
+	}
+	return link, nil
+}
+
+// probeOgg reads the codec header from the first page and the playing time
+// from the granule position of the last page of every logical stream, which
+// follow one another in a chained file
+func probeOgg(data []byte) (*Info, error) {
+	first, ok := parseOggPage(data)
+	if !ok {
+		return nil, errors.New("not an Ogg file")
+	}
+	link, err := parseOggLink(first.Packet)
+	if err != nil {
+		return nil, err
+	}
+	info := &Info{Channels: link.channels, SampleRate: link.sampleRate}
+
+	var lastGranule int64
+	endLink := func() {
+		if samples := lastGranule - link.preSkip; samples > 0 {
+			info.Duration += float64(samples) / float64(link.granuleRate)
+		}
+		lastGranule = 0
+	}
+	for offset := 0; offset < len(data); {
+		page, ok := parseOggPage(data[offset:])
+		if !ok {
+			// Resynchronise on the next capture pattern
+			offset++
+			continue
+		}
+		if page.Flags&oggFlagBOS != 0 && offset > 0 {
+			endLink()
+			if link, err = parseOggLink(page.Packet); err != nil {
+				return nil, err
+			}
+		}
+		// -1 marks pages on which no packet ends
+		if page.GranulePosition > 0 {
+			lastGranule = page.GranulePosition
+		}
+		offset += page.Size
+	}
+	endLink()
+
+	if info.Duration > 0 {
+		info.BitRate = int(float64(len(data)) * 8 / info.Duration)
+	}
+
+	return info, nil
+}
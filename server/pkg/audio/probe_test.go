@@ -0,0 +1,125 @@
+package audio
+
+import (
+	"math"
+	"testing"
+)
+
+// Frame headers of the MP3 streams built by the tests
+var (
+	// MPEG-1 Layer III, 128 kbps, 44.1 kHz, stereo
+	mp3StereoHeader = []byte{0xff, 0xfb, 0x90, 0x00}
+	// MPEG-2 Layer III, 64 kbps, 22.05 kHz, mono
+	mp3MonoHeader = []byte{0xff, 0xf3, 0x80, 0xc0}
+)
+
+// testMP3Frame returns one silent frame with the header
+func testMP3Frame(t *testing.T, header []byte) []byte {
+	t.Helper()
+	frame, ok := parseMP3Frame(header)
+	if !ok {
+		t.Fatalf("invalid test frame header % x", header)
+	}
+	data := make([]byte, frame.Size)
+	copy(data, header)
+	return data
+}
+
+// testMP3 returns count silent frames with the header
+func testMP3(t *testing.T, header []byte, count int) []byte {
+	t.Helper()
+	var data []byte
+	for i := 0; i < count; i++ {
+		data = append(data, testMP3Frame(t, header)...)
+	}
+	return data
+}
+
+// testXingFrame returns an Info header frame with the header, as encoders
+// write before the audio
+func testXingFrame(t *testing.T, header []byte) []byte {
+	t.Helper()
+	frame := testMP3Frame(t, header)
+	xing := 4 + 32
+	copy(frame[xing:], "Info")
+	frame[xing+7] = 0x01 // frame count present
+	return frame
+}
+
+// testID3v2 returns an ID3v2 tag with size bytes of padding
+func testID3v2(size int) []byte {
+	tag := []byte{'I', 'D', '3', 4, 0, 0, byte(size >> 21 & 0x7f), byte(size >> 14 & 0x7f), byte(size >> 7 & 0x7f), byte(size & 0x7f)}
+	return append(tag, make([]byte, size)...)
+}
+
+func TestProbeMP3(t *testing.T) {
+	stereoFrame := 1152.0 / 44100
+	monoFrame := 576.0 / 22050
+
+	tests := []struct {
+		name       string
+		data       []byte
+		duration   float64
+		sampleRate int
+		channels   int
+		wantErr    bool
+	}{
+		{
+			name:       "frames",
+			data:       testMP3(t, mp3StereoHeader, 10),
+			duration:   10 * stereoFrame,
+			sampleRate: 44100,
+			channels:   2,
+		},
+		{
+			name:       "ID3v2 tag",
+			data:       append(testID3v2(300), testMP3(t, mp3StereoHeader, 4)...),
+			duration:   4 * stereoFrame,
+			sampleRate: 44100,
+			channels:   2,
+		},
+		{
+			name:       "header frame is not audio",
+			data:       append(testXingFrame(t, mp3StereoHeader), testMP3(t, mp3StereoHeader, 5)...),
+			duration:   5 * stereoFrame,
+			sampleRate: 44100,
+			channels:   2,
+		},
+		{
+			name:       "leading garbage",
+			data:       append([]byte{0, 1, 2}, testMP3(t, mp3MonoHeader, 8)...),
+			duration:   8 * monoFrame,
+			sampleRate: 22050,
+			channels:   1,
+		},
+		{
+			name:    "no frames",
+			data:    []byte("not audio at all"),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := Probe("mp3", tt.data)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Probe succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if math.Abs(info.Duration-tt.duration) > 1e-9 {
+				t.Errorf("duration %v, want %v", info.Duration, tt.duration)
+			}
+			if info.SampleRate != tt.sampleRate || info.Channels != tt.channels {
+				t.Errorf("got %d Hz with %d channels, want %d Hz with %d", info.SampleRate, info.Channels, tt.sampleRate, tt.channels)
+			}
+			if info.BitRate <= 0 {
+				t.Errorf("bit rate %d, want a positive rate", info.BitRate)
+			}
+		})
+	}
+}
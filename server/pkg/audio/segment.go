@@ -0,0 +1,28 @@
+package audio
+
+import (
+	"errors"
+)
+
+// SliceMP3 returns the audio frames of an MP3 stream that start at or after
+// start seconds and before end seconds. The slice is a valid MP3 stream on
+// its own, without tags or a Xing/Info header frame, as HLS packed audio
+// segments are.
+func SliceMP3(data []byte, start, end float64) ([]byte, error) {
+	var slice []byte
+	var position float64
+	err := mp3AudioFrames(data, func(frame *mp3Frame, frameData []byte) error {
+		if position >= start && position < end {
+			slice = append(slice, frameData...)
+		}
+		position += float64(frame.SamplesPerFrame) / float64(frame.SampleRate)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(slice) == 0 {
+		return nil, errors.New("no MP3 frames in the requested time range")
+	}
+	return slice, nil
+}
@@ -0,0 +1,101 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const wavHeaderSize = 44
+
+// PCM holds signed 16-bit little-endian samples, interleaved by channel
+type PCM struct {
+	SampleRate int
+	Channels   int
+	Samples    []int16
+}
+
+// Duration returns the length of the audio in seconds
+func (p *PCM) Duration() float64 {
+	if p.SampleRate == 0 || p.Channels == 0 {
+		return 0
+	}
+	return float64(len(p.Samples)) / float64(p.Channels) / float64(p.SampleRate)
+}
+
+// EncodeWAV encodes PCM samples as a canonical 16-bit WAV file
+func EncodeWAV(p *PCM) []byte {
+	dataSize := len(p.Samples) * 2
+	blockAlign := p.Channels * 2
+
+	buf := bytes.NewBuffer(make([]byte, 0, wavHeaderSize+dataSize))
+	buf.WriteString("RIFF")
+	binary.Write(buf, binary.LittleEndian, uint32(36+dataSize))
+	buf.WriteString("WAVE")
+
+	buf.WriteString("fmt ")
+	binary.Write(buf, binary.LittleEndian, uint32(16))
+	binary.Write(buf, binary.LittleEndian, uint16(1)) // PCM
+	binary.Write(buf, binary.LittleEndian, uint16(p.Channels))
+	binary.Write(buf, binary.LittleEndian, uint32(p.SampleRate))
+	binary.Write(buf, binary.LittleEndian, uint32(p.SampleRate*blockAlign))
+	binary.Write(buf, binary.LittleEndian, uint16(blockAlign))
+	binary.Write(buf, binary.LittleEndian, uint16(16))
+
+	buf.WriteString("data")
+	binary.Write(buf, binary.LittleEndian, uint32(dataSize))
+	binary.Write(buf, binary.LittleEndian, p.Samples)
+
+	return buf.Bytes()
+}
+
+// DecodeWAV decodes a 16-bit PCM WAV file. Chunks other than "fmt " and
+// "data" are skipped.
+func DecodeWAV(data []byte) (*PCM, error) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return nil, errors.New("not a WAV file")
+	}
+
+	pcm := &PCM{}
+	var haveFormat bool
+	offset := 12
+	for offset+8 <= len(data) {
+		chunkID := string(data[offset : offset+4])
+		chunkSize := int(binary.LittleEndian.Uint32(data[offset+4 : offset+8]))
+		body := offset + 8
+		if body+chunkSize > len(data) {
+			// Streams written without a final size report the chunk as larger than the file
+			chunkSize = len(data) - body
+		}
+
+		switch chunkID {
+		case "fmt ":
+			if chunkSize < 16 {
+				return nil, errors.New("invalid WAV format chunk")
+			}
+			audioFormat := binary.LittleEndian.Uint16(data[body : body+2])
+			bitsPerSample := binary.LittleEndian.Uint16(data[body+14 : body+16])
+			if audioFormat != 1 || bitsPerSample != 16 {
+				return nil, fmt.Errorf("unsupported WAV encoding (format %d, %d bits)", audioFormat, bitsPerSample)
+			}
+			pcm.Channels = int(binary.LittleEndian.Uint16(data[body+2 : body+4]))
+			pcm.SampleRate = int(binary.LittleEndian.Uint32(data[body+4 : body+8]))
+			haveFormat = true
+		case "data":
+			if !haveFormat {
+				return nil, errors.New("WAV data chunk precedes format chunk")
+			}
+			pcm.Samples = make([]int16, chunkSize/2)
+			if err := binary.Read(bytes.NewReader(data[body:body+len(pcm.Samples)*2]), binary.LittleEndian, pcm.Samples); err != nil {
+				return nil, fmt.Errorf("failed to read WAV samples: %w", err)
+			}
+			return pcm, nil
+		}
+
+		// Chunks are padded to an even size
+		offset = body + chunkSize + chunkSize%2
+	}
+
+	return nil, errors.New("WAV file has no data chunk")
+}
@@ -0,0 +1,225 @@
+// Package cf trains collaborative filtering models on implicit feedback:
+// item–item cosine similarities and an alternating least squares matrix
+// factorization. Users and items are dense indices; callers map their IDs.
+package cf
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// Entry is one observed user–item preference strength in (0, 1]
+type Entry struct {
+	User  int
+	Item  int
+	Value float64
+}
+
+// ALSConfig controls the factorization
+type ALSConfig struct {
+	Factors        int
+	Iterations     int
+	Regularization float64
+	// Alpha scales how much more an observed preference is trusted than a
+	// missing one: an entry's confidence is 1 + Alpha*Value
+	Alpha float64
+	// Seed makes the random initialization, and so the factors, repeatable
+	Seed int64
+}
+
+// Model holds the trained factors. The predicted preference of a user for
+// an item is the dot product of their factors.
+type Model struct {
+	Users [][]float64
+	Items [][]float64
+	// Loss is the weighted squared error over all user–item pairs plus the
+	// regularization, after the last iteration
+	Loss float64
+}
+
+// TrainALS factorizes the implicit feedback matrix with the conjugate
+// formulation of Hu, Koren and Volinsky: every pair has preference 1 if it
+// was observed and 0 otherwise, weighted by its confidence. Each half step
+// solves a k×k system per user or item, so the cost is linear in the number
+// of entries.
+func TrainALS(ctx context.Context, users, items int, entries []Entry, config ALSConfig) (*Model, error) {
+	if config.Factors <= 0 || config.Iterations <= 0 {
+		return nil, fmt.Errorf("ALS needs at least one factor and iteration")
+	}
+
+	byUser := make([][]Entry, users)
+	byItem := make([][]Entry, items)
+	for _, entry := range entries {
+		if entry.User < 0 || entry.User >= users || entry.Item < 0 || entry.Item >= items {
+			return nil, fmt.Errorf("entry (%d, %d) is outside the %d×%d matrix", entry.User, entry.Item, users, items)
+		}
+		byUser[entry.User] = append(byUser[entry.User], entry)
+		byItem[entry.Item] = append(byItem[entry.Item], entry)
+	}
+
+	random := rand.New(rand.NewSource(config.Seed))
+	scale := 1 / math.Sqrt(float64(config.Factors))
+	model := &Model{
+		Users: make([][]float64, users),
+		Items: make([][]float64, items),
+	}
+	for i := range model.Items {
+		model.Items[i] = make([]float64, config.Factors)
+		for f := range model.Items[i] {
+			model.Items[i][f] = random.NormFloat64() * scale * 0.1
+		}
+	}
+	for u := range model.Users {
+		model.Users[u] = make([]float64, config.Factors)
+	}
+
+	for iteration := 0; iteration < config.Iterations; iteration++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		solveFactors(model.Users, model.Items, byUser, func(e Entry) int { return e.Item }, config)
+		solveFactors(model.Items, model.Users, byItem, func(e Entry) int { return e.User }, config)
+	}
+
+	model.Loss = loss(model, byUser, config)
+	return model, nil
+}
+
+// solveFactors recomputes every row of solved with the other side fixed
+func solveFactors(solved, fixed [][]float64, observed [][]Entry, other func(Entry) int, config ALSConfig) {
+	k := config.Factors
+	gram := gramMatrix(fixed, k)
+
+	a := make([][]float64, k)
+	for i := range a {
+		a[i] = make([]float64, k)
+	}
+	b := make([]float64, k)
+
+	for row, rowEntries := range observed {
+		if len(rowEntries) == 0 {
+			// Rows without feedback predict nothing
+			clear(solved[row])
+			continue
+		}
+
+		for i := range a {
+			copy(a[i], gram[i])
+			a[i][i] += config.Regularization
+		}
+		clear(b)
+		for _, entry := range rowEntries {
+			y := fixed[other(entry)]
+			confidence := 1 + config.Alpha*entry.Value
+			for i := 0; i < k; i++ {
+				b[i] += confidence * y[i]
+				for j := 0; j <= i; j++ {
+					a[i][j] += (confidence - 1) * y[i] * y[j]
+				}
+			}
+		}
+		for i := 0; i < k; i++ {
+			for j := 0; j < i; j++ {
+				a[j][i] = a[i][j]
+			}
+		}
+
+		solveCholesky(a, b, solved[row])
+	}
+}
+
+// gramMatrix returns FᵀF
+func gramMatrix(factors [][]float64, k int) [][]float64 {
+	gram := make([][]float64, k)
+	for i := range gram {
+		gram[i] = make([]float64, k)
+	}
+	for _, f := range factors {
+		for i := 0; i < k; i++ {
+			for j := 0; j <= i; j++ {
+				gram[i][j] += f[i] * f[j]
+			}
+		}
+	}
+	for i := 0; i < k; i++ {
+		for j := 0; j < i; j++ {
+			gram[j][i] = gram[i][j]
+		}
+	}
+	return gram
+}
+
+// solveCholesky solves a·x = b for a symmetric positive definite a, which
+// it overwrites with its Cholesky factor
+func solveCholesky(a [][]float64, b, x []float64) {
+	k := len(b)
+	for i := 0; i < k; i++ {
+		for j := 0; j <= i; j++ {
+			sum := a[i][j]
+			for p := 0; p < j; p++ {
+				sum -= a[i][p] * a[j][p]
+			}
+			if i == j {
+				a[i][i] = math.Sqrt(math.Max(sum, 1e-12))
+			} else {
+				a[i][j] = sum / a[j][j]
+			}
+		}
+	}
+
+	// Forward substitution with L, then back substitution with Lᵀ
+	for i := 0; i < k; i++ {
+		sum := b[i]
+		for p := 0; p < i; p++ {
+			sum -= a[i][p] * x[p]
+		}
+		x[i] = sum / a[i][i]
+	}
+	for i := k - 1; i >= 0; i-- {
+		sum := x[i]
+		for p := i + 1; p < k; p++ {
+			sum -= a[p][i] * x[p]
+		}
+		x[i] = sum / a[i][i]
+	}
+}
+
+// loss returns the training objective. The error of the unobserved pairs,
+// whose preference is 0, is summed as xᵀ(YᵀY)x over users and corrected
+// for the observed pairs.
+func loss(model *Model, byUser [][]Entry, config ALSConfig) float64 {
+	k := config.Factors
+	gram := gramMatrix(model.Items, k)
+
+	var total float64
+	for u, x := range model.Users {
+		for i := 0; i < k; i++ {
+			for j := 0; j < k; j++ {
+				total += x[i] * gram[i][j] * x[j]
+			}
+		}
+		for _, entry := range byUser[u] {
+			predicted := Dot(x, model.Items[entry.Item])
+			confidence := 1 + config.Alpha*entry.Value
+			total += confidence*(1-predicted)*(1-predicted) - predicted*predicted
+		}
+	}
+
+	for _, factors := range [][][]float64{model.Users, model.Items} {
+		for _, f := range factors {
+			total += config.Regularization * Dot(f, f)
+		}
+	}
+	return total
+}
+
+// Dot returns the dot product of two vectors of equal length
+func Dot(a, b []float64) float64 {
+	var sum float64
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
@@ -0,0 +1,179 @@
+package cf
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestSolveCholesky(t *testing.T) {
+	tests := []struct {
+		name string
+		a    [][]float64
+		b    []float64
+		want []float64
+	}{
+		{
+			name: "1x1",
+			a:    [][]float64{{4}},
+			b:    []float64{8},
+			want: []float64{2},
+		},
+		{
+			name: "diagonal",
+			a:    [][]float64{{2, 0}, {0, 5}},
+			b:    []float64{4, 10},
+			want: []float64{2, 2},
+		},
+		{
+			name: "symmetric positive definite",
+			a:    [][]float64{{4, 2, 0}, {2, 5, 1}, {0, 1, 3}},
+			b:    []float64{8, 15, 11},
+			want: []float64{1, 2, 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// a is overwritten, so check the solution against a copy
+			original := make([][]float64, len(tt.a))
+			for i := range tt.a {
+				original[i] = append([]float64(nil), tt.a[i]...)
+			}
+
+			x := make([]float64, len(tt.b))
+			solveCholesky(tt.a, tt.b, x)
+
+			for i := range original {
+				if got := Dot(original[i], x); math.Abs(got-tt.b[i]) > 1e-9 {
+					t.Errorf("row %d: a·x = %v, want %v", i, got, tt.b[i])
+				}
+			}
+			for i := range tt.want {
+				if math.Abs(x[i]-tt.want[i]) > 1e-9 {
+					t.Errorf("x[%d] = %v, want %v", i, x[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestTrainALSErrors(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		entries []Entry
+		config  ALSConfig
+	}{
+		{
+			name:   "no factors",
+			ctx:    context.Background(),
+			config: ALSConfig{Factors: 0, Iterations: 1},
+		},
+		{
+			name:   "no iterations",
+			ctx:    context.Background(),
+			config: ALSConfig{Factors: 2, Iterations: 0},
+		},
+		{
+			name:    "user outside the matrix",
+			ctx:     context.Background(),
+			entries: []Entry{{User: 2, Item: 0, Value: 1}},
+			config:  ALSConfig{Factors: 2, Iterations: 1},
+		},
+		{
+			name:    "item outside the matrix",
+			ctx:     context.Background(),
+			entries: []Entry{{User: 0, Item: -1, Value: 1}},
+			config:  ALSConfig{Factors: 2, Iterations: 1},
+		},
+		{
+			name:    "cancelled",
+			ctx:     cancelled,
+			entries: []Entry{{User: 0, Item: 0, Value: 1}},
+			config:  ALSConfig{Factors: 2, Iterations: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := TrainALS(tt.ctx, 2, 2, tt.entries, tt.config); err == nil {
+				t.Fatal("TrainALS succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestTrainALS(t *testing.T) {
+	// Users 0-2 read items 0-2 and users 3-5 read items 3-5
+	var blocks []Entry
+	for u := 0; u < 6; u++ {
+		for i := 0; i < 3; i++ {
+			if u == 0 && i == 2 || u == 3 && i == 5 {
+				// Held out: predicted from the user's block
+				continue
+			}
+			blocks = append(blocks, Entry{User: u, Item: u/3*3 + i, Value: 1})
+		}
+	}
+
+	tests := []struct {
+		name    string
+		entries []Entry
+		// preferred is predicted above other for the user
+		user, preferred, other int
+	}{
+		{name: "held out item of the first block", entries: blocks, user: 0, preferred: 2, other: 4},
+		{name: "held out item of the second block", entries: blocks, user: 3, preferred: 5, other: 1},
+		{name: "observed item", entries: blocks, user: 4, preferred: 3, other: 0},
+	}
+
+	config := ALSConfig{Factors: 4, Iterations: 15, Regularization: 0.1, Alpha: 10, Seed: 1}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model, err := TrainALS(context.Background(), 6, 6, tt.entries, config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			preferred := Dot(model.Users[tt.user], model.Items[tt.preferred])
+			other := Dot(model.Users[tt.user], model.Items[tt.other])
+			if preferred <= other {
+				t.Errorf("user %d: item %d scores %v, item %d scores %v", tt.user, tt.preferred, preferred, tt.other, other)
+			}
+
+			again, err := TrainALS(context.Background(), 6, 6, tt.entries, config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if again.Loss != model.Loss {
+				t.Errorf("loss %v differs from %v with the same seed", again.Loss, model.Loss)
+			}
+		})
+	}
+}
+
+func TestTrainALSLossDecreases(t *testing.T) {
+	entries := []Entry{
+		{User: 0, Item: 0, Value: 1}, {User: 0, Item: 1, Value: 0.5},
+		{User: 1, Item: 1, Value: 1}, {User: 1, Item: 2, Value: 0.8},
+		{User: 2, Item: 0, Value: 0.3}, {User: 2, Item: 2, Value: 1},
+	}
+
+	var previous float64
+	for i, iterations := range []int{1, 5, 20} {
+		model, err := TrainALS(context.Background(), 3, 3, entries, ALSConfig{
+			Factors: 2, Iterations: iterations, Regularization: 0.1, Alpha: 5, Seed: 7,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i > 0 && model.Loss > previous+1e-9 {
+			t.Errorf("loss after %d iterations is %v, above %v", iterations, model.Loss, previous)
+		}
+		previous = model.Loss
+	}
+}
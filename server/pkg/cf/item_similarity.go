@@ -0,0 +1,98 @@
+package cf
+
+import (
+	"math"
+	"sort"
+)
+
+// ItemSimilarityConfig controls the item–item similarity computation
+type ItemSimilarityConfig struct {
+	// Neighbours is how many of the most similar items are kept per item
+	Neighbours int
+	// MinCommonUsers drops pairs seen together by fewer users, whose
+	// similarity is mostly noise
+	MinCommonUsers int
+	// MaxItemsPerUser caps the items of one user that are paired, keeping
+	// the strongest preferences, since pairs grow with its square
+	MaxItemsPerUser int
+}
+
+// Neighbour is an item similar to another
+type Neighbour struct {
+	Item        int
+	Similarity  float64
+	CommonUsers int
+}
+
+type pairStats struct {
+	dot    float64
+	common int
+}
+
+// ItemSimilarities returns, for each item, its most similar items by the
+// cosine similarity of their preference columns, most similar first
+func ItemSimilarities(items int, entries []Entry, config ItemSimilarityConfig) [][]Neighbour {
+	byUser := make(map[int][]Entry)
+	norms := make([]float64, items)
+	for _, entry := range entries {
+		byUser[entry.User] = append(byUser[entry.User], entry)
+		norms[entry.Item] += entry.Value * entry.Value
+	}
+	for i := range norms {
+		norms[i] = math.Sqrt(norms[i])
+	}
+
+	pairs := make(map[[2]int]*pairStats)
+	for _, userEntries := range byUser {
+		if config.MaxItemsPerUser > 0 && len(userEntries) > config.MaxItemsPerUser {
+			sort.Slice(userEntries, func(a, b int) bool { return userEntries[a].Value > userEntries[b].Value })
+			userEntries = userEntries[:config.MaxItemsPerUser]
+		}
+
+		for a := 0; a < len(userEntries); a++ {
+			for b := a + 1; b < len(userEntries); b++ {
+				i, j := userEntries[a].Item, userEntries[b].Item
+				if i == j {
+					continue
+				}
+				if i > j {
+					i, j = j, i
+				}
+				stats := pairs[[2]int{i, j}]
+				if stats == nil {
+					stats = &pairStats{}
+					pairs[[2]int{i, j}] = stats
+				}
+				stats.dot += userEntries[a].Value * userEntries[b].Value
+				stats.common++
+			}
+		}
+	}
+
+	neighbours := make([][]Neighbour, items)
+	for pair, stats := range pairs {
+		if stats.common < config.MinCommonUsers {
+			continue
+		}
+		i, j := pair[0], pair[1]
+		if norms[i] == 0 || norms[j] == 0 {
+			continue
+		}
+		similarity := stats.dot / (norms[i] * norms[j])
+		neighbours[i] = append(neighbours[i], Neighbour{Item: j, Similarity: similarity, CommonUsers: stats.common})
+		neighbours[j] = append(neighbours[j], Neighbour{Item: i, Similarity: similarity, CommonUsers: stats.common})
+	}
+
+	for i := range neighbours {
+		sort.Slice(neighbours[i], func(a, b int) bool {
+			if neighbours[i][a].Similarity != neighbours[i][b].Similarity {
+				return neighbours[i][a].Similarity > neighbours[i][b].Similarity
+			}
+			return neighbours[i][a].Item < neighbours[i][b].Item
+		})
+		if config.Neighbours > 0 && len(neighbours[i]) > config.Neighbours {
+			neighbours[i] = neighbours[i][:config.Neighbours]
+		}
+	}
+	return neighbours
+}
@@ -0,0 +1,101 @@
+package cf
+
+import (
+	"math"
+	"testing"
+)
+
+func TestItemSimilarities(t *testing.T) {
+	tests := []struct {
+		name    string
+		items   int
+		entries []Entry
+		config  ItemSimilarityConfig
+		// want lists the neighbours of each item, most similar first
+		want [][]Neighbour
+	}{
+		{
+			name:  "identical columns",
+			items: 2,
+			entries: []Entry{
+				{User: 0, Item: 0, Value: 1}, {User: 0, Item: 1, Value: 1},
+				{User: 1, Item: 0, Value: 0.5}, {User: 1, Item: 1, Value: 0.5},
+			},
+			want: [][]Neighbour{
+				{{Item: 1, Similarity: 1, CommonUsers: 2}},
+				{{Item: 0, Similarity: 1, CommonUsers: 2}},
+			},
+		},
+		{
+			name:  "partly shared users",
+			items: 3,
+			entries: []Entry{
+				{User: 0, Item: 0, Value: 1}, {User: 0, Item: 1, Value: 1},
+				{User: 1, Item: 0, Value: 1}, {User: 1, Item: 2, Value: 1},
+				{User: 2, Item: 1, Value: 1},
+			},
+			want: [][]Neighbour{
+				{{Item: 2, Similarity: 1 / math.Sqrt2, CommonUsers: 1}, {Item: 1, Similarity: 0.5, CommonUsers: 1}},
+				{{Item: 0, Similarity: 0.5, CommonUsers: 1}},
+				{{Item: 0, Similarity: 1 / math.Sqrt2, CommonUsers: 1}},
+			},
+		},
+		{
+			name:  "too few common users",
+			items: 2,
+			entries: []Entry{
+				{User: 0, Item: 0, Value: 1}, {User: 0, Item: 1, Value: 1},
+			},
+			config: ItemSimilarityConfig{MinCommonUsers: 2},
+			want:   [][]Neighbour{nil, nil},
+		},
+		{
+			name:  "neighbours capped",
+			items: 3,
+			entries: []Entry{
+				{User: 0, Item: 0, Value: 1}, {User: 0, Item: 1, Value: 1}, {User: 0, Item: 2, Value: 1},
+				{User: 1, Item: 0, Value: 1}, {User: 1, Item: 1, Value: 1},
+			},
+			config: ItemSimilarityConfig{Neighbours: 1},
+			want: [][]Neighbour{
+				{{Item: 1, Similarity: 1, CommonUsers: 2}},
+				{{Item: 0, Similarity: 1, CommonUsers: 2}},
+				{{Item: 0, Similarity: 1 / math.Sqrt2, CommonUsers: 1}},
+			},
+		},
+		{
+			name:  "weakest preferences of a user dropped",
+			items: 3,
+			entries: []Entry{
+				{User: 0, Item: 0, Value: 1}, {User: 0, Item: 1, Value: 0.9}, {User: 0, Item: 2, Value: 0.1},
+			},
+			config: ItemSimilarityConfig{MaxItemsPerUser: 2},
+			want: [][]Neighbour{
+				{{Item: 1, Similarity: 1, CommonUsers: 1}},
+				{{Item: 0, Similarity: 1, CommonUsers: 1}},
+				nil,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ItemSimilarities(tt.items, tt.entries, tt.config)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got neighbours for %d items, want %d", len(got), len(tt.want))
+			}
+			for item := range tt.want {
+				if len(got[item]) != len(tt.want[item]) {
+					t.Fatalf("item %d: got %v, want %v", item, got[item], tt.want[item])
+				}
+				for i, want := range tt.want[item] {
+					neighbour := got[item][i]
+					if neighbour.Item != want.Item || neighbour.CommonUsers != want.CommonUsers ||
+						math.Abs(neighbour.Similarity-want.Similarity) > 1e-9 {
+						t.Errorf("item %d neighbour %d: got %+v, want %+v", item, i, neighbour, want)
+					}
+				}
+			}
+		})
+	}
+}
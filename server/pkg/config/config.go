@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
@@ -18,6 +19,17 @@ type Config struct {
 	ExternalServices ExternalServicesConfig `yaml:"external_services"`
 	Firebase         FirebaseConfig         `yaml:"firebase"`
 	TTS              TTSConfig              `yaml:"tts"`
+	Audiobook        AudiobookConfig        `yaml:"audiobook"`
+	Ambient          AmbientConfig          `yaml:"ambient"`
+	AudioLifecycle   AudioLifecycleConfig   `yaml:"audio_lifecycle"`
+	Downloads        DownloadsConfig        `yaml:"downloads"`
+	Podcast          PodcastConfig          `yaml:"podcast"`
+	Embedding        EmbeddingConfig        `yaml:"embedding"`
+	// CollaborativeFiltering controls training of the CF model
+	CollaborativeFiltering CollaborativeFilteringConfig `yaml:"collaborative_filtering"`
+	RecommendationCache    RecommendationCacheConfig    `yaml:"recommendation_cache"`
+	// RecommendationPipelines defines the recommendation types by name
+	RecommendationPipelines map[string]RecommendationPipelineConfig `yaml:"recommendation_pipelines"`
 }
 
 type ServerConfig struct {
@@ -59,10 +71,116 @@ type TTSConfig struct {
 	VoiceLanguage   string `yaml:"voice_language"`
 	VoiceName       string `yaml:"voice_name"`
 	AudioEncoding   string `yaml:"audio_encoding"`
+	// Engines lists TTS providers in fallback order, e.g. ["google", "local"]
+	Engines []string `yaml:"engines"`
+	// VoiceEngines overrides the fallback order for individual voices
+	VoiceEngines map[string][]string `yaml:"voice_engines"`
+	// GenderVoices picks the voice for a user's preset gender, by language
+	GenderVoices map[string]map[string]string `yaml:"gender_voices"`
+	// VoicePolicyPath is a YAML file of voice selection rules; empty disables them
+	VoicePolicyPath string `yaml:"voice_policy_path"`
+}
+
+type AudiobookConfig struct {
+	Workers      int           `yaml:"workers"`
+	PollInterval time.Duration `yaml:"poll_interval"`
+	MaxAttempts  int           `yaml:"max_attempts"`
+	LockTimeout  time.Duration `yaml:"lock_timeout"`
+}
+
+// AmbientConfig locates the ambient track library and sets mixing defaults
+type AmbientConfig struct {
+	// LibraryKey is the blob key of the library manifest
+	LibraryKey      string        `yaml:"library_key"`
+	RefreshInterval time.Duration `yaml:"refresh_interval"`
+	BedVolumeDB     float64       `yaml:"bed_volume_db"`
+	DuckDB          float64       `yaml:"duck_db"`
+	FadeIn          time.Duration `yaml:"fade_in"`
+	FadeOut         time.Duration `yaml:"fade_out"`
+}
+
+// AudioLifecycleConfig controls how long synthesized audio is kept
+type AudioLifecycleConfig struct {
+	Interval time.Duration `yaml:"interval"`
+	// IdleTTL removes audio that has not been played for this long
+	IdleTTL   time.Duration `yaml:"idle_ttl"`
+	BatchSize int           `yaml:"batch_size"`
+}
+
+// DownloadsConfig controls offline download packages
+type DownloadsConfig struct {
+	// SigningKey seeds the Ed25519 key that signs package manifests so the
+	// app can detect tampering
+	SigningKey     string        `yaml:"signing_key"`
+	RevokeInterval time.Duration `yaml:"revoke_interval"`
+	// PollInterval is how often pending packages are looked for
+	PollInterval time.Duration `yaml:"poll_interval"`
+}
+
+// PodcastConfig controls private podcast feeds
+type PodcastConfig struct {
+	MaxBooks int `yaml:"max_books"`
+}
+
+// EmbeddingConfig selects the embedder that computes book vectors
+type EmbeddingConfig struct {
+	// Embedder is "local" or "vertex"
+	Embedder string `yaml:"embedder"`
+	// Location and Model select the Vertex AI embedding model
+	Location     string `yaml:"location"`
+	Model        string `yaml:"model"`
+	ChunkRunes   int    `yaml:"chunk_runes"`
+	ChunkOverlap int    `yaml:"chunk_overlap"`
+	BatchSize    int    `yaml:"batch_size"`
+	// RefitDrift is the relative change in the number of books at which the
+	// local embedder is fitted to the books again
+	RefitDrift float64 `yaml:"refit_drift"`
+}
+
+// CollaborativeFilteringConfig controls the matrix factorization and
+// item–item similarities trained on implicit feedback
+type CollaborativeFilteringConfig struct {
+	Factors        int     `yaml:"factors"`
+	Iterations     int     `yaml:"iterations"`
+	Regularization float64 `yaml:"regularization"`
+	// Alpha is the confidence given to observed preferences
+	Alpha           float64 `yaml:"alpha"`
+	Neighbours      int     `yaml:"neighbours"`
+	MinCommonUsers  int     `yaml:"min_common_users"`
+	MaxBooksPerUser int     `yaml:"max_books_per_user"`
+}
+
+// RecommendationCacheConfig controls the in-process and database caches of
+// generated recommendations
+type RecommendationCacheConfig struct {
+	LocalEntries int           `yaml:"local_entries"`
+	LocalTTL     time.Duration `yaml:"local_ttl"`
+	TTL          time.Duration `yaml:"ttl"`
+	// TypeTTLs overrides TTL for recommendation types, e.g. trending
+	TypeTTLs        map[string]time.Duration `yaml:"type_ttls"`
+	CleanupInterval time.Duration            `yaml:"cleanup_interval"`
+}
+
+// RecommendationPipelineConfig defines how a recommendation type generates
+// and ranks candidates
+type RecommendationPipelineConfig struct {
+	Sources []string `yaml:"sources"`
+	// Weights overrides the default feature weights
+	Weights            map[string]float64 `yaml:"weights"`
+	PersonalizeWeights bool               `yaml:"personalize_weights"`
+	PostProcessors     []string           `yaml:"post_processors"`
+	Fallback           string             `yaml:"fallback"`
+	Description        string             `yaml:"description"`
 }
 
 type StorageConfig struct {
-	BucketName string `yaml:"bucket_name"`
+	Backend         string        `yaml:"backend"`
+	BucketName      string        `yaml:"bucket_name"`
+	CredentialsPath string        `yaml:"credentials_path"`
+	LocalPath       string        `yaml:"local_path"`
+	BaseURL         string        `yaml:"base_url"`
+	SigningKey      string        `yaml:"signing_key"`
+	SignedURLExpiry time.Duration `yaml:"signed_url_expiry"`
 }
 
 func Load() (*Config, error) {
@@ -126,6 +244,21 @@ func (c *Config) overrideWithEnvVars() {
 	if bucketName := os.Getenv("STORAGE_BUCKET_NAME"); bucketName != "" {
 		c.ExternalServices.Storage.BucketName = bucketName
 	}
+	if engines := os.Getenv("TTS_ENGINES"); engines != "" {
+		c.TTS.Engines = strings.Split(engines, ",")
+	}
+	if backend := os.Getenv("STORAGE_BACKEND"); backend != "" {
+		c.ExternalServices.Storage.Backend = backend
+	}
+	if signingKey := os.Getenv("STORAGE_SIGNING_KEY"); signingKey != "" {
+		c.ExternalServices.Storage.SigningKey = signingKey
+	}
+	if signingKey := os.Getenv("DOWNLOAD_SIGNING_KEY"); signingKey != "" {
+		c.Downloads.SigningKey = signingKey
+	}
+	if embedder := os.Getenv("EMBEDDER"); embedder != "" {
+		c.Embedding.Embedder = embedder
+	}
 }
 
 func getEnv(key, defaultVal string) string {
@@ -7,10 +7,11 @@ import (
 )
 
 type AppError struct {
-	Code       string `json:"code"`
-	Message    string `json:"message"`
-	StatusCode int    `json:"-"`
-	Err        error  `json:"-"`
+	Code       string                 `json:"code"`
+	Message    string                 `json:"message"`
+	Details    map[string]interface{} `json:"details,omitempty"`
+	StatusCode int                    `json:"-"`
+	Err        error                  `json:"-"`
 }
 
 func (e *AppError) Error() string {
@@ -114,6 +115,28 @@ func InternalServer(message string, err error) *AppError {
 	}
 }
 
+// QuotaExceeded reports that the user has used up a plan allowance. details
+// carries the limit, remaining amount and reset time for the client.
+func QuotaExceeded(message string, details map[string]interface{}) *AppError {
+	return &AppError{
+		Code:       "QUOTA_EXCEEDED",
+		Message:    message,
+		Details:    details,
+		StatusCode: ErrTooManyRequests.StatusCode,
+	}
+}
+
+// NotAcceptable reports that a resource is not available in any media type
+// the client accepts. details lists the types that are available.
+func NotAcceptable(message string, details map[string]interface{}) *AppError {
+	return &AppError{
+		Code:       "NOT_ACCEPTABLE",
+		Message:    message,
+		Details:    details,
+		StatusCode: http.StatusNotAcceptable,
+	}
+}
+
 func GetStatusCode(err error) int {
 	var appErr *AppError
 	if errors.As(err, &appErr) {
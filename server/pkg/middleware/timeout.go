@@ -6,14 +6,35 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/gorilla/mux"
 	"go.uber.org/zap"
 
 	"github.com/ponyo877/roudoku/server/pkg/logger"
 )
 
-func Timeout(duration time.Duration, log *logger.Logger) func(http.Handler) http.Handler {
+// UntimedRoutes lists routes whose responses may outlast the request
+// timeout, such as audio streams and exports
+type UntimedRoutes map[*mux.Route]bool
+
+// Add marks a route as untimed and returns it
+func (u UntimedRoutes) Add(route *mux.Route) *mux.Route {
+	u[route] = true
+	return route
+}
+
+// Timeout cancels requests that run longer than duration. Untimed routes
+// run without a deadline, and the server's write deadline is lifted for them.
+func Timeout(duration time.Duration, log *logger.Logger, untimed UntimedRoutes) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if untimed[mux.CurrentRoute(r)] {
+				if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+					log.WithContext(r.Context()).Warn("Failed to lift write deadline", zap.Error(err))
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
 			ctx, cancel := context.WithTimeout(r.Context(), duration)
 			defer cancel()
 
@@ -0,0 +1,85 @@
+package ssml
+
+import "unicode"
+
+// SplitDialogue splits sentences at 「 and 」 so that each sentence is either
+// dialogue or narration, marks the dialogue and renumbers the sentences.
+// Pieces with nothing to speak, such as the 。 after a closing bracket or the
+// indent before an opening one, are joined to a neighbouring piece.
+// Quotes left open at the end of a paragraph are closed there.
+func SplitDialogue(sentences []Sentence) []Sentence {
+	var result []Sentence
+	depth, paragraph := 0, -1
+
+	for _, sentence := range sentences {
+		if sentence.Paragraph != paragraph {
+			depth, paragraph = 0, sentence.Paragraph
+		}
+
+		runes := []rune(sentence.Text)
+		start := 0
+		emit := func(end int, dialogue bool, last bool) {
+			if end <= start {
+				return
+			}
+			piece := Sentence{
+				Paragraph:       sentence.Paragraph,
+				ParagraphOffset: sentence.ParagraphOffset + start,
+				Offset:          sentence.Offset + start,
+				Length:          end - start,
+				Text:            string(runes[start:end]),
+				Dialogue:        dialogue,
+			}
+
+			if !hasSpokenText(piece.Text) {
+				if n := len(result); n > 0 && result[n-1].Paragraph == piece.Paragraph &&
+					result[n-1].Offset+result[n-1].Length == piece.Offset {
+					result[n-1].Length += piece.Length
+					result[n-1].Text += piece.Text
+					start = end
+					return
+				}
+				if !last {
+					// Leave it for the next piece to pick up
+					return
+				}
+			}
+
+			result = append(result, piece)
+			start = end
+		}
+
+		for i, r := range runes {
+			switch r {
+			case '「':
+				if depth == 0 {
+					emit(i, false, false)
+				}
+				depth++
+			case '」':
+				if depth > 0 {
+					depth--
+					if depth == 0 {
+						emit(i+1, true, false)
+					}
+				}
+			}
+		}
+		emit(len(runes), depth > 0, true)
+	}
+
+	for i := range result {
+		result[i].Index = i
+	}
+	return result
+}
+
+// hasSpokenText reports whether text contains anything a voice would read
+func hasSpokenText(text string) bool {
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			return true
+		}
+	}
+	return false
+}
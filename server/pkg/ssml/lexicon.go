@@ -0,0 +1,192 @@
+package ssml
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Lexicon maps surface forms to the readings engines should speak, rendered
+// as <sub alias="reading">surface</sub>. Where surface forms overlap the
+// longest one starting at the earliest position wins.
+type Lexicon struct {
+	// byFirstRune holds entries by their first character, longest first
+	byFirstRune map[rune][]LexiconEntry
+}
+
+// LexiconEntry is one surface form and its reading
+type LexiconEntry struct {
+	Surface string
+	Reading string
+}
+
+// NewLexicon builds a lexicon from entries. Later entries replace earlier
+// ones with the same surface form, so callers list them from the most
+// general to the most specific.
+func NewLexicon(entries []LexiconEntry) *Lexicon {
+	bySurface := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		if entry.Surface == "" || entry.Reading == "" {
+			continue
+		}
+		bySurface[entry.Surface] = entry.Reading
+	}
+
+	l := &Lexicon{byFirstRune: make(map[rune][]LexiconEntry)}
+	for surface, reading := range bySurface {
+		first := []rune(surface)[0]
+		l.byFirstRune[first] = append(l.byFirstRune[first], LexiconEntry{Surface: surface, Reading: reading})
+	}
+	for _, candidates := range l.byFirstRune {
+		sort.Slice(candidates, func(i, j int) bool {
+			if len(candidates[i].Surface) != len(candidates[j].Surface) {
+				return len(candidates[i].Surface) > len(candidates[j].Surface)
+			}
+			return candidates[i].Surface < candidates[j].Surface
+		})
+	}
+	return l
+}
+
+// Len returns the number of distinct surface forms
+func (l *Lexicon) Len() int {
+	if l == nil {
+		return 0
+	}
+	n := 0
+	for _, candidates := range l.byFirstRune {
+		n += len(candidates)
+	}
+	return n
+}
+
+// lexiconMatch is an occurrence of an entry in a text, in byte offsets
+type lexiconMatch struct {
+	Start, End int
+	Entry      LexiconEntry
+}
+
+// find returns the non-overlapping occurrences of entries in text
+func (l *Lexicon) find(text string) []lexiconMatch {
+	if l == nil || len(l.byFirstRune) == 0 {
+		return nil
+	}
+
+	var matches []lexiconMatch
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		matched := false
+		for _, entry := range l.byFirstRune[r] {
+			if strings.HasPrefix(text[i:], entry.Surface) {
+				matches = append(matches, lexiconMatch{Start: i, End: i + len(entry.Surface), Entry: entry})
+				i += len(entry.Surface)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			i += size
+		}
+	}
+	return matches
+}
+
+// Matches returns the entries that apply to text, in order of appearance
+func (l *Lexicon) Matches(text string) []LexiconEntry {
+	var entries []LexiconEntry
+	for _, match := range l.find(text) {
+		entries = append(entries, match.Entry)
+	}
+	return entries
+}
+
+// RubySpan is a lexicon match in a text, with offsets counted in characters
+// so clients can place the reading without decoding UTF-8 byte offsets
+type RubySpan struct {
+	Start, End int
+	Base       string
+	Reading    string
+}
+
+// Ruby returns the reading of every lexicon match in text, for display as
+// furigana over the surface form
+func (l *Lexicon) Ruby(text string) []RubySpan {
+	var spans []RubySpan
+	offset, last := 0, 0
+	for _, match := range l.find(text) {
+		offset += utf8.RuneCountInString(text[last:match.Start])
+		length := utf8.RuneCountInString(match.Entry.Surface)
+		spans = append(spans, RubySpan{
+			Start:   offset,
+			End:     offset + length,
+			Base:    match.Entry.Surface,
+			Reading: match.Entry.Reading,
+		})
+		offset += length
+		last = match.End
+	}
+	return spans
+}
+
+// Render returns text as SSML character data with every lexicon match
+// wrapped in a <sub> element. A nil lexicon only escapes the text.
+func (l *Lexicon) Render(text string) string {
+	matches := l.find(text)
+	if len(matches) == 0 {
+		return Escape(text)
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, match := range matches {
+		b.WriteString(Escape(text[last:match.Start]))
+		b.WriteString(`<sub alias="`)
+		b.WriteString(Escape(match.Entry.Reading))
+		b.WriteString(`">`)
+		b.WriteString(Escape(match.Entry.Surface))
+		b.WriteString(`</sub>`)
+		last = match.End
+	}
+	b.WriteString(Escape(text[last:]))
+	return b.String()
+}
+
+// Read returns text as it will be spoken, with surface forms replaced by
+// their readings
+func (l *Lexicon) Read(text string) string {
+	matches := l.find(text)
+	var b strings.Builder
+	last := 0
+	for _, match := range matches {
+		b.WriteString(text[last:match.Start])
+		b.WriteString(match.Entry.Reading)
+		last = match.End
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// Fingerprint identifies the entries that apply to text, so synthesized
+// audio can be cached per reading. It is empty when no entry applies.
+func (l *Lexicon) Fingerprint(text string) string {
+	matches := l.Matches(text)
+	if len(matches) == 0 {
+		return ""
+	}
+
+	seen := make(map[string]bool, len(matches))
+	var pairs []string
+	for _, entry := range matches {
+		pair := entry.Surface + "\x00" + entry.Reading
+		if !seen[pair] {
+			seen[pair] = true
+			pairs = append(pairs, pair)
+		}
+	}
+	sort.Strings(pairs)
+
+	sum := sha256.Sum256([]byte(strings.Join(pairs, "\x01")))
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,190 @@
+// Package ssml builds the SSML documents sent to TTS engines and reads them
+// back for engines that render SSML themselves.
+package ssml
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Sentence is a span of the source text. Offsets count characters (runes),
+// not bytes.
+type Sentence struct {
+	Index           int
+	Paragraph       int
+	ParagraphOffset int
+	Offset          int
+	Length          int
+	Text            string
+	// Dialogue is set by SplitDialogue for text inside 「」
+	Dialogue bool
+}
+
+// SplitSentences splits text into sentences at sentence-ending punctuation
+// and paragraphs at newlines. Sentences whose escaped form is longer than
+// maxBytes are split further so every sentence fits in one engine request.
+func SplitSentences(text string, maxBytes int) []Sentence {
+	var sentences []Sentence
+	var current strings.Builder
+	paragraph, paragraphStart, offset, start := 0, 0, 0, 0
+
+	flush := func() {
+		if strings.TrimSpace(current.String()) != "" {
+			sentences = append(sentences, Sentence{
+				Index:           len(sentences),
+				Paragraph:       paragraph,
+				ParagraphOffset: start - paragraphStart,
+				Offset:          start,
+				Length:          offset - start,
+				Text:            current.String(),
+			})
+		}
+		current.Reset()
+		start = offset
+	}
+
+	for _, r := range text {
+		if r == '\n' {
+			flush()
+			offset++
+			start = offset
+			// Blank lines do not start new paragraphs
+			if len(sentences) > 0 && sentences[len(sentences)-1].Paragraph == paragraph {
+				paragraph++
+			}
+			paragraphStart = offset
+			continue
+		}
+
+		if maxBytes > 0 && len(Escape(current.String()+string(r))) > maxBytes {
+			flush()
+		}
+		current.WriteRune(r)
+		offset++
+
+		if isSentenceEnd(r) {
+			flush()
+		}
+	}
+	flush()
+
+	return sentences
+}
+
+// isSentenceEnd reports whether r ends a sentence
+func isSentenceEnd(r rune) bool {
+	switch r {
+	case '。', '！', '？', '!', '?':
+		return true
+	}
+	return false
+}
+
+// MarkName returns the <mark> name used for a sentence
+func MarkName(index int) string {
+	return "s" + strconv.Itoa(index)
+}
+
+// ParseMarkName returns the sentence index encoded in a mark name
+func ParseMarkName(name string) (int, bool) {
+	if !strings.HasPrefix(name, "s") {
+		return 0, false
+	}
+	index, err := strconv.Atoi(name[1:])
+	return index, err == nil
+}
+
+// BuildMarked returns an SSML document that places a <mark> before each
+// sentence, applying lexicon readings when lexicon is non-nil
+func BuildMarked(sentences []Sentence, lexicon *Lexicon) string {
+	var b strings.Builder
+	b.WriteString("<speak>")
+	for _, sentence := range sentences {
+		b.WriteString(`<mark name="`)
+		b.WriteString(MarkName(sentence.Index))
+		b.WriteString(`"/>`)
+		b.WriteString(lexicon.Render(sentence.Text))
+	}
+	b.WriteString("</speak>")
+	return b.String()
+}
+
+// MarkedSize returns the number of bytes a sentence adds to BuildMarked output
+func MarkedSize(sentence Sentence, lexicon *Lexicon) int {
+	return len(`<mark name=""/>`) + len(MarkName(sentence.Index)) + len(lexicon.Render(sentence.Text))
+}
+
+// DocumentOverhead is the number of bytes BuildMarked adds around the sentences
+const DocumentOverhead = len("<speak></speak>")
+
+// Escape escapes text for use as SSML character data
+func Escape(text string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(text))
+	return b.String()
+}
+
+// Segment is a piece of spoken text, optionally preceded by a mark
+type Segment struct {
+	Mark string
+	Text string
+}
+
+// Parse reads an SSML document into segments of spoken text. <sub> elements
+// are replaced by their alias; other elements only contribute their text.
+func Parse(document string) ([]Segment, error) {
+	decoder := xml.NewDecoder(strings.NewReader(document))
+	var segments []Segment
+	current := Segment{}
+	subDepth := 0
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid SSML: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "mark":
+				if current.Mark != "" || current.Text != "" {
+					segments = append(segments, current)
+				}
+				current = Segment{Mark: attr(t, "name")}
+			case "sub":
+				current.Text += attr(t, "alias")
+				subDepth++
+			}
+		case xml.EndElement:
+			if t.Name.Local == "sub" && subDepth > 0 {
+				subDepth--
+			}
+		case xml.CharData:
+			if subDepth == 0 && utf8.Valid(t) {
+				current.Text += string(t)
+			}
+		}
+	}
+	if current.Mark != "" || current.Text != "" {
+		segments = append(segments, current)
+	}
+
+	return segments, nil
+}
+
+func attr(element xml.StartElement, name string) string {
+	for _, a := range element.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
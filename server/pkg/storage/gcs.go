@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	gcs "cloud.google.com/go/storage"
+	"google.golang.org/api/option"
+)
+
+// GCSStore stores blobs in a Cloud Storage bucket
+type GCSStore struct {
+	client *gcs.Client
+	bucket *gcs.BucketHandle
+}
+
+// NewGCSStore creates a Cloud Storage backed store
+func NewGCSStore(ctx context.Context, bucketName, credentialsPath string) (*GCSStore, error) {
+	if bucketName == "" {
+		return nil, fmt.Errorf("storage bucket name is required for the gcs backend")
+	}
+
+	var client *gcs.Client
+	var err error
+
+	if credentialsPath != "" {
+		client, err = gcs.NewClient(ctx, option.WithCredentialsFile(credentialsPath))
+	} else {
+		// Use default credentials (for production with service account)
+		client, err = gcs.NewClient(ctx)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to create storage client: %w", err)
+	}
+
+	return &GCSStore{
+		client: client,
+		bucket: client.Bucket(bucketName),
+	}, nil
+}
+
+// Put uploads a blob
+func (s *GCSStore) Put(ctx context.Context, key string, r io.Reader, opts PutOptions) (*ObjectInfo, error) {
+	writer := s.bucket.Object(key).NewWriter(ctx)
+	writer.ContentType = opts.ContentType
+	if writer.ContentType == "" {
+		writer.ContentType = contentTypeForKey(key)
+	}
+	writer.Metadata = opts.Metadata
+
+	if _, err := io.Copy(writer, r); err != nil {
+		writer.Close()
+		return nil, fmt.Errorf("failed to upload blob: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to upload blob: %w", err)
+	}
+
+	return objectInfoFromAttrs(writer.Attrs()), nil
+}
+
+// Get opens a blob for reading
+func (s *GCSStore) Get(ctx context.Context, key string) (io.ReadCloser, *ObjectInfo, error) {
+	reader, err := s.bucket.Object(key).NewReader(ctx)
+	if err != nil {
+		return nil, nil, mapGCSError(err)
+	}
+
+	info, err := s.Stat(ctx, key)
+	if err != nil {
+		reader.Close()
+		return nil, nil, err
+	}
+
+	return reader, info, nil
+}
+
+// GetRange opens part of a blob for reading
+func (s *GCSStore) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	reader, err := s.bucket.Object(key).NewRangeReader(ctx, offset, length)
+	if err != nil {
+		return nil, mapGCSError(err)
+	}
+	return reader, nil
+}
+
+// Stat returns blob attributes
+func (s *GCSStore) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	attrs, err := s.bucket.Object(key).Attrs(ctx)
+	if err != nil {
+		return nil, mapGCSError(err)
+	}
+	return objectInfoFromAttrs(attrs), nil
+}
+
+// Delete removes a blob
+func (s *GCSStore) Delete(ctx context.Context, key string) error {
+	return mapGCSError(s.bucket.Object(key).Delete(ctx))
+}
+
+// SignedURL returns a V4 signed GET URL for the blob
+func (s *GCSStore) SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	signedURL, err := s.bucket.SignedURL(key, &gcs.SignedURLOptions{
+		Method:  "GET",
+		Expires: time.Now().Add(expiry),
+		Scheme:  gcs.SigningSchemeV4,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to sign URL: %w", err)
+	}
+	return signedURL, nil
+}
+
+// Close releases the underlying client
+func (s *GCSStore) Close() error {
+	return s.client.Close()
+}
+
+func objectInfoFromAttrs(attrs *gcs.ObjectAttrs) *ObjectInfo {
+	if attrs == nil {
+		return nil
+	}
+	return &ObjectInfo{
+		Key:         attrs.Name,
+		Size:        attrs.Size,
+		ContentType: attrs.ContentType,
+		ETag:        hex.EncodeToString(attrs.MD5),
+		Metadata:    attrs.Metadata,
+		UpdatedAt:   attrs.Updated,
+	}
+}
+
+func mapGCSError(err error) error {
+	if errors.Is(err, gcs.ErrObjectNotExist) {
+		return ErrNotFound
+	}
+	return err
+}
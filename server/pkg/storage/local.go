@@ -0,0 +1,285 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const localMetaSuffix = ".meta.json"
+
+// LocalStore stores blobs on the local filesystem. It is intended for
+// development and tests; signed URLs are served by Handler.
+type LocalStore struct {
+	root       string
+	baseURL    string
+	signingKey []byte
+}
+
+// localMeta is persisted next to each blob
+type localMeta struct {
+	ContentType string            `json:"content_type"`
+	ETag        string            `json:"etag"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+}
+
+// NewLocalStore creates a filesystem-backed store rooted at root
+func NewLocalStore(root, baseURL, signingKey string) (*LocalStore, error) {
+	if root == "" {
+		root = "./data/storage"
+	}
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+	if baseURL == "" {
+		baseURL = "/storage"
+	}
+	if signingKey == "" {
+		signingKey = "local-development-signing-key"
+	}
+
+	return &LocalStore{
+		root:       root,
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		signingKey: []byte(signingKey),
+	}, nil
+}
+
+// Put writes a blob atomically and records its content type and ETag
+func (s *LocalStore) Put(ctx context.Context, key string, r io.Reader, opts PutOptions) (*ObjectInfo, error) {
+	fullPath, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create blob directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(fullPath), ".upload-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary blob: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := md5.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, hash), r); err != nil {
+		tmp.Close()
+		return nil, fmt.Errorf("failed to write blob: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write blob: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), fullPath); err != nil {
+		return nil, fmt.Errorf("failed to store blob: %w", err)
+	}
+
+	contentType := opts.ContentType
+	if contentType == "" {
+		contentType = contentTypeForKey(key)
+	}
+	meta := localMeta{
+		ContentType: contentType,
+		ETag:        hex.EncodeToString(hash.Sum(nil)),
+		Metadata:    opts.Metadata,
+	}
+	metaJSON, err := json.Marshal(meta)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode blob metadata: %w", err)
+	}
+	if err := os.WriteFile(fullPath+localMetaSuffix, metaJSON, 0o644); err != nil {
+		return nil, fmt.Errorf("failed to write blob metadata: %w", err)
+	}
+
+	return s.Stat(ctx, key)
+}
+
+// Get opens a blob for reading
+func (s *LocalStore) Get(ctx context.Context, key string) (io.ReadCloser, *ObjectInfo, error) {
+	info, err := s.Stat(ctx, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fullPath, _ := s.path(key)
+	file, err := os.Open(fullPath)
+	if err != nil {
+		return nil, nil, s.mapError(err)
+	}
+
+	return file, info, nil
+}
+
+// GetRange opens part of a blob for reading
+func (s *LocalStore) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	fullPath, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(fullPath)
+	if err != nil {
+		return nil, s.mapError(err)
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to seek blob: %w", err)
+	}
+	if length < 0 {
+		return file, nil
+	}
+
+	return &limitedReadCloser{Reader: io.LimitReader(file, length), Closer: file}, nil
+}
+
+// limitedReadCloser closes the underlying file of a limited reader
+type limitedReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// Stat returns blob attributes
+func (s *LocalStore) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	fullPath, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	stat, err := os.Stat(fullPath)
+	if err != nil {
+		return nil, s.mapError(err)
+	}
+
+	meta := localMeta{ContentType: contentTypeForKey(key)}
+	if metaJSON, err := os.ReadFile(fullPath + localMetaSuffix); err == nil {
+		if err := json.Unmarshal(metaJSON, &meta); err != nil {
+			return nil, fmt.Errorf("failed to decode blob metadata: %w", err)
+		}
+	}
+
+	return &ObjectInfo{
+		Key:         key,
+		Size:        stat.Size(),
+		ContentType: meta.ContentType,
+		ETag:        meta.ETag,
+		Metadata:    meta.Metadata,
+		UpdatedAt:   stat.ModTime(),
+	}, nil
+}
+
+// Delete removes a blob and its metadata
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	fullPath, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(fullPath); err != nil {
+		return s.mapError(err)
+	}
+	if err := os.Remove(fullPath + localMetaSuffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete blob metadata: %w", err)
+	}
+
+	return nil
+}
+
+// SignedURL returns an HMAC-signed URL that Handler will serve until it expires
+func (s *LocalStore) SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	if _, err := s.path(key); err != nil {
+		return "", err
+	}
+
+	expires := time.Now().Add(expiry).Unix()
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("signature", s.sign(key, expires))
+
+	return fmt.Sprintf("%s/%s?%s", s.baseURL, key, query.Encode()), nil
+}
+
+// Handler serves blobs requested through signed URLs. It should be mounted
+// with http.StripPrefix at the path used as the store's base URL.
+func (s *LocalStore) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, "/")
+
+		expires, err := strconv.ParseInt(r.URL.Query().Get("expires"), 10, 64)
+		if err != nil || time.Now().Unix() > expires {
+			http.Error(w, "signed URL expired", http.StatusForbidden)
+			return
+		}
+		expected := s.sign(key, expires)
+		if !hmac.Equal([]byte(expected), []byte(r.URL.Query().Get("signature"))) {
+			http.Error(w, "invalid signature", http.StatusForbidden)
+			return
+		}
+
+		fullPath, err := s.path(key)
+		if err != nil {
+			http.Error(w, "invalid key", http.StatusBadRequest)
+			return
+		}
+		info, err := s.Stat(r.Context(), key)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		file, err := os.Open(fullPath)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		defer file.Close()
+
+		w.Header().Set("Content-Type", info.ContentType)
+		if info.ETag != "" {
+			w.Header().Set("ETag", `"`+info.ETag+`"`)
+		}
+		http.ServeContent(w, r, path.Base(key), info.UpdatedAt, file)
+	})
+}
+
+// path resolves a key to a filesystem path, rejecting keys that escape the root
+func (s *LocalStore) path(key string) (string, error) {
+	cleaned := path.Clean("/" + key)
+	if key == "" || cleaned == "/" || strings.HasSuffix(key, localMetaSuffix) {
+		return "", fmt.Errorf("invalid storage key %q", key)
+	}
+	return filepath.Join(s.root, filepath.FromSlash(cleaned)), nil
+}
+
+func (s *LocalStore) sign(key string, expires int64) string {
+	mac := hmac.New(sha256.New, s.signingKey)
+	fmt.Fprintf(mac, "%s\n%d", key, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *LocalStore) mapError(err error) error {
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// contentTypeForKey guesses a content type from the key's extension
+func contentTypeForKey(key string) string {
+	if contentType := mime.TypeByExtension(path.Ext(key)); contentType != "" {
+		return contentType
+	}
+	return "application/octet-stream"
+}
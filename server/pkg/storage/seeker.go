@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ReadSeeker reads a blob through ranged reads so http.ServeContent can
+// serve byte ranges without downloading the whole object. A ranged read is
+// only opened on the first Read after a Seek.
+type ReadSeeker struct {
+	ctx    context.Context
+	store  BlobStore
+	key    string
+	size   int64
+	pos    int64
+	reader io.ReadCloser
+}
+
+// NewReadSeeker creates a ReadSeeker for the blob described by info
+func NewReadSeeker(ctx context.Context, store BlobStore, info *ObjectInfo) *ReadSeeker {
+	return &ReadSeeker{
+		ctx:   ctx,
+		store: store,
+		key:   info.Key,
+		size:  info.Size,
+	}
+}
+
+// Read reads from the current position
+func (r *ReadSeeker) Read(p []byte) (int, error) {
+	if r.pos >= r.size {
+		return 0, io.EOF
+	}
+	if r.reader == nil {
+		reader, err := r.store.GetRange(r.ctx, r.key, r.pos, -1)
+		if err != nil {
+			return 0, err
+		}
+		r.reader = reader
+	}
+
+	n, err := r.reader.Read(p)
+	r.pos += int64(n)
+	return n, err
+}
+
+// Seek moves the position for the next Read
+func (r *ReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	var pos int64
+	switch whence {
+	case io.SeekStart:
+		pos = offset
+	case io.SeekCurrent:
+		pos = r.pos + offset
+	case io.SeekEnd:
+		pos = r.size + offset
+	default:
+		return 0, errors.New("storage: invalid whence")
+	}
+	if pos < 0 {
+		return 0, errors.New("storage: negative position")
+	}
+
+	if pos != r.pos && r.reader != nil {
+		r.reader.Close()
+		r.reader = nil
+	}
+	r.pos = pos
+	return pos, nil
+}
+
+// Close releases the open ranged read, if any
+func (r *ReadSeeker) Close() error {
+	if r.reader == nil {
+		return nil
+	}
+	err := r.reader.Close()
+	r.reader = nil
+	return err
+}
@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Supported storage backends
+const (
+	BackendLocal = "local"
+	BackendGCS   = "gcs"
+)
+
+// ErrNotFound is returned when a blob does not exist
+var ErrNotFound = errors.New("storage: object not found")
+
+// BlobStore is a minimal object store shared by audio, exports and generated images.
+// Keys are slash-separated paths such as "tts/<hash>.mp3".
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader, opts PutOptions) (*ObjectInfo, error)
+	Get(ctx context.Context, key string) (io.ReadCloser, *ObjectInfo, error)
+	// GetRange reads length bytes starting at offset; a negative length reads to the end
+	GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error)
+	Stat(ctx context.Context, key string) (*ObjectInfo, error)
+	Delete(ctx context.Context, key string) error
+	SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error)
+}
+
+// PutOptions holds optional attributes for a stored blob
+type PutOptions struct {
+	ContentType string
+	Metadata    map[string]string
+}
+
+// ObjectInfo describes a stored blob
+type ObjectInfo struct {
+	Key         string            `json:"key"`
+	Size        int64             `json:"size"`
+	ContentType string            `json:"content_type"`
+	ETag        string            `json:"etag"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+}
+
+// Config represents blob storage configuration
+type Config struct {
+	Backend         string
+	BucketName      string
+	CredentialsPath string
+	LocalPath       string
+	BaseURL         string
+	SigningKey      string
+}
+
+// New creates the BlobStore selected by cfg.Backend
+func New(ctx context.Context, cfg Config) (BlobStore, error) {
+	switch cfg.Backend {
+	case BackendLocal, "":
+		return NewLocalStore(cfg.LocalPath, cfg.BaseURL, cfg.SigningKey)
+	case BackendGCS:
+		return NewGCSStore(ctx, cfg.BucketName, cfg.CredentialsPath)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.Backend)
+	}
+}
+
+// ReadAll reads an entire blob into memory
+func ReadAll(ctx context.Context, store BlobStore, key string) ([]byte, error) {
+	reader, _, err := store.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return io.ReadAll(reader)
+}
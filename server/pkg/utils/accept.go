@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// AcceptQuality returns the q-value an Accept header gives a media type,
+// given as one or more aliases of the same major type such as "audio/wav"
+// and "audio/x-wav". The most specific matching media range wins, and an
+// empty header accepts every type.
+func AcceptQuality(header string, mediaTypes ...string) float64 {
+	if strings.TrimSpace(header) == "" {
+		return 1
+	}
+	if len(mediaTypes) == 0 {
+		return 0
+	}
+
+	aliases := make([]string, len(mediaTypes))
+	for i, mediaType := range mediaTypes {
+		aliases[i] = strings.ToLower(mediaType)
+	}
+	major, _, _ := strings.Cut(aliases[0], "/")
+
+	quality, specificity := 0.0, -1
+	for _, mediaRange := range strings.Split(header, ",") {
+		params := strings.Split(mediaRange, ";")
+		rangeType := strings.ToLower(strings.TrimSpace(params[0]))
+
+		var level int
+		switch {
+		case slices.Contains(aliases, rangeType):
+			level = 2
+		case rangeType == major+"/*":
+			level = 1
+		case rangeType == "*/*":
+			level = 0
+		default:
+			continue
+		}
+		if level < specificity {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range params[1:] {
+			key, value, ok := strings.Cut(param, "=")
+			if !ok || !strings.EqualFold(strings.TrimSpace(key), "q") {
+				continue
+			}
+			if parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				q = parsed
+			}
+		}
+
+		// Aliases listed separately count as one type at their best quality
+		if level > specificity || q > quality {
+			quality, specificity = q, level
+		}
+	}
+
+	return quality
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
 	"github.com/google/uuid"
@@ -122,4 +123,18 @@ func CalculateMeta(page, perPage, totalCount int) *Meta {
 		TotalCount: totalCount,
 		TotalPages: totalPages,
 	}
-}
\ No newline at end of file
+}
+
+// RequestBaseURL returns the scheme and host the client used to reach the
+// server. Behind a load balancer the scheme comes from X-Forwarded-Proto.
+func RequestBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme, _, _ = strings.Cut(proto, ",")
+		scheme = strings.TrimSpace(scheme)
+	}
+	return scheme + "://" + r.Host
+}
@@ -19,9 +19,10 @@ type Response struct {
 }
 
 type ErrorInfo struct {
-	Code      string `json:"code"`
-	Message   string `json:"message"`
-	RequestID string `json:"request_id,omitempty"`
+	Code      string                 `json:"code"`
+	Message   string                 `json:"message"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+	RequestID string                 `json:"request_id,omitempty"`
 }
 
 type Meta struct {
@@ -101,6 +102,7 @@ func WriteError(w http.ResponseWriter, r *http.Request, log *logger.Logger, err
 		Error: &ErrorInfo{
 			Code:      appErr.Code,
 			Message:   appErr.Message,
+			Details:   appErr.Details,
 			RequestID: requestID,
 		},
 	}
@@ -118,6 +120,14 @@ func WriteCreated(w http.ResponseWriter, data interface{}) error {
 	return WriteJSON(w, http.StatusCreated, response)
 }
 
+func WriteAccepted(w http.ResponseWriter, data interface{}) error {
+	response := Response{
+		Success: true,
+		Data:    data,
+	}
+	return WriteJSON(w, http.StatusAccepted, response)
+}
+
 func WriteNoContent(w http.ResponseWriter) {
 	w.WriteHeader(http.StatusNoContent)
 }
\ No newline at end of file
@@ -0,0 +1,246 @@
+// Package voicepolicy picks narration voices from ordered rules matched
+// against book metadata, the listener's local time and their voice preset.
+package voicepolicy
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Policy is an ordered list of rules; the first rule that matches wins
+type Policy struct {
+	// Timezone is the IANA zone used for listeners whose zone is unknown
+	Timezone string `yaml:"timezone"`
+	Rules    []Rule `yaml:"rules"`
+
+	location *time.Location
+}
+
+// Rule adjusts the voice when all of its conditions hold
+type Rule struct {
+	Name        string        `yaml:"name"`
+	Description string        `yaml:"description"`
+	When        Conditions    `yaml:"when"`
+	Voice       VoiceSettings `yaml:"voice"`
+}
+
+// Conditions restrict when a rule applies. Empty conditions always hold;
+// list conditions hold when any element matches.
+type Conditions struct {
+	Genres    []string `yaml:"genres"`
+	Epochs    []string `yaml:"epochs"`
+	Languages []string `yaml:"languages"`
+	// Genders are the listener's preset genders
+	Genders       []string `yaml:"genders"`
+	MinDifficulty int      `yaml:"min_difficulty"`
+	MaxDifficulty int      `yaml:"max_difficulty"`
+	// Hours is a range of the listener's local hours
+	Hours *HourRange `yaml:"hours"`
+}
+
+// HourRange covers the hours From up to but not including To. It wraps
+// past midnight when From is after To, so {22, 5} covers 22:00 to 04:59.
+type HourRange struct {
+	From int `yaml:"from"`
+	To   int `yaml:"to"`
+}
+
+// VoiceSettings is what a rule changes. Absolute values replace the voice
+// the policy starts from; scale and shift adjust it, so a rule can slow the
+// listener's own preset speed down rather than replace it.
+type VoiceSettings struct {
+	Voice string `yaml:"voice"`
+	// GenderVoices picks the voice by the listener's preset gender and takes
+	// precedence over Voice
+	GenderVoices map[string]string `yaml:"gender_voices"`
+	Speed        float32           `yaml:"speed"`
+	SpeedScale   float32           `yaml:"speed_scale"`
+	Pitch        *float32          `yaml:"pitch"`
+	PitchShift   float32           `yaml:"pitch_shift"`
+}
+
+// Context is what rules are matched against. Book fields are empty when the
+// text does not belong to a book.
+type Context struct {
+	Genre           string
+	Epoch           string
+	DifficultyLevel int
+	Language        string
+	Gender          string
+	LocalTime       time.Time
+}
+
+// Voice is the voice a selection starts from and produces
+type Voice struct {
+	Voice string
+	Speed float32
+	Pitch float32
+}
+
+// Decision is the outcome of a selection. Rule is nil when no rule matched
+// and the voice is returned unchanged.
+type Decision struct {
+	Rule  *Rule
+	Voice Voice
+}
+
+// Load reads a policy from a YAML file
+func Load(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read voice policy: %w", err)
+	}
+	return Parse(data)
+}
+
+// Parse reads and validates a policy from YAML
+func Parse(data []byte) (*Policy, error) {
+	var policy Policy
+	if err := yaml.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("failed to parse voice policy: %w", err)
+	}
+	if err := policy.validate(); err != nil {
+		return nil, fmt.Errorf("invalid voice policy: %w", err)
+	}
+	return &policy, nil
+}
+
+func (p *Policy) validate() error {
+	p.location = time.UTC
+	if p.Timezone != "" {
+		location, err := time.LoadLocation(p.Timezone)
+		if err != nil {
+			return fmt.Errorf("unknown timezone %q", p.Timezone)
+		}
+		p.location = location
+	}
+
+	names := make(map[string]bool, len(p.Rules))
+	for i, rule := range p.Rules {
+		if rule.Name == "" {
+			return fmt.Errorf("rule %d has no name", i+1)
+		}
+		if names[rule.Name] {
+			return fmt.Errorf("duplicate rule name %q", rule.Name)
+		}
+		names[rule.Name] = true
+
+		if hours := rule.When.Hours; hours != nil {
+			if hours.From < 0 || hours.From > 23 || hours.To < 0 || hours.To > 24 || hours.From == hours.To {
+				return fmt.Errorf("rule %q: hours must be a non-empty range within 0-24", rule.Name)
+			}
+		}
+		if rule.When.MaxDifficulty != 0 && rule.When.MaxDifficulty < rule.When.MinDifficulty {
+			return fmt.Errorf("rule %q: max_difficulty is below min_difficulty", rule.Name)
+		}
+
+		voice := rule.Voice
+		if voice.Speed != 0 && (voice.Speed < 0.25 || voice.Speed > 4) {
+			return fmt.Errorf("rule %q: speed must be between 0.25 and 4", rule.Name)
+		}
+		if voice.SpeedScale < 0 {
+			return fmt.Errorf("rule %q: speed_scale must be positive", rule.Name)
+		}
+		if voice.Pitch != nil && (*voice.Pitch < -20 || *voice.Pitch > 20) {
+			return fmt.Errorf("rule %q: pitch must be between -20 and 20", rule.Name)
+		}
+	}
+	return nil
+}
+
+// Location returns the zone for listeners whose zone is unknown
+func (p *Policy) Location() *time.Location {
+	if p.location == nil {
+		return time.UTC
+	}
+	return p.location
+}
+
+// Select applies the first rule that matches c to voice
+func (p *Policy) Select(c Context, voice Voice) Decision {
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		if rule.When.matches(c) {
+			return Decision{Rule: rule, Voice: rule.Voice.apply(voice, c.Gender)}
+		}
+	}
+	return Decision{Voice: voice}
+}
+
+func (w Conditions) matches(c Context) bool {
+	if !matchesAny(w.Genres, c.Genre) || !matchesAny(w.Epochs, c.Epoch) ||
+		!matchesAny(w.Languages, c.Language) || !matchesAny(w.Genders, c.Gender) {
+		return false
+	}
+	if (w.MinDifficulty != 0 || w.MaxDifficulty != 0) && c.DifficultyLevel == 0 {
+		return false
+	}
+	if w.MinDifficulty != 0 && c.DifficultyLevel < w.MinDifficulty {
+		return false
+	}
+	if w.MaxDifficulty != 0 && c.DifficultyLevel > w.MaxDifficulty {
+		return false
+	}
+	if w.Hours != nil && !w.Hours.contains(c.LocalTime.Hour()) {
+		return false
+	}
+	return true
+}
+
+func (h HourRange) contains(hour int) bool {
+	if h.From < h.To {
+		return hour >= h.From && hour < h.To
+	}
+	return hour >= h.From || hour < h.To
+}
+
+func (s VoiceSettings) apply(voice Voice, gender string) Voice {
+	if name, ok := s.GenderVoices[gender]; ok && name != "" {
+		voice.Voice = name
+	} else if s.Voice != "" {
+		voice.Voice = s.Voice
+	}
+
+	if s.Speed != 0 {
+		voice.Speed = s.Speed
+	}
+	if s.SpeedScale != 0 {
+		voice.Speed *= s.SpeedScale
+	}
+	voice.Speed = clamp(voice.Speed, 0.25, 4)
+
+	if s.Pitch != nil {
+		voice.Pitch = *s.Pitch
+	}
+	voice.Pitch = clamp(voice.Pitch+s.PitchShift, -20, 20)
+
+	return voice
+}
+
+// matchesAny reports whether value is one of values, ignoring case. An empty
+// list matches anything; an empty value matches no list.
+func matchesAny(values []string, value string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, v := range values {
+		if value != "" && strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func clamp(value, lo, hi float32) float32 {
+	if value < lo {
+		return lo
+	}
+	if value > hi {
+		return hi
+	}
+	return value
+}
@@ -20,6 +20,7 @@ type ReadingAnalyticsRepository interface {
 	GetByUserIDAndDate(ctx context.Context, userID uuid.UUID, date time.Time) (*domain.ReadingAnalytics, error)
 	GetByUserIDDateRange(ctx context.Context, userID uuid.UUID, startDate, endDate time.Time) ([]*domain.ReadingAnalytics, error)
 	Update(ctx context.Context, analytics *domain.ReadingAnalytics) error
+	AddReadingTime(ctx context.Context, userID uuid.UUID, date time.Time, minutes, sessions, sessionMinutes int) error
 	GetAggregatedStats(ctx context.Context, userID uuid.UUID, startDate, endDate time.Time) (*AggregatedStats, error)
 }
 
@@ -224,6 +225,32 @@ func (r *postgresReadingAnalyticsRepository) Update(ctx context.Context, analyti
 	return err
 }
 
+// AddReadingTime adds minutes and sessions to the user's daily row, creating
+// it if needed. sessionMinutes is the running length of the session the
+// minutes belong to and only raises longest_session_minutes.
+func (r *postgresReadingAnalyticsRepository) AddReadingTime(ctx context.Context, userID uuid.UUID, date time.Time, minutes, sessions, sessionMinutes int) error {
+	query := `
+		INSERT INTO reading_analytics (
+			id, user_id, date, total_reading_time_minutes, reading_sessions_count,
+			longest_session_minutes, average_session_minutes, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, CASE WHEN $5 > 0 THEN $4 / $5 ELSE 0 END, NOW(), NOW())
+		ON CONFLICT (user_id, date) DO UPDATE SET
+			total_reading_time_minutes = reading_analytics.total_reading_time_minutes + EXCLUDED.total_reading_time_minutes,
+			reading_sessions_count = reading_analytics.reading_sessions_count + EXCLUDED.reading_sessions_count,
+			longest_session_minutes = GREATEST(reading_analytics.longest_session_minutes, EXCLUDED.longest_session_minutes),
+			average_session_minutes = CASE
+				WHEN reading_analytics.reading_sessions_count + EXCLUDED.reading_sessions_count > 0
+				THEN (reading_analytics.total_reading_time_minutes + EXCLUDED.total_reading_time_minutes) /
+					(reading_analytics.reading_sessions_count + EXCLUDED.reading_sessions_count)
+				ELSE 0 END,
+			updated_at = NOW()`
+
+	_, err := r.db.Exec(ctx, query,
+		uuid.New(), userID, date.Format("2006-01-02"), minutes, sessions, sessionMinutes,
+	)
+	return err
+}
+
 func (r *postgresReadingAnalyticsRepository) GetAggregatedStats(ctx context.Context, userID uuid.UUID, startDate, endDate time.Time) (*AggregatedStats, error) {
 	query := `
 		SELECT 
@@ -609,8 +636,46 @@ func (r *postgresBookProgressRepository) GetByID(ctx context.Context, id uuid.UU
 }
 
 func (r *postgresBookProgressRepository) GetCompletedByUserID(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*domain.BookProgress, error) {
-	// Implementation needed
-	return nil, nil
+	query := `
+		SELECT bp.id, bp.user_id, bp.book_id, bp.current_chapter_id, bp.current_position,
+			   bp.current_page, bp.total_pages, bp.progress_percentage,
+			   bp.estimated_time_remaining_minutes, bp.average_reading_speed_wpm,
+			   bp.started_at, bp.last_read_at, bp.completed_at, bp.is_completed,
+			   bp.is_abandoned, bp.created_at, bp.updated_at,
+			   b.id, b.title, b.author, b.genre
+		FROM book_progress bp
+		LEFT JOIN books b ON bp.book_id = b.id
+		WHERE bp.user_id = $1 AND bp.is_completed = true
+		ORDER BY COALESCE(bp.completed_at, bp.last_read_at) DESC
+		LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.Query(ctx, query, userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var progressList []*domain.BookProgress
+	for rows.Next() {
+		var progress domain.BookProgress
+		var book domain.Book
+		err := rows.Scan(
+			&progress.ID, &progress.UserID, &progress.BookID, &progress.CurrentChapterID,
+			&progress.CurrentPosition, &progress.CurrentPage, &progress.TotalPages,
+			&progress.ProgressPercentage, &progress.EstimatedTimeRemainingMinutes,
+			&progress.AverageReadingSpeedWPM, &progress.StartedAt, &progress.LastReadAt,
+			&progress.CompletedAt, &progress.IsCompleted, &progress.IsAbandoned,
+			&progress.CreatedAt, &progress.UpdatedAt,
+			&book.ID, &book.Title, &book.Author, &book.Genre,
+		)
+		if err != nil {
+			return nil, err
+		}
+		progress.Book = &book
+		progressList = append(progressList, &progress)
+	}
+
+	return progressList, rows.Err()
 }
 
 func (r *postgresBookProgressRepository) Update(ctx context.Context, progress *domain.BookProgress) error {
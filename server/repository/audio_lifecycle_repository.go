@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/ponyo877/roudoku/server/domain"
+)
+
+// AudioLifecycleRepository defines the interface for recording audio cache eviction runs
+type AudioLifecycleRepository interface {
+	CreateRun(ctx context.Context, run *domain.AudioLifecycleRun) error
+	// GetRunsSince returns the runs started at or after since, newest first
+	GetRunsSince(ctx context.Context, since time.Time) ([]*domain.AudioLifecycleRun, error)
+}
+
+type postgresAudioLifecycleRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPostgresAudioLifecycleRepository(db *pgxpool.Pool) AudioLifecycleRepository {
+	return &postgresAudioLifecycleRepository{db: db}
+}
+
+func (r *postgresAudioLifecycleRepository) CreateRun(ctx context.Context, run *domain.AudioLifecycleRun) error {
+	query := `
+		INSERT INTO audio_lifecycle_runs (id, started_at, finished_at, expired_files,
+			evicted_files, reclaimed_bytes, failed_files, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	_, err := r.db.Exec(ctx, query,
+		run.ID, run.StartedAt, run.FinishedAt, run.ExpiredFiles,
+		run.EvictedFiles, run.ReclaimedBytes, run.FailedFiles, run.CreatedAt,
+	)
+	return err
+}
+
+func (r *postgresAudioLifecycleRepository) GetRunsSince(ctx context.Context, since time.Time) ([]*domain.AudioLifecycleRun, error) {
+	query := `
+		SELECT id, started_at, finished_at, expired_files, evicted_files,
+			   reclaimed_bytes, failed_files, created_at
+		FROM audio_lifecycle_runs
+		WHERE started_at >= $1
+		ORDER BY started_at DESC`
+
+	rows, err := r.db.Query(ctx, query, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []*domain.AudioLifecycleRun
+	for rows.Next() {
+		var run domain.AudioLifecycleRun
+		err := rows.Scan(
+			&run.ID, &run.StartedAt, &run.FinishedAt, &run.ExpiredFiles,
+			&run.EvictedFiles, &run.ReclaimedBytes, &run.FailedFiles, &run.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, &run)
+	}
+
+	return runs, rows.Err()
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -17,11 +18,43 @@ type AudioFileRepository interface {
 	Create(ctx context.Context, audioFile *domain.AudioFile) error
 	GetByID(ctx context.Context, id uuid.UUID) (*domain.AudioFile, error)
 	GetByTextHash(ctx context.Context, userID uuid.UUID, textHash string) (*domain.AudioFile, error)
+	// GetByCacheKey returns the user's completed file for the text and
+	// voice configuration, or else the newest one of another user
+	GetByCacheKey(ctx context.Context, userID uuid.UUID, textHash, voiceConfig string) (*domain.AudioFile, error)
+	GetFormatVariants(ctx context.Context, textHash, voiceConfig string) ([]*domain.AudioFile, error)
 	GetByUserID(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*domain.AudioFile, error)
 	Update(ctx context.Context, audioFile *domain.AudioFile) error
 	Delete(ctx context.Context, id uuid.UUID) error
 	GetExpiredFiles(ctx context.Context) ([]*domain.AudioFile, error)
 	DeleteExpiredFiles(ctx context.Context) error
+	GetIdleFiles(ctx context.Context, usedBefore time.Time, limit int) ([]*domain.AudioFile, error)
+	GetLeastRecentlyUsed(ctx context.Context, userID uuid.UUID, limit int) ([]*domain.AudioFile, error)
+	GetStorageUsageOver(ctx context.Context, minBytes int64) ([]*domain.AudioStorageUsage, error)
+	GetStorageTotals(ctx context.Context) (*domain.AudioStorageUsage, error)
+	CountByFilePath(ctx context.Context, filePath string) (int, error)
+	// WithFilePathLock runs fn holding a lock on a blob path. Writing a blob
+	// and recording it, copying a record of it and deleting it all happen
+	// under the lock, so a blob is never deleted while a record of it is
+	// being added.
+	WithFilePathLock(ctx context.Context, filePath string, fn func() error) error
+	RecordPlay(ctx context.Context, id uuid.UUID, playedAt time.Time) error
+	// IsAccessibleBy reports whether the user may play an audio file: they
+	// own a file of the same text, or one of their audiobook jobs narrates
+	// with it
+	IsAccessibleBy(ctx context.Context, id, userID uuid.UUID) (bool, error)
+}
+
+// AudioAlignmentRepository defines the interface for audio alignment operations
+type AudioAlignmentRepository interface {
+	Upsert(ctx context.Context, alignment *domain.AudioAlignment) error
+	GetByAudioFileID(ctx context.Context, audioFileID uuid.UUID) (*domain.AudioAlignment, error)
+}
+
+// VoiceCastRepository defines the interface for per-book voice cast operations
+type VoiceCastRepository interface {
+	Upsert(ctx context.Context, cast *domain.BookVoiceCast) error
+	GetByUserAndBook(ctx context.Context, userID uuid.UUID, bookID int64) (*domain.BookVoiceCast, error)
+	Delete(ctx context.Context, userID uuid.UUID, bookID int64) error
 }
 
 // AudioPlaybackSessionRepository defines the interface for audio playback session operations
@@ -30,6 +63,7 @@ type AudioPlaybackSessionRepository interface {
 	GetByID(ctx context.Context, id uuid.UUID) (*domain.AudioPlaybackSession, error)
 	GetByUserID(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*domain.AudioPlaybackSession, error)
 	GetByAudioFileID(ctx context.Context, audioFileID uuid.UUID, limit, offset int) ([]*domain.AudioPlaybackSession, error)
+	GetLatestByBook(ctx context.Context, userID uuid.UUID, bookID int64) (*domain.AudioPlaybackSession, error)
 	Update(ctx context.Context, session *domain.AudioPlaybackSession) error
 	Delete(ctx context.Context, id uuid.UUID) error
 }
@@ -89,6 +123,9 @@ func NewPostgresAudioFileRepository(db *pgxpool.Pool) AudioFileRepository {
 	return &postgresAudioFileRepository{db: db}
 }
 
+// Create records an audio file. A user has one completed file per text and
+// voice configuration: creating another updates that record with the new
+// file, and the record's ID and creation time are returned in audioFile.
 func (r *postgresAudioFileRepository) Create(ctx context.Context, audioFile *domain.AudioFile) error {
 	query := `
 		INSERT INTO audio_files (
@@ -98,17 +135,28 @@ func (r *postgresAudioFileRepository) Create(ctx context.Context, audioFile *dom
 			expires_at, created_at, updated_at
 		) VALUES (
 			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18
-		)`
-
-	_, err := r.db.Exec(ctx, query,
+		)
+		ON CONFLICT (user_id, text_hash, voice_config) WHERE status = 'completed' DO UPDATE SET
+			book_id = COALESCE(EXCLUDED.book_id, audio_files.book_id),
+			chapter_id = COALESCE(EXCLUDED.chapter_id, audio_files.chapter_id),
+			file_path = EXCLUDED.file_path,
+			file_size_bytes = EXCLUDED.file_size_bytes,
+			duration_seconds = EXCLUDED.duration_seconds,
+			format = EXCLUDED.format,
+			sample_rate = EXCLUDED.sample_rate,
+			bit_rate = EXCLUDED.bit_rate,
+			expires_at = EXCLUDED.expires_at,
+			updated_at = EXCLUDED.updated_at
+		RETURNING id, created_at`
+
+	return r.db.QueryRow(ctx, query,
 		audioFile.ID, audioFile.UserID, audioFile.BookID, audioFile.ChapterID,
 		audioFile.TextContent, audioFile.TextHash, audioFile.VoiceConfig,
 		audioFile.FilePath, audioFile.FileSizeBytes, audioFile.DurationSeconds,
 		audioFile.Format, audioFile.SampleRate, audioFile.BitRate,
 		audioFile.Status, audioFile.ErrorMessage, audioFile.ExpiresAt,
 		audioFile.CreatedAt, audioFile.UpdatedAt,
-	)
-	return err
+	).Scan(&audioFile.ID, &audioFile.CreatedAt)
 }
 
 func (r *postgresAudioFileRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.AudioFile, error) {
@@ -171,6 +219,60 @@ func (r *postgresAudioFileRepository) GetByTextHash(ctx context.Context, userID
 	return &audioFile, nil
 }
 
+// GetByCacheKey finds a completed audio file for the given text hash and voice
+// configuration, preferring the user's own so that identical requests share
+// one synthesized file without copying its record again.
+func (r *postgresAudioFileRepository) GetByCacheKey(ctx context.Context, userID uuid.UUID, textHash, voiceConfig string) (*domain.AudioFile, error) {
+	query := `
+		SELECT id, user_id, book_id, chapter_id, text_content, text_hash,
+			   voice_config, file_path, file_size_bytes, duration_seconds,
+			   format, sample_rate, bit_rate, status, error_message,
+			   play_count, last_played_at, expires_at, created_at, updated_at
+		FROM audio_files 
+		WHERE text_hash = $1 AND voice_config = $2::jsonb AND status = 'completed'
+		ORDER BY user_id = $3 DESC, created_at DESC LIMIT 1`
+
+	var audioFile domain.AudioFile
+	err := r.db.QueryRow(ctx, query, textHash, voiceConfig, userID).Scan(
+		&audioFile.ID, &audioFile.UserID, &audioFile.BookID, &audioFile.ChapterID,
+		&audioFile.TextContent, &audioFile.TextHash, &audioFile.VoiceConfig,
+		&audioFile.FilePath, &audioFile.FileSizeBytes, &audioFile.DurationSeconds,
+		&audioFile.Format, &audioFile.SampleRate, &audioFile.BitRate,
+		&audioFile.Status, &audioFile.ErrorMessage, &audioFile.PlayCount,
+		&audioFile.LastPlayedAt, &audioFile.ExpiresAt, &audioFile.CreatedAt,
+		&audioFile.UpdatedAt,
+	)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &audioFile, nil
+}
+
+// GetFormatVariants finds the completed audio files of the same text and
+// voice configuration in every audio encoding and sample rate
+func (r *postgresAudioFileRepository) GetFormatVariants(ctx context.Context, textHash, voiceConfig string) ([]*domain.AudioFile, error) {
+	query := `
+		SELECT id, user_id, book_id, chapter_id, text_content, text_hash,
+			   voice_config, file_path, file_size_bytes, duration_seconds,
+			   format, sample_rate, bit_rate, status, error_message,
+			   play_count, last_played_at, expires_at, created_at, updated_at
+		FROM audio_files
+		WHERE text_hash = $1 AND status = 'completed'
+		  AND voice_config - 'audio_encoding' - 'sample_rate_hertz' = $2::jsonb - 'audio_encoding' - 'sample_rate_hertz'
+		ORDER BY created_at DESC`
+
+	rows, err := r.db.Query(ctx, query, textHash, voiceConfig)
+	if err != nil {
+		return nil, err
+	}
+	return collectAudioFiles(rows)
+}
+
 func (r *postgresAudioFileRepository) GetByUserID(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*domain.AudioFile, error) {
 	query := `
 		SELECT id, user_id, book_id, chapter_id, text_content, text_hash,
@@ -274,6 +376,458 @@ func (r *postgresAudioFileRepository) DeleteExpiredFiles(ctx context.Context) er
 	return err
 }
 
+// evictableAudioFile limits a query on audio_files to files that may be
+// removed: no audiobook chapter narrates with them, since the chapter would
+// lose its audio, and no playback session opened in the last day is still
+// running on them, since removing the file deletes the session
+const evictableAudioFile = `
+		NOT EXISTS (
+			SELECT 1 FROM audiobook_chapter_tasks t
+			WHERE t.audio_file_id = audio_files.id
+		)
+		AND NOT EXISTS (
+			SELECT 1 FROM audio_playback_sessions p
+			WHERE p.audio_file_id = audio_files.id AND NOT p.completed
+			  AND p.updated_at > NOW() - INTERVAL '1 day'
+		)`
+
+// GetIdleFiles returns evictable files whose last play, or creation when
+// never played, is before usedBefore, least recently used first
+func (r *postgresAudioFileRepository) GetIdleFiles(ctx context.Context, usedBefore time.Time, limit int) ([]*domain.AudioFile, error) {
+	query := `
+		SELECT id, user_id, book_id, chapter_id, text_content, text_hash,
+			   voice_config, file_path, file_size_bytes, duration_seconds,
+			   format, sample_rate, bit_rate, status, error_message,
+			   play_count, last_played_at, expires_at, created_at, updated_at
+		FROM audio_files
+		WHERE COALESCE(last_played_at, created_at) < $1 AND ` + evictableAudioFile + `
+		ORDER BY COALESCE(last_played_at, created_at) ASC
+		LIMIT $2`
+
+	rows, err := r.db.Query(ctx, query, usedBefore, limit)
+	if err != nil {
+		return nil, err
+	}
+	return collectAudioFiles(rows)
+}
+
+// GetLeastRecentlyUsed returns a user's evictable files in eviction order:
+// least recently played first, and less played first among equally recent files
+func (r *postgresAudioFileRepository) GetLeastRecentlyUsed(ctx context.Context, userID uuid.UUID, limit int) ([]*domain.AudioFile, error) {
+	query := `
+		SELECT id, user_id, book_id, chapter_id, text_content, text_hash,
+			   voice_config, file_path, file_size_bytes, duration_seconds,
+			   format, sample_rate, bit_rate, status, error_message,
+			   play_count, last_played_at, expires_at, created_at, updated_at
+		FROM audio_files
+		WHERE user_id = $1 AND ` + evictableAudioFile + `
+		ORDER BY COALESCE(last_played_at, created_at) ASC, play_count ASC
+		LIMIT $2`
+
+	rows, err := r.db.Query(ctx, query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return collectAudioFiles(rows)
+}
+
+// GetStorageUsageOver returns the users keeping more than minBytes of audio.
+// Only records a user owns count, and a blob the user holds through several
+// records counts once.
+func (r *postgresAudioFileRepository) GetStorageUsageOver(ctx context.Context, minBytes int64) ([]*domain.AudioStorageUsage, error) {
+	query := `
+		SELECT user_id, COUNT(*), COALESCE(SUM(file_size_bytes), 0)
+		FROM (
+			SELECT DISTINCT ON (user_id, file_path) user_id, file_size_bytes
+			FROM audio_files
+		) owned
+		GROUP BY user_id
+		HAVING COALESCE(SUM(file_size_bytes), 0) > $1
+		ORDER BY 3 DESC`
+
+	rows, err := r.db.Query(ctx, query, minBytes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var usages []*domain.AudioStorageUsage
+	for rows.Next() {
+		var usage domain.AudioStorageUsage
+		if err := rows.Scan(&usage.UserID, &usage.Files, &usage.Bytes); err != nil {
+			return nil, err
+		}
+		usages = append(usages, &usage)
+	}
+
+	return usages, rows.Err()
+}
+
+// GetStorageTotals returns the audio kept for all users
+func (r *postgresAudioFileRepository) GetStorageTotals(ctx context.Context) (*domain.AudioStorageUsage, error) {
+	query := `SELECT COUNT(*), COALESCE(SUM(file_size_bytes), 0) FROM audio_files`
+
+	var usage domain.AudioStorageUsage
+	if err := r.db.QueryRow(ctx, query).Scan(&usage.Files, &usage.Bytes); err != nil {
+		return nil, err
+	}
+	return &usage, nil
+}
+
+// CountByFilePath returns how many records point at the same blob
+func (r *postgresAudioFileRepository) CountByFilePath(ctx context.Context, filePath string) (int, error) {
+	query := `SELECT COUNT(*) FROM audio_files WHERE file_path = $1`
+
+	var count int
+	if err := r.db.QueryRow(ctx, query, filePath).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// WithFilePathLock holds a session advisory lock on a connection taken out
+// of the pool while fn runs
+func (r *postgresAudioFileRepository) WithFilePathLock(ctx context.Context, filePath string, fn func() error) error {
+	conn, err := r.db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock(hashtextextended($1, 0))`, filePath); err != nil {
+		conn.Release()
+		return fmt.Errorf("failed to lock audio file path: %w", err)
+	}
+	defer func() {
+		if _, err := conn.Exec(context.WithoutCancel(ctx), `SELECT pg_advisory_unlock(hashtextextended($1, 0))`, filePath); err != nil {
+			// Closing the connection releases the lock
+			conn.Hijack().Close(context.Background())
+			return
+		}
+		conn.Release()
+	}()
+
+	return fn()
+}
+
+// RecordPlay counts a play of the file and marks it as recently used
+func (r *postgresAudioFileRepository) RecordPlay(ctx context.Context, id uuid.UUID, playedAt time.Time) error {
+	query := `
+		UPDATE audio_files
+		SET play_count = play_count + 1, last_played_at = $2
+		WHERE id = $1`
+
+	_, err := r.db.Exec(ctx, query, id, playedAt)
+	return err
+}
+
+func (r *postgresAudioFileRepository) IsAccessibleBy(ctx context.Context, id, userID uuid.UUID) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM audio_files f
+			WHERE f.id = $1 AND (
+				EXISTS (
+					SELECT 1 FROM audio_files o
+					WHERE o.user_id = $2 AND o.text_hash = f.text_hash
+				)
+				OR EXISTS (
+					SELECT 1 FROM audiobook_chapter_tasks t
+					JOIN audiobook_jobs j ON j.id = t.job_id
+					WHERE t.audio_file_id = f.id AND j.user_id = $2
+				)
+			)
+		)`
+
+	var accessible bool
+	if err := r.db.QueryRow(ctx, query, id, userID).Scan(&accessible); err != nil {
+		return false, err
+	}
+	return accessible, nil
+}
+
+// collectAudioFiles scans audio_files rows selected in column order and closes rows
+func collectAudioFiles(rows pgx.Rows) ([]*domain.AudioFile, error) {
+	defer rows.Close()
+
+	var audioFiles []*domain.AudioFile
+	for rows.Next() {
+		var audioFile domain.AudioFile
+		err := rows.Scan(
+			&audioFile.ID, &audioFile.UserID, &audioFile.BookID, &audioFile.ChapterID,
+			&audioFile.TextContent, &audioFile.TextHash, &audioFile.VoiceConfig,
+			&audioFile.FilePath, &audioFile.FileSizeBytes, &audioFile.DurationSeconds,
+			&audioFile.Format, &audioFile.SampleRate, &audioFile.BitRate,
+			&audioFile.Status, &audioFile.ErrorMessage, &audioFile.PlayCount,
+			&audioFile.LastPlayedAt, &audioFile.ExpiresAt, &audioFile.CreatedAt,
+			&audioFile.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		audioFiles = append(audioFiles, &audioFile)
+	}
+
+	return audioFiles, rows.Err()
+}
+
+// Audio Alignment Repository implementation
+type postgresAudioAlignmentRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPostgresAudioAlignmentRepository(db *pgxpool.Pool) AudioAlignmentRepository {
+	return &postgresAudioAlignmentRepository{db: db}
+}
+
+func (r *postgresAudioAlignmentRepository) Upsert(ctx context.Context, alignment *domain.AudioAlignment) error {
+	segmentsJSON, err := json.Marshal(alignment.Segments)
+	if err != nil {
+		return fmt.Errorf("failed to marshal alignment segments: %w", err)
+	}
+
+	query := `
+		INSERT INTO audio_alignments (audio_file_id, duration_ms, segments, created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (audio_file_id) DO UPDATE SET
+			duration_ms = EXCLUDED.duration_ms,
+			segments = EXCLUDED.segments`
+
+	_, err = r.db.Exec(ctx, query,
+		alignment.AudioFileID, alignment.DurationMs, segmentsJSON, alignment.CreatedAt,
+	)
+	return err
+}
+
+func (r *postgresAudioAlignmentRepository) GetByAudioFileID(ctx context.Context, audioFileID uuid.UUID) (*domain.AudioAlignment, error) {
+	query := `
+		SELECT audio_file_id, duration_ms, segments, created_at
+		FROM audio_alignments WHERE audio_file_id = $1`
+
+	var alignment domain.AudioAlignment
+	var segmentsJSON []byte
+	err := r.db.QueryRow(ctx, query, audioFileID).Scan(
+		&alignment.AudioFileID, &alignment.DurationMs, &segmentsJSON, &alignment.CreatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(segmentsJSON, &alignment.Segments); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal alignment segments: %w", err)
+	}
+
+	return &alignment, nil
+}
+
+// Voice Cast Repository implementation
+type postgresVoiceCastRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPostgresVoiceCastRepository(db *pgxpool.Pool) VoiceCastRepository {
+	return &postgresVoiceCastRepository{db: db}
+}
+
+func (r *postgresVoiceCastRepository) Upsert(ctx context.Context, cast *domain.BookVoiceCast) error {
+	narratorJSON, err := json.Marshal(cast.Cast.Narrator)
+	if err != nil {
+		return fmt.Errorf("failed to marshal narrator voice: %w", err)
+	}
+	characters := cast.Cast.Characters
+	if characters == nil {
+		characters = []domain.CastCharacter{}
+	}
+	charactersJSON, err := json.Marshal(characters)
+	if err != nil {
+		return fmt.Errorf("failed to marshal character voices: %w", err)
+	}
+
+	query := `
+		INSERT INTO book_voice_casts (user_id, book_id, narrator, characters, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id, book_id) DO UPDATE SET
+			narrator = EXCLUDED.narrator,
+			characters = EXCLUDED.characters,
+			updated_at = EXCLUDED.updated_at`
+
+	_, err = r.db.Exec(ctx, query,
+		cast.UserID, cast.BookID, narratorJSON, charactersJSON, cast.CreatedAt, cast.UpdatedAt,
+	)
+	return err
+}
+
+func (r *postgresVoiceCastRepository) GetByUserAndBook(ctx context.Context, userID uuid.UUID, bookID int64) (*domain.BookVoiceCast, error) {
+	query := `
+		SELECT user_id, book_id, narrator, characters, created_at, updated_at
+		FROM book_voice_casts WHERE user_id = $1 AND book_id = $2`
+
+	var cast domain.BookVoiceCast
+	var narratorJSON, charactersJSON []byte
+	err := r.db.QueryRow(ctx, query, userID, bookID).Scan(
+		&cast.UserID, &cast.BookID, &narratorJSON, &charactersJSON, &cast.CreatedAt, &cast.UpdatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(narratorJSON, &cast.Cast.Narrator); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal narrator voice: %w", err)
+	}
+	if err := json.Unmarshal(charactersJSON, &cast.Cast.Characters); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal character voices: %w", err)
+	}
+
+	return &cast, nil
+}
+
+func (r *postgresVoiceCastRepository) Delete(ctx context.Context, userID uuid.UUID, bookID int64) error {
+	query := `DELETE FROM book_voice_casts WHERE user_id = $1 AND book_id = $2`
+	_, err := r.db.Exec(ctx, query, userID, bookID)
+	return err
+}
+
+// Audio Playback Session Repository implementation
+type postgresAudioPlaybackSessionRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPostgresAudioPlaybackSessionRepository(db *pgxpool.Pool) AudioPlaybackSessionRepository {
+	return &postgresAudioPlaybackSessionRepository{db: db}
+}
+
+const playbackSessionColumns = `
+	s.id, s.user_id, s.audio_file_id, s.start_position_ms, s.end_position_ms,
+	s.duration_ms, s.playback_speed, s.completed, s.device_type, s.recorded_ms,
+	s.ended_at, s.created_at, s.updated_at`
+
+func scanPlaybackSession(row pgx.Row) (*domain.AudioPlaybackSession, error) {
+	var session domain.AudioPlaybackSession
+	err := row.Scan(
+		&session.ID, &session.UserID, &session.AudioFileID, &session.StartPositionMs, &session.EndPositionMs,
+		&session.DurationMs, &session.PlaybackSpeed, &session.Completed, &session.DeviceType, &session.RecordedMs,
+		&session.EndedAt, &session.CreatedAt, &session.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *postgresAudioPlaybackSessionRepository) Create(ctx context.Context, session *domain.AudioPlaybackSession) error {
+	query := `
+		INSERT INTO audio_playback_sessions (
+			id, user_id, audio_file_id, start_position_ms, end_position_ms,
+			duration_ms, playback_speed, completed, device_type, recorded_ms,
+			ended_at, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`
+
+	_, err := r.db.Exec(ctx, query,
+		session.ID, session.UserID, session.AudioFileID, session.StartPositionMs, session.EndPositionMs,
+		session.DurationMs, session.PlaybackSpeed, session.Completed, session.DeviceType, session.RecordedMs,
+		session.EndedAt, session.CreatedAt, session.UpdatedAt,
+	)
+	return err
+}
+
+func (r *postgresAudioPlaybackSessionRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.AudioPlaybackSession, error) {
+	query := `SELECT ` + playbackSessionColumns + ` FROM audio_playback_sessions s WHERE s.id = $1`
+
+	session, err := scanPlaybackSession(r.db.QueryRow(ctx, query, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return session, nil
+}
+
+func (r *postgresAudioPlaybackSessionRepository) GetByUserID(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*domain.AudioPlaybackSession, error) {
+	query := `
+		SELECT ` + playbackSessionColumns + `
+		FROM audio_playback_sessions s
+		WHERE s.user_id = $1
+		ORDER BY s.updated_at DESC
+		LIMIT $2 OFFSET $3`
+
+	return r.list(ctx, query, userID, limit, offset)
+}
+
+func (r *postgresAudioPlaybackSessionRepository) GetByAudioFileID(ctx context.Context, audioFileID uuid.UUID, limit, offset int) ([]*domain.AudioPlaybackSession, error) {
+	query := `
+		SELECT ` + playbackSessionColumns + `
+		FROM audio_playback_sessions s
+		WHERE s.audio_file_id = $1
+		ORDER BY s.updated_at DESC
+		LIMIT $2 OFFSET $3`
+
+	return r.list(ctx, query, audioFileID, limit, offset)
+}
+
+// GetLatestByBook returns the user's most recently updated session on any
+// audio file of the book
+func (r *postgresAudioPlaybackSessionRepository) GetLatestByBook(ctx context.Context, userID uuid.UUID, bookID int64) (*domain.AudioPlaybackSession, error) {
+	query := `
+		SELECT ` + playbackSessionColumns + `
+		FROM audio_playback_sessions s
+		JOIN audio_files af ON af.id = s.audio_file_id
+		WHERE s.user_id = $1 AND af.book_id = $2
+		ORDER BY s.updated_at DESC
+		LIMIT 1`
+
+	session, err := scanPlaybackSession(r.db.QueryRow(ctx, query, userID, bookID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return session, nil
+}
+
+func (r *postgresAudioPlaybackSessionRepository) Update(ctx context.Context, session *domain.AudioPlaybackSession) error {
+	query := `
+		UPDATE audio_playback_sessions SET
+			end_position_ms = $2, duration_ms = $3, playback_speed = $4, completed = $5,
+			recorded_ms = $6, ended_at = $7, updated_at = $8
+		WHERE id = $1`
+
+	_, err := r.db.Exec(ctx, query,
+		session.ID, session.EndPositionMs, session.DurationMs, session.PlaybackSpeed, session.Completed,
+		session.RecordedMs, session.EndedAt, session.UpdatedAt,
+	)
+	return err
+}
+
+func (r *postgresAudioPlaybackSessionRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	query := `DELETE FROM audio_playback_sessions WHERE id = $1`
+	_, err := r.db.Exec(ctx, query, id)
+	return err
+}
+
+func (r *postgresAudioPlaybackSessionRepository) list(ctx context.Context, query string, args ...interface{}) ([]*domain.AudioPlaybackSession, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []*domain.AudioPlaybackSession
+	for rows.Next() {
+		session, err := scanPlaybackSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
+
 // FCM Token Repository implementation
 type postgresFCMTokenRepository struct {
 	db *pgxpool.Pool
@@ -0,0 +1,403 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/ponyo877/roudoku/server/domain"
+)
+
+// AudiobookJobRepository defines the interface for audiobook jobs and their
+// chapter task queue
+type AudiobookJobRepository interface {
+	CreateJob(ctx context.Context, job *domain.AudiobookJob, tasks []*domain.AudiobookChapterTask) error
+	GetJobByID(ctx context.Context, id uuid.UUID) (*domain.AudiobookJob, error)
+	GetLatestJobByBook(ctx context.Context, userID uuid.UUID, bookID int64) (*domain.AudiobookJob, error)
+	GetActiveJob(ctx context.Context, userID uuid.UUID, bookID int64, voiceConfig string) (*domain.AudiobookJob, error)
+	// GetLatestJobsByUser returns the user's latest job for each book, most recent first
+	GetLatestJobsByUser(ctx context.Context, userID uuid.UUID, limit int) ([]*domain.AudiobookJob, error)
+	// GetLatestJobWithAudio returns the latest job of any user that has
+	// narrated at least one chapter of the book
+	GetLatestJobWithAudio(ctx context.Context, bookID int64) (*domain.AudiobookJob, error)
+	GetTasksByJobID(ctx context.Context, jobID uuid.UUID) ([]*domain.AudiobookChapterTask, error)
+
+	// ClaimNextTask locks the next due task for workerID, or returns nil when the queue is empty
+	ClaimNextTask(ctx context.Context, workerID string) (*domain.AudiobookChapterTask, error)
+	// CompleteTask marks a task completed and returns the updated job
+	CompleteTask(ctx context.Context, taskID uuid.UUID, audioFileID uuid.UUID) (*domain.AudiobookJob, error)
+	// FailTask requeues a task at retryAt, or fails it permanently once it has
+	// used all its attempts, and returns the updated job
+	FailTask(ctx context.Context, taskID uuid.UUID, errorMessage string, retryAt time.Time) (*domain.AudiobookJob, error)
+	// RequeueStaleTasks returns tasks locked before lockedBefore to the queue,
+	// or fails them once they have used all their attempts. It returns how
+	// many were requeued and the jobs those failures updated.
+	RequeueStaleTasks(ctx context.Context, lockedBefore time.Time) (int64, []*domain.AudiobookJob, error)
+}
+
+type postgresAudiobookJobRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPostgresAudiobookJobRepository(db *pgxpool.Pool) AudiobookJobRepository {
+	return &postgresAudiobookJobRepository{db: db}
+}
+
+const audiobookJobColumns = `
+	id, user_id, book_id, voice_config, status, total_chapters,
+	completed_chapters, failed_chapters, error_message, completed_at,
+	created_at, updated_at`
+
+const audiobookTaskColumns = `
+	id, job_id, chapter_id, position, status, attempts, max_attempts,
+	next_attempt_at, locked_by, locked_at, audio_file_id, error_message,
+	created_at, updated_at`
+
+func scanAudiobookJob(row pgx.Row) (*domain.AudiobookJob, error) {
+	var job domain.AudiobookJob
+	err := row.Scan(
+		&job.ID, &job.UserID, &job.BookID, &job.VoiceConfig, &job.Status,
+		&job.TotalChapters, &job.CompletedChapters, &job.FailedChapters,
+		&job.ErrorMessage, &job.CompletedAt, &job.CreatedAt, &job.UpdatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+func scanAudiobookTask(row pgx.Row) (*domain.AudiobookChapterTask, error) {
+	var task domain.AudiobookChapterTask
+	err := row.Scan(
+		&task.ID, &task.JobID, &task.ChapterID, &task.Position, &task.Status,
+		&task.Attempts, &task.MaxAttempts, &task.NextAttemptAt, &task.LockedBy,
+		&task.LockedAt, &task.AudioFileID, &task.ErrorMessage,
+		&task.CreatedAt, &task.UpdatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &task, nil
+}
+
+func (r *postgresAudiobookJobRepository) CreateJob(ctx context.Context, job *domain.AudiobookJob, tasks []*domain.AudiobookChapterTask) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO audiobook_jobs (
+			id, user_id, book_id, voice_config, status, total_chapters,
+			completed_chapters, failed_chapters, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		job.ID, job.UserID, job.BookID, job.VoiceConfig, job.Status, job.TotalChapters,
+		job.CompletedChapters, job.FailedChapters, job.CreatedAt, job.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert audiobook job: %w", err)
+	}
+
+	for _, task := range tasks {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO audiobook_chapter_tasks (
+				id, job_id, chapter_id, position, status, attempts, max_attempts,
+				next_attempt_at, created_at, updated_at
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+			task.ID, task.JobID, task.ChapterID, task.Position, task.Status, task.Attempts,
+			task.MaxAttempts, task.NextAttemptAt, task.CreatedAt, task.UpdatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert audiobook chapter task: %w", err)
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *postgresAudiobookJobRepository) GetJobByID(ctx context.Context, id uuid.UUID) (*domain.AudiobookJob, error) {
+	query := `SELECT ` + audiobookJobColumns + ` FROM audiobook_jobs WHERE id = $1`
+	return scanAudiobookJob(r.db.QueryRow(ctx, query, id))
+}
+
+func (r *postgresAudiobookJobRepository) GetLatestJobByBook(ctx context.Context, userID uuid.UUID, bookID int64) (*domain.AudiobookJob, error) {
+	query := `
+		SELECT ` + audiobookJobColumns + `
+		FROM audiobook_jobs
+		WHERE user_id = $1 AND book_id = $2
+		ORDER BY created_at DESC LIMIT 1`
+	return scanAudiobookJob(r.db.QueryRow(ctx, query, userID, bookID))
+}
+
+func (r *postgresAudiobookJobRepository) GetActiveJob(ctx context.Context, userID uuid.UUID, bookID int64, voiceConfig string) (*domain.AudiobookJob, error) {
+	query := `
+		SELECT ` + audiobookJobColumns + `
+		FROM audiobook_jobs
+		WHERE user_id = $1 AND book_id = $2 AND voice_config = $3::jsonb
+		  AND status IN ('queued', 'processing')
+		ORDER BY created_at DESC LIMIT 1`
+	return scanAudiobookJob(r.db.QueryRow(ctx, query, userID, bookID, voiceConfig))
+}
+
+func (r *postgresAudiobookJobRepository) GetLatestJobsByUser(ctx context.Context, userID uuid.UUID, limit int) ([]*domain.AudiobookJob, error) {
+	query := `
+		SELECT ` + audiobookJobColumns + `
+		FROM (
+			SELECT DISTINCT ON (book_id) ` + audiobookJobColumns + `
+			FROM audiobook_jobs
+			WHERE user_id = $1
+			ORDER BY book_id, created_at DESC
+		) latest
+		ORDER BY created_at DESC
+		LIMIT $2`
+
+	rows, err := r.db.Query(ctx, query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []*domain.AudiobookJob
+	for rows.Next() {
+		job, err := scanAudiobookJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, rows.Err()
+}
+
+func (r *postgresAudiobookJobRepository) GetLatestJobWithAudio(ctx context.Context, bookID int64) (*domain.AudiobookJob, error) {
+	query := `
+		SELECT ` + audiobookJobColumns + `
+		FROM audiobook_jobs
+		WHERE book_id = $1 AND completed_chapters > 0
+		ORDER BY created_at DESC LIMIT 1`
+	return scanAudiobookJob(r.db.QueryRow(ctx, query, bookID))
+}
+
+func (r *postgresAudiobookJobRepository) GetTasksByJobID(ctx context.Context, jobID uuid.UUID) ([]*domain.AudiobookChapterTask, error) {
+	query := `
+		SELECT ` + audiobookTaskColumns + `
+		FROM audiobook_chapter_tasks
+		WHERE job_id = $1
+		ORDER BY position`
+
+	rows, err := r.db.Query(ctx, query, jobID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tasks []*domain.AudiobookChapterTask
+	for rows.Next() {
+		task, err := scanAudiobookTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+
+	return tasks, rows.Err()
+}
+
+// ClaimNextTask uses FOR UPDATE SKIP LOCKED so concurrent workers, including
+// workers in other server instances, never claim the same task.
+func (r *postgresAudiobookJobRepository) ClaimNextTask(ctx context.Context, workerID string) (*domain.AudiobookChapterTask, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE audiobook_chapter_tasks
+		SET status = 'processing', attempts = attempts + 1,
+			locked_by = $1, locked_at = NOW(), updated_at = NOW()
+		WHERE id = (
+			SELECT id FROM audiobook_chapter_tasks
+			WHERE status = 'queued' AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at, position
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + audiobookTaskColumns
+
+	task, err := scanAudiobookTask(tx.QueryRow(ctx, query, workerID))
+	if err != nil || task == nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE audiobook_jobs SET status = 'processing', updated_at = NOW()
+		WHERE id = $1 AND status = 'queued'`, task.JobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark audiobook job processing: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+func (r *postgresAudiobookJobRepository) CompleteTask(ctx context.Context, taskID uuid.UUID, audioFileID uuid.UUID) (*domain.AudiobookJob, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var jobID uuid.UUID
+	err = tx.QueryRow(ctx, `
+		UPDATE audiobook_chapter_tasks
+		SET status = 'completed', audio_file_id = $2, error_message = NULL,
+			locked_by = NULL, locked_at = NULL, updated_at = NOW()
+		WHERE id = $1 AND status = 'processing'
+		RETURNING job_id`, taskID, audioFileID).Scan(&jobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to complete audiobook chapter task: %w", err)
+	}
+
+	job, err := r.finishTask(ctx, tx, jobID, 1, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return job, tx.Commit(ctx)
+}
+
+func (r *postgresAudiobookJobRepository) FailTask(ctx context.Context, taskID uuid.UUID, errorMessage string, retryAt time.Time) (*domain.AudiobookJob, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var jobID uuid.UUID
+	var status string
+	err = tx.QueryRow(ctx, `
+		UPDATE audiobook_chapter_tasks
+		SET status = CASE WHEN attempts < max_attempts THEN 'queued' ELSE 'failed' END,
+			next_attempt_at = $3, error_message = $2,
+			locked_by = NULL, locked_at = NULL, updated_at = NOW()
+		WHERE id = $1 AND status = 'processing'
+		RETURNING job_id, status`, taskID, errorMessage, retryAt).Scan(&jobID, &status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fail audiobook chapter task: %w", err)
+	}
+
+	var job *domain.AudiobookJob
+	if status == domain.AudiobookStatusFailed {
+		job, err = r.finishTask(ctx, tx, jobID, 0, 1, &errorMessage)
+	} else {
+		job, err = scanAudiobookJob(tx.QueryRow(ctx,
+			`SELECT `+audiobookJobColumns+` FROM audiobook_jobs WHERE id = $1`, jobID))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return job, tx.Commit(ctx)
+}
+
+// finishTask adds a finished task to the job counters and closes the job once
+// every chapter has finished
+func (r *postgresAudiobookJobRepository) finishTask(ctx context.Context, tx pgx.Tx, jobID uuid.UUID, completed, failed int, errorMessage *string) (*domain.AudiobookJob, error) {
+	query := `
+		UPDATE audiobook_jobs
+		SET completed_chapters = completed_chapters + $2,
+			failed_chapters = failed_chapters + $3,
+			error_message = COALESCE($4, error_message),
+			status = CASE
+				WHEN completed_chapters + failed_chapters + $2 + $3 < total_chapters THEN 'processing'
+				WHEN failed_chapters + $3 > 0 THEN 'failed'
+				ELSE 'completed'
+			END,
+			completed_at = CASE
+				WHEN completed_chapters + failed_chapters + $2 + $3 >= total_chapters THEN NOW()
+				ELSE completed_at
+			END,
+			updated_at = NOW()
+		WHERE id = $1
+		RETURNING ` + audiobookJobColumns
+
+	job, err := scanAudiobookJob(tx.QueryRow(ctx, query, jobID, completed, failed, errorMessage))
+	if err != nil {
+		return nil, fmt.Errorf("failed to update audiobook job progress: %w", err)
+	}
+	return job, nil
+}
+
+func (r *postgresAudiobookJobRepository) RequeueStaleTasks(ctx context.Context, lockedBefore time.Time) (int64, []*domain.AudiobookJob, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// A task that keeps crashing its worker counts its attempts like one
+	// that fails, since ClaimNextTask increments them
+	rows, err := tx.Query(ctx, `
+		UPDATE audiobook_chapter_tasks
+		SET status = CASE WHEN attempts < max_attempts THEN 'queued' ELSE 'failed' END,
+			error_message = CASE WHEN attempts < max_attempts THEN error_message
+				ELSE 'worker stopped responding' END,
+			locked_by = NULL, locked_at = NULL,
+			next_attempt_at = NOW(), updated_at = NOW()
+		WHERE status = 'processing' AND locked_at < $1
+		RETURNING job_id, status`, lockedBefore)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var requeued int64
+	failedByJob := make(map[uuid.UUID]int)
+	var failedJobIDs []uuid.UUID
+	for rows.Next() {
+		var jobID uuid.UUID
+		var status string
+		if err := rows.Scan(&jobID, &status); err != nil {
+			rows.Close()
+			return 0, nil, err
+		}
+		if status != domain.AudiobookStatusFailed {
+			requeued++
+			continue
+		}
+		if failedByJob[jobID] == 0 {
+			failedJobIDs = append(failedJobIDs, jobID)
+		}
+		failedByJob[jobID]++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, nil, err
+	}
+
+	errorMessage := "worker stopped responding"
+	var jobs []*domain.AudiobookJob
+	for _, jobID := range failedJobIDs {
+		job, err := r.finishTask(ctx, tx, jobID, 0, failedByJob[jobID], &errorMessage)
+		if err != nil {
+			return 0, nil, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	return requeued, jobs, tx.Commit(ctx)
+}
@@ -0,0 +1,518 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/ponyo877/roudoku/server/domain"
+)
+
+// Similarity types of a similar books search
+const (
+	SimilarityContent = "content"
+	SimilarityGenre   = "genre"
+	SimilarityStyle   = "style"
+	SimilarityHybrid  = "hybrid"
+)
+
+// similarityColumns maps a single-vector similarity type to its column
+var similarityColumns = map[string]string{
+	SimilarityContent: "content_vector",
+	SimilarityGenre:   "genre_vector",
+	SimilarityStyle:   "style_vector",
+}
+
+// hybridSimilarity blends the cosine similarities of the three vectors of
+// the target book, passed as $8 to $10; a missing vector contributes nothing
+const hybridSimilarity = `
+	0.6 * COALESCE(1 - (bv.content_vector <=> $8::vector), 0) +
+	0.25 * COALESCE(1 - (bv.genre_vector <=> $9::vector), 0) +
+	0.15 * COALESCE(1 - (bv.style_vector <=> $10::vector), 0)`
+
+// Filters drop neighbours after the index scan, so the scan fetches this
+// many candidates per requested result, within the limits of the index
+// search parameters
+const (
+	similarCandidatesPerResult = 10
+	minSimilarCandidates       = 100
+	maxSimilarCandidates       = 1000
+)
+
+// postgresBookVectorRepository implements BookVectorRepository using
+// pgvector. Vectors are exchanged in pgvector's text form.
+type postgresBookVectorRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewPostgresBookVectorRepository creates a new pgvector book vector repository
+func NewPostgresBookVectorRepository(db *pgxpool.Pool) BookVectorRepository {
+	return &postgresBookVectorRepository{db: db}
+}
+
+const bookVectorColumns = `book_id, content_vector::text, genre_vector::text, style_vector::text,
+	difficulty_score, popularity_score, quality_score, novelty_score,
+	embedding_model, content_hash, last_updated`
+
+// bookContentHashSQL computes BookContentHash of the chapters of books b
+const bookContentHashSQL = `(
+	SELECT encode(sha256(convert_to(string_agg(c.content, E'\n' ORDER BY c.position), 'UTF8')), 'hex')
+	FROM chapters c WHERE c.book_id = b.id
+)`
+
+// BookContentHash returns the hash recorded with a book's vectors: the
+// SHA-256 of its chapters' content in reading order, separated by newlines
+func BookContentHash(chapters []*domain.Chapter) string {
+	hash := sha256.New()
+	for i, chapter := range chapters {
+		if i > 0 {
+			hash.Write([]byte("\n"))
+		}
+		hash.Write([]byte(chapter.Content))
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Create stores the vectors of a book, replacing any stored before
+func (r *postgresBookVectorRepository) Create(ctx context.Context, vector *domain.BookVector) error {
+	args, err := bookVectorArgs(vector)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO book_vectors (
+			book_id, content_vector, genre_vector, style_vector,
+			difficulty_score, popularity_score, quality_score, novelty_score,
+			embedding_model, content_hash, last_updated
+		) VALUES ($1, $2::vector, $3::vector, $4::vector, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (book_id) DO UPDATE SET
+			content_vector = EXCLUDED.content_vector,
+			genre_vector = EXCLUDED.genre_vector,
+			style_vector = EXCLUDED.style_vector,
+			difficulty_score = EXCLUDED.difficulty_score,
+			popularity_score = EXCLUDED.popularity_score,
+			quality_score = EXCLUDED.quality_score,
+			novelty_score = EXCLUDED.novelty_score,
+			embedding_model = EXCLUDED.embedding_model,
+			content_hash = EXCLUDED.content_hash,
+			last_updated = EXCLUDED.last_updated`
+
+	if _, err := r.db.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to store book vector: %w", err)
+	}
+	return nil
+}
+
+// GetByBookID returns the vectors of a book, or nil when none are stored
+func (r *postgresBookVectorRepository) GetByBookID(ctx context.Context, bookID int64) (*domain.BookVector, error) {
+	query := `SELECT ` + bookVectorColumns + ` FROM book_vectors WHERE book_id = $1`
+
+	vector, err := scanBookVector(r.db.QueryRow(ctx, query, bookID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get book vector: %w", err)
+	}
+	return vector, nil
+}
+
+// GetSimilarBooks runs an approximate nearest-neighbour scan over the
+// vector index and filters the candidates to active books the reader may
+// open and has not finished. A book without the compared vector has no
+// neighbours. The target book's vectors are loaded first and passed as
+// parameters, since the index can only order by distance to a value, not
+// to a column of a joined row.
+func (r *postgresBookVectorRepository) GetSimilarBooks(ctx context.Context, q SimilarBooksQuery) ([]*SimilarBook, error) {
+	if q.Count <= 0 {
+		return []*SimilarBook{}, nil
+	}
+
+	// Hybrid searches draw candidates from the content index and re-rank them
+	column, ok := similarityColumns[q.SimilarityType]
+	if q.SimilarityType == SimilarityHybrid {
+		column, ok = similarityColumns[SimilarityContent], true
+	}
+	if !ok {
+		return nil, fmt.Errorf("unsupported similarity type %q", q.SimilarityType)
+	}
+	similarity := "1 - (bv." + column + " <=> $8::vector)"
+	if q.SimilarityType == SimilarityHybrid {
+		similarity = hybridSimilarity
+	}
+
+	candidates := min(max(q.Count*similarCandidatesPerResult, minSimilarCandidates), maxSimilarCandidates)
+	excluded := q.ExcludeBookIDs
+	if excluded == nil {
+		excluded = []int64{}
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var content, genre, style *string
+	err = tx.QueryRow(ctx,
+		`SELECT content_vector::text, genre_vector::text, style_vector::text FROM book_vectors WHERE book_id = $1`,
+		q.BookID).Scan(&content, &genre, &style)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return []*SimilarBook{}, nil
+		}
+		return nil, fmt.Errorf("failed to get book vector: %w", err)
+	}
+	vectors := map[string]*string{"content_vector": content, "genre_vector": genre, "style_vector": style}
+	if vectors[column] == nil {
+		return []*SimilarBook{}, nil
+	}
+	// $8 is the searched vector; hybrid searches add the genre and style
+	// vectors as $9 and $10
+	targets := []interface{}{vectors[column]}
+	if q.SimilarityType == SimilarityHybrid {
+		targets = append(targets, genre, style)
+	}
+
+	query := `
+		WITH candidates AS (
+			SELECT book_id
+			FROM book_vectors
+			WHERE book_id <> $1
+			ORDER BY ` + column + ` <=> $8::vector
+			LIMIT $2
+		)
+		SELECT bv.book_id, ` + similarity + ` AS similarity
+		FROM candidates c
+		JOIN book_vectors bv ON bv.book_id = c.book_id
+		JOIN books b ON b.id = bv.book_id
+		WHERE bv.` + column + ` IS NOT NULL
+			AND b.is_active
+			AND ($3 OR NOT b.is_premium)
+			AND NOT (bv.book_id = ANY($4))
+			AND NOT EXISTS (
+				SELECT 1 FROM book_progress bp
+				WHERE bp.user_id = $5 AND bp.book_id = bv.book_id
+					AND (bp.is_completed OR ($6 AND bp.is_abandoned))
+			)
+		ORDER BY similarity DESC
+		LIMIT $7`
+
+	// Let the index scan return as many candidates as are asked for
+	searchParams := fmt.Sprintf("SET LOCAL hnsw.ef_search = %d", candidates)
+	if _, err := tx.Exec(ctx, searchParams); err != nil {
+		return nil, fmt.Errorf("failed to set vector search parameters: %w", err)
+	}
+
+	args := append([]interface{}{
+		q.BookID, candidates, q.IncludePremium, excluded, q.ReaderID, q.ExcludeAbandoned, q.Count,
+	}, targets...)
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search similar books: %w", err)
+	}
+	defer rows.Close()
+
+	similar := []*SimilarBook{}
+	for rows.Next() {
+		book := &SimilarBook{}
+		if err := rows.Scan(&book.BookID, &book.Similarity); err != nil {
+			return nil, fmt.Errorf("failed to scan similar book: %w", err)
+		}
+		similar = append(similar, book)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to search similar books: %w", err)
+	}
+
+	return similar, tx.Commit(ctx)
+}
+
+// UpdateVector replaces the vectors and scores of a book that has vectors
+func (r *postgresBookVectorRepository) UpdateVector(ctx context.Context, vector *domain.BookVector) error {
+	args, err := bookVectorArgs(vector)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE book_vectors SET
+			content_vector = $2::vector, genre_vector = $3::vector, style_vector = $4::vector,
+			difficulty_score = $5, popularity_score = $6, quality_score = $7, novelty_score = $8,
+			embedding_model = $9, content_hash = $10, last_updated = $11
+		WHERE book_id = $1`
+
+	tag, err := r.db.Exec(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update book vector: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetBooksForGenreVector returns the vectors of the most popular active
+// books in any of the genres
+func (r *postgresBookVectorRepository) GetBooksForGenreVector(ctx context.Context, genres []string, count int) ([]*domain.BookVector, error) {
+	query := `
+		SELECT ` + prefixColumns("bv.", bookVectorColumns) + `
+		FROM book_vectors bv
+		JOIN books b ON b.id = bv.book_id
+		WHERE b.is_active AND b.genre = ANY($1) AND bv.genre_vector IS NOT NULL
+		ORDER BY bv.popularity_score DESC
+		LIMIT $2`
+
+	rows, err := r.db.Query(ctx, query, genres, count)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get genre book vectors: %w", err)
+	}
+	defer rows.Close()
+
+	var vectors []*domain.BookVector
+	for rows.Next() {
+		vector, err := scanBookVector(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan book vector: %w", err)
+		}
+		vectors = append(vectors, vector)
+	}
+	return vectors, rows.Err()
+}
+
+// ListStaleBooks returns active books with chapters whose vectors are
+// missing, were embedded by another model, or were embedded from text that
+// has since changed, in ID order after afterID
+func (r *postgresBookVectorRepository) ListStaleBooks(ctx context.Context, model string, afterID int64, limit int) ([]int64, error) {
+	query := `
+		SELECT id FROM (
+			SELECT b.id, ` + bookContentHashSQL + ` AS content_hash
+			FROM books b
+			WHERE b.is_active AND b.id > $2
+		) b
+		LEFT JOIN book_vectors bv ON bv.book_id = b.id
+		WHERE b.content_hash IS NOT NULL
+			AND (bv.book_id IS NULL
+				OR bv.embedding_model IS DISTINCT FROM $1
+				OR bv.content_hash IS DISTINCT FROM b.content_hash)
+		ORDER BY b.id
+		LIMIT $3`
+
+	rows, err := r.db.Query(ctx, query, model, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stale book vectors: %w", err)
+	}
+	defer rows.Close()
+
+	var bookIDs []int64
+	for rows.Next() {
+		var bookID int64
+		if err := rows.Scan(&bookID); err != nil {
+			return nil, fmt.Errorf("failed to scan book ID: %w", err)
+		}
+		bookIDs = append(bookIDs, bookID)
+	}
+	return bookIDs, rows.Err()
+}
+
+// ListEmbeddableBooks returns active books with chapters in ID order after
+// afterID
+func (r *postgresBookVectorRepository) ListEmbeddableBooks(ctx context.Context, afterID int64, limit int) ([]int64, error) {
+	query := `
+		SELECT b.id FROM books b
+		WHERE b.is_active AND b.id > $1
+			AND EXISTS (SELECT 1 FROM chapters c WHERE c.book_id = b.id)
+		ORDER BY b.id
+		LIMIT $2`
+
+	rows, err := r.db.Query(ctx, query, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list embeddable books: %w", err)
+	}
+	defer rows.Close()
+
+	var bookIDs []int64
+	for rows.Next() {
+		var bookID int64
+		if err := rows.Scan(&bookID); err != nil {
+			return nil, fmt.Errorf("failed to scan book ID: %w", err)
+		}
+		bookIDs = append(bookIDs, bookID)
+	}
+	return bookIDs, rows.Err()
+}
+
+// CountEmbeddableBooks counts the books ListEmbeddableBooks returns
+func (r *postgresBookVectorRepository) CountEmbeddableBooks(ctx context.Context) (int, error) {
+	query := `
+		SELECT COUNT(*) FROM books b
+		WHERE b.is_active AND EXISTS (SELECT 1 FROM chapters c WHERE c.book_id = b.id)`
+
+	var count int
+	if err := r.db.QueryRow(ctx, query).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count embeddable books: %w", err)
+	}
+	return count, nil
+}
+
+func (r *postgresBookVectorRepository) GetLatestEmbedderSnapshot(ctx context.Context, family string) (*domain.EmbedderSnapshot, error) {
+	query := `
+		SELECT family, version, documents, statistics, created_at
+		FROM embedder_snapshots
+		WHERE family = $1
+		ORDER BY version DESC
+		LIMIT 1`
+
+	var snapshot domain.EmbedderSnapshot
+	err := r.db.QueryRow(ctx, query, family).Scan(
+		&snapshot.Family, &snapshot.Version, &snapshot.Documents, &snapshot.Statistics, &snapshot.CreatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get embedder snapshot: %w", err)
+	}
+	return &snapshot, nil
+}
+
+func (r *postgresBookVectorRepository) CreateEmbedderSnapshot(ctx context.Context, snapshot *domain.EmbedderSnapshot) error {
+	query := `
+		INSERT INTO embedder_snapshots (family, version, documents, statistics, created_at)
+		SELECT $1, COALESCE(MAX(version), 0) + 1, $2, $3, $4
+		FROM embedder_snapshots WHERE family = $1
+		RETURNING version`
+
+	err := r.db.QueryRow(ctx, query,
+		snapshot.Family, snapshot.Documents, snapshot.Statistics, snapshot.CreatedAt,
+	).Scan(&snapshot.Version)
+	if err != nil {
+		return fmt.Errorf("failed to store embedder snapshot: %w", err)
+	}
+	return nil
+}
+
+// bookVectorArgs returns the insert and update arguments of a book vector,
+// checking that every vector present has its column's dimensions
+func bookVectorArgs(vector *domain.BookVector) ([]interface{}, error) {
+	dims := []struct {
+		name   string
+		values []float64
+		size   int
+	}{
+		{"content", vector.ContentVector, domain.BookContentVectorDims},
+		{"genre", vector.GenreVector, domain.BookGenreVectorDims},
+		{"style", vector.StyleVector, domain.BookStyleVectorDims},
+	}
+	for _, d := range dims {
+		if len(d.values) > 0 && len(d.values) != d.size {
+			return nil, fmt.Errorf("%s vector has %d dimensions, want %d", d.name, len(d.values), d.size)
+		}
+	}
+
+	return []interface{}{
+		vector.BookID,
+		formatVector(vector.ContentVector),
+		formatVector(vector.GenreVector),
+		formatVector(vector.StyleVector),
+		vector.DifficultyScore,
+		vector.PopularityScore,
+		vector.QualityScore,
+		vector.NoveltyScore,
+		vector.EmbeddingModel,
+		vector.ContentHash,
+		vector.LastUpdated,
+	}, nil
+}
+
+func scanBookVector(row pgx.Row) (*domain.BookVector, error) {
+	var vector domain.BookVector
+	var content, genre, style, model, contentHash *string
+	err := row.Scan(
+		&vector.BookID, &content, &genre, &style,
+		&vector.DifficultyScore, &vector.PopularityScore, &vector.QualityScore, &vector.NoveltyScore,
+		&model, &contentHash, &vector.LastUpdated,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if vector.ContentVector, err = parseVector(content); err != nil {
+		return nil, err
+	}
+	if vector.GenreVector, err = parseVector(genre); err != nil {
+		return nil, err
+	}
+	if vector.StyleVector, err = parseVector(style); err != nil {
+		return nil, err
+	}
+	if model != nil {
+		vector.EmbeddingModel = *model
+	}
+	if contentHash != nil {
+		vector.ContentHash = *contentHash
+	}
+	return &vector, nil
+}
+
+// formatVector returns the pgvector text form of a vector, or nil for SQL
+// NULL when the vector is empty
+func formatVector(values []float64) *string {
+	if len(values) == 0 {
+		return nil
+	}
+
+	var b strings.Builder
+	b.WriteByte('[')
+	for i, v := range values {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		// pgvector stores single precision floats
+		b.WriteString(strconv.FormatFloat(v, 'g', -1, 32))
+	}
+	b.WriteByte(']')
+
+	text := b.String()
+	return &text
+}
+
+// parseVector parses the pgvector text form "[1,2,3]"
+func parseVector(text *string) ([]float64, error) {
+	if text == nil {
+		return nil, nil
+	}
+
+	body := strings.TrimSuffix(strings.TrimPrefix(*text, "["), "]")
+	if body == "" {
+		return []float64{}, nil
+	}
+
+	parts := strings.Split(body, ",")
+	values := make([]float64, len(parts))
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid vector component %q: %w", part, err)
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// prefixColumns qualifies every column of a comma separated list
+func prefixColumns(prefix, columns string) string {
+	parts := strings.Split(columns, ",")
+	for i, part := range parts {
+		parts[i] = prefix + strings.TrimSpace(part)
+	}
+	return strings.Join(parts, ", ")
+}
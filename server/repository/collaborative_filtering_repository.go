@@ -0,0 +1,230 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/ponyo877/roudoku/server/domain"
+)
+
+// CollaborativeFilteringRepository stores trained collaborative filtering
+// models and serves their scores
+type CollaborativeFilteringRepository interface {
+	// SaveModel records a trained model and replaces the factors and book
+	// similarities of the previous one in a single transaction
+	SaveModel(ctx context.Context, model *domain.CFModel, userFactors map[uuid.UUID][]float64, bookFactors map[int64][]float64, similarities []*domain.BookSimilarity) error
+	GetLatestModel(ctx context.Context) (*domain.CFModel, error)
+	// GetUserFactors returns the user's factors, or nil when the user was
+	// not part of the latest model
+	GetUserFactors(ctx context.Context, userID uuid.UUID) ([]float64, error)
+	// ScoreBooksByFactors returns the active books with the largest
+	// predicted preference for the user factors, best first
+	ScoreBooksByFactors(ctx context.Context, factors []float64, excludeBookIDs []int64, count int) ([]*domain.CFScore, error)
+	// GetBookNeighbours returns the most similar books of each book
+	GetBookNeighbours(ctx context.Context, bookIDs []int64, perBook int) ([]*domain.BookSimilarity, error)
+}
+
+// postgresCollaborativeFilteringRepository implements
+// CollaborativeFilteringRepository. Factors are pgvector vectors so the
+// best books for a user are found with the negative inner product <#>.
+type postgresCollaborativeFilteringRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewPostgresCollaborativeFilteringRepository creates a new collaborative filtering repository
+func NewPostgresCollaborativeFilteringRepository(db *pgxpool.Pool) CollaborativeFilteringRepository {
+	return &postgresCollaborativeFilteringRepository{db: db}
+}
+
+// SaveModel inserts factors and similarities with unnest so that each table
+// is written by one statement
+func (r *postgresCollaborativeFilteringRepository) SaveModel(
+	ctx context.Context,
+	model *domain.CFModel,
+	userFactors map[uuid.UUID][]float64,
+	bookFactors map[int64][]float64,
+	similarities []*domain.BookSimilarity,
+) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO cf_models (
+			id, factors, iterations, regularization, alpha, users, books, interactions, loss, trained_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		model.ID, model.Factors, model.Iterations, model.Regularization, model.Alpha,
+		model.Users, model.Books, model.Interactions, model.Loss, model.TrainedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store CF model: %w", err)
+	}
+
+	for _, table := range []string{"cf_user_factors", "cf_book_factors", "book_similarities"} {
+		if _, err := tx.Exec(ctx, "DELETE FROM "+table); err != nil {
+			return fmt.Errorf("failed to clear %s: %w", table, err)
+		}
+	}
+
+	userIDs := make([]uuid.UUID, 0, len(userFactors))
+	userVectors := make([]*string, 0, len(userFactors))
+	for userID, factors := range userFactors {
+		userIDs = append(userIDs, userID)
+		userVectors = append(userVectors, formatVector(factors))
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO cf_user_factors (user_id, model_id, factors)
+		SELECT t.user_id, $1, t.factors::vector
+		FROM unnest($2::uuid[], $3::text[]) AS t(user_id, factors)
+		JOIN users u ON u.id = t.user_id`,
+		model.ID, userIDs, userVectors)
+	if err != nil {
+		return fmt.Errorf("failed to store CF user factors: %w", err)
+	}
+
+	bookIDs := make([]int64, 0, len(bookFactors))
+	bookVectors := make([]*string, 0, len(bookFactors))
+	for bookID, factors := range bookFactors {
+		bookIDs = append(bookIDs, bookID)
+		bookVectors = append(bookVectors, formatVector(factors))
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO cf_book_factors (book_id, model_id, factors)
+		SELECT t.book_id, $1, t.factors::vector
+		FROM unnest($2::bigint[], $3::text[]) AS t(book_id, factors)
+		JOIN books b ON b.id = t.book_id`,
+		model.ID, bookIDs, bookVectors)
+	if err != nil {
+		return fmt.Errorf("failed to store CF book factors: %w", err)
+	}
+
+	books := make([]int64, len(similarities))
+	similarBooks := make([]int64, len(similarities))
+	scores := make([]float64, len(similarities))
+	commonUsers := make([]int32, len(similarities))
+	for i, similarity := range similarities {
+		books[i] = similarity.BookID
+		similarBooks[i] = similarity.SimilarBookID
+		scores[i] = similarity.Similarity
+		commonUsers[i] = int32(similarity.CommonUsers)
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO book_similarities (book_id, similar_book_id, similarity, common_users, calculated_at)
+		SELECT t.book_id, t.similar_book_id, t.similarity, t.common_users, $1
+		FROM unnest($2::bigint[], $3::bigint[], $4::float8[], $5::int[])
+			AS t(book_id, similar_book_id, similarity, common_users)
+		JOIN books a ON a.id = t.book_id
+		JOIN books b ON b.id = t.similar_book_id`,
+		model.TrainedAt, books, similarBooks, scores, commonUsers)
+	if err != nil {
+		return fmt.Errorf("failed to store book similarities: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// GetLatestModel returns the most recently trained model, or nil when no
+// model has been trained
+func (r *postgresCollaborativeFilteringRepository) GetLatestModel(ctx context.Context) (*domain.CFModel, error) {
+	query := `
+		SELECT id, factors, iterations, regularization, alpha, users, books, interactions, loss, trained_at
+		FROM cf_models
+		ORDER BY trained_at DESC
+		LIMIT 1`
+
+	var model domain.CFModel
+	err := r.db.QueryRow(ctx, query).Scan(
+		&model.ID, &model.Factors, &model.Iterations, &model.Regularization, &model.Alpha,
+		&model.Users, &model.Books, &model.Interactions, &model.Loss, &model.TrainedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get CF model: %w", err)
+	}
+	return &model, nil
+}
+
+func (r *postgresCollaborativeFilteringRepository) GetUserFactors(ctx context.Context, userID uuid.UUID) ([]float64, error) {
+	var text *string
+	err := r.db.QueryRow(ctx, `SELECT factors::text FROM cf_user_factors WHERE user_id = $1`, userID).Scan(&text)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get CF user factors: %w", err)
+	}
+	return parseVector(text)
+}
+
+// ScoreBooksByFactors orders by the negative inner product, so the first
+// rows have the largest predicted preference
+func (r *postgresCollaborativeFilteringRepository) ScoreBooksByFactors(ctx context.Context, factors []float64, excludeBookIDs []int64, count int) ([]*domain.CFScore, error) {
+	if excludeBookIDs == nil {
+		excludeBookIDs = []int64{}
+	}
+
+	query := `
+		SELECT f.book_id, -(f.factors <#> $1::vector) AS score
+		FROM cf_book_factors f
+		JOIN books b ON b.id = f.book_id
+		WHERE b.is_active AND NOT (f.book_id = ANY($2))
+		ORDER BY f.factors <#> $1::vector
+		LIMIT $3`
+
+	rows, err := r.db.Query(ctx, query, formatVector(factors), excludeBookIDs, count)
+	if err != nil {
+		return nil, fmt.Errorf("failed to score books by CF factors: %w", err)
+	}
+	defer rows.Close()
+
+	scores := []*domain.CFScore{}
+	for rows.Next() {
+		score := &domain.CFScore{Source: domain.CFSourceFactorization}
+		if err := rows.Scan(&score.BookID, &score.Score); err != nil {
+			return nil, err
+		}
+		scores = append(scores, score)
+	}
+	return scores, rows.Err()
+}
+
+func (r *postgresCollaborativeFilteringRepository) GetBookNeighbours(ctx context.Context, bookIDs []int64, perBook int) ([]*domain.BookSimilarity, error) {
+	query := `
+		SELECT book_id, similar_book_id, similarity, common_users, calculated_at
+		FROM (
+			SELECT s.*, ROW_NUMBER() OVER (PARTITION BY s.book_id ORDER BY s.similarity DESC) AS rank
+			FROM book_similarities s
+			WHERE s.book_id = ANY($1)
+		) ranked
+		WHERE rank <= $2
+		ORDER BY book_id, similarity DESC`
+
+	rows, err := r.db.Query(ctx, query, bookIDs, perBook)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get book neighbours: %w", err)
+	}
+	defer rows.Close()
+
+	var neighbours []*domain.BookSimilarity
+	for rows.Next() {
+		var similarity domain.BookSimilarity
+		err := rows.Scan(
+			&similarity.BookID, &similarity.SimilarBookID, &similarity.Similarity,
+			&similarity.CommonUsers, &similarity.CalculatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		neighbours = append(neighbours, &similarity)
+	}
+	return neighbours, rows.Err()
+}
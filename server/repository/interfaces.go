@@ -12,9 +12,13 @@ import (
 type BookRepository interface {
 	Create(ctx context.Context, book *domain.Book) error
 	GetByID(ctx context.Context, id int64) (*domain.Book, error)
+	// GetByIDs retrieves the active books among the IDs, in no particular order
+	GetByIDs(ctx context.Context, ids []int64) ([]*domain.Book, error)
 	Update(ctx context.Context, book *domain.Book) error
 	Delete(ctx context.Context, id int64) error
 	List(ctx context.Context, req *domain.BookSearchRequest) ([]*domain.Book, int, error)
+	// UpdateEmbedding stores the book's content embedding in books.embedding
+	UpdateEmbedding(ctx context.Context, bookID int64, embedding []float64) error
 
 	// Chapter operations
 	CreateChapter(ctx context.Context, chapter *domain.Chapter) error
@@ -41,6 +45,9 @@ type SwipeRepository interface {
 	Create(ctx context.Context, swipeLog *domain.SwipeLog) error
 	GetByUserID(ctx context.Context, userID uuid.UUID) ([]*domain.SwipeLog, error)
 	GetByQuoteID(ctx context.Context, quoteID uuid.UUID) ([]*domain.SwipeLog, error)
+	// GetBookAffinities returns the books whose quotes the user liked more
+	// than rejected, most liked first. Recent swipes count more.
+	GetBookAffinities(ctx context.Context, userID uuid.UUID, limit int) ([]*BookInteraction, error)
 }
 
 // SessionRepository defines the interface for reading session data operations
@@ -51,6 +58,7 @@ type SessionRepository interface {
 	Delete(ctx context.Context, id uuid.UUID) error
 	GetByUserID(ctx context.Context, userID uuid.UUID, limit int) ([]*domain.ReadingSession, error)
 	GetByBookID(ctx context.Context, bookID int64, limit int) ([]*domain.ReadingSession, error)
+	GetLatestByUserAndBook(ctx context.Context, userID uuid.UUID, bookID int64) (*domain.ReadingSession, error)
 }
 
 // RatingRepository defines the interface for rating data operations
@@ -0,0 +1,271 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/ponyo877/roudoku/server/domain"
+)
+
+// OfflineDownloadRepository defines the interface for offline download
+// package operations. The Active methods cover pending packages too, since
+// those hold a slot as well.
+type OfflineDownloadRepository interface {
+	// CreateWithinLimit records a package unless the user already holds
+	// limit packages, and reports whether it was recorded. The count and
+	// the insert run in one transaction under a lock on the user, so
+	// concurrent requests cannot both take the last slot. A negative limit
+	// is unlimited. It returns ErrDuplicateKey when the user already holds
+	// a package for the book.
+	CreateWithinLimit(ctx context.Context, download *domain.OfflineDownload, limit int) (bool, error)
+	Update(ctx context.Context, download *domain.OfflineDownload) error
+	// UpdatePending writes a package only while it is still pending, and
+	// reports whether it did, so a package revoked or deleted while it was
+	// built stays that way
+	UpdatePending(ctx context.Context, download *domain.OfflineDownload) (bool, error)
+	// ClaimPending locks the next pending package for building, or returns
+	// nil when none is waiting. Packages locked before lockedBefore are
+	// claimed again, since their worker stopped responding.
+	ClaimPending(ctx context.Context, lockedBefore time.Time) (*domain.OfflineDownload, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*domain.OfflineDownload, error)
+	GetActiveByBook(ctx context.Context, userID uuid.UUID, bookID int64) (*domain.OfflineDownload, error)
+	GetActiveByUserID(ctx context.Context, userID uuid.UUID) ([]*domain.OfflineDownload, error)
+	CountActiveByUserID(ctx context.Context, userID uuid.UUID) (int, error)
+	// GetUsersWithActiveDownloads returns the users holding active packages
+	GetUsersWithActiveDownloads(ctx context.Context) ([]uuid.UUID, error)
+	// ExtendActive moves the expiry of a user's active packages to expiresAt
+	ExtendActive(ctx context.Context, userID uuid.UUID, expiresAt time.Time) error
+}
+
+type postgresOfflineDownloadRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPostgresOfflineDownloadRepository(db *pgxpool.Pool) OfflineDownloadRepository {
+	return &postgresOfflineDownloadRepository{db: db}
+}
+
+const offlineDownloadColumns = `
+	id, user_id, book_id, status, package_path, package_size_bytes, manifest_sha256,
+	chapter_count, audio_chapter_count, skip_audio, attempts, locked_at, error_message,
+	expires_at, revoked_at, revoke_reason, created_at, updated_at`
+
+// heldOfflineDownload matches the packages that use a download slot
+const heldOfflineDownload = `status IN ('pending', 'active')`
+
+func scanOfflineDownload(row pgx.Row) (*domain.OfflineDownload, error) {
+	var download domain.OfflineDownload
+	err := row.Scan(
+		&download.ID, &download.UserID, &download.BookID, &download.Status,
+		&download.PackagePath, &download.PackageSizeBytes, &download.ManifestSHA256,
+		&download.ChapterCount, &download.AudioChapterCount, &download.SkipAudio,
+		&download.Attempts, &download.LockedAt, &download.ErrorMessage, &download.ExpiresAt,
+		&download.RevokedAt, &download.RevokeReason, &download.CreatedAt, &download.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &download, nil
+}
+
+func (r *postgresOfflineDownloadRepository) CreateWithinLimit(ctx context.Context, download *domain.OfflineDownload, limit int) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM users WHERE id = $1 FOR UPDATE`, download.UserID); err != nil {
+		return false, err
+	}
+
+	if limit >= 0 {
+		var held int
+		query := `SELECT COUNT(*) FROM offline_downloads WHERE user_id = $1 AND ` + heldOfflineDownload
+		if err := tx.QueryRow(ctx, query, download.UserID).Scan(&held); err != nil {
+			return false, err
+		}
+		if held >= limit {
+			return false, nil
+		}
+	}
+
+	query := `
+		INSERT INTO offline_downloads (` + offlineDownloadColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)`
+
+	_, err = tx.Exec(ctx, query,
+		download.ID, download.UserID, download.BookID, download.Status,
+		download.PackagePath, download.PackageSizeBytes, download.ManifestSHA256,
+		download.ChapterCount, download.AudioChapterCount, download.SkipAudio,
+		download.Attempts, download.LockedAt, download.ErrorMessage, download.ExpiresAt,
+		download.RevokedAt, download.RevokeReason, download.CreatedAt, download.UpdatedAt,
+	)
+	if err != nil {
+		return false, offlineDownloadWriteError(err)
+	}
+
+	return true, tx.Commit(ctx)
+}
+
+const updateOfflineDownload = `
+		UPDATE offline_downloads
+		SET status = $2, package_path = $3, package_size_bytes = $4, manifest_sha256 = $5,
+			chapter_count = $6, audio_chapter_count = $7, skip_audio = $8, attempts = $9,
+			locked_at = $10, error_message = $11, expires_at = $12,
+			revoked_at = $13, revoke_reason = $14, updated_at = $15
+		WHERE id = $1`
+
+func offlineDownloadUpdateArgs(download *domain.OfflineDownload) []interface{} {
+	return []interface{}{
+		download.ID, download.Status, download.PackagePath, download.PackageSizeBytes,
+		download.ManifestSHA256, download.ChapterCount, download.AudioChapterCount,
+		download.SkipAudio, download.Attempts, download.LockedAt, download.ErrorMessage,
+		download.ExpiresAt, download.RevokedAt, download.RevokeReason, download.UpdatedAt,
+	}
+}
+
+func (r *postgresOfflineDownloadRepository) Update(ctx context.Context, download *domain.OfflineDownload) error {
+	_, err := r.db.Exec(ctx, updateOfflineDownload, offlineDownloadUpdateArgs(download)...)
+	return offlineDownloadWriteError(err)
+}
+
+func (r *postgresOfflineDownloadRepository) UpdatePending(ctx context.Context, download *domain.OfflineDownload) (bool, error) {
+	tag, err := r.db.Exec(ctx, updateOfflineDownload+` AND status = 'pending'`, offlineDownloadUpdateArgs(download)...)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// ClaimPending uses FOR UPDATE SKIP LOCKED so concurrent workers never claim
+// the same package
+func (r *postgresOfflineDownloadRepository) ClaimPending(ctx context.Context, lockedBefore time.Time) (*domain.OfflineDownload, error) {
+	query := `
+		UPDATE offline_downloads
+		SET attempts = attempts + 1, locked_at = NOW(), updated_at = NOW()
+		WHERE id = (
+			SELECT id FROM offline_downloads
+			WHERE status = 'pending' AND (locked_at IS NULL OR locked_at < $1)
+			ORDER BY updated_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING` + offlineDownloadColumns
+
+	download, err := scanOfflineDownload(r.db.QueryRow(ctx, query, lockedBefore))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return download, nil
+}
+
+func (r *postgresOfflineDownloadRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.OfflineDownload, error) {
+	query := `SELECT` + offlineDownloadColumns + ` FROM offline_downloads WHERE id = $1`
+
+	download, err := scanOfflineDownload(r.db.QueryRow(ctx, query, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return download, nil
+}
+
+func (r *postgresOfflineDownloadRepository) GetActiveByBook(ctx context.Context, userID uuid.UUID, bookID int64) (*domain.OfflineDownload, error) {
+	query := `SELECT` + offlineDownloadColumns + `
+		FROM offline_downloads
+		WHERE user_id = $1 AND book_id = $2 AND ` + heldOfflineDownload
+
+	download, err := scanOfflineDownload(r.db.QueryRow(ctx, query, userID, bookID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return download, nil
+}
+
+func (r *postgresOfflineDownloadRepository) GetActiveByUserID(ctx context.Context, userID uuid.UUID) ([]*domain.OfflineDownload, error) {
+	query := `SELECT` + offlineDownloadColumns + `
+		FROM offline_downloads
+		WHERE user_id = $1 AND ` + heldOfflineDownload + `
+		ORDER BY created_at DESC`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var downloads []*domain.OfflineDownload
+	for rows.Next() {
+		download, err := scanOfflineDownload(rows)
+		if err != nil {
+			return nil, err
+		}
+		downloads = append(downloads, download)
+	}
+
+	return downloads, rows.Err()
+}
+
+func (r *postgresOfflineDownloadRepository) CountActiveByUserID(ctx context.Context, userID uuid.UUID) (int, error) {
+	query := `SELECT COUNT(*) FROM offline_downloads WHERE user_id = $1 AND ` + heldOfflineDownload
+
+	var count int
+	if err := r.db.QueryRow(ctx, query, userID).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *postgresOfflineDownloadRepository) GetUsersWithActiveDownloads(ctx context.Context) ([]uuid.UUID, error) {
+	query := `SELECT DISTINCT user_id FROM offline_downloads WHERE ` + heldOfflineDownload
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIDs []uuid.UUID
+	for rows.Next() {
+		var userID uuid.UUID
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+
+	return userIDs, rows.Err()
+}
+
+func (r *postgresOfflineDownloadRepository) ExtendActive(ctx context.Context, userID uuid.UUID, expiresAt time.Time) error {
+	query := `
+		UPDATE offline_downloads
+		SET expires_at = $2, updated_at = NOW()
+		WHERE user_id = $1 AND ` + heldOfflineDownload + ` AND expires_at < $2`
+
+	_, err := r.db.Exec(ctx, query, userID, expiresAt)
+	return err
+}
+
+func offlineDownloadWriteError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrDuplicateKey
+	}
+	return err
+}
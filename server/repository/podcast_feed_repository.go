@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/ponyo877/roudoku/server/domain"
+)
+
+// PodcastFeedRepository defines the interface for private podcast feed operations
+type PodcastFeedRepository interface {
+	// Rotate revokes the user's feed in use and stores feed in its place
+	Rotate(ctx context.Context, feed *domain.PodcastFeed) error
+	GetActiveByUserID(ctx context.Context, userID uuid.UUID) (*domain.PodcastFeed, error)
+	GetActiveByTokenHash(ctx context.Context, tokenHash string) (*domain.PodcastFeed, error)
+	// Revoke revokes the user's feed in use and reports whether there was one
+	Revoke(ctx context.Context, userID uuid.UUID, revokedAt time.Time) (bool, error)
+	TouchAccess(ctx context.Context, id uuid.UUID, accessedAt time.Time) error
+}
+
+type postgresPodcastFeedRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPostgresPodcastFeedRepository(db *pgxpool.Pool) PodcastFeedRepository {
+	return &postgresPodcastFeedRepository{db: db}
+}
+
+const podcastFeedColumns = `
+	id, user_id, token_hash, last_accessed_at, revoked_at, created_at`
+
+func scanPodcastFeed(row pgx.Row) (*domain.PodcastFeed, error) {
+	var feed domain.PodcastFeed
+	err := row.Scan(
+		&feed.ID, &feed.UserID, &feed.TokenHash, &feed.LastAccessedAt,
+		&feed.RevokedAt, &feed.CreatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &feed, nil
+}
+
+func (r *postgresPodcastFeedRepository) Rotate(ctx context.Context, feed *domain.PodcastFeed) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		UPDATE podcast_feeds SET revoked_at = $2
+		WHERE user_id = $1 AND revoked_at IS NULL`,
+		feed.UserID, feed.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO podcast_feeds (`+podcastFeedColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		feed.ID, feed.UserID, feed.TokenHash, feed.LastAccessedAt,
+		feed.RevokedAt, feed.CreatedAt,
+	)
+	if err != nil {
+		return podcastFeedWriteError(err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *postgresPodcastFeedRepository) GetActiveByUserID(ctx context.Context, userID uuid.UUID) (*domain.PodcastFeed, error) {
+	query := `SELECT` + podcastFeedColumns + `
+		FROM podcast_feeds
+		WHERE user_id = $1 AND revoked_at IS NULL`
+	return scanPodcastFeed(r.db.QueryRow(ctx, query, userID))
+}
+
+func (r *postgresPodcastFeedRepository) GetActiveByTokenHash(ctx context.Context, tokenHash string) (*domain.PodcastFeed, error) {
+	query := `SELECT` + podcastFeedColumns + `
+		FROM podcast_feeds
+		WHERE token_hash = $1 AND revoked_at IS NULL`
+	return scanPodcastFeed(r.db.QueryRow(ctx, query, tokenHash))
+}
+
+func (r *postgresPodcastFeedRepository) Revoke(ctx context.Context, userID uuid.UUID, revokedAt time.Time) (bool, error) {
+	query := `
+		UPDATE podcast_feeds SET revoked_at = $2
+		WHERE user_id = $1 AND revoked_at IS NULL`
+
+	tag, err := r.db.Exec(ctx, query, userID, revokedAt)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *postgresPodcastFeedRepository) TouchAccess(ctx context.Context, id uuid.UUID, accessedAt time.Time) error {
+	query := `UPDATE podcast_feeds SET last_accessed_at = $2 WHERE id = $1`
+	_, err := r.db.Exec(ctx, query, id, accessedAt)
+	return err
+}
+
+func podcastFeedWriteError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrDuplicateKey
+	}
+	return err
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -73,6 +74,47 @@ func (r *postgresBookRepository) GetByID(ctx context.Context, id int64) (*domain
 	return r.bookMapper.EntityToDomain(entity), nil
 }
 
+// GetByIDs retrieves the active books among the IDs
+func (r *postgresBookRepository) GetByIDs(ctx context.Context, ids []int64) ([]*domain.Book, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	query := `
+		SELECT id, title, author, epoch, word_count, content_url, summary, genre,
+			difficulty_level, estimated_reading_minutes, download_count, rating_average, rating_count,
+			is_premium, is_active, created_at, updated_at
+		FROM books
+		WHERE id = ANY($1) AND is_active = true
+	`
+
+	rows, err := r.GetConnection().Query(ctx, query, ids)
+	if err != nil {
+		return nil, r.HandleError(err, "get books by IDs")
+	}
+	defer rows.Close()
+
+	var entities []*ent.BookEntity
+	for rows.Next() {
+		entity := &ent.BookEntity{}
+		err := rows.Scan(
+			&entity.ID, &entity.Title, &entity.Author, &entity.Epoch, &entity.WordCount,
+			&entity.ContentURL, &entity.Summary, &entity.Genre, &entity.DifficultyLevel,
+			&entity.EstimatedReadingMinutes, &entity.DownloadCount, &entity.RatingAverage,
+			&entity.RatingCount, &entity.IsPremium, &entity.IsActive, &entity.CreatedAt, &entity.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan book: %w", err)
+		}
+		entities = append(entities, entity)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, r.HandleError(err, "get books by IDs")
+	}
+
+	return r.bookMapper.EntityToDomainSlice(entities), nil
+}
+
 // List retrieves books based on search criteria
 func (r *postgresBookRepository) List(ctx context.Context, req *domain.BookSearchRequest) ([]*domain.Book, int, error) {
 	var conditions []string
@@ -228,6 +270,23 @@ func (r *postgresBookRepository) Update(ctx context.Context, book *domain.Book)
 	return nil
 }
 
+// UpdateEmbedding stores the book's content embedding as a JSON array
+func (r *postgresBookRepository) UpdateEmbedding(ctx context.Context, bookID int64, embedding []float64) error {
+	data, err := json.Marshal(embedding)
+	if err != nil {
+		return fmt.Errorf("failed to encode book embedding: %w", err)
+	}
+
+	query := `UPDATE books SET embedding = $2 WHERE id = $1`
+
+	_, err = r.db.Exec(ctx, query, bookID, string(data))
+	if err != nil {
+		return fmt.Errorf("failed to update book embedding: %w", err)
+	}
+
+	return nil
+}
+
 // Delete soft deletes a book by setting is_active to false
 func (r *postgresBookRepository) Delete(ctx context.Context, id int64) error {
 	query := `UPDATE books SET is_active = false, updated_at = NOW() WHERE id = $1`
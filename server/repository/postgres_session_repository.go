@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/ponyo877/roudoku/server/domain"
@@ -164,4 +165,31 @@ func (r *postgresSessionRepository) GetByBookID(ctx context.Context, bookID int6
 
 	sessions := r.mapper.EntityToDomainSlice(entities)
 	return sessions, nil
-}
\ No newline at end of file
+}
+
+// GetLatestByUserAndBook retrieves the user's most recently updated reading
+// session of a book
+func (r *postgresSessionRepository) GetLatestByUserAndBook(ctx context.Context, userID uuid.UUID, bookID int64) (*domain.ReadingSession, error) {
+	entity := new(ent.ReadingSessionEntity)
+
+	query := `
+		SELECT id, user_id, book_id, start_pos, current_pos, duration_sec, mood, weather, created_at, updated_at
+		FROM reading_sessions
+		WHERE user_id = $1 AND book_id = $2
+		ORDER BY updated_at DESC
+		LIMIT 1`
+
+	err := r.db.QueryRow(ctx, query, userID, bookID).Scan(
+		&entity.ID, &entity.UserID, &entity.BookID, &entity.StartPos,
+		&entity.CurrentPos, &entity.DurationSec, &entity.Mood, &entity.Weather,
+		&entity.CreatedAt, &entity.UpdatedAt)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get latest reading session: %w", err)
+	}
+
+	return r.mapper.EntityToDomain(entity), nil
+}
@@ -108,4 +108,43 @@ func (r *postgresSwipeRepository) GetByQuoteID(ctx context.Context, quoteID uuid
 
 	swipeLogs := r.mapper.EntityToDomainSlice(entities)
 	return swipeLogs, nil
-}
\ No newline at end of file
+}
+// swipeAffinityHalfLifeDays is how many days it takes a swipe to count half
+const swipeAffinityHalfLifeDays = 30
+
+// GetBookAffinities scores each book by its liked quotes minus its rejected
+// ones, weighting every swipe by its age
+func (r *postgresSwipeRepository) GetBookAffinities(ctx context.Context, userID uuid.UUID, limit int) ([]*BookInteraction, error) {
+	query := `
+		SELECT book_id, SUM(sign * weight) AS score, COUNT(*) AS swipes
+		FROM (
+			SELECT q.book_id,
+				CASE s.choice WHEN 1 THEN 1.0 ELSE -1.0 END AS sign,
+				POWER(0.5, EXTRACT(EPOCH FROM NOW() - s.created_at) / 86400 / $3) AS weight
+			FROM swipe_logs s
+			JOIN quotes q ON q.id = s.quote_id
+			JOIN books b ON b.id = q.book_id
+			WHERE s.user_id = $1 AND b.is_active
+		) weighted
+		GROUP BY book_id
+		HAVING SUM(sign * weight) > 0
+		ORDER BY score DESC, book_id
+		LIMIT $2`
+
+	rows, err := r.db.Query(ctx, query, userID, limit, swipeAffinityHalfLifeDays)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get swipe book affinities: %w", err)
+	}
+	defer rows.Close()
+
+	var affinities []*BookInteraction
+	for rows.Next() {
+		affinity := new(BookInteraction)
+		if err := rows.Scan(&affinity.BookID, &affinity.Score, &affinity.Interactions); err != nil {
+			return nil, fmt.Errorf("failed to scan swipe affinity row: %w", err)
+		}
+		affinities = append(affinities, affinity)
+	}
+
+	return affinities, rows.Err()
+}
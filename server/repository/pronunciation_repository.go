@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/ponyo877/roudoku/server/domain"
+)
+
+// PronunciationRepository defines the interface for pronunciation lexicon operations
+type PronunciationRepository interface {
+	// Create and Update return ErrDuplicateKey when the surface form already
+	// has a reading in the same scope
+	Create(ctx context.Context, entry *domain.PronunciationEntry) error
+	GetByID(ctx context.Context, id uuid.UUID) (*domain.PronunciationEntry, error)
+	Update(ctx context.Context, entry *domain.PronunciationEntry) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	List(ctx context.Context, filter *domain.PronunciationFilter) ([]*domain.PronunciationEntry, int, error)
+	// ListApplicable returns the global entries plus those of the given
+	// author and book, ordered from the most general scope to the most specific
+	ListApplicable(ctx context.Context, bookID *int64, author string) ([]*domain.PronunciationEntry, error)
+}
+
+type postgresPronunciationRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPostgresPronunciationRepository(db *pgxpool.Pool) PronunciationRepository {
+	return &postgresPronunciationRepository{db: db}
+}
+
+const pronunciationColumns = `
+	id, scope, book_id, author, surface, reading, note, created_at, updated_at`
+
+func scanPronunciationEntry(row pgx.Row) (*domain.PronunciationEntry, error) {
+	var entry domain.PronunciationEntry
+	err := row.Scan(
+		&entry.ID, &entry.Scope, &entry.BookID, &entry.Author, &entry.Surface,
+		&entry.Reading, &entry.Note, &entry.CreatedAt, &entry.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (r *postgresPronunciationRepository) Create(ctx context.Context, entry *domain.PronunciationEntry) error {
+	query := `
+		INSERT INTO pronunciation_entries (id, scope, book_id, author, surface, reading, note, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+
+	_, err := r.db.Exec(ctx, query,
+		entry.ID, entry.Scope, entry.BookID, entry.Author, entry.Surface,
+		entry.Reading, entry.Note, entry.CreatedAt, entry.UpdatedAt,
+	)
+	return pronunciationWriteError(err)
+}
+
+func (r *postgresPronunciationRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.PronunciationEntry, error) {
+	query := `SELECT` + pronunciationColumns + ` FROM pronunciation_entries WHERE id = $1`
+
+	entry, err := scanPronunciationEntry(r.db.QueryRow(ctx, query, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (r *postgresPronunciationRepository) Update(ctx context.Context, entry *domain.PronunciationEntry) error {
+	query := `
+		UPDATE pronunciation_entries
+		SET scope = $2, book_id = $3, author = $4, surface = $5, reading = $6, note = $7, updated_at = $8
+		WHERE id = $1`
+
+	_, err := r.db.Exec(ctx, query,
+		entry.ID, entry.Scope, entry.BookID, entry.Author, entry.Surface,
+		entry.Reading, entry.Note, entry.UpdatedAt,
+	)
+	return pronunciationWriteError(err)
+}
+
+func (r *postgresPronunciationRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	query := `DELETE FROM pronunciation_entries WHERE id = $1`
+	_, err := r.db.Exec(ctx, query, id)
+	return err
+}
+
+func (r *postgresPronunciationRepository) List(ctx context.Context, filter *domain.PronunciationFilter) ([]*domain.PronunciationEntry, int, error) {
+	var conditions []string
+	var args []interface{}
+	argIndex := 1
+
+	if filter.Scope != "" {
+		conditions = append(conditions, fmt.Sprintf("scope = $%d", argIndex))
+		args = append(args, filter.Scope)
+		argIndex++
+	}
+	if filter.BookID != nil {
+		conditions = append(conditions, fmt.Sprintf("book_id = $%d", argIndex))
+		args = append(args, *filter.BookID)
+		argIndex++
+	}
+	if filter.Author != "" {
+		conditions = append(conditions, fmt.Sprintf("author = $%d", argIndex))
+		args = append(args, filter.Author)
+		argIndex++
+	}
+	if filter.Surface != "" {
+		conditions = append(conditions, fmt.Sprintf("(surface ILIKE $%d OR reading ILIKE $%d)", argIndex, argIndex))
+		args = append(args, "%"+filter.Surface+"%")
+		argIndex++
+	}
+
+	whereClause := ""
+	if len(conditions) > 0 {
+		whereClause = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	if err := r.db.QueryRow(ctx, "SELECT COUNT(*) FROM pronunciation_entries"+whereClause, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count pronunciation entries: %w", err)
+	}
+
+	query := `SELECT` + pronunciationColumns + ` FROM pronunciation_entries` + whereClause +
+		fmt.Sprintf(" ORDER BY surface, scope LIMIT $%d OFFSET $%d", argIndex, argIndex+1)
+	args = append(args, filter.Limit, filter.Offset)
+
+	entries, err := r.list(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return entries, total, nil
+}
+
+func (r *postgresPronunciationRepository) ListApplicable(ctx context.Context, bookID *int64, author string) ([]*domain.PronunciationEntry, error) {
+	query := `SELECT` + pronunciationColumns + `
+		FROM pronunciation_entries
+		WHERE scope = 'global'
+			OR (scope = 'author' AND author = $1)
+			OR (scope = 'book' AND book_id = $2)
+		ORDER BY CASE scope WHEN 'global' THEN 0 WHEN 'author' THEN 1 ELSE 2 END, surface`
+
+	return r.list(ctx, query, author, bookID)
+}
+
+func (r *postgresPronunciationRepository) list(ctx context.Context, query string, args ...interface{}) ([]*domain.PronunciationEntry, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pronunciation entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*domain.PronunciationEntry
+	for rows.Next() {
+		entry, err := scanPronunciationEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan pronunciation entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// pronunciationWriteError reports unique surface form violations as ErrDuplicateKey
+func pronunciationWriteError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrDuplicateKey
+	}
+	return err
+}
@@ -26,9 +26,22 @@ type UserPreferencesRepository interface {
 type BookVectorRepository interface {
 	Create(ctx context.Context, vector *domain.BookVector) error
 	GetByBookID(ctx context.Context, bookID int64) (*domain.BookVector, error)
-	GetSimilarBooks(ctx context.Context, bookID int64, count int, similarityType string) ([]*SimilarBook, error)
+	// GetSimilarBooks returns the nearest neighbours of a book by cosine
+	// similarity of its vectors, most similar first
+	GetSimilarBooks(ctx context.Context, query SimilarBooksQuery) ([]*SimilarBook, error)
 	UpdateVector(ctx context.Context, vector *domain.BookVector) error
 	GetBooksForGenreVector(ctx context.Context, genres []string, count int) ([]*domain.BookVector, error)
+	// ListStaleBooks returns books whose vectors need to be (re)computed by
+	// the embedding model
+	ListStaleBooks(ctx context.Context, model string, afterID int64, limit int) ([]int64, error)
+	// ListEmbeddableBooks returns the books an embedder is fitted to
+	ListEmbeddableBooks(ctx context.Context, afterID int64, limit int) ([]int64, error)
+	CountEmbeddableBooks(ctx context.Context) (int, error)
+	// GetLatestEmbedderSnapshot returns the newest snapshot of a family, or nil
+	GetLatestEmbedderSnapshot(ctx context.Context, family string) (*domain.EmbedderSnapshot, error)
+	// CreateEmbedderSnapshot stores a snapshot as the next version of its
+	// family and sets its Version
+	CreateEmbedderSnapshot(ctx context.Context, snapshot *domain.EmbedderSnapshot) error
 }
 
 type UserInteractionRepository interface {
@@ -38,6 +51,7 @@ type UserInteractionRepository interface {
 	Update(ctx context.Context, interaction *domain.UserInteraction) error
 	GetUserBookMatrix(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID]map[int64]float64, error)
 	GetMostInteractedBooks(ctx context.Context, userID uuid.UUID, count int) ([]*BookInteraction, error)
+	GetBookIDsByTypes(ctx context.Context, userID uuid.UUID, interactionTypes []string, limit int) ([]int64, error)
 }
 
 type UserSimilarityRepository interface {
@@ -49,12 +63,25 @@ type UserSimilarityRepository interface {
 }
 
 type RecommendationCacheRepository interface {
-	Create(ctx context.Context, cache *domain.RecommendationCache) error
-	GetByUserIDAndType(ctx context.Context, userID uuid.UUID, recType string) (*domain.RecommendationCache, error)
+	// Generation returns the latest invalidation generation covering the
+	// user's cached recommendations
+	Generation(ctx context.Context, userID uuid.UUID) (int64, error)
+	// Create stores the entry, replacing any entry with the same user, type
+	// and parameter hash, unless the user's cache was invalidated after
+	// generation. It reports whether the entry was stored.
+	Create(ctx context.Context, cache *domain.RecommendationCache, generation int64) (bool, error)
+	// Get returns the unexpired entry for the key, or nil
+	Get(ctx context.Context, userID uuid.UUID, recType, paramsHash string) (*domain.RecommendationCache, error)
 	Update(ctx context.Context, cache *domain.RecommendationCache) error
 	Delete(ctx context.Context, id uuid.UUID) error
-	DeleteExpired(ctx context.Context) error
+	DeleteExpired(ctx context.Context) (int64, error)
 	InvalidateUserCache(ctx context.Context, userID uuid.UUID) error
+	// InvalidateAll deletes the cached recommendations of every user
+	InvalidateAll(ctx context.Context) error
+	// ListenInvalidations calls handle for every invalidation, including
+	// those made by database triggers and other processes, until ctx is
+	// cancelled or the connection fails. ready is called once listening.
+	ListenInvalidations(ctx context.Context, ready func(), handle func(*domain.RecommendationCacheInvalidation)) error
 }
 
 type RecommendationFeedbackRepository interface {
@@ -98,6 +125,23 @@ type SimilarBook struct {
 	Book       *domain.Book `json:"book,omitempty"`
 }
 
+// SimilarBooksQuery selects the vectors compared by a similar books search
+// and filters its results
+type SimilarBooksQuery struct {
+	BookID int64
+	Count  int
+	// SimilarityType is content, genre or style to compare one vector, or
+	// hybrid for a weighted blend of all three
+	SimilarityType string
+	// IncludePremium keeps premium books in the results
+	IncludePremium bool
+	// ReaderID excludes the books the user has completed, and with
+	// ExcludeAbandoned also those they gave up on
+	ReaderID         *uuid.UUID
+	ExcludeAbandoned bool
+	ExcludeBookIDs   []int64
+}
+
 type BookInteraction struct {
 	BookID      int64   `json:"book_id"`
 	Score       float64 `json:"score"`
@@ -310,6 +354,69 @@ func (r *postgresUserInteractionRepository) GetMostInteractedBooks(ctx context.C
 	return interactions, rows.Err()
 }
 
+// GetBookIDsByTypes returns the books the user has interacted with in any of
+// the given ways, most recent first
+func (r *postgresUserInteractionRepository) GetBookIDsByTypes(ctx context.Context, userID uuid.UUID, interactionTypes []string, limit int) ([]int64, error) {
+	query := `
+		SELECT book_id
+		FROM user_interactions
+		WHERE user_id = $1 AND interaction_type = ANY($2)
+		GROUP BY book_id
+		ORDER BY MAX(created_at) DESC
+		LIMIT $3`
+
+	rows, err := r.db.Query(ctx, query, userID, interactionTypes, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var bookIDs []int64
+	for rows.Next() {
+		var bookID int64
+		if err := rows.Scan(&bookID); err != nil {
+			return nil, err
+		}
+		bookIDs = append(bookIDs, bookID)
+	}
+
+	return bookIDs, rows.Err()
+}
+
+// GetUserBookMatrix returns the implicit preference of each user for each
+// book they interacted with, the strongest of their interactions with it.
+// A nil userIDs returns the matrix of all users.
+func (r *postgresUserInteractionRepository) GetUserBookMatrix(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID]map[int64]float64, error) {
+	query := `
+		SELECT ui.user_id, ui.book_id, MAX(ui.implicit_score)::float8
+		FROM user_interactions ui
+		JOIN books b ON b.id = ui.book_id AND b.is_active
+		WHERE ui.implicit_score > 0 AND ($1::uuid[] IS NULL OR ui.user_id = ANY($1))
+		GROUP BY ui.user_id, ui.book_id`
+
+	rows, err := r.db.Query(ctx, query, userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user book matrix: %w", err)
+	}
+	defer rows.Close()
+
+	matrix := make(map[uuid.UUID]map[int64]float64)
+	for rows.Next() {
+		var userID uuid.UUID
+		var bookID int64
+		var score float64
+		if err := rows.Scan(&userID, &bookID, &score); err != nil {
+			return nil, err
+		}
+		if matrix[userID] == nil {
+			matrix[userID] = make(map[int64]float64)
+		}
+		matrix[userID][bookID] = score
+	}
+
+	return matrix, rows.Err()
+}
+
 // Subscription Plan Repository
 type postgresSubscriptionPlanRepository struct {
 	db *pgxpool.Pool
@@ -323,6 +430,7 @@ func (r *postgresSubscriptionPlanRepository) GetAll(ctx context.Context) ([]*dom
 	query := `
 		SELECT id, name, description, price_monthly, price_yearly, features,
 			   max_premium_books, max_tts_minutes_per_day, max_offline_downloads,
+			   max_audio_storage_mb,
 			   has_advanced_analytics, has_ai_recommendations, has_priority_support,
 			   is_active, sort_order, created_at, updated_at
 		FROM subscription_plans
@@ -342,7 +450,7 @@ func (r *postgresSubscriptionPlanRepository) GetAll(ctx context.Context) ([]*dom
 			&plan.ID, &plan.Name, &plan.Description, &plan.PriceMonthly,
 			&plan.PriceYearly, &featuresJSON, &plan.MaxPremiumBooks,
 			&plan.MaxTTSMinutesPerDay, &plan.MaxOfflineDownloads,
-			&plan.HasAdvancedAnalytics, &plan.HasAIRecommendations,
+			&plan.MaxAudioStorageMB, &plan.HasAdvancedAnalytics, &plan.HasAIRecommendations,
 			&plan.HasPrioritySupport, &plan.IsActive, &plan.SortOrder,
 			&plan.CreatedAt, &plan.UpdatedAt,
 		)
@@ -367,6 +475,7 @@ func (r *postgresSubscriptionPlanRepository) GetByID(ctx context.Context, id uui
 	query := `
 		SELECT id, name, description, price_monthly, price_yearly, features,
 			   max_premium_books, max_tts_minutes_per_day, max_offline_downloads,
+			   max_audio_storage_mb,
 			   has_advanced_analytics, has_ai_recommendations, has_priority_support,
 			   is_active, sort_order, created_at, updated_at
 		FROM subscription_plans WHERE id = $1`
@@ -377,7 +486,7 @@ func (r *postgresSubscriptionPlanRepository) GetByID(ctx context.Context, id uui
 		&plan.ID, &plan.Name, &plan.Description, &plan.PriceMonthly,
 		&plan.PriceYearly, &featuresJSON, &plan.MaxPremiumBooks,
 		&plan.MaxTTSMinutesPerDay, &plan.MaxOfflineDownloads,
-		&plan.HasAdvancedAnalytics, &plan.HasAIRecommendations,
+		&plan.MaxAudioStorageMB, &plan.HasAdvancedAnalytics, &plan.HasAIRecommendations,
 		&plan.HasPrioritySupport, &plan.IsActive, &plan.SortOrder,
 		&plan.CreatedAt, &plan.UpdatedAt,
 	)
@@ -403,6 +512,7 @@ func (r *postgresSubscriptionPlanRepository) GetActive(ctx context.Context) ([]*
 	query := `
 		SELECT id, name, description, price_monthly, price_yearly, features,
 			   max_premium_books, max_tts_minutes_per_day, max_offline_downloads,
+			   max_audio_storage_mb,
 			   has_advanced_analytics, has_ai_recommendations, has_priority_support,
 			   is_active, sort_order, created_at, updated_at
 		FROM subscription_plans
@@ -423,7 +533,7 @@ func (r *postgresSubscriptionPlanRepository) GetActive(ctx context.Context) ([]*
 			&plan.ID, &plan.Name, &plan.Description, &plan.PriceMonthly,
 			&plan.PriceYearly, &featuresJSON, &plan.MaxPremiumBooks,
 			&plan.MaxTTSMinutesPerDay, &plan.MaxOfflineDownloads,
-			&plan.HasAdvancedAnalytics, &plan.HasAIRecommendations,
+			&plan.MaxAudioStorageMB, &plan.HasAdvancedAnalytics, &plan.HasAIRecommendations,
 			&plan.HasPrioritySupport, &plan.IsActive, &plan.SortOrder,
 			&plan.CreatedAt, &plan.UpdatedAt,
 		)
@@ -482,7 +592,7 @@ func (r *postgresUserSubscriptionRepository) GetByUserID(ctx context.Context, us
 			   us.created_at, us.updated_at,
 			   sp.id, sp.name, sp.description, sp.price_monthly, sp.price_yearly,
 			   sp.features, sp.max_premium_books, sp.max_tts_minutes_per_day,
-			   sp.max_offline_downloads, sp.has_advanced_analytics,
+			   sp.max_offline_downloads, sp.max_audio_storage_mb, sp.has_advanced_analytics,
 			   sp.has_ai_recommendations, sp.has_priority_support
 		FROM user_subscriptions us
 		LEFT JOIN subscription_plans sp ON us.plan_id = sp.id
@@ -504,7 +614,7 @@ func (r *postgresUserSubscriptionRepository) GetByUserID(ctx context.Context, us
 		&plan.ID, &plan.Name, &plan.Description, &plan.PriceMonthly,
 		&plan.PriceYearly, &featuresJSON, &plan.MaxPremiumBooks,
 		&plan.MaxTTSMinutesPerDay, &plan.MaxOfflineDownloads,
-		&plan.HasAdvancedAnalytics, &plan.HasAIRecommendations, &plan.HasPrioritySupport,
+		&plan.MaxAudioStorageMB, &plan.HasAdvancedAnalytics, &plan.HasAIRecommendations, &plan.HasPrioritySupport,
 	)
 
 	if err != nil {
@@ -535,10 +645,6 @@ func (r *postgresUserInteractionRepository) Update(ctx context.Context, interact
 	return nil
 }
 
-func (r *postgresUserInteractionRepository) GetUserBookMatrix(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID]map[int64]float64, error) {
-	return nil, nil
-}
-
 func (r *postgresSubscriptionPlanRepository) Create(ctx context.Context, plan *domain.SubscriptionPlan) error {
 	return nil
 }
@@ -563,39 +669,15 @@ func (r *postgresUserSubscriptionRepository) GetByStatus(ctx context.Context, st
 	return nil, nil
 }
 
-// Stub implementations for remaining recommendation repositories
-
-// Book Vector Repository
-type postgresBookVectorRepository struct {
-	db *pgxpool.Pool
-}
-
-func NewPostgresBookVectorRepository(db *pgxpool.Pool) BookVectorRepository {
-	return &postgresBookVectorRepository{db: db}
-}
-
-func (r *postgresBookVectorRepository) Create(ctx context.Context, vector *domain.BookVector) error {
-	return nil
-}
-
-func (r *postgresBookVectorRepository) GetByBookID(ctx context.Context, bookID int64) (*domain.BookVector, error) {
-	return nil, nil
-}
-
-func (r *postgresBookVectorRepository) GetSimilarBooks(ctx context.Context, bookID int64, count int, similarityType string) ([]*SimilarBook, error) {
-	// Stub implementation - return some mock similar books
-	return []*SimilarBook{}, nil
-}
-
-func (r *postgresBookVectorRepository) UpdateVector(ctx context.Context, vector *domain.BookVector) error {
-	return nil
-}
+// User Similarity Repository
 
-func (r *postgresBookVectorRepository) GetBooksForGenreVector(ctx context.Context, genres []string, count int) ([]*domain.BookVector, error) {
-	return nil, nil
-}
+// Users must share this many books to be compared, and each user keeps
+// this many of their most similar users
+const (
+	minCommonBooksForSimilarity = 2
+	maxStoredSimilarUsers       = 50
+)
 
-// User Similarity Repository
 type postgresUserSimilarityRepository struct {
 	db *pgxpool.Pool
 }
@@ -605,23 +687,169 @@ func NewPostgresUserSimilarityRepository(db *pgxpool.Pool) UserSimilarityReposit
 }
 
 func (r *postgresUserSimilarityRepository) Create(ctx context.Context, similarity *domain.UserSimilarity) error {
-	return nil
+	query := `
+		INSERT INTO user_similarities (
+			user_a_id, user_b_id, similarity_score, similarity_type, common_books_count, last_calculated
+		) VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_a_id, user_b_id, similarity_type) DO UPDATE SET
+			similarity_score = EXCLUDED.similarity_score,
+			common_books_count = EXCLUDED.common_books_count,
+			last_calculated = EXCLUDED.last_calculated`
+
+	_, err := r.db.Exec(ctx, query,
+		similarity.UserAID, similarity.UserBID, similarity.SimilarityScore,
+		similarity.SimilarityType, similarity.CommonBooksCount, similarity.LastCalculated,
+	)
+	return err
 }
 
+// GetSimilarUsers returns the users most similar to the user, who is
+// UserAID of every result
 func (r *postgresUserSimilarityRepository) GetSimilarUsers(ctx context.Context, userID uuid.UUID, count int) ([]*domain.UserSimilarity, error) {
-	return []*domain.UserSimilarity{}, nil
+	query := `
+		SELECT user_a_id, user_b_id, similarity_score, similarity_type, common_books_count, last_calculated
+		FROM user_similarities
+		WHERE user_a_id = $1
+		ORDER BY similarity_score DESC
+		LIMIT $2`
+
+	rows, err := r.db.Query(ctx, query, userID, count)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanUserSimilarities(rows)
 }
 
 func (r *postgresUserSimilarityRepository) UpdateSimilarity(ctx context.Context, similarity *domain.UserSimilarity) error {
-	return nil
+	return r.Create(ctx, similarity)
 }
 
+// CalculateAndStoreSimilarities replaces the user's cosine similarities
+// with those of the users who share enough books with them, computed over
+// implicit preferences. Similarities are stored in both directions.
 func (r *postgresUserSimilarityRepository) CalculateAndStoreSimilarities(ctx context.Context, userID uuid.UUID) error {
-	return nil
+	query := `
+		WITH prefs AS (
+			SELECT user_id, book_id, MAX(implicit_score)::float8 AS score
+			FROM user_interactions
+			WHERE implicit_score > 0
+			GROUP BY user_id, book_id
+		),
+		mine AS (
+			SELECT book_id, score FROM prefs WHERE user_id = $1
+		),
+		common AS (
+			SELECT p.user_id, SUM(p.score * m.score) AS dot, COUNT(*) AS common_books
+			FROM prefs p
+			JOIN mine m ON m.book_id = p.book_id
+			WHERE p.user_id <> $1
+			GROUP BY p.user_id
+			HAVING COUNT(*) >= $2
+		),
+		norms AS (
+			SELECT user_id, sqrt(SUM(score * score)) AS norm
+			FROM prefs
+			WHERE user_id = $1 OR user_id IN (SELECT user_id FROM common)
+			GROUP BY user_id
+		)
+		SELECT c.user_id, c.dot / (n.norm * mine_norm.norm) AS similarity, c.common_books
+		FROM common c
+		JOIN norms n ON n.user_id = c.user_id
+		CROSS JOIN (SELECT norm FROM norms WHERE user_id = $1) mine_norm
+		ORDER BY similarity DESC
+		LIMIT $3`
+
+	rows, err := r.db.Query(ctx, query, userID, minCommonBooksForSimilarity, maxStoredSimilarUsers)
+	if err != nil {
+		return fmt.Errorf("failed to calculate user similarities: %w", err)
+	}
+	var others []uuid.UUID
+	var scores []float64
+	var commons []int
+	for rows.Next() {
+		var other uuid.UUID
+		var score float64
+		var common int
+		if err := rows.Scan(&other, &score, &common); err != nil {
+			rows.Close()
+			return err
+		}
+		others = append(others, other)
+		scores = append(scores, score)
+		commons = append(commons, common)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to calculate user similarities: %w", err)
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		DELETE FROM user_similarities
+		WHERE (user_a_id = $1 OR user_b_id = $1) AND similarity_type = 'cosine'`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to clear user similarities: %w", err)
+	}
+
+	if len(others) > 0 {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO user_similarities (
+				user_a_id, user_b_id, similarity_score, similarity_type, common_books_count, last_calculated
+			)
+			SELECT a, b, score, 'cosine', common, NOW()
+			FROM unnest($2::uuid[], $3::float8[], $4::int[]) AS t(other, score, common)
+			CROSS JOIN LATERAL (VALUES ($1::uuid, t.other), (t.other, $1::uuid)) AS pair(a, b)
+			ON CONFLICT (user_a_id, user_b_id, similarity_type) DO UPDATE SET
+				similarity_score = EXCLUDED.similarity_score,
+				common_books_count = EXCLUDED.common_books_count,
+				last_calculated = EXCLUDED.last_calculated`,
+			userID, others, scores, commons)
+		if err != nil {
+			return fmt.Errorf("failed to store user similarities: %w", err)
+		}
+	}
+
+	return tx.Commit(ctx)
 }
 
 func (r *postgresUserSimilarityRepository) GetBatch(ctx context.Context, userIDs []uuid.UUID) ([]*domain.UserSimilarity, error) {
-	return nil, nil
+	query := `
+		SELECT user_a_id, user_b_id, similarity_score, similarity_type, common_books_count, last_calculated
+		FROM user_similarities
+		WHERE user_a_id = ANY($1)
+		ORDER BY user_a_id, similarity_score DESC`
+
+	rows, err := r.db.Query(ctx, query, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanUserSimilarities(rows)
+}
+
+func scanUserSimilarities(rows pgx.Rows) ([]*domain.UserSimilarity, error) {
+	similarities := []*domain.UserSimilarity{}
+	for rows.Next() {
+		var similarity domain.UserSimilarity
+		err := rows.Scan(
+			&similarity.UserAID, &similarity.UserBID, &similarity.SimilarityScore,
+			&similarity.SimilarityType, &similarity.CommonBooksCount, &similarity.LastCalculated,
+		)
+		if err != nil {
+			return nil, err
+		}
+		similarities = append(similarities, &similarity)
+	}
+
+	return similarities, rows.Err()
 }
 
 // Recommendation Cache Repository
@@ -629,34 +857,186 @@ type postgresRecommendationCacheRepository struct {
 	db *pgxpool.Pool
 }
 
+// recommendationCacheChannel is notified by invalidate_recommendation_cache
+const recommendationCacheChannel = "recommendation_cache_invalidated"
+
 func NewPostgresRecommendationCacheRepository(db *pgxpool.Pool) RecommendationCacheRepository {
 	return &postgresRecommendationCacheRepository{db: db}
 }
 
-func (r *postgresRecommendationCacheRepository) Create(ctx context.Context, cache *domain.RecommendationCache) error {
-	return nil
+// recommendationCacheAllUsers is the recommendation_cache_generations key
+// of invalidations covering all users
+var recommendationCacheAllUsers = uuid.Nil
+
+func (r *postgresRecommendationCacheRepository) Generation(ctx context.Context, userID uuid.UUID) (int64, error) {
+	var generation int64
+	err := r.db.QueryRow(ctx, `
+		SELECT COALESCE(MAX(generation), 0) FROM recommendation_cache_generations
+		WHERE user_id = ANY($1)`,
+		[]uuid.UUID{userID, recommendationCacheAllUsers},
+	).Scan(&generation)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get recommendation cache generation: %w", err)
+	}
+	return generation, nil
 }
 
-func (r *postgresRecommendationCacheRepository) GetByUserIDAndType(ctx context.Context, userID uuid.UUID, recType string) (*domain.RecommendationCache, error) {
-	return nil, nil
+// Create locks the generations the entry depends on, so that an
+// invalidation either commits before and is seen here, or waits and deletes
+// the entry
+func (r *postgresRecommendationCacheRepository) Create(ctx context.Context, cache *domain.RecommendationCache, generation int64) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	keys := []uuid.UUID{cache.UserID, recommendationCacheAllUsers}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO recommendation_cache_generations (user_id, generation)
+		SELECT unnest($1::uuid[]), 0
+		ON CONFLICT (user_id) DO NOTHING`, keys)
+	if err != nil {
+		return false, fmt.Errorf("failed to lock recommendation cache generation: %w", err)
+	}
+
+	var current int64
+	err = tx.QueryRow(ctx, `
+		SELECT COALESCE(MAX(generation), 0) FROM (
+			SELECT generation FROM recommendation_cache_generations
+			WHERE user_id = ANY($1)
+			FOR SHARE
+		) locked`, keys).Scan(&current)
+	if err != nil {
+		return false, fmt.Errorf("failed to lock recommendation cache generation: %w", err)
+	}
+	if current > generation {
+		return false, nil
+	}
+
+	query := `
+		INSERT INTO recommendation_cache (
+			id, user_id, recommendation_type, params_hash, book_ids, scores,
+			response, context_filters, expires_at, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (user_id, recommendation_type, params_hash) DO UPDATE SET
+			id = EXCLUDED.id,
+			book_ids = EXCLUDED.book_ids,
+			scores = EXCLUDED.scores,
+			response = EXCLUDED.response,
+			context_filters = EXCLUDED.context_filters,
+			expires_at = EXCLUDED.expires_at,
+			created_at = EXCLUDED.created_at`
+
+	_, err = tx.Exec(ctx, query,
+		cache.ID, cache.UserID, cache.RecommendationType, cache.ParamsHash, cache.BookIDs, cache.Scores,
+		cache.Response, cache.ContextFilters, cache.ExpiresAt, cache.CreatedAt,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to cache recommendations: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to cache recommendations: %w", err)
+	}
+	return true, nil
+}
+
+func (r *postgresRecommendationCacheRepository) Get(ctx context.Context, userID uuid.UUID, recType, paramsHash string) (*domain.RecommendationCache, error) {
+	query := `
+		SELECT id, user_id, recommendation_type, params_hash, book_ids, scores,
+			response, context_filters, expires_at, created_at
+		FROM recommendation_cache
+		WHERE user_id = $1 AND recommendation_type = $2 AND params_hash = $3 AND expires_at > NOW()`
+
+	var cache domain.RecommendationCache
+	err := r.db.QueryRow(ctx, query, userID, recType, paramsHash).Scan(
+		&cache.ID, &cache.UserID, &cache.RecommendationType, &cache.ParamsHash, &cache.BookIDs, &cache.Scores,
+		&cache.Response, &cache.ContextFilters, &cache.ExpiresAt, &cache.CreatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get cached recommendations: %w", err)
+	}
+	return &cache, nil
 }
 
 func (r *postgresRecommendationCacheRepository) Update(ctx context.Context, cache *domain.RecommendationCache) error {
+	query := `
+		UPDATE recommendation_cache SET
+			book_ids = $2, scores = $3, response = $4, context_filters = $5, expires_at = $6
+		WHERE id = $1`
+
+	_, err := r.db.Exec(ctx, query,
+		cache.ID, cache.BookIDs, cache.Scores, cache.Response, cache.ContextFilters, cache.ExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update cached recommendations: %w", err)
+	}
 	return nil
 }
 
 func (r *postgresRecommendationCacheRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	return nil
+	_, err := r.db.Exec(ctx, `DELETE FROM recommendation_cache WHERE id = $1`, id)
+	return err
 }
 
-func (r *postgresRecommendationCacheRepository) DeleteExpired(ctx context.Context) error {
-	return nil
+func (r *postgresRecommendationCacheRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM recommendation_cache WHERE expires_at <= NOW()`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired recommendations: %w", err)
+	}
+	return tag.RowsAffected(), nil
 }
 
 func (r *postgresRecommendationCacheRepository) InvalidateUserCache(ctx context.Context, userID uuid.UUID) error {
+	_, err := r.db.Exec(ctx, `SELECT invalidate_recommendation_cache($1)`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to invalidate recommendation cache: %w", err)
+	}
 	return nil
 }
 
+func (r *postgresRecommendationCacheRepository) InvalidateAll(ctx context.Context) error {
+	_, err := r.db.Exec(ctx, `SELECT invalidate_recommendation_cache(NULL)`)
+	if err != nil {
+		return fmt.Errorf("failed to invalidate recommendation cache: %w", err)
+	}
+	return nil
+}
+
+// ListenInvalidations takes a connection out of the pool for as long as it
+// listens, so that notifications are never delivered to other queries
+func (r *postgresRecommendationCacheRepository) ListenInvalidations(ctx context.Context, ready func(), handle func(*domain.RecommendationCacheInvalidation)) error {
+	pooled, err := r.db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+recommendationCacheChannel); err != nil {
+		return fmt.Errorf("failed to listen for recommendation cache invalidations: %w", err)
+	}
+	ready()
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		var invalidation domain.RecommendationCacheInvalidation
+		if err := json.Unmarshal([]byte(notification.Payload), &invalidation); err != nil {
+			return fmt.Errorf("invalid recommendation cache invalidation %q: %w", notification.Payload, err)
+		}
+		handle(&invalidation)
+	}
+}
+
+// Stub implementations for remaining recommendation repositories
+
 // Recommendation Feedback Repository
 type postgresRecommendationFeedbackRepository struct {
 	db *pgxpool.Pool
@@ -696,21 +1076,120 @@ func NewPostgresUsageTrackingRepository(db *pgxpool.Pool) UsageTrackingRepositor
 }
 
 func (r *postgresUsageTrackingRepository) Create(ctx context.Context, usage *domain.UsageTracking) error {
-	return nil
+	metadataJSON, err := json.Marshal(usage.Metadata)
+	if err != nil {
+		return fmt.Errorf("failed to marshal usage metadata: %w", err)
+	}
+
+	query := `
+		INSERT INTO usage_tracking (
+			id, user_id, feature_type, usage_date, usage_count, usage_value,
+			metadata, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+
+	_, err = r.db.Exec(ctx, query,
+		usage.ID, usage.UserID, usage.FeatureType, usage.UsageDate.Format("2006-01-02"),
+		usage.UsageCount, usage.UsageValue, metadataJSON, usage.CreatedAt, usage.UpdatedAt,
+	)
+	return err
 }
 
 func (r *postgresUsageTrackingRepository) GetByUserIDAndPeriod(ctx context.Context, userID uuid.UUID, start, end time.Time) ([]*domain.UsageTracking, error) {
-	return []*domain.UsageTracking{}, nil
+	query := `
+		SELECT id, user_id, feature_type, usage_date, usage_count, usage_value,
+			   metadata, created_at, updated_at
+		FROM usage_tracking
+		WHERE user_id = $1 AND usage_date >= $2 AND usage_date <= $3
+		ORDER BY usage_date ASC`
+
+	rows, err := r.db.Query(ctx, query, userID, start.Format("2006-01-02"), end.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var usages []*domain.UsageTracking
+	for rows.Next() {
+		usage, err := scanUsageTracking(rows)
+		if err != nil {
+			return nil, err
+		}
+		usages = append(usages, usage)
+	}
+
+	return usages, rows.Err()
 }
 
 func (r *postgresUsageTrackingRepository) GetUsageForFeature(ctx context.Context, userID uuid.UUID, featureType string, date time.Time) (*domain.UsageTracking, error) {
-	return nil, nil
+	query := `
+		SELECT id, user_id, feature_type, usage_date, usage_count, usage_value,
+			   metadata, created_at, updated_at
+		FROM usage_tracking
+		WHERE user_id = $1 AND feature_type = $2 AND usage_date = $3`
+
+	usage, err := scanUsageTracking(r.db.QueryRow(ctx, query, userID, featureType, date.Format("2006-01-02")))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return usage, nil
 }
 
+// UpdateUsage adds count and value to today's usage of a feature, creating
+// the day's row on first use
 func (r *postgresUsageTrackingRepository) UpdateUsage(ctx context.Context, userID uuid.UUID, featureType string, count int, value float64) error {
-	return nil
+	query := `
+		INSERT INTO usage_tracking (
+			id, user_id, feature_type, usage_date, usage_count, usage_value, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW())
+		ON CONFLICT (user_id, feature_type, usage_date) DO UPDATE SET
+			usage_count = usage_tracking.usage_count + EXCLUDED.usage_count,
+			usage_value = usage_tracking.usage_value + EXCLUDED.usage_value,
+			updated_at = NOW()`
+
+	_, err := r.db.Exec(ctx, query,
+		uuid.New(), userID, featureType, time.Now().Format("2006-01-02"), count, value,
+	)
+	return err
 }
 
 func (r *postgresUsageTrackingRepository) GetAggregatedUsage(ctx context.Context, userID uuid.UUID, featureType string, start, end time.Time) (*AggregatedUsage, error) {
-	return nil, nil
+	query := `
+		SELECT
+			COALESCE(SUM(usage_count), 0),
+			COALESCE(SUM(usage_value), 0),
+			COALESCE(SUM(usage_value) / GREATEST($4::date - $3::date + 1, 1), 0),
+			COALESCE(TO_CHAR((ARRAY_AGG(usage_date ORDER BY usage_value DESC))[1], 'YYYY-MM-DD'), ''),
+			COALESCE(MAX(usage_value), 0)
+		FROM usage_tracking
+		WHERE user_id = $1 AND feature_type = $2 AND usage_date >= $3 AND usage_date <= $4`
+
+	var usage AggregatedUsage
+	err := r.db.QueryRow(ctx, query, userID, featureType, start.Format("2006-01-02"), end.Format("2006-01-02")).Scan(
+		&usage.TotalCount, &usage.TotalValue, &usage.AvgDaily, &usage.PeakDay, &usage.PeakValue,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &usage, nil
+}
+
+func scanUsageTracking(row pgx.Row) (*domain.UsageTracking, error) {
+	var usage domain.UsageTracking
+	var metadataJSON []byte
+	err := row.Scan(
+		&usage.ID, &usage.UserID, &usage.FeatureType, &usage.UsageDate, &usage.UsageCount,
+		&usage.UsageValue, &metadataJSON, &usage.CreatedAt, &usage.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(metadataJSON) > 0 {
+		if err := json.Unmarshal(metadataJSON, &usage.Metadata); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal usage metadata: %w", err)
+		}
+	}
+	return &usage, nil
 }
\ No newline at end of file
@@ -42,6 +42,9 @@ type AdminDashboardService interface {
 	GetModelPerformance(ctx context.Context) (*dto.ModelPerformanceResponse, error)
 	GetRecommendationQuality(ctx context.Context) (*dto.RecommendationQualityResponse, error)
 	GetABTestSummary(ctx context.Context) (*dto.ABTestSummaryResponse, error)
+
+	// Storage
+	GetAudioStorage(ctx context.Context) (*dto.AudioStorageResponse, error)
 }
 
 type adminDashboardService struct {
@@ -52,6 +55,8 @@ type adminDashboardService struct {
 	analyticsRepo       repository.ReadingAnalyticsRepository
 	interactionRepo     repository.UserInteractionRepository
 	feedbackRepo        repository.RecommendationFeedbackRepository
+	audioFileRepo       repository.AudioFileRepository
+	audioLifecycleRepo  repository.AudioLifecycleRepository
 	abTestingService    ABTestingService
 	subscriptionService SubscriptionService
 }
@@ -64,6 +69,8 @@ func NewAdminDashboardService(
 	analyticsRepo repository.ReadingAnalyticsRepository,
 	interactionRepo repository.UserInteractionRepository,
 	feedbackRepo repository.RecommendationFeedbackRepository,
+	audioFileRepo repository.AudioFileRepository,
+	audioLifecycleRepo repository.AudioLifecycleRepository,
 	abTestingService ABTestingService,
 	subscriptionService SubscriptionService,
 	logger *logger.Logger,
@@ -76,6 +83,8 @@ func NewAdminDashboardService(
 		analyticsRepo:       analyticsRepo,
 		interactionRepo:     interactionRepo,
 		feedbackRepo:        feedbackRepo,
+		audioFileRepo:       audioFileRepo,
+		audioLifecycleRepo:  audioLifecycleRepo,
 		abTestingService:    abTestingService,
 		subscriptionService: subscriptionService,
 	}
@@ -575,4 +584,75 @@ func (s *adminDashboardService) GetRecommendationQuality(ctx context.Context) (*
 
 func (s *adminDashboardService) GetABTestSummary(ctx context.Context) (*dto.ABTestSummaryResponse, error) {
 	return nil, fmt.Errorf("not implemented")
-}
\ No newline at end of file
+}
+
+// audioStoragePeriods are the windows reclaimed audio storage is reported over
+var audioStoragePeriods = []struct {
+	name   string
+	window time.Duration
+}{
+	{"24h", 24 * time.Hour},
+	{"7d", 7 * 24 * time.Hour},
+	{"30d", 30 * 24 * time.Hour},
+}
+
+// recentAudioLifecycleRuns is how many runs are listed on the dashboard
+const recentAudioLifecycleRuns = 20
+
+// GetAudioStorage reports synthesized audio storage and the space reclaimed by the lifecycle worker
+func (s *adminDashboardService) GetAudioStorage(ctx context.Context) (*dto.AudioStorageResponse, error) {
+	s.logger.Info("Getting audio storage")
+
+	now := time.Now()
+	totals, err := s.audioFileRepo.GetStorageTotals(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audio storage totals: %w", err)
+	}
+
+	longest := audioStoragePeriods[len(audioStoragePeriods)-1].window
+	runs, err := s.audioLifecycleRepo.GetRunsSince(ctx, now.Add(-longest))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audio lifecycle runs: %w", err)
+	}
+
+	response := &dto.AudioStorageResponse{
+		TotalFiles:  totals.Files,
+		TotalBytes:  totals.Bytes,
+		Reclaimed:   make([]dto.AudioReclaimedPeriod, 0, len(audioStoragePeriods)),
+		RecentRuns:  make([]dto.AudioLifecycleRun, 0, recentAudioLifecycleRuns),
+		GeneratedAt: now,
+	}
+
+	for _, period := range audioStoragePeriods {
+		reclaimed := dto.AudioReclaimedPeriod{Period: period.name}
+		since := now.Add(-period.window)
+		for _, run := range runs {
+			if run.StartedAt.Before(since) {
+				continue
+			}
+			reclaimed.Runs++
+			reclaimed.ExpiredFiles += run.ExpiredFiles
+			reclaimed.EvictedFiles += run.EvictedFiles
+			reclaimed.ReclaimedBytes += run.ReclaimedBytes
+			reclaimed.FailedFiles += run.FailedFiles
+		}
+		response.Reclaimed = append(response.Reclaimed, reclaimed)
+	}
+
+	for i, run := range runs {
+		if i == recentAudioLifecycleRuns {
+			break
+		}
+		response.RecentRuns = append(response.RecentRuns, dto.AudioLifecycleRun{
+			ID:             run.ID,
+			StartedAt:      run.StartedAt,
+			FinishedAt:     run.FinishedAt,
+			ExpiredFiles:   run.ExpiredFiles,
+			EvictedFiles:   run.EvictedFiles,
+			ReclaimedBytes: run.ReclaimedBytes,
+			FailedFiles:    run.FailedFiles,
+		})
+	}
+
+	return response, nil
+}
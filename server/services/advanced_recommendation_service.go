@@ -200,7 +200,12 @@ func (s *advancedRecommendationService) GenerateSequentialRecommendations(ctx co
 	}
 
 	// 3. Find thematically similar books
-	similarBooks, err := s.vectorRepo.GetSimilarBooks(ctx, lastBookID, 10, "content")
+	similarBooks, err := s.vectorRepo.GetSimilarBooks(ctx, repository.SimilarBooksQuery{
+		BookID:         lastBookID,
+		Count:          10,
+		SimilarityType: repository.SimilarityContent,
+		ReaderID:       &userID,
+	})
 	if err == nil {
 		for _, similar := range similarBooks {
 			book, err := s.bookRepo.GetByID(ctx, similar.BookID)
@@ -347,7 +352,13 @@ func (s *advancedRecommendationService) generateEmbeddingBasedRecommendations(ct
 
 	// For each top book, find similar books using embeddings
 	for _, bookID := range topBooks {
-		similarBooks, err := s.vectorRepo.GetSimilarBooks(ctx, bookID, count/len(topBooks)+1, "content")
+		similarBooks, err := s.vectorRepo.GetSimilarBooks(ctx, repository.SimilarBooksQuery{
+			BookID:         bookID,
+			Count:          count/len(topBooks) + 1,
+			SimilarityType: repository.SimilarityHybrid,
+			ReaderID:       &userID,
+			ExcludeBookIDs: topBooks,
+		})
 		if err != nil {
 			continue
 		}
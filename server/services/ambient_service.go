@@ -0,0 +1,495 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ponyo877/roudoku/server/domain"
+	"github.com/ponyo877/roudoku/server/dto"
+	"github.com/ponyo877/roudoku/server/pkg/audio"
+	apperrors "github.com/ponyo877/roudoku/server/pkg/errors"
+	"github.com/ponyo877/roudoku/server/pkg/logger"
+	"github.com/ponyo877/roudoku/server/pkg/storage"
+	"github.com/ponyo877/roudoku/server/repository"
+)
+
+// AmbientService defines the interface for mixing ambient tracks under narration
+type AmbientService interface {
+	// ListTracks returns the curated ambient library
+	ListTracks(ctx context.Context) (*dto.AmbientTrackListResponse, error)
+	// MixAudio lays an ambient track under a synthesized audio file and
+	// stores the result as a new audio file
+	MixAudio(ctx context.Context, userID, audioFileID uuid.UUID, req *dto.AmbientMixRequest) (*dto.AmbientMixResponse, error)
+}
+
+// AmbientConfig locates the ambient library and sets mixing defaults
+type AmbientConfig struct {
+	// LibraryKey is the blob key of the JSON library manifest
+	LibraryKey string
+	// RefreshInterval is how long a loaded manifest is used before it is read again
+	RefreshInterval time.Duration
+	// Mix holds the default mix settings; zero fields use audio.DefaultMixOptions
+	Mix audio.MixOptions
+}
+
+// ambientMixConfig records how a mixed file was produced. It is added to
+// the narration's voice config, so the same mix is served from the cache.
+type ambientMixConfig struct {
+	Track     string  `json:"track"`
+	TrackETag string  `json:"track_etag"`
+	BedGainDB float64 `json:"bed_gain_db"`
+	DuckDB    float64 `json:"duck_db"`
+	FadeIn    float64 `json:"fade_in"`
+	FadeOut   float64 `json:"fade_out"`
+}
+
+// ambientService implements AmbientService
+type ambientService struct {
+	*BaseService
+	audioFileRepo repository.AudioFileRepository
+	bookRepo      repository.BookRepository
+	blobStore     storage.BlobStore
+	ttsService    TTSService
+	config        AmbientConfig
+
+	mu         sync.Mutex
+	library    *domain.AmbientLibrary
+	libraryAge time.Time
+}
+
+// NewAmbientService creates a new ambient service
+func NewAmbientService(
+	audioFileRepo repository.AudioFileRepository,
+	bookRepo repository.BookRepository,
+	blobStore storage.BlobStore,
+	ttsService TTSService,
+	config AmbientConfig,
+	logger *logger.Logger,
+) AmbientService {
+	if config.LibraryKey == "" {
+		config.LibraryKey = "ambient/library.json"
+	}
+	if config.RefreshInterval <= 0 {
+		config.RefreshInterval = 5 * time.Minute
+	}
+
+	defaults := audio.DefaultMixOptions()
+	if config.Mix.BedGainDB == 0 {
+		config.Mix.BedGainDB = defaults.BedGainDB
+	}
+	if config.Mix.DuckDB == 0 {
+		config.Mix.DuckDB = defaults.DuckDB
+	}
+	if config.Mix.DuckThreshold <= 0 {
+		config.Mix.DuckThreshold = defaults.DuckThreshold
+	}
+	if config.Mix.Attack <= 0 {
+		config.Mix.Attack = defaults.Attack
+	}
+	if config.Mix.Release <= 0 {
+		config.Mix.Release = defaults.Release
+	}
+	if config.Mix.FadeIn <= 0 {
+		config.Mix.FadeIn = defaults.FadeIn
+	}
+	if config.Mix.FadeOut <= 0 {
+		config.Mix.FadeOut = defaults.FadeOut
+	}
+
+	return &ambientService{
+		BaseService:   NewBaseService(logger),
+		audioFileRepo: audioFileRepo,
+		bookRepo:      bookRepo,
+		blobStore:     blobStore,
+		ttsService:    ttsService,
+		config:        config,
+	}
+}
+
+// ListTracks returns the curated ambient library
+func (s *ambientService) ListTracks(ctx context.Context) (*dto.AmbientTrackListResponse, error) {
+	library, err := s.loadLibrary(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &dto.AmbientTrackListResponse{Tracks: make([]dto.AmbientTrackResponse, 0, len(library.Tracks))}
+	for i := range library.Tracks {
+		response.Tracks = append(response.Tracks, ambientTrackResponse(&library.Tracks[i]))
+	}
+	return response, nil
+}
+
+// MixAudio lays an ambient track under a synthesized audio file. The track
+// is the one requested, or else chosen from the narrated text and the book.
+// MP3 and Ogg narration is mixed from its WAV variant, which is synthesized
+// when it does not exist yet.
+func (s *ambientService) MixAudio(ctx context.Context, userID, audioFileID uuid.UUID, req *dto.AmbientMixRequest) (*dto.AmbientMixResponse, error) {
+	if err := s.ValidateStruct(req); err != nil {
+		return nil, err
+	}
+
+	accessible, err := s.audioFileRepo.IsAccessibleBy(ctx, audioFileID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check audio file access: %w", err)
+	}
+	if !accessible {
+		return nil, apperrors.NotFound("Audio file not found")
+	}
+
+	requested, err := s.audioFileRepo.GetByID(ctx, audioFileID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audio file: %w", err)
+	}
+	if requested == nil || requested.Status != "completed" {
+		return nil, apperrors.NotFound("Audio file not found")
+	}
+	source, err := s.wavNarration(ctx, userID, requested)
+	if err != nil {
+		return nil, err
+	}
+
+	library, err := s.loadLibrary(ctx)
+	if err != nil {
+		return nil, err
+	}
+	track, selection, err := s.chooseTrack(ctx, library, source, req.TrackID)
+	if err != nil {
+		return nil, err
+	}
+
+	trackInfo, err := s.blobStore.Stat(ctx, track.Key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, apperrors.NotFound("Ambient track file not found")
+		}
+		return nil, fmt.Errorf("failed to stat ambient track: %w", err)
+	}
+
+	options := s.mixOptions(track, req)
+	voiceConfig, err := ambientVoiceConfig(source.VoiceConfig, ambientMixConfig{
+		Track:     track.ID,
+		TrackETag: trackInfo.ETag,
+		BedGainDB: options.BedGainDB,
+		DuckDB:    options.DuckDB,
+		FadeIn:    options.FadeIn.Seconds(),
+		FadeOut:   options.FadeOut.Seconds(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &dto.AmbientMixResponse{
+		SourceAudioFileID: requested.ID,
+		Track:             ambientTrackResponse(track),
+		Selection:         selection,
+	}
+
+	cached, err := s.audioFileRepo.GetByCacheKey(ctx, userID, source.TextHash, voiceConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up mixed audio: %w", err)
+	}
+	if cached != nil {
+		if _, err := s.blobStore.Stat(ctx, cached.FilePath); err == nil {
+			response.AudioFileID = cached.ID
+			response.Duration = cached.DurationSeconds
+			response.Cached = true
+			return response, nil
+		}
+		s.logger.Warn("Mixed audio file is missing, mixing again")
+	}
+
+	mixed, err := s.mix(ctx, source, track, options)
+	if err != nil {
+		return nil, err
+	}
+
+	audioFile, err := s.storeMix(ctx, userID, source, voiceConfig, mixed)
+	if err != nil {
+		return nil, err
+	}
+
+	response.AudioFileID = audioFile.ID
+	response.Duration = audioFile.DurationSeconds
+	return response, nil
+}
+
+// wavNarration returns the narration as PCM audio: the file itself when it
+// is WAV, else its WAV variant, synthesizing one with the same voice
+// settings when none exists
+func (s *ambientService) wavNarration(ctx context.Context, userID uuid.UUID, narration *domain.AudioFile) (*domain.AudioFile, error) {
+	if narration.Format == "wav" {
+		return narration, nil
+	}
+
+	variants, err := s.audioFileRepo.GetFormatVariants(ctx, narration.TextHash, narration.VoiceConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audio format variants: %w", err)
+	}
+	for _, variant := range variants {
+		if variant.Format == "wav" {
+			return variant, nil
+		}
+	}
+
+	if s.ttsService == nil {
+		return nil, apperrors.BadRequest(fmt.Sprintf("Ambient mixing requires WAV narration, not %s; synthesize it with format wav", narration.Format), nil)
+	}
+	wav, err := s.ttsService.SynthesizeFormat(ctx, userID, narration, "wav")
+	if err != nil {
+		return nil, fmt.Errorf("failed to synthesize WAV narration: %w", err)
+	}
+	return wav, nil
+}
+
+// chooseTrack returns the requested track, or picks one by scene keywords in
+// the narrated text, then by the book's genre, then the library default
+func (s *ambientService) chooseTrack(ctx context.Context, library *domain.AmbientLibrary, source *domain.AudioFile, trackID string) (*domain.AmbientTrack, string, error) {
+	if trackID != "" {
+		for i := range library.Tracks {
+			if library.Tracks[i].ID == trackID {
+				return &library.Tracks[i], domain.AmbientSelectionUser, nil
+			}
+		}
+		return nil, "", apperrors.BadRequest("Unknown ambient track", nil)
+	}
+
+	var genre string
+	if source.BookID != nil {
+		book, err := s.bookRepo.GetByID(ctx, *source.BookID)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to get book: %w", err)
+		}
+		if book != nil && book.Genre != nil {
+			genre = *book.Genre
+		}
+	}
+
+	track, selection := selectAmbientTrack(library.Tracks, source.TextContent, genre)
+	if track == nil {
+		return nil, "", apperrors.NotFound("No ambient track matches this narration")
+	}
+	return track, selection, nil
+}
+
+// selectAmbientTrack scores tracks by how often their keywords occur in the
+// text. Without any keyword hit, the first track for the genre is used and
+// then the default track. Ties go to the track listed first.
+func selectAmbientTrack(tracks []domain.AmbientTrack, text, genre string) (*domain.AmbientTrack, string) {
+	var best *domain.AmbientTrack
+	var bestScore int
+	for i := range tracks {
+		var score int
+		for _, keyword := range tracks[i].Keywords {
+			if keyword != "" {
+				score += strings.Count(text, keyword)
+			}
+		}
+		if score > bestScore {
+			best, bestScore = &tracks[i], score
+		}
+	}
+	if best != nil {
+		return best, domain.AmbientSelectionScene
+	}
+
+	if genre != "" {
+		for i := range tracks {
+			for _, trackGenre := range tracks[i].Genres {
+				if strings.EqualFold(trackGenre, genre) {
+					return &tracks[i], domain.AmbientSelectionGenre
+				}
+			}
+		}
+	}
+
+	for i := range tracks {
+		if tracks[i].Default {
+			return &tracks[i], domain.AmbientSelectionDefault
+		}
+	}
+	return nil, ""
+}
+
+// mixOptions applies the track's trim and the request's overrides to the defaults
+func (s *ambientService) mixOptions(track *domain.AmbientTrack, req *dto.AmbientMixRequest) audio.MixOptions {
+	options := s.config.Mix
+	if req.BedVolumeDB != nil {
+		options.BedGainDB = *req.BedVolumeDB
+	}
+	options.BedGainDB += track.GainDB
+	if req.DuckDB != nil {
+		options.DuckDB = *req.DuckDB
+	}
+	if req.FadeInSeconds != nil {
+		options.FadeIn = time.Duration(*req.FadeInSeconds * float64(time.Second))
+	}
+	if req.FadeOutSeconds != nil {
+		options.FadeOut = time.Duration(*req.FadeOutSeconds * float64(time.Second))
+	}
+	return options
+}
+
+// mix decodes the narration and the track and returns the mixed WAV file
+func (s *ambientService) mix(ctx context.Context, source *domain.AudioFile, track *domain.AmbientTrack, options audio.MixOptions) ([]byte, error) {
+	narrationData, err := storage.ReadAll(ctx, s.blobStore, source.FilePath)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, apperrors.NotFound("Audio file not found")
+		}
+		return nil, fmt.Errorf("failed to read audio file: %w", err)
+	}
+	narration, err := audio.DecodeWAV(narrationData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode narration: %w", err)
+	}
+
+	trackData, err := storage.ReadAll(ctx, s.blobStore, track.Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ambient track: %w", err)
+	}
+	bed, err := audio.DecodeWAV(trackData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode ambient track %s: %w", track.ID, err)
+	}
+
+	mixed, err := audio.Mix(narration, bed, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mix ambient track: %w", err)
+	}
+	return audio.EncodeWAV(mixed), nil
+}
+
+// storeMix writes the mixed audio next to synthesized audio and records it
+// as an audio file of the same text
+func (s *ambientService) storeMix(ctx context.Context, userID uuid.UUID, source *domain.AudioFile, voiceConfig string, data []byte) (*domain.AudioFile, error) {
+	info, err := audio.Probe("wav", data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to probe mixed audio: %w", err)
+	}
+
+	filePath := fmt.Sprintf("tts/%s.wav", cacheKey(source.TextHash, voiceConfig))
+	putOpts := storage.PutOptions{
+		ContentType: contentTypeForFormat("wav"),
+		Metadata:    map[string]string{"text_hash": source.TextHash},
+	}
+	now := time.Now()
+	audioFile := &domain.AudioFile{
+		ID:              uuid.New(),
+		UserID:          userID,
+		BookID:          source.BookID,
+		ChapterID:       source.ChapterID,
+		TextContent:     source.TextContent,
+		TextHash:        source.TextHash,
+		VoiceConfig:     voiceConfig,
+		FilePath:        filePath,
+		FileSizeBytes:   int64(len(data)),
+		DurationSeconds: info.Duration,
+		Format:          "wav",
+		SampleRate:      info.SampleRate,
+		BitRate:         info.BitRate,
+		Status:          "completed",
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	err = s.audioFileRepo.WithFilePathLock(ctx, filePath, func() error {
+		if _, err := s.blobStore.Put(ctx, filePath, bytes.NewReader(data), putOpts); err != nil {
+			return fmt.Errorf("failed to write mixed audio: %w", err)
+		}
+		if err := s.audioFileRepo.Create(ctx, audioFile); err != nil {
+			return fmt.Errorf("failed to record mixed audio: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return audioFile, nil
+}
+
+// loadLibrary returns the library manifest, reading it again once the
+// refresh interval has passed. A missing manifest is an empty library.
+func (s *ambientService) loadLibrary(ctx context.Context) (*domain.AmbientLibrary, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.library != nil && time.Since(s.libraryAge) < s.config.RefreshInterval {
+		return s.library, nil
+	}
+
+	library := &domain.AmbientLibrary{}
+	data, err := storage.ReadAll(ctx, s.blobStore, s.config.LibraryKey)
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		s.logger.Warn("Ambient library manifest not found")
+	case err != nil:
+		return nil, fmt.Errorf("failed to read ambient library: %w", err)
+	default:
+		if err := json.Unmarshal(data, library); err != nil {
+			return nil, fmt.Errorf("failed to parse ambient library: %w", err)
+		}
+		if err := validateAmbientLibrary(library); err != nil {
+			return nil, err
+		}
+	}
+
+	s.library = library
+	s.libraryAge = time.Now()
+	return library, nil
+}
+
+// validateAmbientLibrary checks that every track has a unique ID and a file
+func validateAmbientLibrary(library *domain.AmbientLibrary) error {
+	seen := make(map[string]bool, len(library.Tracks))
+	for i, track := range library.Tracks {
+		if track.ID == "" || track.Key == "" {
+			return fmt.Errorf("ambient track %d: id and key are required", i)
+		}
+		if seen[track.ID] {
+			return fmt.Errorf("ambient track %q is listed twice", track.ID)
+		}
+		seen[track.ID] = true
+	}
+	return nil
+}
+
+// ambientVoiceConfig adds the mix settings to the narration's voice config
+func ambientVoiceConfig(voiceConfig string, mix ambientMixConfig) (string, error) {
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal([]byte(voiceConfig), &fields); err != nil {
+		return "", fmt.Errorf("failed to parse voice config: %w", err)
+	}
+	if _, ok := fields["ambient"]; ok {
+		return "", apperrors.BadRequest("Audio file already has an ambient track", nil)
+	}
+
+	encoded, err := json.Marshal(mix)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode mix config: %w", err)
+	}
+	fields["ambient"] = encoded
+
+	config, err := json.Marshal(fields)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode voice config: %w", err)
+	}
+	return string(config), nil
+}
+
+func ambientTrackResponse(track *domain.AmbientTrack) dto.AmbientTrackResponse {
+	return dto.AmbientTrackResponse{
+		ID:     track.ID,
+		Name:   track.Name,
+		Scene:  track.Scene,
+		Genres: track.Genres,
+	}
+}
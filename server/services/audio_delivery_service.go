@@ -0,0 +1,528 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ponyo877/roudoku/server/domain"
+	"github.com/ponyo877/roudoku/server/pkg/audio"
+	apperrors "github.com/ponyo877/roudoku/server/pkg/errors"
+	"github.com/ponyo877/roudoku/server/pkg/logger"
+	"github.com/ponyo877/roudoku/server/pkg/storage"
+	"github.com/ponyo877/roudoku/server/pkg/utils"
+	"github.com/ponyo877/roudoku/server/repository"
+)
+
+// AudioDeliveryService defines the interface for delivering stored audio to players
+type AudioDeliveryService interface {
+	// OpenAudio opens a stored audio file for streaming with range support.
+	// accept is the client's Accept header; the same audio in another
+	// format is served when the client prefers that format.
+	OpenAudio(ctx context.Context, userID, audioFileID uuid.UUID, accept string) (*AudioStream, error)
+	// OpenAuthorizedAudio opens a stored audio file like OpenAudio without
+	// checking the user may play it, for callers that authorize it
+	// themselves such as podcast feeds
+	OpenAuthorizedAudio(ctx context.Context, audioFileID uuid.UUID, accept string) (*AudioStream, error)
+	// BuildBookPlaylist returns an HLS playlist of the chapters narrated by
+	// the user's latest audiobook job for the book. Segments are served
+	// under audioBaseURL, the URL of the audio routes.
+	BuildBookPlaylist(ctx context.Context, userID uuid.UUID, bookID int64, audioBaseURL string) (string, error)
+	// OpenAudioSegment returns one HLS segment of an MP3 audio file
+	OpenAudioSegment(ctx context.Context, userID, audioFileID uuid.UUID, index int) ([]byte, error)
+	// ExportBookM4B opens an M4B audiobook of the chapters narrated by the
+	// user's latest audiobook job for the book
+	ExportBookM4B(ctx context.Context, userID uuid.UUID, bookID int64) (*AudioStream, error)
+}
+
+// AudioStream is an opened audio file. Content must be closed by the caller.
+// AudioFileID differs from the requested file when another format was chosen.
+type AudioStream struct {
+	AudioFileID uuid.UUID
+	Content     *storage.ReadSeeker
+	Name        string
+	ContentType string
+	ETag        string
+	ModTime     time.Time
+}
+
+// audioDeliveryService implements AudioDeliveryService
+type audioDeliveryService struct {
+	*BaseService
+	audioFileRepo    repository.AudioFileRepository
+	audiobookJobRepo repository.AudiobookJobRepository
+	bookRepo         repository.BookRepository
+	blobStore        storage.BlobStore
+}
+
+// NewAudioDeliveryService creates a new audio delivery service
+func NewAudioDeliveryService(
+	audioFileRepo repository.AudioFileRepository,
+	audiobookJobRepo repository.AudiobookJobRepository,
+	bookRepo repository.BookRepository,
+	blobStore storage.BlobStore,
+	logger *logger.Logger,
+) AudioDeliveryService {
+	return &audioDeliveryService{
+		BaseService:      NewBaseService(logger),
+		audioFileRepo:    audioFileRepo,
+		audiobookJobRepo: audiobookJobRepo,
+		bookRepo:         bookRepo,
+		blobStore:        blobStore,
+	}
+}
+
+// formatMediaTypes lists the media types clients use for each audio format
+var formatMediaTypes = map[string][]string{
+	"mp3": {"audio/mpeg", "audio/mp3"},
+	"wav": {"audio/wav", "audio/x-wav", "audio/wave"},
+	"ogg": {"audio/ogg", "audio/opus"},
+}
+
+// OpenAudio opens a stored audio file for streaming with range support.
+// Files the user may not play are reported as not found.
+func (s *audioDeliveryService) OpenAudio(ctx context.Context, userID, audioFileID uuid.UUID, accept string) (*AudioStream, error) {
+	accessible, err := s.audioFileRepo.IsAccessibleBy(ctx, audioFileID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check audio file access: %w", err)
+	}
+	if !accessible {
+		return nil, apperrors.NotFound("Audio file not found")
+	}
+
+	return s.OpenAuthorizedAudio(ctx, audioFileID, accept)
+}
+
+func (s *audioDeliveryService) OpenAuthorizedAudio(ctx context.Context, audioFileID uuid.UUID, accept string) (*AudioStream, error) {
+	audioFile, err := s.audioFileRepo.GetByID(ctx, audioFileID)
+	if err != nil {
+		s.logger.Error("Failed to get audio file")
+		return nil, fmt.Errorf("failed to get audio file: %w", err)
+	}
+	if audioFile == nil || audioFile.Status != "completed" {
+		return nil, apperrors.NotFound("Audio file not found")
+	}
+
+	audioFile, err = s.negotiateFormat(ctx, audioFile, accept)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := s.blobStore.Stat(ctx, audioFile.FilePath)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, apperrors.NotFound("Audio file not found")
+		}
+		return nil, fmt.Errorf("failed to stat audio file: %w", err)
+	}
+
+	return &AudioStream{
+		AudioFileID: audioFile.ID,
+		Content:     storage.NewReadSeeker(ctx, s.blobStore, info),
+		Name:        path.Base(audioFile.FilePath),
+		ContentType: contentTypeForFormat(audioFile.Format),
+		ETag:        info.ETag,
+		ModTime:     info.UpdatedAt,
+	}, nil
+}
+
+// negotiateFormat picks the file the Accept header prefers among the audio
+// file and its variants in other formats. The requested file wins ties.
+func (s *audioDeliveryService) negotiateFormat(ctx context.Context, audioFile *domain.AudioFile, accept string) (*domain.AudioFile, error) {
+	best := audioFile
+	bestQuality := acceptQualityForFormat(accept, audioFile.Format)
+	if bestQuality >= 1 {
+		return audioFile, nil
+	}
+
+	variants, err := s.audioFileRepo.GetFormatVariants(ctx, audioFile.TextHash, audioFile.VoiceConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audio format variants: %w", err)
+	}
+
+	available := []string{contentTypeForFormat(audioFile.Format)}
+	for _, variant := range variants {
+		if variant.ID == audioFile.ID {
+			continue
+		}
+		contentType := contentTypeForFormat(variant.Format)
+		if !slices.Contains(available, contentType) {
+			available = append(available, contentType)
+		}
+		if quality := acceptQualityForFormat(accept, variant.Format); quality > bestQuality {
+			best, bestQuality = variant, quality
+		}
+	}
+
+	if bestQuality <= 0 {
+		return nil, apperrors.NotAcceptable(
+			"Audio is not available in an accepted format; synthesize it with the format parameter",
+			map[string]interface{}{"available": available})
+	}
+	return best, nil
+}
+
+// acceptQualityForFormat returns the q-value an Accept header gives an audio format
+func acceptQualityForFormat(accept, format string) float64 {
+	mediaTypes, ok := formatMediaTypes[format]
+	if !ok {
+		mediaTypes = []string{contentTypeForFormat(format)}
+	}
+	return utils.AcceptQuality(accept, mediaTypes...)
+}
+
+// hlsSegmentFormat is the chapter audio format HLS players accept as packed
+// audio segments; WAV and Ogg are not valid HLS segments
+const hlsSegmentFormat = "mp3"
+
+// hlsSegmentSeconds is the length of the segments chapters are split into
+const hlsSegmentSeconds = 10
+
+// hlsSegmentCount returns how many segments audio of the given duration is
+// split into. The last segment runs to the end of the audio and absorbs a
+// remainder too short to be worth a segment, so that a duration rounded up
+// in the database never lists a segment without audio.
+func hlsSegmentCount(duration float64) int {
+	return max(1, int(math.Ceil((duration-0.5)/hlsSegmentSeconds)))
+}
+
+// BuildBookPlaylist returns an HLS playlist splitting every chapter into MP3
+// segments of hlsSegmentSeconds, served by the authenticated segment route
+// so that the playlist never expires. Chapters narrated in another format
+// are served from their MP3 variant, and each chapter after the first
+// starts with a discontinuity since it was encoded separately. While the
+// job is still running the playlist is an EVENT playlist holding the
+// chapters finished so far in reading order, so players can start
+// listening and pick up new chapters on reload.
+func (s *audioDeliveryService) BuildBookPlaylist(ctx context.Context, userID uuid.UUID, bookID int64, audioBaseURL string) (string, error) {
+	job, err := s.audiobookJobRepo.GetLatestJobByBook(ctx, userID, bookID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get audiobook job: %w", err)
+	}
+	if job == nil {
+		return "", apperrors.NotFound("Audiobook not found")
+	}
+
+	tasks, err := s.audiobookJobRepo.GetTasksByJobID(ctx, job.ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get audiobook tasks: %w", err)
+	}
+
+	chapters, err := s.bookRepo.GetChaptersByBookID(ctx, bookID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get book chapters: %w", err)
+	}
+	titles := make(map[uuid.UUID]string, len(chapters))
+	for _, chapter := range chapters {
+		titles[chapter.ID] = chapter.Title
+	}
+
+	finished := job.IsFinished()
+	var segments strings.Builder
+	var segmentCount int
+	var targetDuration float64
+	for _, task := range tasks {
+		if task.Status != domain.AudiobookStatusCompleted || task.AudioFileID == nil {
+			if finished {
+				// Chapters that permanently failed are left out of the finished book
+				continue
+			}
+			// EVENT playlists may only grow at the end
+			break
+		}
+
+		audioFile, err := s.audioFileRepo.GetByID(ctx, *task.AudioFileID)
+		if err != nil {
+			return "", fmt.Errorf("failed to get chapter audio: %w", err)
+		}
+		if audioFile == nil {
+			continue
+		}
+		audioFile, err = s.hlsSegment(ctx, audioFile)
+		if err != nil {
+			return "", err
+		}
+
+		if segmentCount > 0 {
+			segments.WriteString("#EXT-X-DISCONTINUITY\n")
+		}
+		title := strings.NewReplacer("\n", " ", "\r", " ").Replace(titles[task.ChapterID])
+		count := hlsSegmentCount(audioFile.DurationSeconds)
+		for index := 0; index < count; index++ {
+			duration := float64(hlsSegmentSeconds)
+			if index == count-1 {
+				duration = math.Max(audioFile.DurationSeconds-float64(index*hlsSegmentSeconds), 0)
+			}
+			fmt.Fprintf(&segments, "#EXTINF:%.3f,%s\n%s/%s/segments/%d.mp3\n", duration, title, audioBaseURL, audioFile.ID, index)
+			targetDuration = math.Max(targetDuration, duration)
+			segmentCount++
+		}
+	}
+
+	if segmentCount == 0 {
+		return "", apperrors.NotFound("Audiobook audio is not ready yet")
+	}
+
+	playlistType := "EVENT"
+	if finished {
+		playlistType = "VOD"
+	}
+
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n")
+	b.WriteString("#EXT-X-VERSION:3\n")
+	fmt.Fprintf(&b, "#EXT-X-TARGETDURATION:%d\n", int(math.Ceil(targetDuration)))
+	b.WriteString("#EXT-X-MEDIA-SEQUENCE:0\n")
+	fmt.Fprintf(&b, "#EXT-X-PLAYLIST-TYPE:%s\n", playlistType)
+	b.WriteString(segments.String())
+	if finished {
+		b.WriteString("#EXT-X-ENDLIST\n")
+	}
+
+	return b.String(), nil
+}
+
+// OpenAudioSegment cuts the index-th segment of hlsSegmentSeconds out of an
+// MP3 audio file. Files the user may not play are reported as not found.
+func (s *audioDeliveryService) OpenAudioSegment(ctx context.Context, userID, audioFileID uuid.UUID, index int) ([]byte, error) {
+	accessible, err := s.audioFileRepo.IsAccessibleBy(ctx, audioFileID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check audio file access: %w", err)
+	}
+	if !accessible {
+		return nil, apperrors.NotFound("Audio file not found")
+	}
+
+	audioFile, err := s.audioFileRepo.GetByID(ctx, audioFileID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audio file: %w", err)
+	}
+	if audioFile == nil || audioFile.Status != "completed" || audioFile.Format != hlsSegmentFormat {
+		return nil, apperrors.NotFound("Audio segment not found")
+	}
+	count := hlsSegmentCount(audioFile.DurationSeconds)
+	if index < 0 || index >= count {
+		return nil, apperrors.NotFound("Audio segment not found")
+	}
+	start := float64(index * hlsSegmentSeconds)
+	end := start + hlsSegmentSeconds
+	if index == count-1 {
+		end = math.Inf(1)
+	}
+
+	data, err := storage.ReadAll(ctx, s.blobStore, audioFile.FilePath)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, apperrors.NotFound("Audio file not found")
+		}
+		return nil, fmt.Errorf("failed to read audio file: %w", err)
+	}
+
+	segment, err := audio.SliceMP3(data, start, end)
+	if err != nil {
+		return nil, apperrors.NotFound("Audio segment not found")
+	}
+	return segment, nil
+}
+
+// hlsSegment returns the chapter audio, or its MP3 variant when it was
+// narrated in a format HLS cannot play
+func (s *audioDeliveryService) hlsSegment(ctx context.Context, audioFile *domain.AudioFile) (*domain.AudioFile, error) {
+	if audioFile.Format == hlsSegmentFormat {
+		return audioFile, nil
+	}
+
+	variants, err := s.audioFileRepo.GetFormatVariants(ctx, audioFile.TextHash, audioFile.VoiceConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audio format variants: %w", err)
+	}
+	for _, variant := range variants {
+		if variant.Format == hlsSegmentFormat {
+			return variant, nil
+		}
+	}
+
+	return nil, apperrors.New("UNSUPPORTED_FORMAT",
+		fmt.Sprintf("HLS playback needs MP3 chapters, not %s; download the chapters or narrate the audiobook as MP3", audioFile.Format),
+		http.StatusUnprocessableEntity)
+}
+
+// m4bFormats are the chapter audio formats that can be packaged as M4B
+// without re-encoding
+var m4bFormats = []string{"mp3", "ogg"}
+
+// ExportBookM4B packages every narrated chapter of a finished audiobook job
+// into one M4B file with chapter markers and book metadata. The file is kept
+// in the blob store per user and book and rebuilt only when the chapters or
+// titles it was built from change.
+func (s *audioDeliveryService) ExportBookM4B(ctx context.Context, userID uuid.UUID, bookID int64) (*AudioStream, error) {
+	job, err := s.audiobookJobRepo.GetLatestJobByBook(ctx, userID, bookID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audiobook job: %w", err)
+	}
+	if job == nil {
+		return nil, apperrors.NotFound("Audiobook not found")
+	}
+	if !job.IsFinished() {
+		return nil, apperrors.New("CONFLICT", "The audiobook is still being generated", http.StatusConflict)
+	}
+
+	book, err := s.bookRepo.GetByID(ctx, bookID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get book: %w", err)
+	}
+	if book == nil {
+		return nil, apperrors.NotFound("Book not found")
+	}
+
+	tasks, err := s.audiobookJobRepo.GetTasksByJobID(ctx, job.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audiobook tasks: %w", err)
+	}
+
+	chapters, err := s.bookRepo.GetChaptersByBookID(ctx, bookID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get book chapters: %w", err)
+	}
+	titles := make(map[uuid.UUID]string, len(chapters))
+	for _, chapter := range chapters {
+		titles[chapter.ID] = chapter.Title
+	}
+
+	// Chapters that permanently failed are left out, as in the playlist
+	var audioFiles []*domain.AudioFile
+	var chapterTitles []string
+	for i, task := range tasks {
+		if task.Status != domain.AudiobookStatusCompleted || task.AudioFileID == nil {
+			continue
+		}
+
+		audioFile, err := s.audioFileRepo.GetByID(ctx, *task.AudioFileID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get chapter audio: %w", err)
+		}
+		if audioFile == nil || audioFile.Status != "completed" {
+			continue
+		}
+		if !slices.Contains(m4bFormats, audioFile.Format) || (len(audioFiles) > 0 && audioFile.Format != audioFiles[0].Format) {
+			return nil, apperrors.New("UNSUPPORTED_FORMAT",
+				"M4B export needs every chapter narrated as MP3 or every chapter as Ogg Opus",
+				http.StatusUnprocessableEntity)
+		}
+
+		title := titles[task.ChapterID]
+		if title == "" {
+			title = fmt.Sprintf("Chapter %d", i+1)
+		}
+		audioFiles = append(audioFiles, audioFile)
+		chapterTitles = append(chapterTitles, title)
+	}
+	if len(audioFiles) == 0 {
+		return nil, apperrors.NotFound("Audiobook has no narrated chapters")
+	}
+
+	meta := audio.M4BMetadata{Title: book.Title, Author: book.Author}
+	if book.Genre != nil {
+		meta.Genre = *book.Genre
+	}
+
+	key := fmt.Sprintf("exports/m4b/%s/%d.m4b", userID, bookID)
+	source := m4bSourceHash(meta, chapterTitles, audioFiles)
+
+	info, err := s.blobStore.Stat(ctx, key)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("failed to stat audiobook export: %w", err)
+	}
+	if info == nil || info.Metadata["source"] != source {
+		info, err = s.storeM4B(ctx, key, source, meta, chapterTitles, audioFiles)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &AudioStream{
+		Content:     storage.NewReadSeeker(ctx, s.blobStore, info),
+		Name:        book.Title + ".m4b",
+		ContentType: "audio/mp4",
+		ETag:        info.ETag,
+		ModTime:     info.UpdatedAt,
+	}, nil
+}
+
+// storeM4B streams the M4B file built from the chapter audio into the blob
+// store at key, reading one chapter at a time
+func (s *audioDeliveryService) storeM4B(ctx context.Context, key, source string, meta audio.M4BMetadata, titles []string, audioFiles []*domain.AudioFile) (*storage.ObjectInfo, error) {
+	var missing bool
+	chapters := make([]audio.M4BChapter, len(audioFiles))
+	for i, audioFile := range audioFiles {
+		filePath := audioFile.FilePath
+		chapters[i] = audio.M4BChapter{
+			Title: titles[i],
+			Audio: func() ([]byte, error) {
+				data, err := storage.ReadAll(ctx, s.blobStore, filePath)
+				if errors.Is(err, storage.ErrNotFound) {
+					missing = true
+				}
+				return data, err
+			},
+		}
+	}
+
+	reader, writer := io.Pipe()
+	written := make(chan error, 1)
+	go func() {
+		err := audio.WriteM4B(writer, audioFiles[0].Format, meta, chapters)
+		writer.CloseWithError(err)
+		written <- err
+	}()
+
+	info, err := s.blobStore.Put(ctx, key, reader, storage.PutOptions{
+		ContentType: "audio/mp4",
+		Metadata:    map[string]string{"source": source},
+	})
+	reader.CloseWithError(err)
+	writeErr := <-written
+	if missing {
+		s.deleteExport(ctx, key)
+		return nil, apperrors.NotFound("Chapter audio is missing; regenerate the audiobook")
+	}
+	if writeErr != nil {
+		s.logger.WithError(writeErr).Error("Failed to package audiobook as M4B")
+		s.deleteExport(ctx, key)
+		return nil, fmt.Errorf("failed to package audiobook: %w", writeErr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to store audiobook export: %w", err)
+	}
+	return info, nil
+}
+
+// deleteExport removes a partly written export, so it is not served as if
+// it were complete
+func (s *audioDeliveryService) deleteExport(ctx context.Context, key string) {
+	if err := s.blobStore.Delete(ctx, key); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		s.logger.WithError(err).Error("Failed to delete audiobook export")
+	}
+}
+
+// m4bSourceHash identifies the metadata, chapter titles and chapter audio
+// an M4B export is built from
+func m4bSourceHash(meta audio.M4BMetadata, titles []string, audioFiles []*domain.AudioFile) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%q %q %q\n", meta.Title, meta.Author, meta.Genre)
+	for i, audioFile := range audioFiles {
+		fmt.Fprintf(hash, "%s %q\n", audioFile.ID, titles[i])
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
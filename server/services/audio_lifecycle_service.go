@@ -0,0 +1,309 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/ponyo877/roudoku/server/domain"
+	"github.com/ponyo877/roudoku/server/pkg/logger"
+	"github.com/ponyo877/roudoku/server/pkg/storage"
+	"github.com/ponyo877/roudoku/server/repository"
+)
+
+// unlimitedAudioStorage marks a plan without a synthesized audio budget
+const unlimitedAudioStorage = -1
+
+// AudioLifecycleService removes synthesized audio that has expired, has not
+// been played for a long time, or pushes a user over their plan's storage budget
+type AudioLifecycleService interface {
+	// RunWorker runs a lifecycle pass every interval until ctx is cancelled
+	RunWorker(ctx context.Context)
+	// RunOnce runs a single lifecycle pass and records it
+	RunOnce(ctx context.Context) (*domain.AudioLifecycleRun, error)
+}
+
+// AudioLifecycleConfig configures the audio lifecycle worker
+type AudioLifecycleConfig struct {
+	Interval time.Duration
+	// IdleTTL is how long a file may go unplayed before it is removed
+	IdleTTL time.Duration
+	// BatchSize is how many files are loaded at a time
+	BatchSize int
+}
+
+// audioLifecycleService implements AudioLifecycleService
+type audioLifecycleService struct {
+	*BaseService
+	audioFileRepo    repository.AudioFileRepository
+	lifecycleRepo    repository.AudioLifecycleRepository
+	subscriptionRepo repository.UserSubscriptionRepository
+	planRepo         repository.SubscriptionPlanRepository
+	blobStore        storage.BlobStore
+	config           AudioLifecycleConfig
+}
+
+// NewAudioLifecycleService creates a new audio lifecycle service
+func NewAudioLifecycleService(
+	audioFileRepo repository.AudioFileRepository,
+	lifecycleRepo repository.AudioLifecycleRepository,
+	subscriptionRepo repository.UserSubscriptionRepository,
+	planRepo repository.SubscriptionPlanRepository,
+	blobStore storage.BlobStore,
+	config AudioLifecycleConfig,
+	logger *logger.Logger,
+) AudioLifecycleService {
+	if config.Interval <= 0 {
+		config.Interval = time.Hour
+	}
+	if config.IdleTTL <= 0 {
+		config.IdleTTL = 90 * 24 * time.Hour
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 500
+	}
+
+	return &audioLifecycleService{
+		BaseService:      NewBaseService(logger),
+		audioFileRepo:    audioFileRepo,
+		lifecycleRepo:    lifecycleRepo,
+		subscriptionRepo: subscriptionRepo,
+		planRepo:         planRepo,
+		blobStore:        blobStore,
+		config:           config,
+	}
+}
+
+// RunWorker runs a pass at start and then every interval
+func (s *audioLifecycleService) RunWorker(ctx context.Context) {
+	s.logger.Info("Audio lifecycle worker started")
+
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			s.logger.WithError(err).Error("Audio lifecycle run failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			s.logger.Info("Audio lifecycle worker stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce removes expired and idle files first, then evicts the least
+// recently used files of users who are still over budget. The run is
+// recorded even when a step fails part way.
+func (s *audioLifecycleService) RunOnce(ctx context.Context) (*domain.AudioLifecycleRun, error) {
+	run := &domain.AudioLifecycleRun{
+		ID:        uuid.New(),
+		StartedAt: time.Now(),
+	}
+
+	err := s.expireFiles(ctx, run)
+	if err == nil {
+		err = s.enforceBudgets(ctx, run)
+	}
+
+	run.FinishedAt = time.Now()
+	run.CreatedAt = run.FinishedAt
+	if recordErr := s.lifecycleRepo.CreateRun(context.WithoutCancel(ctx), run); recordErr != nil {
+		s.logger.WithError(recordErr).Error("Failed to record audio lifecycle run")
+	}
+
+	s.logger.WithFields(
+		zap.Int("expired_files", run.ExpiredFiles),
+		zap.Int("evicted_files", run.EvictedFiles),
+		zap.Int64("reclaimed_bytes", run.ReclaimedBytes),
+		zap.Int("failed_files", run.FailedFiles),
+	).Info("Audio lifecycle run finished")
+
+	return run, err
+}
+
+// expireFiles removes files past their expiry and files unplayed for the
+// idle TTL. Idle files that audiobooks or open playback sessions use are kept.
+func (s *audioLifecycleService) expireFiles(ctx context.Context, run *domain.AudioLifecycleRun) error {
+	expired, err := s.audioFileRepo.GetExpiredFiles(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get expired audio files: %w", err)
+	}
+	for _, audioFile := range expired {
+		if s.removeFile(ctx, audioFile, run) {
+			run.ExpiredFiles++
+		}
+	}
+
+	usedBefore := time.Now().Add(-s.config.IdleTTL)
+	for ctx.Err() == nil {
+		idle, err := s.audioFileRepo.GetIdleFiles(ctx, usedBefore, s.config.BatchSize)
+		if err != nil {
+			return fmt.Errorf("failed to get idle audio files: %w", err)
+		}
+
+		removed := 0
+		for _, audioFile := range idle {
+			if s.removeFile(ctx, audioFile, run) {
+				run.ExpiredFiles++
+				removed++
+			}
+		}
+		// Files that could not be removed come back in the next batch, so
+		// stop once a batch makes no progress
+		if len(idle) < s.config.BatchSize || removed == 0 {
+			break
+		}
+	}
+
+	return ctx.Err()
+}
+
+// enforceBudgets evicts the least recently used files of every user whose
+// audio exceeds their plan's storage budget
+func (s *audioLifecycleService) enforceBudgets(ctx context.Context, run *domain.AudioLifecycleRun) error {
+	minBudget, err := s.minimumBudget(ctx)
+	if err != nil {
+		return err
+	}
+	if minBudget < 0 {
+		return nil
+	}
+
+	usages, err := s.audioFileRepo.GetStorageUsageOver(ctx, minBudget)
+	if err != nil {
+		return fmt.Errorf("failed to get audio storage usage: %w", err)
+	}
+
+	for _, usage := range usages {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		budget, err := s.userBudget(ctx, usage.UserID)
+		if err != nil {
+			s.logger.WithError(err).Error("Failed to get audio storage budget")
+			continue
+		}
+		if budget < 0 || usage.Bytes <= budget {
+			continue
+		}
+
+		if err := s.evictUser(ctx, usage, budget, run); err != nil {
+			s.logger.WithError(err).Error("Failed to evict audio files")
+		}
+	}
+
+	return nil
+}
+
+// evictUser removes a user's least recently used files until their usage
+// fits the budget. Only the user's own records are removed; a blob other
+// users' records share stays in place for them.
+func (s *audioLifecycleService) evictUser(ctx context.Context, usage *domain.AudioStorageUsage, budget int64, run *domain.AudioLifecycleRun) error {
+	remaining := usage.Bytes
+	for remaining > budget && ctx.Err() == nil {
+		files, err := s.audioFileRepo.GetLeastRecentlyUsed(ctx, usage.UserID, s.config.BatchSize)
+		if err != nil {
+			return fmt.Errorf("failed to get least recently used audio files: %w", err)
+		}
+
+		removed := 0
+		for _, audioFile := range files {
+			if remaining <= budget {
+				break
+			}
+			if s.removeFile(ctx, audioFile, run) {
+				run.EvictedFiles++
+				remaining -= audioFile.FileSizeBytes
+				removed++
+			}
+		}
+		if removed == 0 {
+			break
+		}
+	}
+
+	return ctx.Err()
+}
+
+// minimumBudget returns the smallest storage budget of any plan in bytes, so
+// only users who may be over budget are loaded
+func (s *audioLifecycleService) minimumBudget(ctx context.Context) (int64, error) {
+	plans, err := s.planRepo.GetActive(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get subscription plans: %w", err)
+	}
+
+	minMB := freePlanAudioStorageMB
+	for _, plan := range plans {
+		if plan.MaxAudioStorageMB != unlimitedAudioStorage && plan.MaxAudioStorageMB < minMB {
+			minMB = plan.MaxAudioStorageMB
+		}
+	}
+	return megabytes(minMB), nil
+}
+
+// userBudget returns the user's storage budget in bytes, or a negative
+// value when their plan is unlimited
+func (s *audioLifecycleService) userBudget(ctx context.Context, userID uuid.UUID) (int64, error) {
+	subscription, err := s.subscriptionRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	if subscription == nil || subscription.Plan == nil || !subscription.IsActive() {
+		return megabytes(freePlanAudioStorageMB), nil
+	}
+	if subscription.Plan.MaxAudioStorageMB == unlimitedAudioStorage {
+		return unlimitedAudioStorage, nil
+	}
+	return megabytes(subscription.Plan.MaxAudioStorageMB), nil
+}
+
+// removeFile deletes a file's blob and then its record. The blob is kept
+// while another record still points at it, and the record is kept when the
+// blob cannot be deleted so the next run tries again. Both happen under the
+// blob's path lock, so no record of the blob is added in between.
+func (s *audioLifecycleService) removeFile(ctx context.Context, audioFile *domain.AudioFile, run *domain.AudioLifecycleRun) bool {
+	var references int
+	err := s.audioFileRepo.WithFilePathLock(ctx, audioFile.FilePath, func() error {
+		var err error
+		references, err = s.audioFileRepo.CountByFilePath(ctx, audioFile.FilePath)
+		if err != nil {
+			return fmt.Errorf("failed to count audio file references: %w", err)
+		}
+
+		if references <= 1 {
+			err := s.blobStore.Delete(ctx, audioFile.FilePath)
+			if err != nil && !errors.Is(err, storage.ErrNotFound) {
+				return fmt.Errorf("failed to delete audio blob: %w", err)
+			}
+		}
+
+		if err := s.audioFileRepo.Delete(ctx, audioFile.ID); err != nil {
+			return fmt.Errorf("failed to delete audio file record: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to remove audio file")
+		run.FailedFiles++
+		return false
+	}
+
+	if references <= 1 {
+		run.ReclaimedBytes += audioFile.FileSizeBytes
+	}
+	return true
+}
+
+func megabytes(mb int) int64 {
+	return int64(mb) * 1024 * 1024
+}
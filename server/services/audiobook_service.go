@@ -0,0 +1,570 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ponyo877/roudoku/server/domain"
+	"github.com/ponyo877/roudoku/server/dto"
+	"github.com/ponyo877/roudoku/server/pkg/errors"
+	"github.com/ponyo877/roudoku/server/pkg/logger"
+	"github.com/ponyo877/roudoku/server/repository"
+)
+
+// AudiobookService defines the interface for whole-book narration jobs
+type AudiobookService interface {
+	CreateJob(ctx context.Context, userID uuid.UUID, bookID int64, req *dto.CreateAudiobookJobRequest) (*dto.AudiobookJobResponse, error)
+	GetJob(ctx context.Context, userID uuid.UUID, jobID uuid.UUID) (*dto.AudiobookJobResponse, error)
+	GetLatestJob(ctx context.Context, userID uuid.UUID, bookID int64) (*dto.AudiobookJobResponse, error)
+	// Voice casts for multi-voice narration
+	GetCast(ctx context.Context, userID uuid.UUID, bookID int64) (*dto.VoiceCastResponse, error)
+	SaveCast(ctx context.Context, userID uuid.UUID, bookID int64, req *dto.SaveVoiceCastRequest) (*dto.VoiceCastResponse, error)
+	DeleteCast(ctx context.Context, userID uuid.UUID, bookID int64) error
+	// RunWorkers processes queued chapter tasks until ctx is cancelled
+	RunWorkers(ctx context.Context)
+}
+
+// AudiobookWorkerConfig configures the audiobook task workers
+type AudiobookWorkerConfig struct {
+	Workers      int
+	PollInterval time.Duration
+	MaxAttempts  int
+	// LockTimeout is how long a task may stay claimed before it is returned to the queue
+	LockTimeout time.Duration
+}
+
+// audiobookVoiceConfig is stored in audiobook_jobs.voice_config. The user's
+// cast for the book is copied in when the job is created, so editing the
+// cast does not change a job that is already running.
+type audiobookVoiceConfig struct {
+	domain.TTSConfig
+	Cast *domain.VoiceCast `json:"cast,omitempty"`
+	// VoiceRule names the voice policy rule that chose the voice
+	VoiceRule string `json:"voice_rule,omitempty"`
+}
+
+// audiobookService implements AudiobookService
+type audiobookService struct {
+	*BaseService
+	jobRepo             repository.AudiobookJobRepository
+	castRepo            repository.VoiceCastRepository
+	bookRepo            repository.BookRepository
+	ttsService          TTSService
+	notificationService NotificationService
+	config              AudiobookWorkerConfig
+}
+
+// NewAudiobookService creates a new audiobook service
+func NewAudiobookService(
+	jobRepo repository.AudiobookJobRepository,
+	castRepo repository.VoiceCastRepository,
+	bookRepo repository.BookRepository,
+	ttsService TTSService,
+	notificationService NotificationService,
+	config AudiobookWorkerConfig,
+	logger *logger.Logger,
+) AudiobookService {
+	if config.Workers <= 0 {
+		config.Workers = 1
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = 5 * time.Second
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 5
+	}
+	if config.LockTimeout <= 0 {
+		config.LockTimeout = 15 * time.Minute
+	}
+
+	return &audiobookService{
+		BaseService:         NewBaseService(logger),
+		jobRepo:             jobRepo,
+		castRepo:            castRepo,
+		bookRepo:            bookRepo,
+		ttsService:          ttsService,
+		notificationService: notificationService,
+		config:              config,
+	}
+}
+
+// CreateJob queues synthesis of every chapter of a book. An unfinished job
+// for the same book and voice is returned instead of queueing a duplicate.
+func (s *audiobookService) CreateJob(ctx context.Context, userID uuid.UUID, bookID int64, req *dto.CreateAudiobookJobRequest) (*dto.AudiobookJobResponse, error) {
+	s.logger.Info("Creating audiobook job")
+
+	if err := s.ValidateStruct(req); err != nil {
+		return nil, err
+	}
+
+	book, err := s.bookRepo.GetByID(ctx, bookID)
+	if err != nil {
+		return nil, err
+	}
+
+	chapters, err := s.bookRepo.GetChaptersByBookID(ctx, book.ID)
+	if err != nil {
+		s.logger.Error("Failed to get book chapters")
+		return nil, fmt.Errorf("failed to get book chapters: %w", err)
+	}
+	if len(chapters) == 0 {
+		return nil, errors.BadRequest("Book has no chapters to narrate", nil)
+	}
+
+	voiceConfig, err := s.resolveVoice(ctx, userID, bookID, req)
+	if err != nil {
+		return nil, err
+	}
+	cast, err := s.castRepo.GetByUserAndBook(ctx, userID, bookID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get voice cast: %w", err)
+	}
+	if cast != nil {
+		voiceConfig.Cast = &cast.Cast
+	}
+
+	voiceConfigJSON, err := json.Marshal(voiceConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode voice config: %w", err)
+	}
+
+	existing, err := s.jobRepo.GetActiveJob(ctx, userID, bookID, string(voiceConfigJSON))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check existing audiobook jobs: %w", err)
+	}
+	if existing != nil {
+		return s.buildJobResponse(ctx, existing)
+	}
+
+	now := time.Now()
+	job := &domain.AudiobookJob{
+		ID:            uuid.New(),
+		UserID:        userID,
+		BookID:        bookID,
+		VoiceConfig:   string(voiceConfigJSON),
+		Status:        domain.AudiobookStatusQueued,
+		TotalChapters: len(chapters),
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+
+	tasks := make([]*domain.AudiobookChapterTask, 0, len(chapters))
+	for _, chapter := range chapters {
+		tasks = append(tasks, &domain.AudiobookChapterTask{
+			ID:            uuid.New(),
+			JobID:         job.ID,
+			ChapterID:     chapter.ID,
+			Position:      chapter.Position,
+			Status:        domain.AudiobookStatusQueued,
+			MaxAttempts:   s.config.MaxAttempts,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		})
+	}
+
+	if err := s.jobRepo.CreateJob(ctx, job, tasks); err != nil {
+		s.logger.Error("Failed to create audiobook job")
+		return nil, fmt.Errorf("failed to create audiobook job: %w", err)
+	}
+
+	return s.buildJobResponse(ctx, job)
+}
+
+// GetJob returns the progress of one of the user's jobs
+func (s *audiobookService) GetJob(ctx context.Context, userID uuid.UUID, jobID uuid.UUID) (*dto.AudiobookJobResponse, error) {
+	job, err := s.jobRepo.GetJobByID(ctx, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audiobook job: %w", err)
+	}
+	if job == nil || job.UserID != userID {
+		return nil, errors.NotFound("Audiobook job not found")
+	}
+
+	return s.buildJobResponse(ctx, job)
+}
+
+// GetLatestJob returns the progress of the user's most recent job for a book
+func (s *audiobookService) GetLatestJob(ctx context.Context, userID uuid.UUID, bookID int64) (*dto.AudiobookJobResponse, error) {
+	job, err := s.jobRepo.GetLatestJobByBook(ctx, userID, bookID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audiobook job: %w", err)
+	}
+	if job == nil {
+		return nil, errors.NotFound("Audiobook job not found")
+	}
+
+	return s.buildJobResponse(ctx, job)
+}
+
+// GetCast returns the voices the user has chosen for a book
+func (s *audiobookService) GetCast(ctx context.Context, userID uuid.UUID, bookID int64) (*dto.VoiceCastResponse, error) {
+	cast, err := s.castRepo.GetByUserAndBook(ctx, userID, bookID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get voice cast: %w", err)
+	}
+	if cast == nil {
+		return nil, errors.NotFound("Voice cast not found")
+	}
+
+	return s.buildCastResponse(cast), nil
+}
+
+// SaveCast sets the voices used for the narration and dialogue of a book
+// in the user's future audiobook jobs
+func (s *audiobookService) SaveCast(ctx context.Context, userID uuid.UUID, bookID int64, req *dto.SaveVoiceCastRequest) (*dto.VoiceCastResponse, error) {
+	if err := s.ValidateStruct(req); err != nil {
+		return nil, err
+	}
+	for _, character := range req.Characters {
+		if character.Voice.Voice == "" {
+			return nil, errors.BadRequest("Every character needs a voice", nil)
+		}
+	}
+
+	if _, err := s.bookRepo.GetByID(ctx, bookID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	cast := &domain.BookVoiceCast{
+		UserID: userID,
+		BookID: bookID,
+		Cast: domain.VoiceCast{
+			Narrator:   castVoiceToConfig(req.Narrator),
+			Characters: make([]domain.CastCharacter, 0, len(req.Characters)),
+		},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	for _, character := range req.Characters {
+		cast.Cast.Characters = append(cast.Cast.Characters, domain.CastCharacter{
+			Name:  character.Name,
+			Voice: castVoiceToConfig(character.Voice),
+		})
+	}
+
+	if err := s.castRepo.Upsert(ctx, cast); err != nil {
+		s.logger.Error("Failed to save voice cast")
+		return nil, fmt.Errorf("failed to save voice cast: %w", err)
+	}
+
+	return s.buildCastResponse(cast), nil
+}
+
+// DeleteCast returns a book to single-voice narration
+func (s *audiobookService) DeleteCast(ctx context.Context, userID uuid.UUID, bookID int64) error {
+	if err := s.castRepo.Delete(ctx, userID, bookID); err != nil {
+		return fmt.Errorf("failed to delete voice cast: %w", err)
+	}
+	return nil
+}
+
+// RunWorkers starts the configured number of workers and a reaper that
+// requeues tasks abandoned by crashed workers. It returns when ctx is done.
+func (s *audiobookService) RunWorkers(ctx context.Context) {
+	s.logger.Info("Starting audiobook workers")
+
+	hostname, _ := os.Hostname()
+	var wg sync.WaitGroup
+
+	for i := 0; i < s.config.Workers; i++ {
+		workerID := fmt.Sprintf("%s-%d-%d", hostname, os.Getpid(), i)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.runWorker(ctx, workerID)
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.runReaper(ctx)
+	}()
+
+	wg.Wait()
+	s.logger.Info("Audiobook workers stopped")
+}
+
+// runWorker drains the queue, then waits for the next poll
+func (s *audiobookService) runWorker(ctx context.Context, workerID string) {
+	ticker := time.NewTicker(s.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil {
+			processed, err := s.processNextTask(ctx, workerID)
+			if err != nil {
+				s.logger.WithError(err).Error("Failed to process audiobook task")
+				break
+			}
+			if !processed {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runReaper periodically requeues tasks whose worker stopped responding
+func (s *audiobookService) runReaper(ctx context.Context) {
+	ticker := time.NewTicker(s.config.LockTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			requeued, failedJobs, err := s.jobRepo.RequeueStaleTasks(ctx, time.Now().Add(-s.config.LockTimeout))
+			if err != nil {
+				s.logger.WithError(err).Error("Failed to requeue stale audiobook tasks")
+				continue
+			}
+			if requeued > 0 {
+				s.logger.Warn("Requeued stale audiobook tasks")
+			}
+			if len(failedJobs) > 0 {
+				s.logger.Warn("Failed stale audiobook tasks that used all their attempts")
+			}
+			for _, job := range failedJobs {
+				if job.IsFinished() {
+					s.notifyJobFinished(ctx, job)
+				}
+			}
+		}
+	}
+}
+
+// processNextTask synthesizes one queued chapter. It reports false when no
+// task was due.
+func (s *audiobookService) processNextTask(ctx context.Context, workerID string) (bool, error) {
+	task, err := s.jobRepo.ClaimNextTask(ctx, workerID)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim audiobook task: %w", err)
+	}
+	if task == nil {
+		return false, nil
+	}
+
+	job, err := s.jobRepo.GetJobByID(ctx, task.JobID)
+	if err != nil {
+		return true, s.failTask(ctx, task, fmt.Errorf("failed to load audiobook job: %w", err))
+	}
+	if job == nil {
+		return true, s.failTask(ctx, task, fmt.Errorf("audiobook job %s not found", task.JobID))
+	}
+
+	audioFile, err := s.synthesizeTask(ctx, job, task)
+	if err != nil {
+		if ctx.Err() != nil {
+			// Shutting down; the reaper will requeue the task
+			return true, nil
+		}
+		return true, s.failTask(ctx, task, err)
+	}
+
+	job, err = s.jobRepo.CompleteTask(ctx, task.ID, audioFile.ID)
+	if err != nil {
+		return true, err
+	}
+	if job.IsFinished() {
+		s.notifyJobFinished(ctx, job)
+	}
+
+	return true, nil
+}
+
+// synthesizeTask narrates the task's chapter with the job's voice and cast
+func (s *audiobookService) synthesizeTask(ctx context.Context, job *domain.AudiobookJob, task *domain.AudiobookChapterTask) (*domain.AudioFile, error) {
+	var voice audiobookVoiceConfig
+	if err := json.Unmarshal([]byte(job.VoiceConfig), &voice); err != nil {
+		return nil, fmt.Errorf("invalid job voice config: %w", err)
+	}
+
+	chapter, err := s.bookRepo.GetChapterByID(ctx, task.ChapterID.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chapter: %w", err)
+	}
+	if chapter == nil {
+		return nil, fmt.Errorf("chapter %s not found", task.ChapterID)
+	}
+
+	return s.ttsService.SynthesizeChapter(ctx, job.UserID, chapter, &voice.TTSConfig, voice.Cast)
+}
+
+// failTask records a failed attempt and schedules a retry with exponential backoff
+func (s *audiobookService) failTask(ctx context.Context, task *domain.AudiobookChapterTask, cause error) error {
+	s.logger.WithError(cause).Warn("Audiobook chapter synthesis failed")
+
+	backoff := 30 * time.Second << (task.Attempts - 1)
+	if backoff > 30*time.Minute || backoff <= 0 {
+		backoff = 30 * time.Minute
+	}
+
+	job, err := s.jobRepo.FailTask(ctx, task.ID, cause.Error(), time.Now().Add(backoff))
+	if err != nil {
+		return err
+	}
+	if job != nil && job.IsFinished() {
+		s.notifyJobFinished(ctx, job)
+	}
+
+	return nil
+}
+
+// notifyJobFinished tells the user their audiobook is ready
+func (s *audiobookService) notifyJobFinished(ctx context.Context, job *domain.AudiobookJob) {
+	if s.notificationService == nil {
+		return
+	}
+
+	title := "オーディオブックの準備ができました"
+	body := "すべての章を聴けるようになりました。"
+	if job.Status == domain.AudiobookStatusFailed {
+		title = "オーディオブックの作成が完了しました"
+		body = fmt.Sprintf("%d章の音声を作成できませんでした。", job.FailedChapters)
+	}
+	if book, err := s.bookRepo.GetByID(ctx, job.BookID); err == nil && book != nil {
+		body = fmt.Sprintf("「%s」%s", book.Title, body)
+	}
+
+	data := map[string]interface{}{
+		"type":    "audiobook_ready",
+		"job_id":  job.ID.String(),
+		"book_id": fmt.Sprintf("%d", job.BookID),
+		"status":  job.Status,
+	}
+
+	if err := s.notificationService.SendNotificationToUser(ctx, job.UserID, title, body, data, "general"); err != nil {
+		s.logger.WithError(err).Error("Failed to send audiobook notification")
+	}
+}
+
+// resolveVoice fills unset request fields with the voice the TTS service
+// selects for the book and the user
+func (s *audiobookService) resolveVoice(ctx context.Context, userID uuid.UUID, bookID int64, req *dto.CreateAudiobookJobRequest) (audiobookVoiceConfig, error) {
+	selection, err := s.ttsService.SelectVoice(ctx, userID, &dto.VoiceSelectionRequest{
+		BookID:   &bookID,
+		Timezone: req.Timezone,
+		Language: req.Language,
+	})
+	if err != nil {
+		return audiobookVoiceConfig{}, err
+	}
+
+	voice := audiobookVoiceConfig{
+		TTSConfig: domain.TTSConfig{
+			Language:   selection.Language,
+			Voice:      selection.Voice,
+			Speed:      selection.Speed,
+			Pitch:      selection.Pitch,
+			VolumeGain: req.VolumeGain,
+		},
+	}
+	if selection.VoiceRule != nil {
+		voice.VoiceRule = selection.VoiceRule.Name
+	}
+
+	if req.Voice != "" {
+		voice.Voice = req.Voice
+	}
+	if req.Speed != 0 {
+		voice.Speed = req.Speed
+	}
+	if req.Pitch != 0 {
+		voice.Pitch = req.Pitch
+	}
+	return voice, nil
+}
+
+// buildJobResponse converts a job and its tasks into the API response
+func (s *audiobookService) buildJobResponse(ctx context.Context, job *domain.AudiobookJob) (*dto.AudiobookJobResponse, error) {
+	tasks, err := s.jobRepo.GetTasksByJobID(ctx, job.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audiobook tasks: %w", err)
+	}
+
+	response := &dto.AudiobookJobResponse{
+		ID:                job.ID,
+		BookID:            job.BookID,
+		Status:            job.Status,
+		TotalChapters:     job.TotalChapters,
+		CompletedChapters: job.CompletedChapters,
+		FailedChapters:    job.FailedChapters,
+		ErrorMessage:      job.ErrorMessage,
+		CompletedAt:       job.CompletedAt,
+		CreatedAt:         job.CreatedAt,
+		UpdatedAt:         job.UpdatedAt,
+	}
+	if job.TotalChapters > 0 {
+		response.Progress = float64(job.CompletedChapters) / float64(job.TotalChapters)
+	}
+
+	var voice audiobookVoiceConfig
+	if err := json.Unmarshal([]byte(job.VoiceConfig), &voice); err == nil && voice.VoiceRule != "" {
+		response.VoiceRule = &dto.VoiceRule{Name: voice.VoiceRule}
+	}
+
+	for _, task := range tasks {
+		response.Chapters = append(response.Chapters, dto.AudiobookChapterProgress{
+			ChapterID:    task.ChapterID,
+			Position:     task.Position,
+			Status:       task.Status,
+			Attempts:     task.Attempts,
+			AudioFileID:  task.AudioFileID,
+			ErrorMessage: task.ErrorMessage,
+		})
+	}
+
+	return response, nil
+}
+
+// buildCastResponse converts a book voice cast into the API response
+func (s *audiobookService) buildCastResponse(cast *domain.BookVoiceCast) *dto.VoiceCastResponse {
+	response := &dto.VoiceCastResponse{
+		BookID:     cast.BookID,
+		Narrator:   configToCastVoice(cast.Cast.Narrator),
+		Characters: make([]dto.CastCharacter, 0, len(cast.Cast.Characters)),
+		UpdatedAt:  cast.UpdatedAt,
+	}
+	for _, character := range cast.Cast.Characters {
+		response.Characters = append(response.Characters, dto.CastCharacter{
+			Name:  character.Name,
+			Voice: configToCastVoice(character.Voice),
+		})
+	}
+	return response
+}
+
+func castVoiceToConfig(voice dto.CastVoice) domain.TTSConfig {
+	return domain.TTSConfig{
+		Language:   voice.Language,
+		Voice:      voice.Voice,
+		Speed:      voice.Speed,
+		Pitch:      voice.Pitch,
+		VolumeGain: voice.VolumeGain,
+	}
+}
+
+func configToCastVoice(voice domain.TTSConfig) dto.CastVoice {
+	return dto.CastVoice{
+		Language:   voice.Language,
+		Voice:      voice.Voice,
+		Speed:      voice.Speed,
+		Pitch:      voice.Pitch,
+		VolumeGain: voice.VolumeGain,
+	}
+}
@@ -0,0 +1,446 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+
+	"github.com/ponyo877/roudoku/server/domain"
+	apperrors "github.com/ponyo877/roudoku/server/pkg/errors"
+	"github.com/ponyo877/roudoku/server/pkg/logger"
+	"github.com/ponyo877/roudoku/server/repository"
+)
+
+// BookEmbeddingService defines the interface for computing the vectors used
+// to find similar books
+type BookEmbeddingService interface {
+	// EmbedBook computes and stores the vectors of a book. Unless force is
+	// set, a book whose vectors are current is skipped. It reports whether
+	// vectors were written.
+	EmbedBook(ctx context.Context, bookID int64, force bool) (bool, error)
+	// EmbedStaleBooks embeds every book whose vectors are missing, were made
+	// by another model, or were made from text that has since changed
+	EmbedStaleBooks(ctx context.Context) (*BookEmbeddingRun, error)
+}
+
+// BookEmbeddingConfig controls how book text is split for the embedder
+type BookEmbeddingConfig struct {
+	// ChunkRunes is the longest chunk of text embedded at once
+	ChunkRunes int
+	// ChunkOverlap is how many runes consecutive chunks share, so that
+	// text at a chunk boundary is seen in context
+	ChunkOverlap int
+	// BatchSize is how many chunks are sent to the embedder at a time
+	BatchSize int
+	// Refit fits a CorpusEmbedder to the books again on the next stale run,
+	// even when a snapshot of its statistics is stored
+	Refit bool
+	// RefitDrift is the relative change in the number of embeddable books
+	// since the stored snapshot at which a stale run fits again
+	RefitDrift float64
+}
+
+// BookEmbeddingRun counts the books processed by EmbedStaleBooks
+type BookEmbeddingRun struct {
+	Embedded int
+	Failed   int
+}
+
+// Scores are normalized against these values
+const (
+	maxDifficultyLevel      = 5
+	maxRating               = 5
+	popularityDownloadScale = 1e6
+)
+
+// bookEmbeddingService implements BookEmbeddingService
+type bookEmbeddingService struct {
+	*BaseService
+	bookRepo   repository.BookRepository
+	vectorRepo repository.BookVectorRepository
+	cacheRepo  repository.RecommendationCacheRepository
+	embedder   Embedder
+	config     BookEmbeddingConfig
+	// loaded is set once a CorpusEmbedder has loaded its statistics
+	loaded bool
+}
+
+// NewBookEmbeddingService creates a new book embedding service
+func NewBookEmbeddingService(
+	bookRepo repository.BookRepository,
+	vectorRepo repository.BookVectorRepository,
+	cacheRepo repository.RecommendationCacheRepository,
+	embedder Embedder,
+	config BookEmbeddingConfig,
+	logger *logger.Logger,
+) BookEmbeddingService {
+	if config.ChunkRunes <= 0 {
+		config.ChunkRunes = 1000
+	}
+	if config.ChunkOverlap < 0 || config.ChunkOverlap >= config.ChunkRunes {
+		config.ChunkOverlap = config.ChunkRunes / 10
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 8
+	}
+	if config.RefitDrift <= 0 {
+		config.RefitDrift = 0.1
+	}
+
+	return &bookEmbeddingService{
+		BaseService: NewBaseService(logger),
+		bookRepo:    bookRepo,
+		vectorRepo:  vectorRepo,
+		cacheRepo:   cacheRepo,
+		embedder:    embedder,
+		config:      config,
+	}
+}
+
+// EmbedBook embeds the book and invalidates the cached recommendations,
+// which may have been made from its previous vectors
+func (s *bookEmbeddingService) EmbedBook(ctx context.Context, bookID int64, force bool) (bool, error) {
+	embedded, err := s.embedBook(ctx, bookID, force)
+	if err != nil || !embedded {
+		return embedded, err
+	}
+	return true, s.cacheRepo.InvalidateAll(ctx)
+}
+
+// embedBook pools the embeddings of the book's chunks into its content
+// vector, weighting each chunk by its length, and derives the genre and
+// style vectors and scores from the book's metadata and text
+func (s *bookEmbeddingService) embedBook(ctx context.Context, bookID int64, force bool) (bool, error) {
+	if s.embedder.Dimensions() != domain.BookContentVectorDims {
+		return false, fmt.Errorf("embedder %s produces %d dimensions, book vectors have %d",
+			s.embedder.Model(), s.embedder.Dimensions(), domain.BookContentVectorDims)
+	}
+	if err := s.loadEmbedder(ctx, false); err != nil {
+		return false, err
+	}
+
+	book, err := s.bookRepo.GetByID(ctx, bookID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get book: %w", err)
+	}
+	chapters, err := s.bookRepo.GetChaptersByBookID(ctx, bookID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get book chapters: %w", err)
+	}
+	if len(chapters) == 0 {
+		return false, apperrors.NotFound("Book has no chapters")
+	}
+
+	contentHash := repository.BookContentHash(chapters)
+	existing, err := s.vectorRepo.GetByBookID(ctx, bookID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get book vector: %w", err)
+	}
+	if !force && existing != nil && existing.EmbeddingModel == s.embedder.Model() && existing.ContentHash == contentHash {
+		return false, nil
+	}
+
+	chunks := s.chunkChapters(chapters)
+	if len(chunks) == 0 {
+		return false, apperrors.NotFound("Book has no text")
+	}
+
+	content := make([]float64, s.embedder.Dimensions())
+	for start := 0; start < len(chunks); start += s.config.BatchSize {
+		batch := chunks[start:min(start+s.config.BatchSize, len(chunks))]
+		embeddings, err := s.embedder.Embed(ctx, batch)
+		if err != nil {
+			return false, fmt.Errorf("failed to embed book text: %w", err)
+		}
+		for i, embedding := range embeddings {
+			weight := float64(utf8.RuneCountInString(batch[i]))
+			for j, v := range embedding {
+				content[j] += weight * v
+			}
+		}
+	}
+	normalizeVector(content)
+
+	vector := &domain.BookVector{
+		BookID:          bookID,
+		ContentVector:   content,
+		GenreVector:     genreVector(book),
+		StyleVector:     styleVector(chapters),
+		DifficultyScore: math.Min(float64(book.DifficultyLevel)/maxDifficultyLevel, 1),
+		PopularityScore: math.Min(math.Log1p(float64(book.DownloadCount))/math.Log1p(popularityDownloadScale), 1),
+		QualityScore:    math.Min(book.RatingAverage/maxRating, 1),
+		EmbeddingModel:  s.embedder.Model(),
+		ContentHash:     contentHash,
+		LastUpdated:     time.Now(),
+	}
+	if existing != nil {
+		vector.NoveltyScore = existing.NoveltyScore
+	}
+
+	if err := s.vectorRepo.Create(ctx, vector); err != nil {
+		return false, err
+	}
+	if err := s.bookRepo.UpdateEmbedding(ctx, bookID, content); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// EmbedStaleBooks walks the stale books in ID order. A book that fails is
+// logged and counted, and the run continues with the next one. The cached
+// recommendations are invalidated once, after the last book.
+func (s *bookEmbeddingService) EmbedStaleBooks(ctx context.Context) (*BookEmbeddingRun, error) {
+	run := &BookEmbeddingRun{}
+	err := s.embedStaleBooks(ctx, run)
+	if run.Embedded > 0 {
+		// Books embedded before a failure or cancellation are stored too
+		if invalidateErr := s.cacheRepo.InvalidateAll(context.WithoutCancel(ctx)); invalidateErr != nil && err == nil {
+			err = invalidateErr
+		}
+	}
+	return run, err
+}
+
+func (s *bookEmbeddingService) embedStaleBooks(ctx context.Context, run *BookEmbeddingRun) error {
+	if err := s.loadEmbedder(ctx, true); err != nil {
+		return err
+	}
+
+	var afterID int64
+	for {
+		bookIDs, err := s.vectorRepo.ListStaleBooks(ctx, s.embedder.Model(), afterID, 100)
+		if err != nil {
+			return err
+		}
+		if len(bookIDs) == 0 {
+			break
+		}
+
+		for _, bookID := range bookIDs {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			if _, err := s.embedBook(ctx, bookID, true); err != nil {
+				s.logger.WithError(err).WithFields(zap.Int64("book_id", bookID)).Error("Failed to embed book")
+				run.Failed++
+				continue
+			}
+			run.Embedded++
+		}
+		afterID = bookIDs[len(bookIDs)-1]
+	}
+
+	s.logger.WithFields(
+		zap.String("embedding_model", s.embedder.Model()),
+		zap.Int("embedded_books", run.Embedded),
+		zap.Int("failed_books", run.Failed),
+	).Info("Book embedding run finished")
+
+	return nil
+}
+
+// loadEmbedder loads the latest stored statistics of a CorpusEmbedder,
+// once per service. The embedder is fitted to the books when no snapshot is
+// stored and, when refit is allowed, on request or when the number of
+// embeddable books has drifted from the snapshot. Fitting again changes the
+// embedder's model, so every book becomes stale.
+func (s *bookEmbeddingService) loadEmbedder(ctx context.Context, allowRefit bool) error {
+	embedder, ok := s.embedder.(CorpusEmbedder)
+	if !ok || s.loaded {
+		return nil
+	}
+
+	snapshot, err := s.vectorRepo.GetLatestEmbedderSnapshot(ctx, embedder.Family())
+	if err != nil {
+		return fmt.Errorf("failed to get embedder snapshot: %w", err)
+	}
+
+	refit := snapshot == nil
+	if snapshot != nil && allowRefit {
+		books, err := s.vectorRepo.CountEmbeddableBooks(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to count embeddable books: %w", err)
+		}
+		drift := math.Abs(float64(books-snapshot.Documents)) / float64(max(snapshot.Documents, 1))
+		refit = s.config.Refit || drift > s.config.RefitDrift
+	}
+
+	if refit {
+		snapshot, err = embedder.Fit(ctx, s.walkEmbeddableBooks(ctx))
+		if err != nil {
+			return fmt.Errorf("failed to fit embedder to the books: %w", err)
+		}
+		if err := s.vectorRepo.CreateEmbedderSnapshot(ctx, snapshot); err != nil {
+			return fmt.Errorf("failed to store embedder snapshot: %w", err)
+		}
+	}
+
+	if err := embedder.Load(snapshot); err != nil {
+		return err
+	}
+	s.loaded = true
+
+	s.logger.WithFields(
+		zap.String("embedding_model", embedder.Model()),
+		zap.Int("snapshot_books", snapshot.Documents),
+		zap.Bool("fitted", refit),
+	).Info("Embedder statistics loaded")
+	return nil
+}
+
+// walkEmbeddableBooks passes the text of every embeddable book to visit
+func (s *bookEmbeddingService) walkEmbeddableBooks(ctx context.Context) func(visit func(document string)) error {
+	return func(visit func(document string)) error {
+		var afterID int64
+		for {
+			bookIDs, err := s.vectorRepo.ListEmbeddableBooks(ctx, afterID, 100)
+			if err != nil {
+				return err
+			}
+			if len(bookIDs) == 0 {
+				return nil
+			}
+
+			for _, bookID := range bookIDs {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				chapters, err := s.bookRepo.GetChaptersByBookID(ctx, bookID)
+				if err != nil {
+					return fmt.Errorf("failed to get book chapters: %w", err)
+				}
+				var text strings.Builder
+				for _, chapter := range chapters {
+					text.WriteString(chapter.Content)
+					text.WriteString("\n")
+				}
+				visit(text.String())
+			}
+			afterID = bookIDs[len(bookIDs)-1]
+		}
+	}
+}
+
+// chunkChapters splits every chapter into chunks of at most ChunkRunes
+// runes. Chunks never span chapters and end at a sentence end when there
+// is one in the last quarter of the chunk.
+func (s *bookEmbeddingService) chunkChapters(chapters []*domain.Chapter) []string {
+	var chunks []string
+	for _, chapter := range chapters {
+		text := []rune(strings.TrimSpace(chapter.Content))
+		for start := 0; start < len(text); {
+			end := min(start+s.config.ChunkRunes, len(text))
+			if end < len(text) {
+				for i := end - 1; i > end-s.config.ChunkRunes/4; i-- {
+					if isSentenceEnd(text[i]) {
+						end = i + 1
+						break
+					}
+				}
+			}
+
+			if chunk := strings.TrimSpace(string(text[start:end])); chunk != "" {
+				chunks = append(chunks, chunk)
+			}
+			if end == len(text) {
+				break
+			}
+			start = max(end-s.config.ChunkOverlap, start+1)
+		}
+	}
+	return chunks
+}
+
+func isSentenceEnd(r rune) bool {
+	switch r {
+	case '。', '！', '？', '!', '?', '.', '\n':
+		return true
+	}
+	return false
+}
+
+// genreVector hashes the book's genre into the genre vector. Aozora Bunko
+// genres are NDC classes such as "NDC 913", so every prefix of a class
+// number is a feature too, and books in neighbouring classes are similar.
+func genreVector(book *domain.Book) []float64 {
+	if book.Genre == nil || strings.TrimSpace(*book.Genre) == "" {
+		return nil
+	}
+
+	features := make(map[string]float64)
+	for _, field := range strings.Fields(*book.Genre) {
+		isNumber := strings.IndexFunc(field, func(r rune) bool { return !unicode.IsDigit(r) }) < 0
+		if !isNumber {
+			features["genre:"+strings.ToLower(field)] = 1
+			continue
+		}
+		for n := 1; n <= len(field); n++ {
+			features["class:"+field[:n]] = float64(n) / float64(len(field))
+		}
+	}
+	return normalizeVector(hashFeatures(features, domain.BookGenreVectorDims))
+}
+
+// styleVector hashes features of the writing style into the style vector:
+// how scripts follow each other, which separates kanji-heavy prose from
+// kana-heavy prose, and the distribution of sentence lengths and dialogue
+func styleVector(chapters []*domain.Chapter) []float64 {
+	features := make(map[string]float64)
+	for _, chapter := range chapters {
+		var window [3]byte
+		sentenceLength := 0
+		for i, r := range []rune(chapter.Content) {
+			window[0], window[1], window[2] = window[1], window[2], scriptClass(r)
+			if i >= 2 {
+				features["script:"+string(window[:])]++
+			}
+
+			switch {
+			case r == '「' || r == '『':
+				features["dialogue"]++
+			case isSentenceEnd(r):
+				if sentenceLength > 0 {
+					features[fmt.Sprintf("sentence:%d", min(sentenceLength/10, 10))]++
+				}
+				sentenceLength = 0
+			default:
+				sentenceLength++
+			}
+		}
+	}
+	if len(features) == 0 {
+		return nil
+	}
+
+	for feature, count := range features {
+		features[feature] = 1 + math.Log(count)
+	}
+	return normalizeVector(hashFeatures(features, domain.BookStyleVectorDims))
+}
+
+// scriptClass returns a letter for the script of a rune
+func scriptClass(r rune) byte {
+	switch {
+	case unicode.Is(unicode.Han, r):
+		return 'k'
+	case unicode.Is(unicode.Hiragana, r):
+		return 'h'
+	case unicode.Is(unicode.Katakana, r):
+		return 'a'
+	case unicode.IsLetter(r):
+		return 'l'
+	case unicode.IsDigit(r):
+		return 'd'
+	case unicode.IsSpace(r):
+		return 's'
+	default:
+		return 'p'
+	}
+}
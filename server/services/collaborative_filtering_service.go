@@ -0,0 +1,277 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/ponyo877/roudoku/server/domain"
+	"github.com/ponyo877/roudoku/server/pkg/cf"
+	"github.com/ponyo877/roudoku/server/pkg/logger"
+	"github.com/ponyo877/roudoku/server/repository"
+)
+
+// CollaborativeFilteringService defines the interface for training and
+// serving collaborative filtering on implicit feedback
+type CollaborativeFilteringService interface {
+	// Train builds the implicit feedback matrix of all users, computes the
+	// item–item similarities and the matrix factorization, and stores them
+	Train(ctx context.Context) (*domain.CFModel, error)
+	// ScoreBooks returns CF scores of books the user has not interacted
+	// with, best first
+	ScoreBooks(ctx context.Context, userID uuid.UUID, count int) ([]*domain.CFScore, error)
+}
+
+// CollaborativeFilteringConfig controls training
+type CollaborativeFilteringConfig struct {
+	Factors        int
+	Iterations     int
+	Regularization float64
+	// Alpha is the confidence given to observed preferences
+	Alpha float64
+	// Neighbours is how many similar books are kept per book
+	Neighbours     int
+	MinCommonUsers int
+	// MaxBooksPerUser caps the books of one user paired for item–item
+	// similarities
+	MaxBooksPerUser int
+}
+
+// Item–item scores of users outside the latest model are built from the
+// neighbours of this many of their strongest preferences
+const (
+	cfSeedBooks         = 20
+	cfNeighboursPerSeed = 20
+)
+
+// collaborativeFilteringService implements CollaborativeFilteringService
+type collaborativeFilteringService struct {
+	*BaseService
+	interactionRepo repository.UserInteractionRepository
+	cfRepo          repository.CollaborativeFilteringRepository
+	config          CollaborativeFilteringConfig
+}
+
+// NewCollaborativeFilteringService creates a new collaborative filtering service
+func NewCollaborativeFilteringService(
+	interactionRepo repository.UserInteractionRepository,
+	cfRepo repository.CollaborativeFilteringRepository,
+	config CollaborativeFilteringConfig,
+	logger *logger.Logger,
+) CollaborativeFilteringService {
+	if config.Factors <= 0 {
+		config.Factors = 32
+	}
+	if config.Iterations <= 0 {
+		config.Iterations = 15
+	}
+	if config.Regularization <= 0 {
+		config.Regularization = 0.1
+	}
+	if config.Alpha <= 0 {
+		config.Alpha = 40
+	}
+	if config.Neighbours <= 0 {
+		config.Neighbours = 50
+	}
+	if config.MinCommonUsers <= 0 {
+		config.MinCommonUsers = 2
+	}
+	if config.MaxBooksPerUser <= 0 {
+		config.MaxBooksPerUser = 200
+	}
+
+	return &collaborativeFilteringService{
+		BaseService:     NewBaseService(logger),
+		interactionRepo: interactionRepo,
+		cfRepo:          cfRepo,
+		config:          config,
+	}
+}
+
+// Train indexes users and books in ID order, so the same interactions
+// always train the same model
+func (s *collaborativeFilteringService) Train(ctx context.Context) (*domain.CFModel, error) {
+	started := time.Now()
+
+	matrix, err := s.interactionRepo.GetUserBookMatrix(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	userIDs := make([]uuid.UUID, 0, len(matrix))
+	bookIndex := make(map[int64]int)
+	for userID, books := range matrix {
+		userIDs = append(userIDs, userID)
+		for bookID := range books {
+			bookIndex[bookID] = 0
+		}
+	}
+	sort.Slice(userIDs, func(i, j int) bool { return userIDs[i].String() < userIDs[j].String() })
+	bookIDs := make([]int64, 0, len(bookIndex))
+	for bookID := range bookIndex {
+		bookIDs = append(bookIDs, bookID)
+	}
+	sort.Slice(bookIDs, func(i, j int) bool { return bookIDs[i] < bookIDs[j] })
+	for i, bookID := range bookIDs {
+		bookIndex[bookID] = i
+	}
+
+	var entries []cf.Entry
+	for u, userID := range userIDs {
+		for bookID, score := range matrix[userID] {
+			entries = append(entries, cf.Entry{User: u, Item: bookIndex[bookID], Value: score})
+		}
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no interactions to train collaborative filtering on")
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].User != entries[j].User {
+			return entries[i].User < entries[j].User
+		}
+		return entries[i].Item < entries[j].Item
+	})
+
+	factorization, err := cf.TrainALS(ctx, len(userIDs), len(bookIDs), entries, cf.ALSConfig{
+		Factors:        s.config.Factors,
+		Iterations:     s.config.Iterations,
+		Regularization: s.config.Regularization,
+		Alpha:          s.config.Alpha,
+		Seed:           1,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to factorize interactions: %w", err)
+	}
+
+	neighbours := cf.ItemSimilarities(len(bookIDs), entries, cf.ItemSimilarityConfig{
+		Neighbours:      s.config.Neighbours,
+		MinCommonUsers:  s.config.MinCommonUsers,
+		MaxItemsPerUser: s.config.MaxBooksPerUser,
+	})
+
+	model := &domain.CFModel{
+		ID:             uuid.New(),
+		Factors:        s.config.Factors,
+		Iterations:     s.config.Iterations,
+		Regularization: s.config.Regularization,
+		Alpha:          s.config.Alpha,
+		Users:          len(userIDs),
+		Books:          len(bookIDs),
+		Interactions:   len(entries),
+		Loss:           factorization.Loss,
+		TrainedAt:      time.Now(),
+	}
+
+	userFactors := make(map[uuid.UUID][]float64, len(userIDs))
+	for u, userID := range userIDs {
+		userFactors[userID] = factorization.Users[u]
+	}
+	bookFactors := make(map[int64][]float64, len(bookIDs))
+	var similarities []*domain.BookSimilarity
+	for i, bookID := range bookIDs {
+		bookFactors[bookID] = factorization.Items[i]
+		for _, neighbour := range neighbours[i] {
+			similarities = append(similarities, &domain.BookSimilarity{
+				BookID:        bookID,
+				SimilarBookID: bookIDs[neighbour.Item],
+				Similarity:    neighbour.Similarity,
+				CommonUsers:   neighbour.CommonUsers,
+				CalculatedAt:  model.TrainedAt,
+			})
+		}
+	}
+
+	if err := s.cfRepo.SaveModel(ctx, model, userFactors, bookFactors, similarities); err != nil {
+		return nil, err
+	}
+
+	s.logger.WithFields(
+		zap.String("model_id", model.ID.String()),
+		zap.Int("users", model.Users),
+		zap.Int("books", model.Books),
+		zap.Int("interactions", model.Interactions),
+		zap.Int("book_similarities", len(similarities)),
+		zap.Float64("loss", model.Loss),
+		zap.Duration("duration", time.Since(started)),
+	).Info("Collaborative filtering model trained")
+
+	return model, nil
+}
+
+// ScoreBooks scores users of the latest model by their factors. Users who
+// started reading after it was trained are scored by the item–item
+// neighbours of the books they interacted with: the similarity-weighted
+// average of their preferences.
+func (s *collaborativeFilteringService) ScoreBooks(ctx context.Context, userID uuid.UUID, count int) ([]*domain.CFScore, error) {
+	matrix, err := s.interactionRepo.GetUserBookMatrix(ctx, []uuid.UUID{userID})
+	if err != nil {
+		return nil, err
+	}
+	preferences := matrix[userID]
+	seen := make([]int64, 0, len(preferences))
+	for bookID := range preferences {
+		seen = append(seen, bookID)
+	}
+
+	factors, err := s.cfRepo.GetUserFactors(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if factors != nil {
+		return s.cfRepo.ScoreBooksByFactors(ctx, factors, seen, count)
+	}
+	if len(preferences) == 0 {
+		return []*domain.CFScore{}, nil
+	}
+
+	// Start from the strongest preferences
+	sort.Slice(seen, func(i, j int) bool {
+		if preferences[seen[i]] != preferences[seen[j]] {
+			return preferences[seen[i]] > preferences[seen[j]]
+		}
+		return seen[i] < seen[j]
+	})
+	seeds := seen[:min(cfSeedBooks, len(seen))]
+
+	neighbours, err := s.cfRepo.GetBookNeighbours(ctx, seeds, cfNeighboursPerSeed)
+	if err != nil {
+		return nil, err
+	}
+
+	weighted := make(map[int64]float64)
+	weights := make(map[int64]float64)
+	for _, neighbour := range neighbours {
+		if _, ok := preferences[neighbour.SimilarBookID]; ok {
+			continue
+		}
+		weighted[neighbour.SimilarBookID] += neighbour.Similarity * preferences[neighbour.BookID]
+		weights[neighbour.SimilarBookID] += math.Abs(neighbour.Similarity)
+	}
+
+	scores := make([]*domain.CFScore, 0, len(weighted))
+	for bookID, sum := range weighted {
+		// Books backed by a single weak neighbour are discounted
+		confidence := weights[bookID] / (weights[bookID] + 1)
+		scores = append(scores, &domain.CFScore{
+			BookID: bookID,
+			Score:  sum / weights[bookID] * confidence,
+			Source: domain.CFSourceItemItem,
+		})
+	}
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].Score != scores[j].Score {
+			return scores[i].Score > scores[j].Score
+		}
+		return scores[i].BookID < scores[j].BookID
+	})
+	if len(scores) > count {
+		scores = scores[:count]
+	}
+	return scores, nil
+}
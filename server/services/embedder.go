@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+
+	"github.com/ponyo877/roudoku/server/domain"
+)
+
+// Embedder provider names
+const (
+	EmbedderLocal  = "local"
+	EmbedderVertex = "vertex"
+)
+
+// Embedder turns text into fixed-size vectors whose cosine similarity
+// reflects the similarity of the texts
+type Embedder interface {
+	// Name returns the provider name used in configuration
+	Name() string
+	// Model identifies the model and its settings. It is stored with book
+	// vectors, and books are re-embedded when it changes.
+	Model() string
+	Dimensions() int
+	// Embed returns one vector per text, in order. Callers keep batches
+	// within the provider's request limits.
+	Embed(ctx context.Context, texts []string) ([][]float64, error)
+}
+
+// CorpusEmbedder is an Embedder whose features are weighted by statistics
+// of the whole book corpus. The statistics are fitted once and stored as a
+// snapshot; its Model names the loaded snapshot, so all books are embedded
+// against the same statistics until the embedder is fitted again.
+type CorpusEmbedder interface {
+	Embedder
+	// Family names the features the statistics apply to. Snapshots of
+	// another family cannot be loaded.
+	Family() string
+	// Fit computes the corpus statistics from the documents that walk
+	// passes to visit, one document per book
+	Fit(ctx context.Context, walk func(visit func(document string)) error) (*domain.EmbedderSnapshot, error)
+	// Load uses the statistics of a stored snapshot
+	Load(snapshot *domain.EmbedderSnapshot) error
+}
+
+// hashFeatures projects weighted features into a vector of the given size
+// with the hashing trick: each feature adds its weight, with a sign taken
+// from its hash, to one component
+func hashFeatures(features map[string]float64, dims int) []float64 {
+	vector := make([]float64, dims)
+	for feature, weight := range features {
+		hash := fnv.New64a()
+		hash.Write([]byte(feature))
+		sum := hash.Sum64()
+		if sum>>63 == 1 {
+			weight = -weight
+		}
+		vector[sum%uint64(dims)] += weight
+	}
+	return vector
+}
+
+// normalizeVector scales a vector to unit length in place. The zero vector
+// is left unchanged.
+func normalizeVector(vector []float64) []float64 {
+	var norm float64
+	for _, v := range vector {
+		norm += v * v
+	}
+	if norm == 0 {
+		return vector
+	}
+	norm = math.Sqrt(norm)
+	for i := range vector {
+		vector[i] /= norm
+	}
+	return vector
+}
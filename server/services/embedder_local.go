@@ -0,0 +1,231 @@
+package services
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"time"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/ponyo877/roudoku/server/domain"
+)
+
+// localEmbedderVersion changes whenever the features below change, so that
+// stored vectors are recomputed
+const localEmbedderVersion = 2
+
+// localNGramWeights weighs character n-grams by length. Single characters
+// mostly carry script and kana grammar; longer n-grams carry vocabulary.
+var localNGramWeights = map[int]float64{1: 0.5, 2: 1.0, 3: 1.0}
+
+const (
+	// localFeatureBits sizes the hashed feature space in which document
+	// frequencies are counted. It is large enough that distinct n-grams
+	// rarely share a bucket.
+	localFeatureBits = 20
+	// localProjectionNonzeros is how many vector components each feature
+	// adds to in the random projection
+	localProjectionNonzeros = 4
+)
+
+// localEmbedder is an offline embedder for development, CI and deployments
+// without Vertex AI. It weighs the character 1- to 3-grams of a text, which
+// need no word segmentation for Japanese, by sublinear term frequency and
+// by inverse document frequency over the books of its snapshot, and
+// reduces the TF-IDF vector to the vector size with a sparse random
+// projection that preserves cosine similarity.
+type localEmbedder struct {
+	dims int
+	// snapshot is the loaded corpus statistics, whose documentFrequency is
+	// counted per hashed feature
+	snapshot          *domain.EmbedderSnapshot
+	documentFrequency []uint32
+}
+
+// NewLocalEmbedder creates the offline character n-gram embedder
+func NewLocalEmbedder(dims int) CorpusEmbedder {
+	return &localEmbedder{dims: dims}
+}
+
+// Name returns the provider name
+func (e *localEmbedder) Name() string {
+	return EmbedderLocal
+}
+
+// Family returns the feature version and vector size
+func (e *localEmbedder) Family() string {
+	return fmt.Sprintf("local-char-ngram-tfidf-v%d-%d", localEmbedderVersion, e.dims)
+}
+
+// Model returns the family and the version of the loaded snapshot, which
+// changes only when the embedder is fitted again
+func (e *localEmbedder) Model() string {
+	if e.snapshot == nil {
+		return e.Family()
+	}
+	return fmt.Sprintf("%s-s%d", e.Family(), e.snapshot.Version)
+}
+
+// Dimensions returns the vector size
+func (e *localEmbedder) Dimensions() int {
+	return e.dims
+}
+
+// Fit counts in how many documents each feature occurs. The counts are
+// stored gzip-compressed, since most features of the space do not occur.
+func (e *localEmbedder) Fit(ctx context.Context, walk func(visit func(document string)) error) (*domain.EmbedderSnapshot, error) {
+	documentFrequency := make([]uint32, 1<<localFeatureBits)
+	documents := 0
+	err := walk(func(document string) {
+		seen := make(map[uint32]bool)
+		for _, run := range textRuns(document) {
+			for n := range localNGramWeights {
+				for start := 0; start+n <= len(run); start++ {
+					seen[localFeature(run[start:start+n])] = true
+				}
+			}
+		}
+		for feature := range seen {
+			documentFrequency[feature]++
+		}
+		documents++
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var statistics bytes.Buffer
+	writer := gzip.NewWriter(&statistics)
+	if err := binary.Write(writer, binary.LittleEndian, documentFrequency); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return &domain.EmbedderSnapshot{
+		Family:     e.Family(),
+		Documents:  documents,
+		Statistics: statistics.Bytes(),
+		CreatedAt:  time.Now(),
+	}, nil
+}
+
+// Load reads the document frequencies of a snapshot
+func (e *localEmbedder) Load(snapshot *domain.EmbedderSnapshot) error {
+	if snapshot.Family != e.Family() {
+		return fmt.Errorf("embedder snapshot of %s cannot be loaded into %s", snapshot.Family, e.Family())
+	}
+
+	reader, err := gzip.NewReader(bytes.NewReader(snapshot.Statistics))
+	if err != nil {
+		return fmt.Errorf("invalid embedder snapshot: %w", err)
+	}
+	documentFrequency := make([]uint32, 1<<localFeatureBits)
+	if err := binary.Read(reader, binary.LittleEndian, documentFrequency); err != nil {
+		return fmt.Errorf("invalid embedder snapshot: %w", err)
+	}
+
+	e.snapshot = snapshot
+	e.documentFrequency = documentFrequency
+	return nil
+}
+
+// Embed returns the unit-length TF-IDF vector of each text, projected to
+// the vector size
+func (e *localEmbedder) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	vectors := make([][]float64, len(texts))
+	for i, text := range texts {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		counts := make(map[uint32]float64)
+		for _, run := range textRuns(text) {
+			for n, weight := range localNGramWeights {
+				for start := 0; start+n <= len(run); start++ {
+					counts[localFeature(run[start:start+n])] += weight
+				}
+			}
+		}
+
+		vector := make([]float64, e.dims)
+		for feature, count := range counts {
+			e.project(vector, feature, (1+math.Log(count))*e.idf(feature))
+		}
+		vectors[i] = normalizeVector(vector)
+	}
+	return vectors, nil
+}
+
+// idf returns the smoothed inverse document frequency of a feature, which
+// is 1 for every feature before a snapshot is loaded
+func (e *localEmbedder) idf(feature uint32) float64 {
+	if e.snapshot == nil {
+		return 1
+	}
+	return 1 + math.Log(float64(1+e.snapshot.Documents)/float64(1+e.documentFrequency[feature]))
+}
+
+// project adds a weighted feature to the vector. Each feature adds to a few
+// components chosen, with signs, by hashing the feature, so the projection
+// is fixed and needs no stored matrix.
+func (e *localEmbedder) project(vector []float64, feature uint32, weight float64) {
+	weight /= math.Sqrt(localProjectionNonzeros)
+	for k := uint64(0); k < localProjectionNonzeros; k++ {
+		h := splitMix64(uint64(feature)*localProjectionNonzeros + k)
+		if h>>63 == 1 {
+			vector[h%uint64(e.dims)] -= weight
+		} else {
+			vector[h%uint64(e.dims)] += weight
+		}
+	}
+}
+
+// localFeature hashes an n-gram into the feature space
+func localFeature(gram []rune) uint32 {
+	hash := fnv.New64a()
+	hash.Write([]byte(string(gram)))
+	return uint32(hash.Sum64() & (1<<localFeatureBits - 1))
+}
+
+// splitMix64 scrambles x into a well-distributed 64-bit value
+func splitMix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ x>>30) * 0xbf58476d1ce4e5b9
+	x = (x ^ x>>27) * 0x94d049bb133111eb
+	return x ^ x>>31
+}
+
+// textRuns normalizes full-width and half-width forms and case, and splits
+// the text at whitespace and punctuation so n-grams do not span them
+func textRuns(text string) [][]rune {
+	text = strings.ToLower(norm.NFKC.String(text))
+
+	var runs [][]rune
+	var run []rune
+	for _, r := range text {
+		if unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r) {
+			if len(run) > 0 {
+				runs = append(runs, run)
+				run = nil
+			}
+			continue
+		}
+		run = append(run, r)
+	}
+	if len(run) > 0 {
+		runs = append(runs, run)
+	}
+	return runs
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"google.golang.org/api/option"
+	htransport "google.golang.org/api/transport/http"
+)
+
+const (
+	vertexDefaultLocation = "asia-northeast1"
+	// The multilingual model handles Japanese text and returns 768
+	// dimensions by default
+	vertexDefaultModel = "text-multilingual-embedding-002"
+	vertexTaskType     = "SEMANTIC_SIMILARITY"
+)
+
+// VertexEmbedderConfig locates the Vertex AI embedding model
+type VertexEmbedderConfig struct {
+	ProjectID       string
+	Location        string
+	Model           string
+	CredentialsPath string
+	Dimensions      int
+}
+
+// vertexEmbedder embeds text with a Vertex AI text embedding model
+type vertexEmbedder struct {
+	config     VertexEmbedderConfig
+	endpoint   string
+	httpClient *http.Client
+}
+
+// NewVertexEmbedder creates a Vertex AI embedder
+func NewVertexEmbedder(config VertexEmbedderConfig) (Embedder, error) {
+	if config.ProjectID == "" {
+		return nil, fmt.Errorf("vertex embedder requires a Google Cloud project ID")
+	}
+	if config.Location == "" {
+		config.Location = vertexDefaultLocation
+	}
+	if config.Model == "" {
+		config.Model = vertexDefaultModel
+	}
+
+	opts := []option.ClientOption{option.WithScopes("https://www.googleapis.com/auth/cloud-platform")}
+	if config.CredentialsPath != "" {
+		opts = append(opts, option.WithCredentialsFile(config.CredentialsPath))
+	}
+	// Without a credentials file, default credentials are used (for production with service account)
+
+	httpClient, _, err := htransport.NewClient(context.Background(), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Vertex AI HTTP client: %w", err)
+	}
+
+	endpoint := fmt.Sprintf("https://%s-aiplatform.googleapis.com/v1/projects/%s/locations/%s/publishers/google/models/%s:predict",
+		config.Location, config.ProjectID, config.Location, config.Model)
+
+	return &vertexEmbedder{config: config, endpoint: endpoint, httpClient: httpClient}, nil
+}
+
+// Name returns the provider name
+func (e *vertexEmbedder) Name() string {
+	return EmbedderVertex
+}
+
+// Model returns the Vertex AI model and vector size
+func (e *vertexEmbedder) Model() string {
+	return fmt.Sprintf("vertex-%s-%d", e.config.Model, e.config.Dimensions)
+}
+
+// Dimensions returns the vector size
+func (e *vertexEmbedder) Dimensions() int {
+	return e.config.Dimensions
+}
+
+type vertexPredictRequest struct {
+	Instances  []vertexInstance `json:"instances"`
+	Parameters struct {
+		AutoTruncate         bool `json:"autoTruncate"`
+		OutputDimensionality int  `json:"outputDimensionality,omitempty"`
+	} `json:"parameters"`
+}
+
+type vertexInstance struct {
+	Content  string `json:"content"`
+	TaskType string `json:"task_type"`
+}
+
+type vertexPredictResponse struct {
+	Predictions []struct {
+		Embeddings struct {
+			Values []float64 `json:"values"`
+		} `json:"embeddings"`
+	} `json:"predictions"`
+}
+
+// Embed sends the texts in one prediction request. Texts longer than the
+// model's input limit are truncated by the service.
+func (e *vertexEmbedder) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	if len(texts) == 0 {
+		return [][]float64{}, nil
+	}
+
+	var body vertexPredictRequest
+	for _, text := range texts {
+		body.Instances = append(body.Instances, vertexInstance{Content: text, TaskType: vertexTaskType})
+	}
+	body.Parameters.AutoTruncate = true
+	body.Parameters.OutputDimensionality = e.config.Dimensions
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode Vertex AI request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Vertex AI request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("vertex AI embedding failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("vertex AI embedding failed with status %d: %s", resp.StatusCode, message)
+	}
+
+	var result vertexPredictResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode Vertex AI response: %w", err)
+	}
+	if len(result.Predictions) != len(texts) {
+		return nil, fmt.Errorf("vertex AI returned %d embeddings for %d texts", len(result.Predictions), len(texts))
+	}
+
+	vectors := make([][]float64, len(texts))
+	for i, prediction := range result.Predictions {
+		if len(prediction.Embeddings.Values) != e.config.Dimensions {
+			return nil, fmt.Errorf("vertex AI returned %d dimensions, want %d", len(prediction.Embeddings.Values), e.config.Dimensions)
+		}
+		vectors[i] = prediction.Embeddings.Values
+	}
+	return vectors, nil
+}
@@ -151,9 +151,13 @@ func (s *notificationService) DeactivateToken(ctx context.Context, tokenID uuid.
 }
 
 func (s *notificationService) GetNotificationPreferences(ctx context.Context, userID uuid.UUID) (*domain.NotificationPreferences, error) {
-	prefs, err := s.notificationPrefRepo.GetByUserID(ctx, userID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get notification preferences: %w", err)
+	var prefs *domain.NotificationPreferences
+	if s.notificationPrefRepo != nil {
+		var err error
+		prefs, err = s.notificationPrefRepo.GetByUserID(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get notification preferences: %w", err)
+		}
 	}
 
 	// Create default preferences if none exist
@@ -177,9 +181,11 @@ func (s *notificationService) GetNotificationPreferences(ctx context.Context, us
 			UpdatedAt:             now,
 		}
 
-		err = s.notificationPrefRepo.Create(ctx, prefs)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create default notification preferences: %w", err)
+		// Defaults are used without being persisted when no repository is configured
+		if s.notificationPrefRepo != nil {
+			if err := s.notificationPrefRepo.Create(ctx, prefs); err != nil {
+				return nil, fmt.Errorf("failed to create default notification preferences: %w", err)
+			}
 		}
 	}
 
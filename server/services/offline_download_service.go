@@ -0,0 +1,786 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ponyo877/roudoku/server/domain"
+	"github.com/ponyo877/roudoku/server/dto"
+	apperrors "github.com/ponyo877/roudoku/server/pkg/errors"
+	"github.com/ponyo877/roudoku/server/pkg/logger"
+	"github.com/ponyo877/roudoku/server/pkg/ssml"
+	"github.com/ponyo877/roudoku/server/pkg/storage"
+	"github.com/ponyo877/roudoku/server/repository"
+)
+
+// Reasons recorded when packages are revoked
+const (
+	revokeReasonSubscriptionEnded = "subscription_ended"
+	revokeReasonPlanLimit         = "plan_limit"
+)
+
+const (
+	// offlinePackageMaxAttempts is how often building a package is tried
+	// before it fails
+	offlinePackageMaxAttempts = 3
+	// offlinePackageLockTimeout is how long a package may be built before
+	// another worker takes it over
+	offlinePackageLockTimeout = 10 * time.Minute
+)
+
+// OfflineDownloadService packages books for offline reading and tracks the
+// packages each user holds against their plan's limit
+type OfflineDownloadService interface {
+	// CreateDownload reserves a slot and queues the package to be built;
+	// the response is pending until GetDownload reports it active
+	CreateDownload(ctx context.Context, userID uuid.UUID, req *dto.CreateOfflineDownloadRequest) (*dto.OfflineDownloadResponse, error)
+	ListDownloads(ctx context.Context, userID uuid.UUID) (*dto.OfflineDownloadListResponse, error)
+	GetDownload(ctx context.Context, userID uuid.UUID, downloadID uuid.UUID) (*dto.OfflineDownloadResponse, error)
+	DeleteDownload(ctx context.Context, userID uuid.UUID, downloadID uuid.UUID) error
+	// RevokeUserDownloads revokes every active package of a user and returns how many were revoked
+	RevokeUserDownloads(ctx context.Context, userID uuid.UUID, reason string) (int, error)
+	// SigningKey returns the public key that verifies package manifests
+	SigningKey(ctx context.Context) *dto.OfflineSigningKeyResponse
+	// RunRevoker periodically revokes the packages of users whose
+	// subscription has ended until ctx is cancelled
+	RunRevoker(ctx context.Context)
+	// RunPackager builds pending packages until ctx is cancelled
+	RunPackager(ctx context.Context)
+}
+
+// OfflineDownloadConfig configures offline download packages
+type OfflineDownloadConfig struct {
+	// SigningKey is the secret the Ed25519 key signing package manifests is
+	// derived from
+	SigningKey string
+	// URLExpiry is how long package download links stay valid
+	URLExpiry time.Duration
+	// RevokeInterval is how often subscriptions are checked for ended access
+	RevokeInterval time.Duration
+	// PollInterval is how often the packager looks for pending packages
+	PollInterval time.Duration
+}
+
+// offlineDownloadService implements OfflineDownloadService
+type offlineDownloadService struct {
+	*BaseService
+	downloadRepo        repository.OfflineDownloadRepository
+	bookRepo            repository.BookRepository
+	audiobookJobRepo    repository.AudiobookJobRepository
+	audioFileRepo       repository.AudioFileRepository
+	subscriptionRepo    repository.UserSubscriptionRepository
+	subscriptionService SubscriptionService
+	lexicons            PronunciationService
+	blobStore           storage.BlobStore
+	config              OfflineDownloadConfig
+	signer              ed25519.PrivateKey
+	keyID               string
+}
+
+// NewOfflineDownloadService creates a new offline download service
+func NewOfflineDownloadService(
+	downloadRepo repository.OfflineDownloadRepository,
+	bookRepo repository.BookRepository,
+	audiobookJobRepo repository.AudiobookJobRepository,
+	audioFileRepo repository.AudioFileRepository,
+	subscriptionRepo repository.UserSubscriptionRepository,
+	subscriptionService SubscriptionService,
+	lexicons PronunciationService,
+	blobStore storage.BlobStore,
+	config OfflineDownloadConfig,
+	logger *logger.Logger,
+) (OfflineDownloadService, error) {
+	if config.SigningKey == "" {
+		return nil, errors.New("offline download signing key is not configured")
+	}
+	if config.URLExpiry <= 0 {
+		config.URLExpiry = 15 * time.Minute
+	}
+	if config.RevokeInterval <= 0 {
+		config.RevokeInterval = 15 * time.Minute
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = 5 * time.Second
+	}
+
+	seed := sha256.Sum256([]byte(config.SigningKey))
+	signer := ed25519.NewKeyFromSeed(seed[:])
+	keySum := sha256.Sum256(signer.Public().(ed25519.PublicKey))
+
+	return &offlineDownloadService{
+		BaseService:         NewBaseService(logger),
+		downloadRepo:        downloadRepo,
+		bookRepo:            bookRepo,
+		audiobookJobRepo:    audiobookJobRepo,
+		audioFileRepo:       audioFileRepo,
+		subscriptionRepo:    subscriptionRepo,
+		subscriptionService: subscriptionService,
+		lexicons:            lexicons,
+		blobStore:           blobStore,
+		config:              config,
+		signer:              signer,
+		keyID:               hex.EncodeToString(keySum[:8]),
+	}, nil
+}
+
+// CreateDownload checks that the user may download the book and queues its
+// package, which RunPackager builds with the chapter text, ruby, narration
+// and a signed manifest. Downloading a book the user already holds rebuilds
+// its package without using another slot.
+func (s *offlineDownloadService) CreateDownload(ctx context.Context, userID uuid.UUID, req *dto.CreateOfflineDownloadRequest) (*dto.OfflineDownloadResponse, error) {
+	s.logger.Info("Creating offline download")
+
+	if err := s.ValidateStruct(req); err != nil {
+		return nil, err
+	}
+
+	quota, err := s.subscriptionService.GetOfflineDownloadQuota(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get offline download quota: %w", err)
+	}
+	if !quota.Available {
+		return nil, apperrors.New("FORBIDDEN", "Offline downloads require a subscription", http.StatusForbidden)
+	}
+
+	book, err := s.bookRepo.GetByID(ctx, req.BookID)
+	if err != nil {
+		return nil, err
+	}
+	if book.IsPremium {
+		allowed, err := s.subscriptionService.CanAccessPremiumBook(ctx, userID, book.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check premium book access: %w", err)
+		}
+		if !allowed {
+			return nil, apperrors.New("FORBIDDEN", "This book is not included in your plan", http.StatusForbidden)
+		}
+	}
+
+	subscription, err := s.subscriptionRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user subscription: %w", err)
+	}
+	if subscription == nil {
+		return nil, apperrors.New("FORBIDDEN", "Offline downloads require a subscription", http.StatusForbidden)
+	}
+
+	chapters, err := s.bookRepo.GetChaptersByBookID(ctx, book.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get book chapters: %w", err)
+	}
+	if len(chapters) == 0 {
+		return nil, apperrors.BadRequest("Book has no chapters to download", nil)
+	}
+
+	download, err := s.downloadRepo.GetActiveByBook(ctx, userID, book.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get offline download: %w", err)
+	}
+
+	now := time.Now()
+	if download != nil {
+		if download.Status == domain.OfflineDownloadStatusPending {
+			return s.buildDownloadResponse(ctx, download)
+		}
+		download.Status = domain.OfflineDownloadStatusPending
+		download.SkipAudio = req.SkipAudio
+		download.Attempts = 0
+		download.LockedAt = nil
+		download.ErrorMessage = nil
+		download.ExpiresAt = subscription.CurrentPeriodEnd
+		download.UpdatedAt = now
+		if err := s.downloadRepo.Update(ctx, download); err != nil {
+			return nil, fmt.Errorf("failed to queue offline download: %w", err)
+		}
+		return s.buildDownloadResponse(ctx, download)
+	}
+
+	// A new package takes a slot of the plan's limit. CreateWithinLimit
+	// enforces the limit again for concurrent requests.
+	allowed, err := s.subscriptionService.CanDownloadOffline(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check offline download quota: %w", err)
+	}
+	if !allowed {
+		return nil, s.downloadLimitReached(quota)
+	}
+
+	download = &domain.OfflineDownload{
+		ID:        uuid.New(),
+		UserID:    userID,
+		BookID:    book.ID,
+		Status:    domain.OfflineDownloadStatusPending,
+		SkipAudio: req.SkipAudio,
+		ExpiresAt: subscription.CurrentPeriodEnd,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	download.PackagePath = fmt.Sprintf("downloads/%s/%s.zip", userID, download.ID)
+
+	created, err := s.downloadRepo.CreateWithinLimit(ctx, download, quota.Limit)
+	if err != nil {
+		if errors.Is(err, repository.ErrDuplicateKey) {
+			return nil, apperrors.New("CONFLICT", "The book is already being downloaded", http.StatusConflict)
+		}
+		return nil, fmt.Errorf("failed to record offline download: %w", err)
+	}
+	if !created {
+		return nil, s.downloadLimitReached(quota)
+	}
+
+	if err := s.subscriptionService.TrackFeatureUsage(ctx, userID, "offline_download", 1, 0); err != nil {
+		s.logger.WithError(err).Error("Failed to record offline download usage")
+	}
+
+	return s.buildDownloadResponse(ctx, download)
+}
+
+// downloadLimitReached reports that the user holds as many packages as
+// their plan allows
+func (s *offlineDownloadService) downloadLimitReached(quota *dto.OfflineDownloadQuotaResponse) error {
+	return apperrors.QuotaExceeded("Offline download limit reached; remove a downloaded book first", map[string]interface{}{
+		"limit":  quota.Limit,
+		"active": quota.Active,
+	})
+}
+
+// ListDownloads returns the user's active packages and remaining allowance
+func (s *offlineDownloadService) ListDownloads(ctx context.Context, userID uuid.UUID) (*dto.OfflineDownloadListResponse, error) {
+	downloads, err := s.downloadRepo.GetActiveByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get offline downloads: %w", err)
+	}
+
+	quota, err := s.subscriptionService.GetOfflineDownloadQuota(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get offline download quota: %w", err)
+	}
+
+	response := &dto.OfflineDownloadListResponse{
+		Downloads: make([]dto.OfflineDownloadResponse, 0, len(downloads)),
+		Quota:     *quota,
+	}
+	for _, download := range downloads {
+		item, err := s.buildDownloadResponse(ctx, download)
+		if err != nil {
+			return nil, err
+		}
+		response.Downloads = append(response.Downloads, *item)
+	}
+	return response, nil
+}
+
+// GetDownload returns a package's status. Clients call it before the
+// package expires to learn whether it was revoked or extended.
+func (s *offlineDownloadService) GetDownload(ctx context.Context, userID uuid.UUID, downloadID uuid.UUID) (*dto.OfflineDownloadResponse, error) {
+	download, err := s.getDownload(ctx, userID, downloadID)
+	if err != nil {
+		return nil, err
+	}
+	return s.buildDownloadResponse(ctx, download)
+}
+
+// DeleteDownload removes a package the user no longer needs, freeing its
+// slot. A pending package is dropped once the packager finishes it.
+func (s *offlineDownloadService) DeleteDownload(ctx context.Context, userID uuid.UUID, downloadID uuid.UUID) error {
+	download, err := s.getDownload(ctx, userID, downloadID)
+	if err != nil {
+		return err
+	}
+	if !download.IsHeld() {
+		return nil
+	}
+
+	s.deletePackage(ctx, download)
+	download.Status = domain.OfflineDownloadStatusDeleted
+	download.UpdatedAt = time.Now()
+	if err := s.downloadRepo.Update(ctx, download); err != nil {
+		return fmt.Errorf("failed to delete offline download: %w", err)
+	}
+	return nil
+}
+
+// RevokeUserDownloads revokes every active package of a user
+func (s *offlineDownloadService) RevokeUserDownloads(ctx context.Context, userID uuid.UUID, reason string) (int, error) {
+	downloads, err := s.downloadRepo.GetActiveByUserID(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get offline downloads: %w", err)
+	}
+	return s.revoke(ctx, downloads, reason)
+}
+
+// RunRevoker checks subscriptions at start and then every revoke interval
+func (s *offlineDownloadService) RunRevoker(ctx context.Context) {
+	ticker := time.NewTicker(s.config.RevokeInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.revokeEnded(ctx); err != nil && ctx.Err() == nil {
+			s.logger.WithError(err).Error("Failed to revoke offline downloads")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SigningKey returns the public half of the manifest signing key
+func (s *offlineDownloadService) SigningKey(ctx context.Context) *dto.OfflineSigningKeyResponse {
+	return &dto.OfflineSigningKeyResponse{
+		Algorithm: "Ed25519",
+		KeyID:     s.keyID,
+		PublicKey: base64.StdEncoding.EncodeToString(s.signer.Public().(ed25519.PublicKey)),
+	}
+}
+
+// RunPackager drains the pending packages, then waits for the next poll
+func (s *offlineDownloadService) RunPackager(ctx context.Context) {
+	ticker := time.NewTicker(s.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil {
+			processed, err := s.packageNext(ctx)
+			if err != nil {
+				s.logger.WithError(err).Error("Failed to build offline package")
+				break
+			}
+			if !processed {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// packageNext builds one pending package. It reports false when none was
+// waiting. A failed build is retried after the lock timeout until the
+// package has used all its attempts.
+func (s *offlineDownloadService) packageNext(ctx context.Context) (bool, error) {
+	download, err := s.downloadRepo.ClaimPending(ctx, time.Now().Add(-offlinePackageLockTimeout))
+	if err != nil {
+		return false, fmt.Errorf("failed to claim offline download: %w", err)
+	}
+	if download == nil {
+		return false, nil
+	}
+
+	buildErr := s.buildPackage(ctx, download)
+	if buildErr != nil && ctx.Err() != nil {
+		// Shutting down; the package is claimed again after the lock timeout
+		return true, nil
+	}
+
+	download.UpdatedAt = time.Now()
+	if buildErr == nil {
+		download.Status = domain.OfflineDownloadStatusActive
+		download.LockedAt = nil
+		download.ErrorMessage = nil
+	} else {
+		s.logger.WithError(buildErr).Warn("Failed to build offline package")
+		message := buildErr.Error()
+		download.ErrorMessage = &message
+		if download.Attempts >= offlinePackageMaxAttempts {
+			download.Status = domain.OfflineDownloadStatusFailed
+			download.LockedAt = nil
+		}
+	}
+
+	updated, err := s.downloadRepo.UpdatePending(ctx, download)
+	if err != nil {
+		return true, fmt.Errorf("failed to record offline package: %w", err)
+	}
+	if !updated || download.Status == domain.OfflineDownloadStatusFailed {
+		// Revoked or deleted while it was built, or given up on
+		s.deletePackage(ctx, download)
+	}
+	return true, nil
+}
+
+// buildPackage loads the book and writes the package of a claimed download
+func (s *offlineDownloadService) buildPackage(ctx context.Context, download *domain.OfflineDownload) error {
+	book, err := s.bookRepo.GetByID(ctx, download.BookID)
+	if err != nil {
+		return fmt.Errorf("failed to get book: %w", err)
+	}
+	chapters, err := s.bookRepo.GetChaptersByBookID(ctx, book.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get book chapters: %w", err)
+	}
+
+	var audioFiles map[uuid.UUID]*domain.AudioFile
+	if !download.SkipAudio {
+		audioFiles, err = s.chapterAudio(ctx, download.UserID, book.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	manifest := &domain.OfflinePackageManifest{
+		Version:    domain.OfflinePackageVersion,
+		KeyID:      s.keyID,
+		DownloadID: download.ID,
+		UserID:     download.UserID,
+		Book: domain.OfflinePackageBook{
+			ID:     book.ID,
+			Title:  book.Title,
+			Author: book.Author,
+			Genre:  book.Genre,
+		},
+		IssuedAt:  time.Now(),
+		ExpiresAt: download.ExpiresAt,
+	}
+	return s.storePackage(ctx, download, manifest, chapters, audioFiles)
+}
+
+// revokeEnded revokes the packages of users who lost offline access and the
+// oldest packages of users now over a smaller limit. Packages of users who
+// still have access are extended to the end of their current period.
+func (s *offlineDownloadService) revokeEnded(ctx context.Context) error {
+	userIDs, err := s.downloadRepo.GetUsersWithActiveDownloads(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get users with offline downloads: %w", err)
+	}
+
+	for _, userID := range userIDs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		subscription, err := s.subscriptionRepo.GetByUserID(ctx, userID)
+		if err != nil {
+			s.logger.WithError(err).Error("Failed to get user subscription")
+			continue
+		}
+
+		if subscription == nil || !subscription.CanAccessFeature("offline_downloads") {
+			if _, err := s.RevokeUserDownloads(ctx, userID, revokeReasonSubscriptionEnded); err != nil {
+				s.logger.WithError(err).Error("Failed to revoke offline downloads")
+			}
+			continue
+		}
+
+		if err := s.downloadRepo.ExtendActive(ctx, userID, subscription.CurrentPeriodEnd); err != nil {
+			s.logger.WithError(err).Error("Failed to extend offline downloads")
+		}
+
+		limit := subscription.Plan.MaxOfflineDownloads
+		if limit == -1 {
+			continue
+		}
+		downloads, err := s.downloadRepo.GetActiveByUserID(ctx, userID)
+		if err != nil {
+			s.logger.WithError(err).Error("Failed to get offline downloads")
+			continue
+		}
+		if len(downloads) > limit {
+			// Newest first, so the most recent downloads are kept
+			if _, err := s.revoke(ctx, downloads[limit:], revokeReasonPlanLimit); err != nil {
+				s.logger.WithError(err).Error("Failed to revoke offline downloads")
+			}
+		}
+	}
+
+	return nil
+}
+
+// revoke marks packages revoked and removes them from storage
+func (s *offlineDownloadService) revoke(ctx context.Context, downloads []*domain.OfflineDownload, reason string) (int, error) {
+	revoked := 0
+	for _, download := range downloads {
+		s.deletePackage(ctx, download)
+
+		now := time.Now()
+		download.Status = domain.OfflineDownloadStatusRevoked
+		download.RevokedAt = &now
+		download.RevokeReason = &reason
+		download.UpdatedAt = now
+		if err := s.downloadRepo.Update(ctx, download); err != nil {
+			return revoked, fmt.Errorf("failed to revoke offline download: %w", err)
+		}
+		revoked++
+	}
+
+	if revoked > 0 {
+		s.logger.Info(fmt.Sprintf("Revoked %d offline downloads (%s)", revoked, reason))
+	}
+	return revoked, nil
+}
+
+// chapterAudio returns the narration of the user's latest audiobook job for
+// the book by chapter. Chapters whose audio is missing from storage are left
+// out, so the package still carries their text.
+func (s *offlineDownloadService) chapterAudio(ctx context.Context, userID uuid.UUID, bookID int64) (map[uuid.UUID]*domain.AudioFile, error) {
+	job, err := s.audiobookJobRepo.GetLatestJobByBook(ctx, userID, bookID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audiobook job: %w", err)
+	}
+	if job == nil {
+		return nil, nil
+	}
+
+	tasks, err := s.audiobookJobRepo.GetTasksByJobID(ctx, job.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audiobook tasks: %w", err)
+	}
+
+	audioFiles := make(map[uuid.UUID]*domain.AudioFile)
+	for _, task := range tasks {
+		if task.Status != domain.AudiobookStatusCompleted || task.AudioFileID == nil {
+			continue
+		}
+
+		audioFile, err := s.audioFileRepo.GetByID(ctx, *task.AudioFileID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get chapter audio: %w", err)
+		}
+		if audioFile == nil {
+			continue
+		}
+		if _, err := s.blobStore.Stat(ctx, audioFile.FilePath); err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				s.logger.Warn("Chapter audio is missing, packaging text only")
+				continue
+			}
+			return nil, fmt.Errorf("failed to stat chapter audio: %w", err)
+		}
+		audioFiles[task.ChapterID] = audioFile
+	}
+	return audioFiles, nil
+}
+
+// storePackage streams the package zip into the blob store and records its
+// size, manifest checksum and contents on download
+func (s *offlineDownloadService) storePackage(ctx context.Context, download *domain.OfflineDownload, manifest *domain.OfflinePackageManifest, chapters []*domain.Chapter, audioFiles map[uuid.UUID]*domain.AudioFile) error {
+	var lexicon *ssml.Lexicon
+	if s.lexicons != nil {
+		var err error
+		lexicon, err = s.lexicons.LexiconFor(ctx, &download.BookID)
+		if err != nil {
+			return fmt.Errorf("failed to load pronunciation lexicon: %w", err)
+		}
+	}
+
+	reader, writer := io.Pipe()
+	written := make(chan error, 1)
+	var manifestSum string
+	go func() {
+		var err error
+		manifestSum, err = s.writePackage(ctx, writer, manifest, chapters, audioFiles, lexicon)
+		writer.CloseWithError(err)
+		written <- err
+	}()
+
+	putOpts := storage.PutOptions{
+		ContentType: "application/zip",
+		Metadata:    map[string]string{"download_id": download.ID.String()},
+	}
+	info, err := s.blobStore.Put(ctx, download.PackagePath, reader, putOpts)
+	reader.CloseWithError(err)
+	writeErr := <-written
+	if err != nil && (writeErr == nil || errors.Is(writeErr, err)) {
+		return fmt.Errorf("failed to store offline package: %w", err)
+	}
+	if writeErr != nil {
+		return fmt.Errorf("failed to build offline package: %w", writeErr)
+	}
+
+	download.PackageSizeBytes = info.Size
+	download.ManifestSHA256 = manifestSum
+	download.ChapterCount = len(manifest.Chapters)
+	download.AudioChapterCount = 0
+	for _, chapter := range manifest.Chapters {
+		if chapter.AudioPath != "" {
+			download.AudioChapterCount++
+		}
+	}
+	return nil
+}
+
+// writePackage writes the package zip to w and returns the SHA-256 of its
+// manifest. Every file is listed in the manifest with its checksum, and
+// manifest.sig holds the Ed25519 signature of the manifest, which the app
+// verifies with the public key from SigningKey to detect tampering.
+func (s *offlineDownloadService) writePackage(ctx context.Context, w io.Writer, manifest *domain.OfflinePackageManifest, chapters []*domain.Chapter, audioFiles map[uuid.UUID]*domain.AudioFile, lexicon *ssml.Lexicon) (string, error) {
+	zw := zip.NewWriter(w)
+	add := func(path string, method uint16, write func(io.Writer) error) error {
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: path, Method: method, Modified: manifest.IssuedAt})
+		if err != nil {
+			return err
+		}
+		hash := sha256.New()
+		counter := &countingWriter{}
+		if err := write(io.MultiWriter(fw, hash, counter)); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		manifest.Files = append(manifest.Files, domain.OfflinePackageFile{
+			Path:   path,
+			Size:   counter.n,
+			SHA256: hex.EncodeToString(hash.Sum(nil)),
+		})
+		return nil
+	}
+
+	for i, chapter := range chapters {
+		entry := domain.OfflinePackageChapter{
+			ID:       chapter.ID,
+			Position: chapter.Position,
+			Title:    chapter.Title,
+			TextPath: fmt.Sprintf("chapters/%04d.txt", i+1),
+			RubyPath: fmt.Sprintf("chapters/%04d.ruby.json", i+1),
+		}
+
+		if err := add(entry.TextPath, zip.Deflate, writeBytes([]byte(chapter.Content))); err != nil {
+			return "", err
+		}
+
+		spans := lexicon.Ruby(chapter.Content)
+		ruby := make([]domain.OfflinePackageRuby, 0, len(spans))
+		for _, span := range spans {
+			ruby = append(ruby, domain.OfflinePackageRuby{Start: span.Start, End: span.End, Base: span.Base, Reading: span.Reading})
+		}
+		rubyJSON, err := json.Marshal(ruby)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode ruby: %w", err)
+		}
+		if err := add(entry.RubyPath, zip.Deflate, writeBytes(rubyJSON)); err != nil {
+			return "", err
+		}
+
+		if audioFile, ok := audioFiles[chapter.ID]; ok {
+			entry.AudioPath = fmt.Sprintf("audio/%04d.%s", i+1, audioFile.Format)
+			entry.AudioFormat = audioFile.Format
+			entry.DurationSeconds = audioFile.DurationSeconds
+			// Audio is already compressed
+			err := add(entry.AudioPath, zip.Store, func(w io.Writer) error {
+				body, _, err := s.blobStore.Get(ctx, audioFile.FilePath)
+				if err != nil {
+					return err
+				}
+				defer body.Close()
+				_, err = io.Copy(w, body)
+				return err
+			})
+			if err != nil {
+				return "", err
+			}
+		}
+
+		manifest.Chapters = append(manifest.Chapters, entry)
+	}
+
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	manifestSum := sha256.Sum256(manifestJSON)
+
+	fw, err := zw.Create("manifest.json")
+	if err != nil {
+		return "", err
+	}
+	if _, err := fw.Write(manifestJSON); err != nil {
+		return "", err
+	}
+
+	signature := ed25519.Sign(s.signer, manifestJSON)
+	fw, err = zw.Create("manifest.sig")
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.WriteString(fw, hex.EncodeToString(signature)); err != nil {
+		return "", err
+	}
+
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(manifestSum[:]), nil
+}
+
+// deletePackage removes a package from storage. The record is the source of
+// truth for revocation, so failures are only logged.
+func (s *offlineDownloadService) deletePackage(ctx context.Context, download *domain.OfflineDownload) {
+	err := s.blobStore.Delete(ctx, download.PackagePath)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		s.logger.WithError(err).Error("Failed to delete offline package")
+	}
+}
+
+func (s *offlineDownloadService) getDownload(ctx context.Context, userID uuid.UUID, downloadID uuid.UUID) (*domain.OfflineDownload, error) {
+	download, err := s.downloadRepo.GetByID(ctx, downloadID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get offline download: %w", err)
+	}
+	if download == nil || download.UserID != userID || download.Status == domain.OfflineDownloadStatusDeleted {
+		return nil, apperrors.NotFound("Offline download not found")
+	}
+	return download, nil
+}
+
+// buildDownloadResponse converts a download to the API response, with a
+// fresh download link while the package is active
+func (s *offlineDownloadService) buildDownloadResponse(ctx context.Context, download *domain.OfflineDownload) (*dto.OfflineDownloadResponse, error) {
+	response := &dto.OfflineDownloadResponse{
+		ID:                download.ID,
+		BookID:            download.BookID,
+		Status:            download.Status,
+		PackageSizeBytes:  download.PackageSizeBytes,
+		ManifestSHA256:    download.ManifestSHA256,
+		ChapterCount:      download.ChapterCount,
+		AudioChapterCount: download.AudioChapterCount,
+		ErrorMessage:      download.ErrorMessage,
+		ExpiresAt:         download.ExpiresAt,
+		RevokedAt:         download.RevokedAt,
+		RevokeReason:      download.RevokeReason,
+		CreatedAt:         download.CreatedAt,
+		UpdatedAt:         download.UpdatedAt,
+	}
+
+	if download.IsActive() {
+		url, err := s.blobStore.SignedURL(ctx, download.PackagePath, s.config.URLExpiry)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign package URL: %w", err)
+		}
+		response.DownloadURL = url
+	}
+	return response, nil
+}
+
+// writeBytes returns a package writer for in-memory content
+func writeBytes(data []byte) func(io.Writer) error {
+	return func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	}
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,315 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ponyo877/roudoku/server/domain"
+	"github.com/ponyo877/roudoku/server/dto"
+	apperrors "github.com/ponyo877/roudoku/server/pkg/errors"
+	"github.com/ponyo877/roudoku/server/pkg/logger"
+	"github.com/ponyo877/roudoku/server/repository"
+)
+
+// PlaybackService defines the interface for audio playback session operations
+type PlaybackService interface {
+	StartSession(ctx context.Context, userID uuid.UUID, req *dto.StartPlaybackRequest) (*dto.PlaybackSessionResponse, error)
+	Heartbeat(ctx context.Context, userID, sessionID uuid.UUID, req *dto.PlaybackHeartbeatRequest) (*dto.PlaybackSessionResponse, error)
+	EndSession(ctx context.Context, userID, sessionID uuid.UUID, req *dto.EndPlaybackRequest) (*dto.PlaybackSessionResponse, error)
+	GetResumePosition(ctx context.Context, userID uuid.UUID, bookID int64) (*dto.ResumePositionResponse, error)
+	// RecordExternalPlay counts a play of an audio file fetched outside the
+	// app's player, such as by a podcast app, which reports no positions
+	RecordExternalPlay(ctx context.Context, userID, audioFileID uuid.UUID) error
+}
+
+// externalPlayWindow is how long repeated fetches of the same file by an
+// external player count as a single play
+const externalPlayWindow = 30 * time.Minute
+
+// playbackService implements PlaybackService
+type playbackService struct {
+	*BaseService
+	sessionRepo   repository.AudioPlaybackSessionRepository
+	audioFileRepo repository.AudioFileRepository
+	analyticsRepo repository.ReadingAnalyticsRepository
+	// subscriptionService meters listening time against the TTS quota
+	subscriptionService SubscriptionService
+}
+
+// NewPlaybackService creates a new playback service
+func NewPlaybackService(
+	sessionRepo repository.AudioPlaybackSessionRepository,
+	audioFileRepo repository.AudioFileRepository,
+	analyticsRepo repository.ReadingAnalyticsRepository,
+	subscriptionService SubscriptionService,
+	logger *logger.Logger,
+) PlaybackService {
+	return &playbackService{
+		BaseService:         NewBaseService(logger),
+		sessionRepo:         sessionRepo,
+		audioFileRepo:       audioFileRepo,
+		analyticsRepo:       analyticsRepo,
+		subscriptionService: subscriptionService,
+	}
+}
+
+// StartSession starts a playback session on an audio file. Files the user
+// may not play are reported as not found.
+func (s *playbackService) StartSession(ctx context.Context, userID uuid.UUID, req *dto.StartPlaybackRequest) (*dto.PlaybackSessionResponse, error) {
+	if err := s.ValidateStruct(req); err != nil {
+		return nil, err
+	}
+
+	accessible, err := s.audioFileRepo.IsAccessibleBy(ctx, req.AudioFileID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check audio file access: %w", err)
+	}
+	if !accessible {
+		return nil, apperrors.NotFound("Audio file not found")
+	}
+
+	audioFile, err := s.audioFileRepo.GetByID(ctx, req.AudioFileID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audio file: %w", err)
+	}
+	if audioFile == nil || audioFile.Status != "completed" {
+		return nil, apperrors.NotFound("Audio file not found")
+	}
+
+	speed := req.PlaybackSpeed
+	if speed == 0 {
+		speed = 1.0
+	}
+
+	now := time.Now()
+	session := &domain.AudioPlaybackSession{
+		ID:              uuid.New(),
+		UserID:          userID,
+		AudioFileID:     req.AudioFileID,
+		StartPositionMs: req.PositionMs,
+		PlaybackSpeed:   speed,
+		DeviceType:      req.DeviceType,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+
+	if err := s.sessionRepo.Create(ctx, session); err != nil {
+		s.logger.Error("Failed to create playback session")
+		return nil, fmt.Errorf("failed to create playback session: %w", err)
+	}
+
+	if err := s.analyticsRepo.AddReadingTime(ctx, userID, now, 0, 1, 0); err != nil {
+		s.logger.WithError(err).Warn("Failed to count playback session in reading analytics")
+	}
+
+	if err := s.audioFileRepo.RecordPlay(ctx, audioFile.ID, now); err != nil {
+		s.logger.WithError(err).Warn("Failed to record audio file play")
+	}
+
+	return s.convertSessionToResponse(session), nil
+}
+
+// Heartbeat records the current position of a playing session
+func (s *playbackService) Heartbeat(ctx context.Context, userID, sessionID uuid.UUID, req *dto.PlaybackHeartbeatRequest) (*dto.PlaybackSessionResponse, error) {
+	if err := s.ValidateStruct(req); err != nil {
+		return nil, err
+	}
+
+	session, err := s.getOpenSession(ctx, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	s.advance(session, req.PositionMs, now)
+	if req.PlaybackSpeed > 0 {
+		session.PlaybackSpeed = req.PlaybackSpeed
+	}
+
+	if err := s.saveSession(ctx, session); err != nil {
+		return nil, err
+	}
+
+	return s.convertSessionToResponse(session), nil
+}
+
+// EndSession records the final position of a session and closes it
+func (s *playbackService) EndSession(ctx context.Context, userID, sessionID uuid.UUID, req *dto.EndPlaybackRequest) (*dto.PlaybackSessionResponse, error) {
+	if err := s.ValidateStruct(req); err != nil {
+		return nil, err
+	}
+
+	session, err := s.getOpenSession(ctx, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	s.advance(session, req.PositionMs, now)
+	session.Completed = req.Completed
+	session.EndedAt = &now
+
+	if err := s.saveSession(ctx, session); err != nil {
+		return nil, err
+	}
+
+	return s.convertSessionToResponse(session), nil
+}
+
+// GetResumePosition returns where the user left off in the book
+func (s *playbackService) GetResumePosition(ctx context.Context, userID uuid.UUID, bookID int64) (*dto.ResumePositionResponse, error) {
+	session, err := s.sessionRepo.GetLatestByBook(ctx, userID, bookID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest playback session: %w", err)
+	}
+	if session == nil {
+		return nil, apperrors.NotFound("No playback found for this book")
+	}
+
+	audioFile, err := s.audioFileRepo.GetByID(ctx, session.AudioFileID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audio file: %w", err)
+	}
+	if audioFile == nil {
+		return nil, apperrors.NotFound("No playback found for this book")
+	}
+
+	return &dto.ResumePositionResponse{
+		BookID:        bookID,
+		ChapterID:     audioFile.ChapterID,
+		AudioFileID:   session.AudioFileID,
+		PositionMs:    session.CurrentPositionMs(),
+		PlaybackSpeed: session.PlaybackSpeed,
+		Completed:     session.Completed,
+		UpdatedAt:     session.UpdatedAt,
+	}, nil
+}
+
+// RecordExternalPlay records an ended session for the play. External players
+// fetch a file several times while buffering or resuming, so a play is only
+// counted once per file within externalPlayWindow.
+func (s *playbackService) RecordExternalPlay(ctx context.Context, userID, audioFileID uuid.UUID) error {
+	now := time.Now()
+
+	recent, err := s.sessionRepo.GetByUserID(ctx, userID, 20, 0)
+	if err != nil {
+		return fmt.Errorf("failed to get playback sessions: %w", err)
+	}
+	for _, session := range recent {
+		if session.AudioFileID == audioFileID && now.Sub(session.UpdatedAt) < externalPlayWindow {
+			return nil
+		}
+	}
+
+	session := &domain.AudioPlaybackSession{
+		ID:            uuid.New(),
+		UserID:        userID,
+		AudioFileID:   audioFileID,
+		PlaybackSpeed: 1.0,
+		EndedAt:       &now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+
+	if err := s.sessionRepo.Create(ctx, session); err != nil {
+		s.logger.Error("Failed to create playback session")
+		return fmt.Errorf("failed to create playback session: %w", err)
+	}
+
+	if err := s.analyticsRepo.AddReadingTime(ctx, userID, now, 0, 1, 0); err != nil {
+		s.logger.WithError(err).Warn("Failed to count playback session in reading analytics")
+	}
+
+	if err := s.audioFileRepo.RecordPlay(ctx, audioFileID, now); err != nil {
+		s.logger.WithError(err).Warn("Failed to record audio file play")
+	}
+
+	return nil
+}
+
+// Helper methods
+
+func (s *playbackService) getOpenSession(ctx context.Context, userID, sessionID uuid.UUID) (*domain.AudioPlaybackSession, error) {
+	session, err := s.sessionRepo.GetByID(ctx, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get playback session: %w", err)
+	}
+	if session == nil || session.UserID != userID {
+		return nil, apperrors.NotFound("Playback session not found")
+	}
+	if session.IsEnded() {
+		return nil, apperrors.BadRequest("Playback session has already ended", nil)
+	}
+	return session, nil
+}
+
+// advance moves the session to the reported position and credits the time
+// listened since the last update. Forward progress is converted to real time
+// using the playback speed and capped by the wall-clock time that passed, so
+// seeking ahead or replaying a heartbeat does not inflate listening time.
+func (s *playbackService) advance(session *domain.AudioPlaybackSession, positionMs int, now time.Time) {
+	progressed := positionMs - session.CurrentPositionMs()
+	if progressed > 0 {
+		speed := session.PlaybackSpeed
+		if speed <= 0 {
+			speed = 1.0
+		}
+		listened := time.Duration(float64(progressed)/speed) * time.Millisecond
+		if elapsed := now.Sub(session.UpdatedAt); listened > elapsed {
+			listened = elapsed
+		}
+		if listened > 0 {
+			session.DurationMs += int(listened.Milliseconds())
+		}
+	}
+
+	session.EndPositionMs = &positionMs
+	session.UpdatedAt = now
+}
+
+// saveSession persists the session and credits listening time that has not
+// yet been reported to reading analytics and to the TTS usage quota
+func (s *playbackService) saveSession(ctx context.Context, session *domain.AudioPlaybackSession) error {
+	if session.DurationMs > session.RecordedMs {
+		// Analytics count whole minutes, so only the minute boundaries crossed
+		// since the last report are added
+		minutes := session.DurationMs/60000 - session.RecordedMs/60000
+		if minutes > 0 {
+			if err := s.analyticsRepo.AddReadingTime(ctx, session.UserID, session.UpdatedAt, minutes, 0, session.DurationMs/60000); err != nil {
+				s.logger.WithError(err).Warn("Failed to record listening time in reading analytics")
+			}
+			// Listening is metered in minutes without counting as a synthesis
+			if err := s.subscriptionService.TrackFeatureUsage(ctx, session.UserID, "tts_minutes", 0, float64(minutes)); err != nil {
+				s.logger.WithError(err).Warn("Failed to record listening time in TTS usage")
+			}
+		}
+
+		session.RecordedMs = session.DurationMs
+	}
+
+	if err := s.sessionRepo.Update(ctx, session); err != nil {
+		s.logger.Error("Failed to update playback session")
+		return fmt.Errorf("failed to update playback session: %w", err)
+	}
+
+	return nil
+}
+
+func (s *playbackService) convertSessionToResponse(session *domain.AudioPlaybackSession) *dto.PlaybackSessionResponse {
+	return &dto.PlaybackSessionResponse{
+		ID:              session.ID,
+		AudioFileID:     session.AudioFileID,
+		StartPositionMs: session.StartPositionMs,
+		PositionMs:      session.CurrentPositionMs(),
+		ListenedMs:      session.DurationMs,
+		PlaybackSpeed:   session.PlaybackSpeed,
+		Completed:       session.Completed,
+		DeviceType:      session.DeviceType,
+		EndedAt:         session.EndedAt,
+		CreatedAt:       session.CreatedAt,
+		UpdatedAt:       session.UpdatedAt,
+	}
+}
@@ -0,0 +1,468 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ponyo877/roudoku/server/domain"
+	"github.com/ponyo877/roudoku/server/dto"
+	apperrors "github.com/ponyo877/roudoku/server/pkg/errors"
+	"github.com/ponyo877/roudoku/server/pkg/logger"
+	"github.com/ponyo877/roudoku/server/repository"
+)
+
+// PodcastService defines the interface for private podcast feeds of a
+// user's audiobooks. Feed and episode URLs are addressed by a secret token
+// so podcast apps can fetch them without signing in.
+type PodcastService interface {
+	// CreateFeed issues a new feed token, revoking the previous one.
+	// baseURL is the URL the podcast routes are served under.
+	CreateFeed(ctx context.Context, userID uuid.UUID, baseURL string) (*dto.PodcastFeedResponse, error)
+	GetFeed(ctx context.Context, userID uuid.UUID) (*dto.PodcastFeedResponse, error)
+	RevokeFeed(ctx context.Context, userID uuid.UUID) error
+	// BuildFeed returns the RSS 2.0 document of the token's feed
+	BuildFeed(ctx context.Context, token, baseURL string) ([]byte, error)
+	// OpenEpisode opens the audio of an episode in the token's feed. When
+	// countPlay is set the fetch is counted as a play by the feed's owner.
+	OpenEpisode(ctx context.Context, token string, audioFileID uuid.UUID, countPlay bool) (*AudioStream, error)
+}
+
+// PodcastConfig configures podcast feeds
+type PodcastConfig struct {
+	// MaxBooks caps how many audiobooks a feed lists
+	MaxBooks int
+}
+
+// favouriteInteractionTypes are the interactions that put a book in the feed
+// even when the user has not generated its audiobook
+var favouriteInteractionTypes = []string{"like", "bookmark"}
+
+// podcastService implements PodcastService
+type podcastService struct {
+	*BaseService
+	feedRepo             repository.PodcastFeedRepository
+	audiobookJobRepo     repository.AudiobookJobRepository
+	interactionRepo      repository.UserInteractionRepository
+	audioFileRepo        repository.AudioFileRepository
+	bookRepo             repository.BookRepository
+	audioDeliveryService AudioDeliveryService
+	playbackService      PlaybackService
+	subscriptionService  SubscriptionService
+	config               PodcastConfig
+}
+
+// NewPodcastService creates a new podcast service
+func NewPodcastService(
+	feedRepo repository.PodcastFeedRepository,
+	audiobookJobRepo repository.AudiobookJobRepository,
+	interactionRepo repository.UserInteractionRepository,
+	audioFileRepo repository.AudioFileRepository,
+	bookRepo repository.BookRepository,
+	audioDeliveryService AudioDeliveryService,
+	playbackService PlaybackService,
+	subscriptionService SubscriptionService,
+	config PodcastConfig,
+	logger *logger.Logger,
+) PodcastService {
+	if config.MaxBooks <= 0 {
+		config.MaxBooks = 50
+	}
+
+	return &podcastService{
+		BaseService:          NewBaseService(logger),
+		feedRepo:             feedRepo,
+		audiobookJobRepo:     audiobookJobRepo,
+		interactionRepo:      interactionRepo,
+		audioFileRepo:        audioFileRepo,
+		bookRepo:             bookRepo,
+		audioDeliveryService: audioDeliveryService,
+		playbackService:      playbackService,
+		subscriptionService:  subscriptionService,
+		config:               config,
+	}
+}
+
+// CreateFeed stores only the hash of the new token, so the feed URL is
+// returned once and a lost URL is replaced by creating a new feed
+func (s *podcastService) CreateFeed(ctx context.Context, userID uuid.UUID, baseURL string) (*dto.PodcastFeedResponse, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate podcast feed token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(secret)
+
+	feed := &domain.PodcastFeed{
+		ID:        uuid.New(),
+		UserID:    userID,
+		TokenHash: hashFeedToken(token),
+		CreatedAt: time.Now(),
+	}
+
+	if err := s.feedRepo.Rotate(ctx, feed); err != nil {
+		if err == repository.ErrDuplicateKey {
+			return nil, apperrors.New("CONFLICT", "Podcast feed is being created by another request", 409)
+		}
+		s.logger.Error("Failed to create podcast feed")
+		return nil, fmt.Errorf("failed to create podcast feed: %w", err)
+	}
+
+	response := s.convertFeedToResponse(feed)
+	response.FeedURL = feedURL(baseURL, token)
+	return response, nil
+}
+
+// GetFeed returns the user's feed in use without its URL
+func (s *podcastService) GetFeed(ctx context.Context, userID uuid.UUID) (*dto.PodcastFeedResponse, error) {
+	feed, err := s.feedRepo.GetActiveByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get podcast feed: %w", err)
+	}
+	if feed == nil {
+		return nil, apperrors.NotFound("Podcast feed not found")
+	}
+	return s.convertFeedToResponse(feed), nil
+}
+
+// RevokeFeed stops the user's feed and episode URLs from answering
+func (s *podcastService) RevokeFeed(ctx context.Context, userID uuid.UUID) error {
+	revoked, err := s.feedRepo.Revoke(ctx, userID, time.Now())
+	if err != nil {
+		s.logger.Error("Failed to revoke podcast feed")
+		return fmt.Errorf("failed to revoke podcast feed: %w", err)
+	}
+	if !revoked {
+		return apperrors.NotFound("Podcast feed not found")
+	}
+	return nil
+}
+
+// BuildFeed lists one episode per narrated chapter. Each book is a season of
+// a serial podcast with its chapters as episodes in reading order, and
+// publication dates follow that order for apps that sort by date.
+func (s *podcastService) BuildFeed(ctx context.Context, token, baseURL string) ([]byte, error) {
+	feed, err := s.getFeedByToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs, err := s.feedJobs(ctx, feed.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	channel := rssChannel{
+		Title:       "Roudoku audiobooks",
+		Link:        baseURL,
+		Description: "Audiobooks you have generated or favourited on Roudoku",
+		Language:    "ja",
+		Type:        "serial",
+		Block:       "Yes",
+	}
+
+	for i, job := range jobs {
+		items, err := s.bookItems(ctx, job, i+1, baseURL+"/"+token+"/episodes/")
+		if err != nil {
+			return nil, err
+		}
+		channel.Items = append(channel.Items, items...)
+	}
+
+	if err := s.feedRepo.TouchAccess(ctx, feed.ID, time.Now()); err != nil {
+		s.logger.WithError(err).Warn("Failed to record podcast feed access")
+	}
+
+	document, err := xml.MarshalIndent(rssFeed{
+		Version:  "2.0",
+		ITunesNS: "http://www.itunes.com/dtds/podcast-1.0.dtd",
+		Channel:  channel,
+	}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode podcast feed: %w", err)
+	}
+
+	return append([]byte(xml.Header), document...), nil
+}
+
+// OpenEpisode serves the audio only while the chapter is still in the feed
+func (s *podcastService) OpenEpisode(ctx context.Context, token string, audioFileID uuid.UUID, countPlay bool) (*AudioStream, error) {
+	feed, err := s.getFeedByToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	inFeed, err := s.isEpisode(ctx, feed.UserID, audioFileID)
+	if err != nil {
+		return nil, err
+	}
+	if !inFeed {
+		return nil, apperrors.NotFound("Episode not found")
+	}
+
+	stream, err := s.audioDeliveryService.OpenAuthorizedAudio(ctx, audioFileID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if countPlay {
+		if err := s.playbackService.RecordExternalPlay(ctx, feed.UserID, stream.AudioFileID); err != nil {
+			s.logger.WithError(err).Warn("Failed to record podcast episode play")
+		}
+	}
+
+	return stream, nil
+}
+
+// Helper methods
+
+func (s *podcastService) getFeedByToken(ctx context.Context, token string) (*domain.PodcastFeed, error) {
+	feed, err := s.feedRepo.GetActiveByTokenHash(ctx, hashFeedToken(token))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get podcast feed: %w", err)
+	}
+	if feed == nil {
+		return nil, apperrors.NotFound("Podcast feed not found")
+	}
+	return feed, nil
+}
+
+// feedJobs returns the audiobook job each book in the feed is narrated by:
+// the user's own latest jobs first, then the latest job of any user for
+// favourited books the user has not generated. Favourited premium books are
+// listed only while the user's plan includes them. isEpisode serves only
+// the audio of these jobs.
+func (s *podcastService) feedJobs(ctx context.Context, userID uuid.UUID) ([]*domain.AudiobookJob, error) {
+	jobs, err := s.audiobookJobRepo.GetLatestJobsByUser(ctx, userID, s.config.MaxBooks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audiobook jobs: %w", err)
+	}
+
+	listed := make(map[int64]bool, len(jobs))
+	for _, job := range jobs {
+		listed[job.BookID] = true
+	}
+
+	favourites, err := s.interactionRepo.GetBookIDsByTypes(ctx, userID, favouriteInteractionTypes, s.config.MaxBooks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get favourite books: %w", err)
+	}
+
+	for _, bookID := range favourites {
+		if len(jobs) >= s.config.MaxBooks {
+			break
+		}
+		if listed[bookID] {
+			continue
+		}
+
+		allowed, err := s.canAccessBook(ctx, userID, bookID)
+		if err != nil {
+			return nil, err
+		}
+		if !allowed {
+			continue
+		}
+
+		job, err := s.audiobookJobRepo.GetLatestJobWithAudio(ctx, bookID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get audiobook job: %w", err)
+		}
+		if job == nil {
+			continue
+		}
+		jobs = append(jobs, job)
+		listed[bookID] = true
+	}
+
+	return jobs, nil
+}
+
+// canAccessBook reports whether the user's plan includes the book
+func (s *podcastService) canAccessBook(ctx context.Context, userID uuid.UUID, bookID int64) (bool, error) {
+	book, err := s.bookRepo.GetByID(ctx, bookID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get book: %w", err)
+	}
+	if !book.IsPremium {
+		return true, nil
+	}
+
+	allowed, err := s.subscriptionService.CanAccessPremiumBook(ctx, userID, bookID)
+	if err != nil {
+		return false, fmt.Errorf("failed to check premium book access: %w", err)
+	}
+	return allowed, nil
+}
+
+// isEpisode reports whether the audio file narrates a chapter of a book in
+// the user's feed
+func (s *podcastService) isEpisode(ctx context.Context, userID, audioFileID uuid.UUID) (bool, error) {
+	audioFile, err := s.audioFileRepo.GetByID(ctx, audioFileID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get audio file: %w", err)
+	}
+	if audioFile == nil || audioFile.BookID == nil {
+		return false, nil
+	}
+
+	jobs, err := s.feedJobs(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	for _, job := range jobs {
+		if job.BookID != *audioFile.BookID {
+			continue
+		}
+
+		tasks, err := s.audiobookJobRepo.GetTasksByJobID(ctx, job.ID)
+		if err != nil {
+			return false, fmt.Errorf("failed to get audiobook tasks: %w", err)
+		}
+		for _, task := range tasks {
+			if task.Status == domain.AudiobookStatusCompleted && task.AudioFileID != nil && *task.AudioFileID == audioFileID {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	return false, nil
+}
+
+// bookItems returns the episodes of the chapters the job has narrated
+func (s *podcastService) bookItems(ctx context.Context, job *domain.AudiobookJob, season int, episodeBaseURL string) ([]rssItem, error) {
+	book, err := s.bookRepo.GetByID(ctx, job.BookID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get book: %w", err)
+	}
+	if book == nil {
+		return nil, nil
+	}
+
+	tasks, err := s.audiobookJobRepo.GetTasksByJobID(ctx, job.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audiobook tasks: %w", err)
+	}
+
+	chapters, err := s.bookRepo.GetChaptersByBookID(ctx, job.BookID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get book chapters: %w", err)
+	}
+	titles := make(map[uuid.UUID]string, len(chapters))
+	for _, chapter := range chapters {
+		titles[chapter.ID] = chapter.Title
+	}
+
+	// Tasks cover every chapter in reading order, so episode numbers match
+	// chapter numbers even when some chapters have not been narrated
+	var items []rssItem
+	for i, task := range tasks {
+		if task.Status != domain.AudiobookStatusCompleted || task.AudioFileID == nil {
+			continue
+		}
+
+		audioFile, err := s.audioFileRepo.GetByID(ctx, *task.AudioFileID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get chapter audio: %w", err)
+		}
+		if audioFile == nil || audioFile.Status != "completed" {
+			continue
+		}
+
+		title := book.Title
+		if chapterTitle := titles[task.ChapterID]; chapterTitle != "" {
+			title += " – " + chapterTitle
+		}
+
+		items = append(items, rssItem{
+			Title:  title,
+			Author: book.Author,
+			GUID:   rssGUID{IsPermaLink: "false", Value: audioFile.ID.String()},
+			Enclosure: rssEnclosure{
+				URL:    episodeBaseURL + audioFile.ID.String(),
+				Length: audioFile.FileSizeBytes,
+				Type:   contentTypeForFormat(audioFile.Format),
+			},
+			// Chapters of a book are published a minute apart in reading order
+			PubDate:  job.CreatedAt.Add(time.Duration(i) * time.Minute).UTC().Format(time.RFC1123Z),
+			Duration: formatEpisodeDuration(audioFile.DurationSeconds),
+			Season:   season,
+			Episode:  i + 1,
+		})
+	}
+
+	return items, nil
+}
+
+func (s *podcastService) convertFeedToResponse(feed *domain.PodcastFeed) *dto.PodcastFeedResponse {
+	return &dto.PodcastFeedResponse{
+		ID:             feed.ID,
+		LastAccessedAt: feed.LastAccessedAt,
+		CreatedAt:      feed.CreatedAt,
+	}
+}
+
+// hashFeedToken returns the hex SHA-256 of a feed token
+func hashFeedToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func feedURL(baseURL, token string) string {
+	return baseURL + "/" + token + "/feed.xml"
+}
+
+// formatEpisodeDuration formats seconds as HH:MM:SS for itunes:duration
+func formatEpisodeDuration(seconds float64) string {
+	total := int(seconds + 0.5)
+	return fmt.Sprintf("%02d:%02d:%02d", total/3600, total/60%60, total%60)
+}
+
+// RSS 2.0 document with the iTunes podcast extensions
+
+type rssFeed struct {
+	XMLName  xml.Name   `xml:"rss"`
+	Version  string     `xml:"version,attr"`
+	ITunesNS string     `xml:"xmlns:itunes,attr"`
+	Channel  rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title       string    `xml:"title"`
+	Link        string    `xml:"link"`
+	Description string    `xml:"description"`
+	Language    string    `xml:"language"`
+	Type        string    `xml:"itunes:type"`
+	Block       string    `xml:"itunes:block"` // Keeps private feeds out of podcast directories
+	Items       []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title     string       `xml:"title"`
+	Author    string       `xml:"itunes:author"`
+	GUID      rssGUID      `xml:"guid"`
+	Enclosure rssEnclosure `xml:"enclosure"`
+	PubDate   string       `xml:"pubDate"`
+	Duration  string       `xml:"itunes:duration"`
+	Season    int          `xml:"itunes:season"`
+	Episode   int          `xml:"itunes:episode"`
+}
+
+type rssGUID struct {
+	IsPermaLink string `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int64  `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
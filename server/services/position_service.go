@@ -0,0 +1,329 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"github.com/ponyo877/roudoku/server/domain"
+	"github.com/ponyo877/roudoku/server/dto"
+	apperrors "github.com/ponyo877/roudoku/server/pkg/errors"
+	"github.com/ponyo877/roudoku/server/pkg/logger"
+	"github.com/ponyo877/roudoku/server/repository"
+)
+
+// PositionService defines the interface for converting between reading and
+// listening positions in a book, so either mode can resume where the other
+// stopped
+type PositionService interface {
+	// TextToAudio maps a text offset in the book to the chapter audio and
+	// playback position narrating it
+	TextToAudio(ctx context.Context, userID uuid.UUID, bookID int64, req *dto.TextToAudioRequest) (*dto.BookPositionResponse, error)
+	// AudioToText maps a playback position in a chapter's audio to the text
+	// offset in the book
+	AudioToText(ctx context.Context, userID uuid.UUID, bookID int64, req *dto.AudioToTextRequest) (*dto.BookPositionResponse, error)
+	// GetHandoffPosition returns the position the user most recently reached
+	// by reading or listening, in both forms
+	GetHandoffPosition(ctx context.Context, userID uuid.UUID, bookID int64) (*dto.BookPositionResponse, error)
+}
+
+// positionService implements PositionService
+type positionService struct {
+	*BaseService
+	bookRepo            repository.BookRepository
+	audiobookJobRepo    repository.AudiobookJobRepository
+	audioFileRepo       repository.AudioFileRepository
+	alignmentRepo       repository.AudioAlignmentRepository
+	readingSessionRepo  repository.SessionRepository
+	playbackSessionRepo repository.AudioPlaybackSessionRepository
+}
+
+// NewPositionService creates a new position service
+func NewPositionService(
+	bookRepo repository.BookRepository,
+	audiobookJobRepo repository.AudiobookJobRepository,
+	audioFileRepo repository.AudioFileRepository,
+	alignmentRepo repository.AudioAlignmentRepository,
+	readingSessionRepo repository.SessionRepository,
+	playbackSessionRepo repository.AudioPlaybackSessionRepository,
+	logger *logger.Logger,
+) PositionService {
+	return &positionService{
+		BaseService:         NewBaseService(logger),
+		bookRepo:            bookRepo,
+		audiobookJobRepo:    audiobookJobRepo,
+		audioFileRepo:       audioFileRepo,
+		alignmentRepo:       alignmentRepo,
+		readingSessionRepo:  readingSessionRepo,
+		playbackSessionRepo: playbackSessionRepo,
+	}
+}
+
+// bookText locates chapters in the book's text, which is the content of its
+// chapters in reading order. Offsets count runes.
+type bookText struct {
+	chapters []*domain.Chapter
+	starts   []int
+	lengths  []int
+}
+
+// TextToAudio resolves the offset to its chapter and plays the chapter's
+// narration from the start of the sentence containing it
+func (s *positionService) TextToAudio(ctx context.Context, userID uuid.UUID, bookID int64, req *dto.TextToAudioRequest) (*dto.BookPositionResponse, error) {
+	if err := s.ValidateStruct(req); err != nil {
+		return nil, err
+	}
+
+	text, err := s.bookText(ctx, bookID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := text.position(bookID, req.TextOffset)
+	found, err := s.addAudioPosition(ctx, userID, text, response)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, apperrors.NotFound("Chapter has not been narrated")
+	}
+	return response, nil
+}
+
+// AudioToText returns the offset of the start of the sentence playing at
+// the position
+func (s *positionService) AudioToText(ctx context.Context, userID uuid.UUID, bookID int64, req *dto.AudioToTextRequest) (*dto.BookPositionResponse, error) {
+	if err := s.ValidateStruct(req); err != nil {
+		return nil, err
+	}
+
+	text, err := s.bookText(ctx, bookID)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := s.textPosition(ctx, text, bookID, req.AudioFileID, req.PositionMs)
+	if err != nil {
+		return nil, err
+	}
+	if response == nil {
+		return nil, apperrors.NotFound("Audio file does not narrate a chapter of this book")
+	}
+	return response, nil
+}
+
+// GetHandoffPosition compares the latest reading session and the latest
+// playback session of the book and converts whichever was updated last.
+// Listening to audio that does not narrate a chapter, such as a synthesized
+// excerpt, leaves the reading position in effect.
+func (s *positionService) GetHandoffPosition(ctx context.Context, userID uuid.UUID, bookID int64) (*dto.BookPositionResponse, error) {
+	reading, err := s.readingSessionRepo.GetLatestByUserAndBook(ctx, userID, bookID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest reading session: %w", err)
+	}
+	listening, err := s.playbackSessionRepo.GetLatestByBook(ctx, userID, bookID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest playback session: %w", err)
+	}
+	if reading == nil && listening == nil {
+		return nil, apperrors.NotFound("No reading or listening position for this book")
+	}
+
+	text, err := s.bookText(ctx, bookID)
+	if err != nil {
+		return nil, err
+	}
+
+	if listening != nil && (reading == nil || listening.UpdatedAt.After(reading.UpdatedAt)) {
+		response, err := s.textPosition(ctx, text, bookID, listening.AudioFileID, listening.CurrentPositionMs())
+		if err != nil {
+			return nil, err
+		}
+		if response != nil {
+			response.Source = dto.PositionSourceListening
+			response.UpdatedAt = &listening.UpdatedAt
+			return response, nil
+		}
+		if reading == nil {
+			return nil, apperrors.NotFound("No reading or listening position for this book")
+		}
+	}
+
+	response := text.position(bookID, reading.CurrentPos)
+	if _, err := s.addAudioPosition(ctx, userID, text, response); err != nil {
+		return nil, err
+	}
+	response.Source = dto.PositionSourceReading
+	response.UpdatedAt = &reading.UpdatedAt
+	return response, nil
+}
+
+// Helper methods
+
+func (s *positionService) bookText(ctx context.Context, bookID int64) (*bookText, error) {
+	chapters, err := s.bookRepo.GetChaptersByBookID(ctx, bookID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get book chapters: %w", err)
+	}
+	if len(chapters) == 0 {
+		return nil, apperrors.NotFound("Book has no chapters")
+	}
+
+	text := &bookText{
+		chapters: chapters,
+		starts:   make([]int, len(chapters)),
+		lengths:  make([]int, len(chapters)),
+	}
+	offset := 0
+	for i, chapter := range chapters {
+		text.starts[i] = offset
+		text.lengths[i] = utf8.RuneCountInString(chapter.Content)
+		offset += text.lengths[i]
+	}
+	return text, nil
+}
+
+// position returns the chapter position of a book offset. Offsets past the
+// end of the book are moved to the end of the last chapter.
+func (t *bookText) position(bookID int64, offset int) *dto.BookPositionResponse {
+	index := len(t.chapters) - 1
+	for i := range t.chapters {
+		if offset < t.starts[i]+t.lengths[i] {
+			index = i
+			break
+		}
+	}
+	chapterOffset := min(offset-t.starts[index], t.lengths[index])
+
+	return &dto.BookPositionResponse{
+		BookID:        bookID,
+		ChapterID:     t.chapters[index].ID,
+		TextOffset:    t.starts[index] + chapterOffset,
+		ChapterOffset: chapterOffset,
+	}
+}
+
+// chapterIndex returns the reading order index of a chapter
+func (t *bookText) chapterIndex(chapterID uuid.UUID) (int, bool) {
+	for i, chapter := range t.chapters {
+		if chapter.ID == chapterID {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// addAudioPosition fills in the narration of the response's chapter by the
+// user's latest audiobook job and reports whether the chapter has any
+func (s *positionService) addAudioPosition(ctx context.Context, userID uuid.UUID, text *bookText, response *dto.BookPositionResponse) (bool, error) {
+	audioFile, err := s.chapterAudio(ctx, userID, response.BookID, response.ChapterID)
+	if err != nil || audioFile == nil {
+		return false, err
+	}
+
+	alignment, err := s.alignmentRepo.GetByAudioFileID(ctx, audioFile.ID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get audio alignment: %w", err)
+	}
+
+	var positionMs int
+	if alignment != nil && len(alignment.Segments) > 0 {
+		positionMs = alignment.DurationMs
+		if segment, ok := alignment.SegmentForOffset(response.ChapterOffset); ok {
+			positionMs = segment.StartMs
+		}
+		response.Aligned = true
+	} else {
+		// Without sentence timings, assume narration at an even pace
+		index, _ := text.chapterIndex(response.ChapterID)
+		if length := text.lengths[index]; length > 0 {
+			positionMs = int(audioFile.DurationSeconds * 1000 * float64(response.ChapterOffset) / float64(length))
+		}
+	}
+
+	response.AudioFileID = &audioFile.ID
+	response.PositionMs = &positionMs
+	return true, nil
+}
+
+// textPosition returns the text position narrated at positionMs of an audio
+// file, or nil when the file does not narrate a chapter of the book
+func (s *positionService) textPosition(ctx context.Context, text *bookText, bookID int64, audioFileID uuid.UUID, positionMs int) (*dto.BookPositionResponse, error) {
+	audioFile, err := s.audioFileRepo.GetByID(ctx, audioFileID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audio file: %w", err)
+	}
+	if audioFile == nil || audioFile.BookID == nil || *audioFile.BookID != bookID || audioFile.ChapterID == nil {
+		return nil, nil
+	}
+	index, ok := text.chapterIndex(*audioFile.ChapterID)
+	if !ok {
+		return nil, nil
+	}
+
+	alignment, err := s.alignmentRepo.GetByAudioFileID(ctx, audioFile.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audio alignment: %w", err)
+	}
+
+	var chapterOffset int
+	aligned := false
+	if alignment != nil {
+		if segment, ok := alignment.SegmentAt(positionMs); ok {
+			chapterOffset = segment.CharOffset
+			aligned = true
+		}
+	}
+	if !aligned && audioFile.DurationSeconds > 0 {
+		// Without sentence timings, assume narration at an even pace
+		chapterOffset = int(float64(text.lengths[index]) * float64(positionMs) / (audioFile.DurationSeconds * 1000))
+	}
+
+	// Offsets of audio synthesized before the chapter was edited may run past its end
+	chapterOffset = min(chapterOffset, text.lengths[index])
+
+	return &dto.BookPositionResponse{
+		BookID:        bookID,
+		ChapterID:     *audioFile.ChapterID,
+		TextOffset:    text.starts[index] + chapterOffset,
+		ChapterOffset: chapterOffset,
+		AudioFileID:   &audioFile.ID,
+		PositionMs:    &positionMs,
+		Aligned:       aligned,
+	}, nil
+}
+
+// chapterAudio returns the chapter's narration by the user's latest
+// audiobook job for the book, or nil when it has not been narrated
+func (s *positionService) chapterAudio(ctx context.Context, userID uuid.UUID, bookID int64, chapterID uuid.UUID) (*domain.AudioFile, error) {
+	job, err := s.audiobookJobRepo.GetLatestJobByBook(ctx, userID, bookID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audiobook job: %w", err)
+	}
+	if job == nil {
+		return nil, nil
+	}
+
+	tasks, err := s.audiobookJobRepo.GetTasksByJobID(ctx, job.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audiobook tasks: %w", err)
+	}
+
+	for _, task := range tasks {
+		if task.ChapterID != chapterID || task.Status != domain.AudiobookStatusCompleted || task.AudioFileID == nil {
+			continue
+		}
+
+		audioFile, err := s.audioFileRepo.GetByID(ctx, *task.AudioFileID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get chapter audio: %w", err)
+		}
+		if audioFile == nil || audioFile.Status != "completed" {
+			return nil, nil
+		}
+		return audioFile, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,288 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ponyo877/roudoku/server/domain"
+	"github.com/ponyo877/roudoku/server/dto"
+	apperrors "github.com/ponyo877/roudoku/server/pkg/errors"
+	"github.com/ponyo877/roudoku/server/pkg/logger"
+	"github.com/ponyo877/roudoku/server/pkg/ssml"
+	"github.com/ponyo877/roudoku/server/repository"
+)
+
+// PronunciationService defines the interface for the narration pronunciation lexicon
+type PronunciationService interface {
+	ListEntries(ctx context.Context, req *dto.PronunciationListRequest) (*dto.PronunciationListResponse, error)
+	GetEntry(ctx context.Context, id uuid.UUID) (*dto.PronunciationEntryResponse, error)
+	CreateEntry(ctx context.Context, req *dto.PronunciationEntryRequest) (*dto.PronunciationEntryResponse, error)
+	UpdateEntry(ctx context.Context, id uuid.UUID, req *dto.PronunciationEntryRequest) (*dto.PronunciationEntryResponse, error)
+	DeleteEntry(ctx context.Context, id uuid.UUID) error
+	// Preview shows how a text will be read with the lexicon of a book, or
+	// with the global entries only when no book is given
+	Preview(ctx context.Context, req *dto.PronunciationPreviewRequest) (*dto.PronunciationPreviewResponse, error)
+	// LexiconFor returns the lexicon narration applies to a book: global
+	// entries, overridden by the book's author entries, overridden by the
+	// book's own entries. bookID may be nil for text outside any book.
+	LexiconFor(ctx context.Context, bookID *int64) (*ssml.Lexicon, error)
+}
+
+// pronunciationService implements PronunciationService
+type pronunciationService struct {
+	*BaseService
+	pronunciationRepo repository.PronunciationRepository
+	bookRepo          repository.BookRepository
+}
+
+// NewPronunciationService creates a new pronunciation service
+func NewPronunciationService(pronunciationRepo repository.PronunciationRepository, bookRepo repository.BookRepository, logger *logger.Logger) PronunciationService {
+	return &pronunciationService{
+		BaseService:       NewBaseService(logger),
+		pronunciationRepo: pronunciationRepo,
+		bookRepo:          bookRepo,
+	}
+}
+
+// ListEntries returns lexicon entries matching the filter, ordered by surface form
+func (s *pronunciationService) ListEntries(ctx context.Context, req *dto.PronunciationListRequest) (*dto.PronunciationListResponse, error) {
+	if err := s.ValidateStruct(req); err != nil {
+		return nil, err
+	}
+	if req.Limit == 0 {
+		req.Limit = 50
+	}
+
+	entries, total, err := s.pronunciationRepo.List(ctx, &domain.PronunciationFilter{
+		Scope:   req.Scope,
+		BookID:  req.BookID,
+		Author:  req.Author,
+		Surface: req.Query,
+		Limit:   req.Limit,
+		Offset:  req.Offset,
+	})
+	if err != nil {
+		s.logger.Error("Failed to list pronunciation entries")
+		return nil, fmt.Errorf("failed to list pronunciation entries: %w", err)
+	}
+
+	response := &dto.PronunciationListResponse{
+		Entries: make([]*dto.PronunciationEntryResponse, 0, len(entries)),
+		Total:   total,
+		Limit:   req.Limit,
+		Offset:  req.Offset,
+		HasMore: req.Offset+len(entries) < total,
+	}
+	for _, entry := range entries {
+		response.Entries = append(response.Entries, buildPronunciationResponse(entry))
+	}
+
+	return response, nil
+}
+
+// GetEntry returns a single lexicon entry
+func (s *pronunciationService) GetEntry(ctx context.Context, id uuid.UUID) (*dto.PronunciationEntryResponse, error) {
+	entry, err := s.getEntry(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return buildPronunciationResponse(entry), nil
+}
+
+// CreateEntry adds a reading for a surface form in the requested scope
+func (s *pronunciationService) CreateEntry(ctx context.Context, req *dto.PronunciationEntryRequest) (*dto.PronunciationEntryResponse, error) {
+	now := time.Now()
+	entry := &domain.PronunciationEntry{
+		ID:        uuid.New(),
+		CreatedAt: now,
+	}
+	if err := s.applyEntryRequest(ctx, entry, req); err != nil {
+		return nil, err
+	}
+	entry.UpdatedAt = now
+
+	if err := s.pronunciationRepo.Create(ctx, entry); err != nil {
+		return nil, s.writeError("create", err)
+	}
+
+	return buildPronunciationResponse(entry), nil
+}
+
+// UpdateEntry replaces a lexicon entry
+func (s *pronunciationService) UpdateEntry(ctx context.Context, id uuid.UUID, req *dto.PronunciationEntryRequest) (*dto.PronunciationEntryResponse, error) {
+	entry, err := s.getEntry(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyEntryRequest(ctx, entry, req); err != nil {
+		return nil, err
+	}
+	entry.UpdatedAt = time.Now()
+
+	if err := s.pronunciationRepo.Update(ctx, entry); err != nil {
+		return nil, s.writeError("update", err)
+	}
+
+	return buildPronunciationResponse(entry), nil
+}
+
+// DeleteEntry removes a lexicon entry
+func (s *pronunciationService) DeleteEntry(ctx context.Context, id uuid.UUID) error {
+	if _, err := s.getEntry(ctx, id); err != nil {
+		return err
+	}
+	if err := s.pronunciationRepo.Delete(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete pronunciation entry: %w", err)
+	}
+	return nil
+}
+
+// Preview renders text with the applicable lexicon exactly as narration would
+func (s *pronunciationService) Preview(ctx context.Context, req *dto.PronunciationPreviewRequest) (*dto.PronunciationPreviewResponse, error) {
+	if err := s.ValidateStruct(req); err != nil {
+		return nil, err
+	}
+
+	entries, err := s.applicableEntries(ctx, req.BookID)
+	if err != nil {
+		return nil, err
+	}
+	lexicon := newPronunciationLexicon(entries)
+
+	// The last entry for a surface form is the one the lexicon applies
+	scopes := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		scopes[entry.Surface] = entry.Scope
+	}
+
+	sentences := ssml.SplitSentences(req.Text, 0)
+	response := &dto.PronunciationPreviewResponse{
+		Text:    req.Text,
+		SSML:    ssml.BuildMarked(sentences, lexicon),
+		Reading: lexicon.Read(req.Text),
+		Matches: []dto.PronunciationMatch{},
+	}
+	for _, match := range lexicon.Matches(req.Text) {
+		response.Matches = append(response.Matches, dto.PronunciationMatch{
+			Surface: match.Surface,
+			Reading: match.Reading,
+			Scope:   scopes[match.Surface],
+		})
+	}
+
+	return response, nil
+}
+
+// LexiconFor returns the lexicon that applies to a book
+func (s *pronunciationService) LexiconFor(ctx context.Context, bookID *int64) (*ssml.Lexicon, error) {
+	entries, err := s.applicableEntries(ctx, bookID)
+	if err != nil {
+		return nil, err
+	}
+	return newPronunciationLexicon(entries), nil
+}
+
+// applicableEntries returns the entries for a book from the most general
+// scope to the most specific
+func (s *pronunciationService) applicableEntries(ctx context.Context, bookID *int64) ([]*domain.PronunciationEntry, error) {
+	author := ""
+	if bookID != nil {
+		book, err := s.bookRepo.GetByID(ctx, *bookID)
+		if err != nil {
+			return nil, err
+		}
+		author = book.Author
+	}
+
+	entries, err := s.pronunciationRepo.ListApplicable(ctx, bookID, author)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pronunciation entries: %w", err)
+	}
+	return entries, nil
+}
+
+// applyEntryRequest validates req and copies it onto entry. Only the field
+// belonging to the requested scope is kept.
+func (s *pronunciationService) applyEntryRequest(ctx context.Context, entry *domain.PronunciationEntry, req *dto.PronunciationEntryRequest) error {
+	if err := s.ValidateStruct(req); err != nil {
+		return err
+	}
+
+	surface := strings.TrimSpace(req.Surface)
+	reading := strings.TrimSpace(req.Reading)
+	if surface == "" || reading == "" {
+		return apperrors.Validation("Surface and reading must not be blank")
+	}
+
+	entry.Scope = req.Scope
+	entry.BookID = nil
+	entry.Author = nil
+	switch req.Scope {
+	case domain.PronunciationScopeBook:
+		if req.BookID == nil {
+			return apperrors.Validation("book_id is required for book entries")
+		}
+		if _, err := s.bookRepo.GetByID(ctx, *req.BookID); err != nil {
+			return err
+		}
+		entry.BookID = req.BookID
+	case domain.PronunciationScopeAuthor:
+		if req.Author == nil || strings.TrimSpace(*req.Author) == "" {
+			return apperrors.Validation("author is required for author entries")
+		}
+		author := strings.TrimSpace(*req.Author)
+		entry.Author = &author
+	}
+
+	entry.Surface = surface
+	entry.Reading = reading
+	entry.Note = req.Note
+	return nil
+}
+
+func (s *pronunciationService) getEntry(ctx context.Context, id uuid.UUID) (*domain.PronunciationEntry, error) {
+	entry, err := s.pronunciationRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pronunciation entry: %w", err)
+	}
+	if entry == nil {
+		return nil, apperrors.NotFound("Pronunciation entry not found")
+	}
+	return entry, nil
+}
+
+func (s *pronunciationService) writeError(operation string, err error) error {
+	if errors.Is(err, repository.ErrDuplicateKey) {
+		return apperrors.New("CONFLICT", "The surface form already has a reading in this scope", http.StatusConflict)
+	}
+	s.logger.Error(fmt.Sprintf("Failed to %s pronunciation entry", operation))
+	return fmt.Errorf("failed to %s pronunciation entry: %w", operation, err)
+}
+
+func newPronunciationLexicon(entries []*domain.PronunciationEntry) *ssml.Lexicon {
+	lexiconEntries := make([]ssml.LexiconEntry, 0, len(entries))
+	for _, entry := range entries {
+		lexiconEntries = append(lexiconEntries, ssml.LexiconEntry{Surface: entry.Surface, Reading: entry.Reading})
+	}
+	return ssml.NewLexicon(lexiconEntries)
+}
+
+func buildPronunciationResponse(entry *domain.PronunciationEntry) *dto.PronunciationEntryResponse {
+	return &dto.PronunciationEntryResponse{
+		ID:        entry.ID,
+		Scope:     entry.Scope,
+		BookID:    entry.BookID,
+		Author:    entry.Author,
+		Surface:   entry.Surface,
+		Reading:   entry.Reading,
+		Note:      entry.Note,
+		CreatedAt: entry.CreatedAt,
+		UpdatedAt: entry.UpdatedAt,
+	}
+}
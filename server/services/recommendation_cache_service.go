@@ -0,0 +1,457 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/ponyo877/roudoku/server/domain"
+	"github.com/ponyo877/roudoku/server/dto"
+	"github.com/ponyo877/roudoku/server/pkg/cache"
+	"github.com/ponyo877/roudoku/server/pkg/logger"
+	"github.com/ponyo877/roudoku/server/repository"
+)
+
+// RecommendationCacheService caches generated recommendations in memory and
+// in the recommendation_cache table, keyed by user, recommendation type and
+// a hash of the request parameters
+type RecommendationCacheService interface {
+	// Get returns the cached response for the request, or nil
+	Get(ctx context.Context, userID uuid.UUID, req *dto.RecommendationRequest) *dto.RecommendationResponse
+	// Generation marks the state of the user's cache before recommendations
+	// are generated
+	Generation(ctx context.Context, userID uuid.UUID) RecommendationCacheGeneration
+	// Put caches the response until its ExpiresAt, unless the user's cache
+	// was invalidated after generation was taken
+	Put(ctx context.Context, userID uuid.UUID, req *dto.RecommendationRequest, response *dto.RecommendationResponse, generation RecommendationCacheGeneration)
+	// TTL returns how long recommendations of the type are cached
+	TTL(recType string) time.Duration
+	InvalidateUser(ctx context.Context, userID uuid.UUID) error
+	// RunWorker applies invalidations made elsewhere to the in-process
+	// cache and purges expired rows until ctx is cancelled
+	RunWorker(ctx context.Context)
+}
+
+// RecommendationCacheConfig configures both cache tiers
+type RecommendationCacheConfig struct {
+	// LocalEntries is the capacity of the in-process cache
+	LocalEntries int
+	// LocalTTL bounds how long an entry is served from memory
+	LocalTTL time.Duration
+	// TTL is how long recommendations are cached, unless TypeTTLs has an
+	// entry for their type
+	TTL      time.Duration
+	TypeTTLs map[string]time.Duration
+	// CleanupInterval is how often expired rows are deleted
+	CleanupInterval time.Duration
+}
+
+// recommendationCacheListenRetry is the wait before listening again after
+// the invalidation listener lost its connection
+const recommendationCacheListenRetry = 5 * time.Second
+
+type recommendationCacheKey struct {
+	userID     uuid.UUID
+	recType    string
+	paramsHash string
+}
+
+// RecommendationCacheGeneration counts the invalidations of a user's cache
+// in the database and in memory at one point in time
+type RecommendationCacheGeneration struct {
+	database  int64
+	evictions uint64
+	valid     bool
+}
+
+// cachedRecommendations is the response stored in the response column, so
+// that a database hit is served without regenerating book details
+type cachedRecommendations struct {
+	AlgorithmUsed   string                     `json:"algorithm_used"`
+	Recommendations []dto.BookRecommendation   `json:"recommendations"`
+	Context         *dto.RecommendationContext `json:"context,omitempty"`
+	Explanations    map[string]string          `json:"explanations,omitempty"`
+}
+
+// recommendationCacheService implements RecommendationCacheService. The
+// database tier is invalidated by triggers on feedback, ratings, swipes,
+// progress and model tables and by the book embedding command, which notify
+// every server so the in-process tier follows. Memory is only used while
+// that listener is connected.
+type recommendationCacheService struct {
+	*BaseService
+	cacheRepo repository.RecommendationCacheRepository
+	local     *cache.LRU[recommendationCacheKey, *dto.RecommendationResponse]
+	listening atomic.Bool
+	// evictions counts changes to the memory tier by invalidations, so that
+	// an entry read or generated before one is not added after it
+	localMu   sync.Mutex
+	evictions uint64
+	config    RecommendationCacheConfig
+}
+
+// NewRecommendationCacheService creates a new recommendation cache service
+func NewRecommendationCacheService(
+	cacheRepo repository.RecommendationCacheRepository,
+	config RecommendationCacheConfig,
+	logger *logger.Logger,
+) RecommendationCacheService {
+	if config.LocalEntries <= 0 {
+		config.LocalEntries = 10000
+	}
+	if config.LocalTTL <= 0 {
+		config.LocalTTL = 10 * time.Minute
+	}
+	if config.TTL <= 0 {
+		config.TTL = 24 * time.Hour
+	}
+	if config.TypeTTLs == nil {
+		config.TypeTTLs = map[string]time.Duration{"trending": time.Hour}
+	}
+	if config.CleanupInterval <= 0 {
+		config.CleanupInterval = time.Hour
+	}
+
+	return &recommendationCacheService{
+		BaseService: NewBaseService(logger),
+		cacheRepo:   cacheRepo,
+		local:       cache.NewLRU[recommendationCacheKey, *dto.RecommendationResponse](config.LocalEntries),
+		config:      config,
+	}
+}
+
+// Get falls back to the database on a memory miss and keeps the hit in
+// memory. Cache failures are logged and reported as misses.
+func (s *recommendationCacheService) Get(ctx context.Context, userID uuid.UUID, req *dto.RecommendationRequest) *dto.RecommendationResponse {
+	key := s.key(userID, req)
+	if s.listening.Load() {
+		if response, ok := s.local.Get(key); ok {
+			return copyRecommendationResponse(response)
+		}
+	}
+
+	evictions := s.localEvictions()
+	entry, err := s.cacheRepo.Get(ctx, userID, key.recType, key.paramsHash)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to read recommendation cache")
+		return nil
+	}
+	if entry == nil {
+		return nil
+	}
+
+	response, err := decodeCachedRecommendations(entry)
+	if err != nil {
+		s.logger.WithError(err).WithFields(zap.String("cache_id", entry.ID.String())).Error("Discarding unreadable cached recommendations")
+		s.cacheRepo.Delete(ctx, entry.ID)
+		return nil
+	}
+	s.putLocal(key, response, evictions)
+
+	return copyRecommendationResponse(response)
+}
+
+// Generation is invalid when it cannot be read, and nothing is cached then
+func (s *recommendationCacheService) Generation(ctx context.Context, userID uuid.UUID) RecommendationCacheGeneration {
+	evictions := s.localEvictions()
+	database, err := s.cacheRepo.Generation(ctx, userID)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to read recommendation cache generation")
+		return RecommendationCacheGeneration{}
+	}
+	return RecommendationCacheGeneration{database: database, evictions: evictions, valid: true}
+}
+
+func (s *recommendationCacheService) Put(ctx context.Context, userID uuid.UUID, req *dto.RecommendationRequest, response *dto.RecommendationResponse, generation RecommendationCacheGeneration) {
+	if !generation.valid {
+		return
+	}
+	key := s.key(userID, req)
+
+	entry, err := encodeCachedRecommendations(userID, key, req, response)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to encode recommendations for the cache")
+		return
+	}
+	stored, err := s.cacheRepo.Create(ctx, entry, generation.database)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to write recommendation cache")
+		return
+	}
+	if !stored {
+		// The recommendations may predate the invalidation
+		return
+	}
+
+	s.putLocal(key, copyRecommendationResponse(response), generation.evictions)
+}
+
+func (s *recommendationCacheService) TTL(recType string) time.Duration {
+	if ttl, ok := s.config.TypeTTLs[recType]; ok && ttl > 0 {
+		return ttl
+	}
+	return s.config.TTL
+}
+
+// InvalidateUser evicts the memory tier right away, so that the next request
+// on this server sees the change without waiting for the notification
+func (s *recommendationCacheService) InvalidateUser(ctx context.Context, userID uuid.UUID) error {
+	s.evictLocal(&domain.RecommendationCacheInvalidation{UserID: &userID})
+	return s.cacheRepo.InvalidateUserCache(ctx, userID)
+}
+
+// RunWorker listens again after a lost connection. The memory tier is
+// cleared whenever listening starts, since invalidations may have been
+// missed in between.
+func (s *recommendationCacheService) RunWorker(ctx context.Context) {
+	s.logger.Info("Recommendation cache worker started")
+
+	var cleanup sync.WaitGroup
+	cleanup.Add(1)
+	go func() {
+		defer cleanup.Done()
+		s.runCleanup(ctx)
+	}()
+
+	for ctx.Err() == nil {
+		err := s.cacheRepo.ListenInvalidations(ctx, func() {
+			s.clearLocal()
+			s.listening.Store(true)
+		}, s.evictLocal)
+		s.listening.Store(false)
+		if ctx.Err() != nil {
+			break
+		}
+
+		s.logger.WithError(err).Error("Recommendation cache invalidation listener stopped")
+		select {
+		case <-ctx.Done():
+		case <-time.After(recommendationCacheListenRetry):
+		}
+	}
+
+	cleanup.Wait()
+	s.logger.Info("Recommendation cache worker stopped")
+}
+
+func (s *recommendationCacheService) runCleanup(ctx context.Context) {
+	ticker := time.NewTicker(s.config.CleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		deleted, err := s.cacheRepo.DeleteExpired(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				s.logger.WithError(err).Error("Failed to delete expired recommendations")
+			}
+			continue
+		}
+		if deleted > 0 {
+			s.logger.WithFields(zap.Int64("deleted", deleted)).Info("Expired recommendations deleted")
+		}
+	}
+}
+
+// putLocal skips the entry if the memory tier was invalidated since the
+// response was read or generated
+func (s *recommendationCacheService) putLocal(key recommendationCacheKey, response *dto.RecommendationResponse, evictions uint64) {
+	if !s.listening.Load() {
+		return
+	}
+	ttl := time.Until(response.ExpiresAt)
+	if ttl > s.config.LocalTTL {
+		ttl = s.config.LocalTTL
+	}
+	if ttl <= 0 {
+		return
+	}
+
+	s.localMu.Lock()
+	defer s.localMu.Unlock()
+	if s.evictions == evictions {
+		s.local.Set(key, response, ttl)
+	}
+}
+
+func (s *recommendationCacheService) localEvictions() uint64 {
+	s.localMu.Lock()
+	defer s.localMu.Unlock()
+	return s.evictions
+}
+
+func (s *recommendationCacheService) clearLocal() {
+	s.localMu.Lock()
+	defer s.localMu.Unlock()
+	s.evictions++
+	s.local.Clear()
+}
+
+func (s *recommendationCacheService) evictLocal(invalidation *domain.RecommendationCacheInvalidation) {
+	s.localMu.Lock()
+	defer s.localMu.Unlock()
+	s.evictions++
+	s.local.DeleteFunc(func(key recommendationCacheKey) bool {
+		if invalidation.UserID != nil && key.userID != *invalidation.UserID {
+			return false
+		}
+		return len(invalidation.Types) == 0 || slices.Contains(invalidation.Types, key.recType)
+	})
+}
+
+func (s *recommendationCacheService) key(userID uuid.UUID, req *dto.RecommendationRequest) recommendationCacheKey {
+	return recommendationCacheKey{
+		userID:     userID,
+		recType:    req.RecommendationType,
+		paramsHash: recommendationParamsHash(req),
+	}
+}
+
+// recommendationParams are the request fields, besides the type, that
+// change the generated recommendations
+type recommendationParams struct {
+	Count               int                        `json:"count"`
+	Filters             *dto.RecommendationFilters `json:"filters,omitempty"`
+	Context             *dto.RecommendationContext `json:"context,omitempty"`
+	ExcludeBookIDs      []int64                    `json:"exclude_book_ids,omitempty"`
+	IncludeExplanations bool                       `json:"include_explanations"`
+}
+
+func newRecommendationParams(req *dto.RecommendationRequest) recommendationParams {
+	excluded := slices.Clone(req.ExcludeBookIDs)
+	slices.Sort(excluded)
+	return recommendationParams{
+		Count:               req.Count,
+		Filters:             req.Filters,
+		Context:             req.Context,
+		ExcludeBookIDs:      slices.Compact(excluded),
+		IncludeExplanations: req.IncludeExplanations,
+	}
+}
+
+// recommendationParamsHash hashes the JSON encoding of the parameters, whose
+// field order is fixed by the struct
+func recommendationParamsHash(req *dto.RecommendationRequest) string {
+	encoded, _ := json.Marshal(newRecommendationParams(req))
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:])
+}
+
+// copyRecommendationResponse copies a response down to its slices and maps,
+// so that callers modifying a response never modify the cached one
+func copyRecommendationResponse(response *dto.RecommendationResponse) *dto.RecommendationResponse {
+	copied := *response
+	copied.Recommendations = slices.Clone(response.Recommendations)
+	for i := range copied.Recommendations {
+		recommendation := &copied.Recommendations[i]
+		recommendation.Genre = clonePointer(recommendation.Genre)
+		recommendation.Epoch = clonePointer(recommendation.Epoch)
+		recommendation.SimilarityType = clonePointer(recommendation.SimilarityType)
+		recommendation.Reasoning = slices.Clone(recommendation.Reasoning)
+		recommendation.MatchFactors = maps.Clone(recommendation.MatchFactors)
+	}
+	if response.Context != nil {
+		copied.Context = &dto.RecommendationContext{
+			Mood:          clonePointer(response.Context.Mood),
+			TimeOfDay:     clonePointer(response.Context.TimeOfDay),
+			AvailableTime: clonePointer(response.Context.AvailableTime),
+			Location:      clonePointer(response.Context.Location),
+			Device:        clonePointer(response.Context.Device),
+			Purpose:       clonePointer(response.Context.Purpose),
+		}
+	}
+	copied.Explanations = maps.Clone(response.Explanations)
+	return &copied
+}
+
+func clonePointer[T any](value *T) *T {
+	if value == nil {
+		return nil
+	}
+	copied := *value
+	return &copied
+}
+
+func encodeCachedRecommendations(userID uuid.UUID, key recommendationCacheKey, req *dto.RecommendationRequest, response *dto.RecommendationResponse) (*domain.RecommendationCache, error) {
+	bookIDs := make([]int64, len(response.Recommendations))
+	scores := make([]float64, len(response.Recommendations))
+	for i, recommendation := range response.Recommendations {
+		bookIDs[i] = recommendation.BookID
+		scores[i] = recommendation.Score
+	}
+
+	encoded, err := json.Marshal(cachedRecommendations{
+		AlgorithmUsed:   response.AlgorithmUsed,
+		Recommendations: response.Recommendations,
+		Context:         response.Context,
+		Explanations:    response.Explanations,
+	})
+	if err != nil {
+		return nil, err
+	}
+	filters, err := toJSONMap(newRecommendationParams(req))
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.RecommendationCache{
+		ID:                 uuid.New(),
+		UserID:             userID,
+		RecommendationType: key.recType,
+		ParamsHash:         key.paramsHash,
+		BookIDs:            bookIDs,
+		Scores:             scores,
+		Response:           encoded,
+		ContextFilters:     filters,
+		ExpiresAt:          response.ExpiresAt,
+		CreatedAt:          response.GeneratedAt,
+	}, nil
+}
+
+func decodeCachedRecommendations(entry *domain.RecommendationCache) (*dto.RecommendationResponse, error) {
+	var cached cachedRecommendations
+	if err := json.Unmarshal(entry.Response, &cached); err != nil {
+		return nil, err
+	}
+	if len(cached.Recommendations) != len(entry.BookIDs) {
+		return nil, fmt.Errorf("cached recommendations do not match their book IDs")
+	}
+
+	return &dto.RecommendationResponse{
+		Recommendations: cached.Recommendations,
+		TotalCount:      len(cached.Recommendations),
+		AlgorithmUsed:   cached.AlgorithmUsed,
+		GeneratedAt:     entry.CreatedAt,
+		ExpiresAt:       entry.ExpiresAt,
+		Context:         cached.Context,
+		Explanations:    cached.Explanations,
+	}, nil
+}
+
+// toJSONMap converts a value to the map form stored in JSONB columns
+func toJSONMap(value any) (map[string]interface{}, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var result map[string]interface{}
+	if err := json.Unmarshal(encoded, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,579 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/ponyo877/roudoku/server/domain"
+	"github.com/ponyo877/roudoku/server/dto"
+	"github.com/ponyo877/roudoku/server/pkg/logger"
+	"github.com/ponyo877/roudoku/server/repository"
+)
+
+// Features scored by the pipeline scorer. Candidate sources feed one of
+// them; popularity and novelty are derived from the book itself.
+const (
+	FeatureContent       = "content"
+	FeatureCollaborative = "collaborative"
+	FeatureSequential    = "sequential"
+	FeatureSwipeAffinity = "swipe_affinity"
+	FeaturePopularity    = "popularity"
+	FeatureNovelty       = "novelty"
+)
+
+// Candidate sources and post-processors available to pipelines
+const (
+	SourceContent       = "content"
+	SourceCollaborative = "collaborative"
+	SourceSimilarUsers  = "similar_users"
+	SourceTrending      = "trending"
+	SourceSequential    = "sequential"
+	SourceSwipeAffinity = "swipe_affinity"
+
+	PostProcessorFilters       = "filters"
+	PostProcessorExcludeRead   = "exclude_read"
+	PostProcessorDiversity     = "diversity"
+	PostProcessorBusinessRules = "business_rules"
+)
+
+// Each source proposes this many candidates per requested recommendation,
+// leaving room for the post-processors to drop some
+const candidatesPerRecommendation = 3
+
+// RecommendationPipelineConfig defines a recommendation algorithm: the
+// sources that propose books, how their features are weighted and the
+// post-processors applied to the scored books, in order
+type RecommendationPipelineConfig struct {
+	Sources []string
+	// Weights overrides the RecommendationConfig weights by feature name
+	Weights map[string]float64
+	// PersonalizeWeights adjusts the weights to the user's discovery mode
+	PersonalizeWeights bool
+	PostProcessors     []string
+	// Fallback is the pipeline run when this one recommends nothing
+	Fallback string
+	// Description explains the algorithm in the response explanations
+	Description string
+}
+
+// DefaultRecommendationPipelines returns the pipelines of the built-in
+// recommendation types
+func DefaultRecommendationPipelines() map[string]RecommendationPipelineConfig {
+	postProcessors := []string{PostProcessorFilters, PostProcessorExcludeRead, PostProcessorDiversity, PostProcessorBusinessRules}
+	return map[string]RecommendationPipelineConfig{
+		"content_based": {
+			Sources:        []string{SourceContent},
+			PostProcessors: postProcessors,
+			Fallback:       "trending",
+			Description:    "あなたが過去に高評価した本と類似したコンテンツの本を推薦しています",
+		},
+		"collaborative": {
+			Sources:        []string{SourceCollaborative},
+			PostProcessors: postProcessors,
+			Fallback:       "similar_users",
+			Description:    "あなたと似た読書嗜好を持つユーザーが好んだ本を推薦しています",
+		},
+		"similar_users": {
+			Sources:        []string{SourceSimilarUsers},
+			PostProcessors: postProcessors,
+			Fallback:       "content_based",
+			Description:    "あなたと似た読書嗜好を持つユーザーが好んだ本を推薦しています",
+		},
+		"hybrid": {
+			Sources:        []string{SourceContent, SourceCollaborative},
+			PostProcessors: postProcessors,
+			Fallback:       "trending",
+			Description:    "コンテンツベースと協調フィルタリングを組み合わせた高精度な推薦です",
+		},
+		"trending": {
+			Sources:        []string{SourceTrending},
+			Weights:        map[string]float64{FeaturePopularity: 1, FeatureNovelty: 0},
+			PostProcessors: postProcessors,
+			Description:    "現在人気上昇中の高評価本を推薦しています",
+		},
+		"personalized": {
+			Sources:            []string{SourceContent, SourceCollaborative, SourceSequential, SourceSwipeAffinity, SourceTrending},
+			PersonalizeWeights: true,
+			PostProcessors:     postProcessors,
+			Fallback:           "trending",
+			Description:        "あなたの発見モードに最適化された個人向け推薦です",
+		},
+	}
+}
+
+// RecommendationQuery is what candidate sources and post-processors are
+// asked for
+type RecommendationQuery struct {
+	UserID uuid.UUID
+	// Count is how many candidates each source should propose
+	Count          int
+	Filters        *dto.RecommendationFilters
+	ExcludeBookIDs []int64
+}
+
+// recommendationFilters is the filters map passed to GenerateRecommendations
+type recommendationFilters struct {
+	Filters        *dto.RecommendationFilters `json:"filters,omitempty"`
+	ExcludeBookIDs []int64                    `json:"exclude_book_ids,omitempty"`
+}
+
+// SourceCandidate is a book proposed by a candidate source
+type SourceCandidate struct {
+	BookID int64
+	// Score is the strength of the proposal in [0, 1]
+	Score  float64
+	Reason string
+	// Book may be set by sources that already loaded it
+	Book *domain.Book
+}
+
+// CandidateSource proposes books for the feature it feeds
+type CandidateSource interface {
+	Name() string
+	Feature() string
+	// Realtime reports whether new interactions change the candidates
+	// without waiting for a batch job
+	Realtime() bool
+	Candidates(ctx context.Context, query *RecommendationQuery) ([]*SourceCandidate, error)
+}
+
+// RecommendationCandidate is a book proposed by one or more sources
+type RecommendationCandidate struct {
+	Book *domain.Book
+	// Features are in [0, 1], by feature name
+	Features map[string]float64
+	Reasons  []string
+	// Sources are the names of the sources that proposed the book
+	Sources []string
+	Score   float64
+}
+
+// RecommendationPostProcessor filters or adjusts the scored candidates,
+// which it receives best first
+type RecommendationPostProcessor interface {
+	Name() string
+	Process(ctx context.Context, query *RecommendationQuery, candidates []*RecommendationCandidate) ([]*RecommendationCandidate, error)
+}
+
+// recommendationFeatureBuilder merges the proposals of all sources into one
+// candidate per book and derives the book features
+type recommendationFeatureBuilder struct {
+	bookRepo repository.BookRepository
+	logger   *logger.Logger
+}
+
+// Build keeps the strongest proposal of each feature. Books that no longer
+// exist or are inactive are dropped.
+func (b *recommendationFeatureBuilder) Build(ctx context.Context, sources []CandidateSource, proposals [][]*SourceCandidate) []*RecommendationCandidate {
+	books := b.loadBooks(ctx, proposals)
+
+	byBook := make(map[int64]*RecommendationCandidate)
+	var order []int64
+	for i, source := range sources {
+		for _, proposal := range proposals[i] {
+			candidate, ok := byBook[proposal.BookID]
+			if !ok {
+				book := proposal.Book
+				if book == nil {
+					book = books[proposal.BookID]
+				}
+				if book == nil || !book.IsActive {
+					continue
+				}
+				candidate = &RecommendationCandidate{Book: book, Features: make(map[string]float64)}
+				byBook[proposal.BookID] = candidate
+				order = append(order, proposal.BookID)
+			}
+
+			score := math.Max(0, math.Min(proposal.Score, 1))
+			if score > candidate.Features[source.Feature()] {
+				candidate.Features[source.Feature()] = score
+			}
+			if proposal.Reason != "" {
+				candidate.Reasons = append(candidate.Reasons, proposal.Reason)
+			}
+			if !slices.Contains(candidate.Sources, source.Name()) {
+				candidate.Sources = append(candidate.Sources, source.Name())
+			}
+		}
+	}
+
+	candidates := make([]*RecommendationCandidate, 0, len(order))
+	for _, bookID := range order {
+		candidate := byBook[bookID]
+		popularity := popularityScore(candidate.Book)
+		candidate.Features[FeaturePopularity] = popularity
+		candidate.Features[FeatureNovelty] = noveltyScore(candidate.Book, popularity)
+		candidates = append(candidates, candidate)
+	}
+	return candidates
+}
+
+// loadBooks loads, in one query, the books proposed without their details.
+// Books that fail to load are left out of the candidates.
+func (b *recommendationFeatureBuilder) loadBooks(ctx context.Context, proposals [][]*SourceCandidate) map[int64]*domain.Book {
+	var missing []int64
+	seen := make(map[int64]bool)
+	for _, sourceProposals := range proposals {
+		for _, proposal := range sourceProposals {
+			if proposal.Book == nil && !seen[proposal.BookID] {
+				seen[proposal.BookID] = true
+				missing = append(missing, proposal.BookID)
+			}
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	loaded, err := b.bookRepo.GetByIDs(ctx, missing)
+	if err != nil {
+		b.logger.WithError(err).Warn("Failed to load recommended books")
+		return nil
+	}
+	books := make(map[int64]*domain.Book, len(loaded))
+	for _, book := range loaded {
+		books[book.ID] = book
+	}
+	return books
+}
+
+// popularityScore combines downloads on a log scale with the rating
+func popularityScore(book *domain.Book) float64 {
+	downloadScore := math.Log(float64(book.DownloadCount+1)) / 10.0
+	ratingScore := book.RatingAverage / 5.0
+	return math.Min((downloadScore+ratingScore)/2.0, 1)
+}
+
+// noveltyScore favours less popular books and books added recently, whose
+// recency halves every 90 days
+func noveltyScore(book *domain.Book, popularity float64) float64 {
+	days := time.Since(book.CreatedAt).Hours() / 24
+	recency := math.Pow(0.5, math.Max(days, 0)/90)
+	return (1-popularity)/2 + recency/2
+}
+
+// recommendationWeights maps the RecommendationConfig weights to features
+func recommendationWeights(config *domain.RecommendationConfig) map[string]float64 {
+	return map[string]float64{
+		FeatureContent:       config.ContentWeight,
+		FeatureCollaborative: config.CollaborativeWeight,
+		FeatureSequential:    config.SequentialWeight,
+		FeatureSwipeAffinity: config.SwipeAffinityWeight,
+		FeaturePopularity:    config.PopularityWeight,
+		FeatureNovelty:       config.NoveltyWeight,
+	}
+}
+
+// discoveryModeWeights shifts weight from the reader's history to
+// popularity and novelty for adventurous readers, and back for
+// conservative ones. Balanced readers keep the configured weights.
+func discoveryModeWeights(mode string) map[string]float64 {
+	switch mode {
+	case "conservative":
+		return map[string]float64{
+			FeatureContent:       0.6,
+			FeatureCollaborative: 0.3,
+			FeatureNovelty:       0.05,
+			FeaturePopularity:    0.05,
+		}
+	case "adventurous":
+		return map[string]float64{
+			FeatureContent:       0.2,
+			FeatureCollaborative: 0.2,
+			FeatureNovelty:       0.4,
+			FeaturePopularity:    0.2,
+		}
+	default:
+		return nil
+	}
+}
+
+// scoreCandidate is the weighted sum of the candidate features
+func scoreCandidate(candidate *RecommendationCandidate, weights map[string]float64) float64 {
+	var score float64
+	for feature, value := range candidate.Features {
+		score += weights[feature] * value
+	}
+	return score
+}
+
+func sortCandidates(candidates []*RecommendationCandidate) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		return candidates[i].Book.ID < candidates[j].Book.ID
+	})
+}
+
+// recommendationPipeline implements domain.RecommendationAlgorithm
+type recommendationPipeline struct {
+	name            string
+	sources         []CandidateSource
+	builder         *recommendationFeatureBuilder
+	weights         map[string]float64
+	personalize     bool
+	preferencesRepo repository.UserPreferencesRepository
+	postProcessors  []RecommendationPostProcessor
+	fallback        domain.RecommendationAlgorithm
+	logger          *logger.Logger
+}
+
+func (p *recommendationPipeline) GetAlgorithmName() string {
+	return p.name
+}
+
+// SupportsRealtime holds when every source is realtime
+func (p *recommendationPipeline) SupportsRealtime() bool {
+	for _, source := range p.sources {
+		if !source.Realtime() {
+			return false
+		}
+	}
+	return true
+}
+
+// GenerateRecommendations queries the sources concurrently. A failing
+// source is logged and skipped unless all of them fail.
+func (p *recommendationPipeline) GenerateRecommendations(ctx context.Context, userID uuid.UUID, count int, filters map[string]interface{}) ([]*domain.BookRecommendation, error) {
+	var decoded recommendationFilters
+	if filters != nil {
+		encoded, err := json.Marshal(filters)
+		if err != nil {
+			return nil, fmt.Errorf("invalid recommendation filters: %w", err)
+		}
+		if err := json.Unmarshal(encoded, &decoded); err != nil {
+			return nil, fmt.Errorf("invalid recommendation filters: %w", err)
+		}
+	}
+	query := &RecommendationQuery{
+		UserID:         userID,
+		Count:          count * candidatesPerRecommendation,
+		Filters:        decoded.Filters,
+		ExcludeBookIDs: decoded.ExcludeBookIDs,
+	}
+
+	proposals := make([][]*SourceCandidate, len(p.sources))
+	errs := make([]error, len(p.sources))
+	var wg sync.WaitGroup
+	for i, source := range p.sources {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			proposals[i], errs[i] = source.Candidates(ctx, query)
+		}()
+	}
+	wg.Wait()
+
+	failed := 0
+	for i, err := range errs {
+		if err != nil {
+			failed++
+			p.logger.WithError(err).WithFields(
+				zap.String("pipeline", p.name),
+				zap.String("source", p.sources[i].Name()),
+			).Error("Recommendation candidate source failed")
+		}
+	}
+	if failed == len(p.sources) {
+		return nil, fmt.Errorf("all candidate sources of %s failed: %w", p.name, errs[0])
+	}
+
+	candidates := p.builder.Build(ctx, p.sources, proposals)
+
+	weights, err := p.weightsFor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, candidate := range candidates {
+		candidate.Score = scoreCandidate(candidate, weights)
+	}
+	sortCandidates(candidates)
+
+	for _, postProcessor := range p.postProcessors {
+		candidates, err = postProcessor.Process(ctx, query, candidates)
+		if err != nil {
+			return nil, fmt.Errorf("%s post-processor failed: %w", postProcessor.Name(), err)
+		}
+		sortCandidates(candidates)
+	}
+
+	if len(candidates) == 0 && p.fallback != nil {
+		return p.fallback.GenerateRecommendations(ctx, userID, count, filters)
+	}
+	if len(candidates) > count {
+		candidates = candidates[:count]
+	}
+
+	recommendations := make([]*domain.BookRecommendation, len(candidates))
+	for i, candidate := range candidates {
+		similarityType := "hybrid"
+		if len(candidate.Sources) == 1 {
+			similarityType = candidate.Sources[0]
+		}
+		recommendations[i] = &domain.BookRecommendation{
+			Book:           candidate.Book,
+			Score:          candidate.Score,
+			Reasoning:      candidate.Reasons,
+			SimilarityType: similarityType,
+			MatchFactors:   candidate.Features,
+			Confidence:     candidate.Score,
+		}
+	}
+	return recommendations, nil
+}
+
+func (p *recommendationPipeline) weightsFor(ctx context.Context, userID uuid.UUID) (map[string]float64, error) {
+	if !p.personalize {
+		return p.weights, nil
+	}
+
+	prefs, err := p.preferencesRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if prefs == nil {
+		return p.weights, nil
+	}
+
+	adjusted := discoveryModeWeights(prefs.DiscoveryMode)
+	if adjusted == nil {
+		return p.weights, nil
+	}
+	weights := make(map[string]float64, len(p.weights))
+	for feature, weight := range p.weights {
+		weights[feature] = weight
+	}
+	for feature, weight := range adjusted {
+		weights[feature] = weight
+	}
+	return weights, nil
+}
+
+// recommendationPipelineBuilder assembles pipelines from their
+// configuration
+type recommendationPipelineBuilder struct {
+	configs        map[string]RecommendationPipelineConfig
+	sources        map[string]CandidateSource
+	postProcessors map[string]RecommendationPostProcessor
+	builder        *recommendationFeatureBuilder
+	weights        map[string]float64
+	preferences    repository.UserPreferencesRepository
+	logger         *logger.Logger
+
+	built    map[string]*recommendationPipeline
+	building map[string]bool
+}
+
+// Build returns the pipeline and builds its fallbacks first, rejecting
+// unknown names and fallback cycles
+func (b *recommendationPipelineBuilder) Build(name string) (*recommendationPipeline, error) {
+	if pipeline, ok := b.built[name]; ok {
+		return pipeline, nil
+	}
+	config, ok := b.configs[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown recommendation pipeline: %s", name)
+	}
+	if b.building[name] {
+		return nil, fmt.Errorf("recommendation pipeline %s is part of a fallback cycle", name)
+	}
+	b.building[name] = true
+	defer delete(b.building, name)
+
+	if len(config.Sources) == 0 {
+		return nil, fmt.Errorf("recommendation pipeline %s has no sources", name)
+	}
+
+	pipeline := &recommendationPipeline{
+		name:            name,
+		builder:         b.builder,
+		weights:         make(map[string]float64, len(b.weights)),
+		personalize:     config.PersonalizeWeights,
+		preferencesRepo: b.preferences,
+		logger:          b.logger,
+	}
+	for _, sourceName := range config.Sources {
+		source, ok := b.sources[sourceName]
+		if !ok {
+			return nil, fmt.Errorf("recommendation pipeline %s: unknown candidate source: %s", name, sourceName)
+		}
+		pipeline.sources = append(pipeline.sources, source)
+	}
+	for _, postProcessorName := range config.PostProcessors {
+		postProcessor, ok := b.postProcessors[postProcessorName]
+		if !ok {
+			return nil, fmt.Errorf("recommendation pipeline %s: unknown post-processor: %s", name, postProcessorName)
+		}
+		pipeline.postProcessors = append(pipeline.postProcessors, postProcessor)
+	}
+	for feature, weight := range b.weights {
+		pipeline.weights[feature] = weight
+	}
+	for feature, weight := range config.Weights {
+		if _, ok := b.weights[feature]; !ok {
+			return nil, fmt.Errorf("recommendation pipeline %s: unknown feature: %s", name, feature)
+		}
+		pipeline.weights[feature] = weight
+	}
+
+	if config.Fallback != "" {
+		fallback, err := b.Build(config.Fallback)
+		if err != nil {
+			return nil, err
+		}
+		pipeline.fallback = fallback
+	}
+
+	b.built[name] = pipeline
+	return pipeline, nil
+}
+
+// newRecommendationPipelines builds every configured pipeline
+func newRecommendationPipelines(
+	configs map[string]RecommendationPipelineConfig,
+	sources []CandidateSource,
+	postProcessors []RecommendationPostProcessor,
+	bookRepo repository.BookRepository,
+	preferencesRepo repository.UserPreferencesRepository,
+	config *domain.RecommendationConfig,
+	logger *logger.Logger,
+) (map[string]domain.RecommendationAlgorithm, error) {
+	builder := &recommendationPipelineBuilder{
+		configs:        configs,
+		sources:        make(map[string]CandidateSource, len(sources)),
+		postProcessors: make(map[string]RecommendationPostProcessor, len(postProcessors)),
+		builder:        &recommendationFeatureBuilder{bookRepo: bookRepo, logger: logger},
+		weights:        recommendationWeights(config),
+		preferences:    preferencesRepo,
+		logger:         logger,
+		built:          make(map[string]*recommendationPipeline),
+		building:       make(map[string]bool),
+	}
+	for _, source := range sources {
+		builder.sources[source.Name()] = source
+	}
+	for _, postProcessor := range postProcessors {
+		builder.postProcessors[postProcessor.Name()] = postProcessor
+	}
+
+	pipelines := make(map[string]domain.RecommendationAlgorithm, len(configs))
+	for name := range configs {
+		pipeline, err := builder.Build(name)
+		if err != nil {
+			return nil, err
+		}
+		pipelines[name] = pipeline
+	}
+	return pipelines, nil
+}
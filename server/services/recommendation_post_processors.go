@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/ponyo877/roudoku/server/domain"
+	"github.com/ponyo877/roudoku/server/dto"
+	"github.com/ponyo877/roudoku/server/repository"
+)
+
+// filtersPostProcessor drops the books that do not pass the request filters
+// or were explicitly excluded
+type filtersPostProcessor struct{}
+
+func (p *filtersPostProcessor) Name() string { return PostProcessorFilters }
+
+func (p *filtersPostProcessor) Process(ctx context.Context, query *RecommendationQuery, candidates []*RecommendationCandidate) ([]*RecommendationCandidate, error) {
+	return slices.DeleteFunc(candidates, func(candidate *RecommendationCandidate) bool {
+		if slices.Contains(query.ExcludeBookIDs, candidate.Book.ID) {
+			return true
+		}
+		return query.Filters != nil && !passesRecommendationFilters(candidate.Book, query.Filters)
+	}), nil
+}
+
+func passesRecommendationFilters(book *domain.Book, filters *dto.RecommendationFilters) bool {
+	// Check genres
+	if len(filters.Genres) > 0 && book.Genre != nil && !slices.Contains(filters.Genres, *book.Genre) {
+		return false
+	}
+
+	// Check minimum rating
+	if filters.MinRating != nil && book.RatingAverage < *filters.MinRating {
+		return false
+	}
+
+	// Check word count limits
+	if filters.MinWordCount != nil && book.WordCount < *filters.MinWordCount {
+		return false
+	}
+	if filters.MaxWordCount != nil && book.WordCount > *filters.MaxWordCount {
+		return false
+	}
+
+	// Check difficulty levels
+	if len(filters.DifficultyLevels) > 0 && !slices.Contains(filters.DifficultyLevels, book.DifficultyLevel) {
+		return false
+	}
+
+	// Check premium status
+	if !filters.IncludePremium && book.IsPremium {
+		return false
+	}
+
+	return true
+}
+
+// excludeReadPostProcessor drops the books the user is reading, and the
+// books they finished unless the filters ask to keep them
+type excludeReadPostProcessor struct {
+	progressRepo repository.BookProgressRepository
+}
+
+// Finished books beyond this many are not looked up
+const excludeReadMaxCompleted = 1000
+
+func (p *excludeReadPostProcessor) Name() string { return PostProcessorExcludeRead }
+
+func (p *excludeReadPostProcessor) Process(ctx context.Context, query *RecommendationQuery, candidates []*RecommendationCandidate) ([]*RecommendationCandidate, error) {
+	progress, err := p.progressRepo.GetActiveByUserID(ctx, query.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if query.Filters == nil || query.Filters.ExcludeCompleted {
+		completed, err := p.progressRepo.GetCompletedByUserID(ctx, query.UserID, excludeReadMaxCompleted, 0)
+		if err != nil {
+			return nil, err
+		}
+		progress = append(progress, completed...)
+	}
+
+	read := make(map[int64]bool, len(progress))
+	for _, bookProgress := range progress {
+		read[bookProgress.BookID] = true
+	}
+	return slices.DeleteFunc(candidates, func(candidate *RecommendationCandidate) bool {
+		return read[candidate.Book.ID]
+	}), nil
+}
+
+// diversityPostProcessor demotes candidates beyond the first few of the
+// best candidates that share a genre or an author. Demoted candidates stay
+// in the list, so they still fill the recommendations when there are too
+// few others.
+type diversityPostProcessor struct{}
+
+const (
+	diversityMaxPerGenre  = 3
+	diversityMaxPerAuthor = 2
+	// diversityPenalty scales the score of a candidate once for every
+	// candidate it is beyond the cap of its genre or author
+	diversityPenalty = 0.5
+)
+
+func (p *diversityPostProcessor) Name() string { return PostProcessorDiversity }
+
+func (p *diversityPostProcessor) Process(ctx context.Context, query *RecommendationQuery, candidates []*RecommendationCandidate) ([]*RecommendationCandidate, error) {
+	genreCounts := make(map[string]int)
+	authorCounts := make(map[string]int)
+	for _, candidate := range candidates {
+		genre := "unknown"
+		if candidate.Book.Genre != nil {
+			genre = *candidate.Book.Genre
+		}
+		genreCounts[genre]++
+		authorCounts[candidate.Book.Author]++
+
+		excess := max(genreCounts[genre]-diversityMaxPerGenre, authorCounts[candidate.Book.Author]-diversityMaxPerAuthor)
+		if excess > 0 {
+			candidate.Score *= math.Pow(diversityPenalty, float64(excess))
+		}
+	}
+	return candidates, nil
+}
+
+// businessRulesPostProcessor boosts new books and books whose rating is
+// backed by enough ratings to be reliable
+type businessRulesPostProcessor struct{}
+
+func (p *businessRulesPostProcessor) Name() string { return PostProcessorBusinessRules }
+
+func (p *businessRulesPostProcessor) Process(ctx context.Context, query *RecommendationQuery, candidates []*RecommendationCandidate) ([]*RecommendationCandidate, error) {
+	for _, candidate := range candidates {
+		// Boost newer books slightly
+		if time.Since(candidate.Book.CreatedAt) < 30*24*time.Hour {
+			candidate.Score *= 1.1
+		}
+
+		// Boost books with more ratings (reliability)
+		if candidate.Book.RatingCount > 10 {
+			candidate.Score *= 1.05
+		}
+	}
+	return candidates, nil
+}
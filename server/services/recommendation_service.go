@@ -3,14 +3,14 @@ package services
 import (
 	"context"
 	"fmt"
-	"math"
-	"sort"
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
 
 	"github.com/ponyo877/roudoku/server/domain"
 	"github.com/ponyo877/roudoku/server/dto"
+	apperrors "github.com/ponyo877/roudoku/server/pkg/errors"
 	"github.com/ponyo877/roudoku/server/pkg/logger"
 	"github.com/ponyo877/roudoku/server/repository"
 )
@@ -42,36 +42,71 @@ type recommendationService struct {
 	preferencesRepo     repository.UserPreferencesRepository
 	interactionRepo     repository.UserInteractionRepository
 	similarityRepo      repository.UserSimilarityRepository
-	cacheRepo           repository.RecommendationCacheRepository
+	cacheService        RecommendationCacheService
 	feedbackRepo        repository.RecommendationFeedbackRepository
 	vectorRepo          repository.BookVectorRepository
 	bookRepo            repository.BookRepository
-	progressRepo        repository.BookProgressRepository
 	config              *domain.RecommendationConfig
+	pipelines           map[string]domain.RecommendationAlgorithm
+	descriptions        map[string]string
 }
 
-// NewRecommendationService creates a new recommendation service
+// NewRecommendationService creates a new recommendation service. Each
+// recommendation type is generated by the pipeline of the same name;
+// DefaultRecommendationPipelines is used when pipelines is empty.
 func NewRecommendationService(
 	preferencesRepo repository.UserPreferencesRepository,
 	interactionRepo repository.UserInteractionRepository,
 	similarityRepo repository.UserSimilarityRepository,
-	cacheRepo repository.RecommendationCacheRepository,
+	cacheService RecommendationCacheService,
 	feedbackRepo repository.RecommendationFeedbackRepository,
 	vectorRepo repository.BookVectorRepository,
 	bookRepo repository.BookRepository,
 	progressRepo repository.BookProgressRepository,
+	swipeRepo repository.SwipeRepository,
+	cfService CollaborativeFilteringService,
+	pipelines map[string]RecommendationPipelineConfig,
 	logger *logger.Logger,
-) RecommendationService {
+) (RecommendationService, error) {
 	config := &domain.RecommendationConfig{
 		ContentWeight:       0.4,
 		CollaborativeWeight: 0.3,
+		SequentialWeight:    0.3,
+		SwipeAffinityWeight: 0.2,
 		PopularityWeight:    0.2,
 		NoveltyWeight:       0.1,
 		DiversityThreshold:  0.8,
 		MinScore:           0.1,
 		UseRealtime:        true,
 		CacheEnabled:       true,
-		CacheTTLHours:      24,
+	}
+
+	if len(pipelines) == 0 {
+		pipelines = DefaultRecommendationPipelines()
+	}
+
+	sources := []CandidateSource{
+		&contentSource{interactionRepo: interactionRepo, vectorRepo: vectorRepo, bookRepo: bookRepo},
+		&collaborativeSource{cfService: cfService},
+		&similarUsersSource{similarityRepo: similarityRepo, interactionRepo: interactionRepo},
+		&trendingSource{bookRepo: bookRepo},
+		&sequentialSource{progressRepo: progressRepo, bookRepo: bookRepo, vectorRepo: vectorRepo},
+		&swipeAffinitySource{swipeRepo: swipeRepo, vectorRepo: vectorRepo, bookRepo: bookRepo},
+	}
+	postProcessors := []RecommendationPostProcessor{
+		&filtersPostProcessor{},
+		&excludeReadPostProcessor{progressRepo: progressRepo},
+		&diversityPostProcessor{},
+		&businessRulesPostProcessor{},
+	}
+
+	algorithms, err := newRecommendationPipelines(pipelines, sources, postProcessors, bookRepo, preferencesRepo, config, logger)
+	if err != nil {
+		return nil, err
+	}
+	descriptions := make(map[string]string, len(pipelines))
+	for name, pipeline := range pipelines {
+		descriptions[name] = pipeline.Description
 	}
 
 	return &recommendationService{
@@ -79,13 +114,14 @@ func NewRecommendationService(
 		preferencesRepo: preferencesRepo,
 		interactionRepo: interactionRepo,
 		similarityRepo:  similarityRepo,
-		cacheRepo:       cacheRepo,
+		cacheService:    cacheService,
 		feedbackRepo:    feedbackRepo,
 		vectorRepo:      vectorRepo,
 		bookRepo:        bookRepo,
-		progressRepo:    progressRepo,
 		config:          config,
-	}
+		pipelines:       algorithms,
+		descriptions:    descriptions,
+	}, nil
 }
 
 // GetRecommendations generates personalized book recommendations
@@ -102,65 +138,40 @@ func (s *recommendationService) GetRecommendations(ctx context.Context, userID u
 	}
 
 	// Check cache first
+	var generation RecommendationCacheGeneration
 	if s.config.CacheEnabled {
-		cached, err := s.getCachedRecommendations(ctx, userID, req.RecommendationType)
-		if err == nil && cached != nil {
-			return s.convertCacheToResponse(cached, req), nil
+		generation = s.cacheService.Generation(ctx, userID)
+		if cached := s.cacheService.Get(ctx, userID, req); cached != nil {
+			return cached, nil
 		}
 	}
 
-	// Generate recommendations based on type
-	var recommendations []*domain.BookRecommendation
-	var algorithmUsed string
-	var err error
-
-	switch req.RecommendationType {
-	case "content_based":
-		recommendations, err = s.generateContentBasedRecommendations(ctx, userID, req)
-		algorithmUsed = "content_based"
-	case "collaborative":
-		recommendations, err = s.generateCollaborativeRecommendations(ctx, userID, req)
-		algorithmUsed = "collaborative_filtering"
-	case "hybrid":
-		recommendations, err = s.generateHybridRecommendations(ctx, userID, req)
-		algorithmUsed = "hybrid"
-	case "trending":
-		recommendations, err = s.generateTrendingRecommendations(ctx, userID, req)
-		algorithmUsed = "trending"
-	case "personalized":
-		recommendations, err = s.generatePersonalizedRecommendations(ctx, userID, req)
-		algorithmUsed = "personalized_hybrid"
-	default:
-		return nil, fmt.Errorf("unsupported recommendation type: %s", req.RecommendationType)
+	// Generate recommendations with the pipeline of the requested type
+	pipeline, ok := s.pipelines[req.RecommendationType]
+	if !ok {
+		return nil, apperrors.BadRequest(fmt.Sprintf("Unsupported recommendation type: %s", req.RecommendationType), nil)
 	}
 
+	filters, err := toJSONMap(recommendationFilters{Filters: req.Filters, ExcludeBookIDs: req.ExcludeBookIDs})
 	if err != nil {
-		s.logger.Error("Failed to generate recommendations")
-		return nil, fmt.Errorf("failed to generate recommendations: %w", err)
+		return nil, fmt.Errorf("failed to encode recommendation filters: %w", err)
 	}
 
-	// Apply filters and ranking
-	recommendations = s.applyFilters(recommendations, req.Filters)
-	recommendations = s.ensureDiversity(recommendations)
-	recommendations = s.rankRecommendations(recommendations)
-
-	// Limit results
-	if len(recommendations) > req.Count {
-		recommendations = recommendations[:req.Count]
-	}
-
-	// Cache results
-	if s.config.CacheEnabled {
-		s.cacheRecommendations(ctx, userID, req.RecommendationType, recommendations, req)
+	recommendations, err := pipeline.GenerateRecommendations(ctx, userID, req.Count, filters)
+	if err != nil {
+		s.logger.Error("Failed to generate recommendations")
+		return nil, fmt.Errorf("failed to generate recommendations: %w", err)
 	}
+	algorithmUsed := pipeline.GetAlgorithmName()
 
 	// Convert to response
+	generatedAt := time.Now()
 	response := &dto.RecommendationResponse{
 		Recommendations: s.convertToBookRecommendations(recommendations),
 		TotalCount:      len(recommendations),
 		AlgorithmUsed:   algorithmUsed,
-		GeneratedAt:     time.Now(),
-		ExpiresAt:       time.Now().Add(time.Duration(s.config.CacheTTLHours) * time.Hour),
+		GeneratedAt:     generatedAt,
+		ExpiresAt:       generatedAt.Add(s.cacheService.TTL(req.RecommendationType)),
 		Context:         req.Context,
 	}
 
@@ -168,6 +179,11 @@ func (s *recommendationService) GetRecommendations(ctx context.Context, userID u
 		response.Explanations = s.generateExplanations(recommendations, algorithmUsed)
 	}
 
+	// Cache results
+	if s.config.CacheEnabled && len(recommendations) > 0 {
+		s.cacheService.Put(ctx, userID, req, response, generation)
+	}
+
 	return response, nil
 }
 
@@ -304,366 +320,14 @@ func (s *recommendationService) RecordFeedback(ctx context.Context, userID uuid.
 	// Record as user interaction as well
 	s.recordInteractionFromFeedback(ctx, userID, req)
 
-	return nil
-}
-
-// Private helper methods
-
-func (s *recommendationService) generateContentBasedRecommendations(ctx context.Context, userID uuid.UUID, req *dto.RecommendationRequest) ([]*domain.BookRecommendation, error) {
-	// Get user's reading history and preferences
-	interactions, err := s.interactionRepo.GetMostInteractedBooks(ctx, userID, 20)
-	if err != nil {
-		return nil, err
-	}
-
-	if len(interactions) == 0 {
-		// New user - return popular books
-		return s.generateTrendingRecommendations(ctx, userID, req)
-	}
-
-	var recommendations []*domain.BookRecommendation
-
-	// For each highly-rated book, find similar books
-	for _, interaction := range interactions[:min(5, len(interactions))] {
-		if interaction.Score > 0.7 { // Only use books user liked
-			similarBooks, err := s.vectorRepo.GetSimilarBooks(ctx, interaction.BookID, 5, "content")
-			if err != nil {
-				continue
-			}
-
-			for _, similar := range similarBooks {
-				book, err := s.bookRepo.GetByID(ctx, similar.BookID)
-				if err != nil || book == nil {
-					continue
-				}
-
-				score := similar.Similarity * interaction.Score * s.config.ContentWeight
-				
-				recommendation := &domain.BookRecommendation{
-					Book:           book,
-					Score:          score,
-					Reasoning:      []string{fmt.Sprintf("Similar to %s which you enjoyed", getBookTitle(interaction.BookID))},
-					SimilarityType: "content",
-					MatchFactors:   map[string]float64{"content_similarity": similar.Similarity, "user_rating": interaction.Score},
-					Confidence:     score,
-				}
-
-				recommendations = append(recommendations, recommendation)
-			}
-		}
-	}
-
-	return recommendations, nil
-}
-
-func (s *recommendationService) generateCollaborativeRecommendations(ctx context.Context, userID uuid.UUID, req *dto.RecommendationRequest) ([]*domain.BookRecommendation, error) {
-	// Get similar users
-	similarUsers, err := s.similarityRepo.GetSimilarUsers(ctx, userID, 10)
-	if err != nil {
-		return nil, err
-	}
-
-	if len(similarUsers) == 0 {
-		// No similar users found - fall back to content-based
-		return s.generateContentBasedRecommendations(ctx, userID, req)
-	}
-
-	var recommendations []*domain.BookRecommendation
-	bookScores := make(map[int64]float64)
-	bookCounts := make(map[int64]int)
-
-	// Aggregate recommendations from similar users
-	for _, similarUser := range similarUsers {
-		interactions, err := s.interactionRepo.GetMostInteractedBooks(ctx, similarUser.UserAID, 10)
-		if err != nil {
-			continue
-		}
-
-		for _, interaction := range interactions {
-			if interaction.Score > 0.6 { // Only consider books they liked
-				weight := similarUser.SimilarityScore * s.config.CollaborativeWeight
-				bookScores[interaction.BookID] += interaction.Score * weight
-				bookCounts[interaction.BookID]++
-			}
-		}
-	}
-
-	// Convert to recommendations
-	for bookID, score := range bookScores {
-		if bookCounts[bookID] >= 2 { // At least 2 similar users liked it
-			book, err := s.bookRepo.GetByID(ctx, bookID)
-			if err != nil || book == nil {
-				continue
-			}
-
-			avgScore := score / float64(bookCounts[bookID])
-			
-			recommendation := &domain.BookRecommendation{
-				Book:           book,
-				Score:          avgScore,
-				Reasoning:      []string{fmt.Sprintf("Recommended by %d users with similar taste", bookCounts[bookID])},
-				SimilarityType: "collaborative",
-				MatchFactors:   map[string]float64{"collaborative_score": avgScore, "recommender_count": float64(bookCounts[bookID])},
-				Confidence:     avgScore,
-			}
-
-			recommendations = append(recommendations, recommendation)
-		}
-	}
-
-	return recommendations, nil
-}
-
-func (s *recommendationService) generateHybridRecommendations(ctx context.Context, userID uuid.UUID, req *dto.RecommendationRequest) ([]*domain.BookRecommendation, error) {
-	// Get both content-based and collaborative recommendations
-	contentRecs, err := s.generateContentBasedRecommendations(ctx, userID, req)
-	if err != nil {
-		contentRecs = []*domain.BookRecommendation{}
-	}
-
-	collabRecs, err := s.generateCollaborativeRecommendations(ctx, userID, req)
-	if err != nil {
-		collabRecs = []*domain.BookRecommendation{}
-	}
-
-	// Combine and rerank
-	combinedRecs := make(map[int64]*domain.BookRecommendation)
-
-	// Add content-based recommendations
-	for _, rec := range contentRecs {
-		combinedRecs[rec.Book.ID] = rec
-	}
-
-	// Merge collaborative recommendations
-	for _, rec := range collabRecs {
-		if existing, exists := combinedRecs[rec.Book.ID]; exists {
-			// Combine scores
-			existing.Score = (existing.Score + rec.Score) / 2
-			existing.Reasoning = append(existing.Reasoning, rec.Reasoning...)
-			existing.SimilarityType = "hybrid"
-		} else {
-			combinedRecs[rec.Book.ID] = rec
-		}
-	}
-
-	// Convert back to slice
-	var recommendations []*domain.BookRecommendation
-	for _, rec := range combinedRecs {
-		recommendations = append(recommendations, rec)
-	}
-
-	return recommendations, nil
-}
-
-func (s *recommendationService) generateTrendingRecommendations(ctx context.Context, userID uuid.UUID, req *dto.RecommendationRequest) ([]*domain.BookRecommendation, error) {
-	// For now, return highly-rated popular books
-	// In a real system, this would use time-weighted popularity metrics
-	
-	filter := &domain.BookFilter{
-		MinRating: floatPtr(4.0),
-		IsActive:  boolPtr(true),
-	}
-
-	searchReq := &domain.BookSearchRequest{
-		Filter: filter,
-		SortBy: domain.SortByPopularity,
-		Limit:  req.Count * 2, // Get more to allow for filtering
-		Offset: 0,
-	}
-
-	books, _, err := s.bookRepo.List(context.Background(), searchReq)
-	if err != nil {
-		return nil, err
+	if err := s.InvalidateRecommendationCache(ctx, userID); err != nil {
+		s.logger.WithError(err).Error("Failed to invalidate recommendation cache after feedback")
 	}
 
-	var recommendations []*domain.BookRecommendation
-	for _, book := range books {
-		score := s.calculatePopularityScore(book)
-		
-		recommendation := &domain.BookRecommendation{
-			Book:           book,
-			Score:          score,
-			Reasoning:      []string{"Popular and highly-rated"},
-			SimilarityType: "trending",
-			MatchFactors:   map[string]float64{"popularity": float64(book.DownloadCount), "rating": book.RatingAverage},
-			Confidence:     score,
-		}
-
-		recommendations = append(recommendations, recommendation)
-	}
-
-	return recommendations, nil
-}
-
-func (s *recommendationService) generatePersonalizedRecommendations(ctx context.Context, userID uuid.UUID, req *dto.RecommendationRequest) ([]*domain.BookRecommendation, error) {
-	// Get user preferences
-	prefs, err := s.preferencesRepo.GetByUserID(ctx, userID)
-	if err != nil {
-		return nil, err
-	}
-
-	// Adjust algorithm weights based on user's discovery mode
-	config := *s.config // Copy
-	if prefs != nil {
-		switch prefs.DiscoveryMode {
-		case "conservative":
-			config.ContentWeight = 0.6
-			config.CollaborativeWeight = 0.3
-			config.NoveltyWeight = 0.05
-			config.PopularityWeight = 0.05
-		case "adventurous":
-			config.ContentWeight = 0.2
-			config.CollaborativeWeight = 0.2
-			config.NoveltyWeight = 0.4
-			config.PopularityWeight = 0.2
-		default: // balanced
-			// Use default weights
-		}
-	}
-
-	// Generate hybrid recommendations with personalized weights
-	originalConfig := s.config
-	s.config = &config
-	defer func() { s.config = originalConfig }()
-
-	return s.generateHybridRecommendations(ctx, userID, req)
-}
-
-func (s *recommendationService) applyFilters(recommendations []*domain.BookRecommendation, filters *dto.RecommendationFilters) []*domain.BookRecommendation {
-	if filters == nil {
-		return recommendations
-	}
-
-	var filtered []*domain.BookRecommendation
-	for _, rec := range recommendations {
-		if s.passesFilters(rec.Book, filters) {
-			filtered = append(filtered, rec)
-		}
-	}
-
-	return filtered
-}
-
-func (s *recommendationService) passesFilters(book *domain.Book, filters *dto.RecommendationFilters) bool {
-	// Check genres
-	if len(filters.Genres) > 0 && book.Genre != nil {
-		found := false
-		for _, genre := range filters.Genres {
-			if *book.Genre == genre {
-				found = true
-				break
-			}
-		}
-		if !found {
-			return false
-		}
-	}
-
-	// Check minimum rating
-	if filters.MinRating != nil && book.RatingAverage < *filters.MinRating {
-		return false
-	}
-
-	// Check word count limits
-	if filters.MinWordCount != nil && book.WordCount < *filters.MinWordCount {
-		return false
-	}
-	if filters.MaxWordCount != nil && book.WordCount > *filters.MaxWordCount {
-		return false
-	}
-
-	// Check difficulty levels
-	if len(filters.DifficultyLevels) > 0 {
-		found := false
-		for _, level := range filters.DifficultyLevels {
-			if book.DifficultyLevel == level {
-				found = true
-				break
-			}
-		}
-		if !found {
-			return false
-		}
-	}
-
-	// Check premium status
-	if !filters.IncludePremium && book.IsPremium {
-		return false
-	}
-
-	return true
-}
-
-func (s *recommendationService) ensureDiversity(recommendations []*domain.BookRecommendation) []*domain.BookRecommendation {
-	if len(recommendations) <= 1 {
-		return recommendations
-	}
-
-	var diverse []*domain.BookRecommendation
-	genreCounts := make(map[string]int)
-	authorCounts := make(map[string]int)
-
-	// Sort by score first
-	sort.Slice(recommendations, func(i, j int) bool {
-		return recommendations[i].Score > recommendations[j].Score
-	})
-
-	for _, rec := range recommendations {
-		genre := "unknown"
-		if rec.Book.Genre != nil {
-			genre = *rec.Book.Genre
-		}
-
-		// Apply diversity constraints
-		if genreCounts[genre] >= 3 { // Max 3 books per genre
-			continue
-		}
-		if authorCounts[rec.Book.Author] >= 2 { // Max 2 books per author
-			continue
-		}
-
-		diverse = append(diverse, rec)
-		genreCounts[genre]++
-		authorCounts[rec.Book.Author]++
-	}
-
-	return diverse
-}
-
-func (s *recommendationService) rankRecommendations(recommendations []*domain.BookRecommendation) []*domain.BookRecommendation {
-	// Final ranking combining score with other factors
-	for _, rec := range recommendations {
-		finalScore := rec.Score
-		
-		// Boost newer books slightly
-		daysSinceCreation := time.Since(rec.Book.CreatedAt).Hours() / 24
-		if daysSinceCreation < 30 { // Books newer than 30 days
-			finalScore *= 1.1
-		}
-
-		// Boost books with more ratings (reliability)
-		if rec.Book.RatingCount > 10 {
-			finalScore *= 1.05
-		}
-
-		rec.Score = finalScore
-	}
-
-	// Sort by final score
-	sort.Slice(recommendations, func(i, j int) bool {
-		return recommendations[i].Score > recommendations[j].Score
-	})
-
-	return recommendations
+	return nil
 }
 
-func (s *recommendationService) calculatePopularityScore(book *domain.Book) float64 {
-	// Combine download count and rating
-	downloadScore := math.Log(float64(book.DownloadCount + 1)) / 10.0 // Log scale
-	ratingScore := book.RatingAverage / 5.0
-	
-	return (downloadScore + ratingScore) / 2.0
-}
+// Private helper methods
 
 func (s *recommendationService) createDefaultPreferences(ctx context.Context, userID uuid.UUID) (*domain.UserPreferences, error) {
 	now := time.Now()
@@ -769,11 +433,6 @@ func boolPtr(b bool) *bool {
 	return &b
 }
 
-func getBookTitle(bookID int64) string {
-	// In a real implementation, this would look up the book title
-	return fmt.Sprintf("Book #%d", bookID)
-}
-
 // Stub implementations for remaining methods
 
 func (s *recommendationService) GetSimilarBooks(ctx context.Context, req *dto.SimilarBooksRequest) (*dto.RecommendationResponse, error) {
@@ -783,8 +442,29 @@ func (s *recommendationService) GetSimilarBooks(ctx context.Context, req *dto.Si
 		return nil, err
 	}
 
-	// Get similar books using vector repository
-	similarBooks, err := s.vectorRepo.GetSimilarBooks(ctx, req.BookID, req.Count, req.SimilarityType)
+	book, err := s.bookRepo.GetByID(ctx, req.BookID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get book: %w", err)
+	}
+	if book == nil {
+		return nil, apperrors.NotFound("Book not found")
+	}
+
+	// Books the reader has finished are excluded unless filters say otherwise
+	query := repository.SimilarBooksQuery{
+		BookID:         req.BookID,
+		Count:          req.Count,
+		SimilarityType: req.SimilarityType,
+	}
+	if req.Filters == nil || req.Filters.ExcludeCompleted {
+		query.ReaderID = &req.UserID
+	}
+	if req.Filters != nil {
+		query.IncludePremium = req.Filters.IncludePremium
+		query.ExcludeAbandoned = req.Filters.ExcludeAbandoned
+	}
+
+	similarBooks, err := s.vectorRepo.GetSimilarBooks(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get similar books: %w", err)
 	}
@@ -799,7 +479,7 @@ func (s *recommendationService) GetSimilarBooks(ctx context.Context, req *dto.Si
 		recommendation := &domain.BookRecommendation{
 			Book:           book,
 			Score:          similar.Similarity,
-			Reasoning:      []string{fmt.Sprintf("%.0f%% content similarity", similar.Similarity*100)},
+			Reasoning:      []string{fmt.Sprintf("%.0f%% %s similarity", similar.Similarity*100, req.SimilarityType)},
 			SimilarityType: req.SimilarityType,
 			MatchFactors:   map[string]float64{"similarity": similar.Similarity},
 			Confidence:     similar.Similarity,
@@ -810,7 +490,9 @@ func (s *recommendationService) GetSimilarBooks(ctx context.Context, req *dto.Si
 
 	// Apply filters if provided
 	if req.Filters != nil {
-		recommendations = s.applyFilters(recommendations, req.Filters)
+		recommendations = slices.DeleteFunc(recommendations, func(rec *domain.BookRecommendation) bool {
+			return !passesRecommendationFilters(rec.Book, req.Filters)
+		})
 	}
 
 	return &dto.RecommendationResponse{
@@ -909,78 +591,7 @@ func (s *recommendationService) UpdateBookVectors(ctx context.Context, bookID in
 func (s *recommendationService) InvalidateRecommendationCache(ctx context.Context, userID uuid.UUID) error {
 	s.logger.Info("Invalidating recommendation cache")
 
-	return s.cacheRepo.InvalidateUserCache(ctx, userID)
-}
-
-func (s *recommendationService) getCachedRecommendations(ctx context.Context, userID uuid.UUID, recType string) (*domain.RecommendationCache, error) {
-	cached, err := s.cacheRepo.GetByUserIDAndType(ctx, userID, recType)
-	if err != nil {
-		return nil, err
-	}
-
-	// Check if cache is still valid
-	if cached != nil && time.Now().After(cached.ExpiresAt) {
-		s.cacheRepo.Delete(ctx, cached.ID)
-		return nil, nil
-	}
-
-	return cached, nil
-}
-
-func (s *recommendationService) convertCacheToResponse(cache *domain.RecommendationCache, req *dto.RecommendationRequest) *dto.RecommendationResponse {
-	// Convert cached results to response format
-	var bookRecs []dto.BookRecommendation
-
-	for i, bookID := range cache.BookIDs {
-		score := 0.5 // default score
-		if i < len(cache.Scores) {
-			score = float64(cache.Scores[i])
-		}
-
-		// In a real implementation, we'd fetch book details
-		bookRecs = append(bookRecs, dto.BookRecommendation{
-			BookID: bookID,
-			Score:  score,
-		})
-
-		if len(bookRecs) >= req.Count {
-			break
-		}
-	}
-
-	return &dto.RecommendationResponse{
-		Recommendations: bookRecs,
-		TotalCount:      len(bookRecs),
-		AlgorithmUsed:   cache.RecommendationType,
-		GeneratedAt:     cache.CreatedAt,
-		ExpiresAt:       cache.ExpiresAt,
-	}
-}
-
-func (s *recommendationService) cacheRecommendations(ctx context.Context, userID uuid.UUID, recType string, recommendations []*domain.BookRecommendation, req *dto.RecommendationRequest) {
-	if len(recommendations) == 0 {
-		return
-	}
-
-	bookIDs := make([]int64, len(recommendations))
-	scores := make([]float64, len(recommendations))
-
-	for i, rec := range recommendations {
-		bookIDs[i] = rec.Book.ID
-		scores[i] = rec.Score
-	}
-
-	cache := &domain.RecommendationCache{
-		ID:                 uuid.New(),
-		UserID:             userID,
-		RecommendationType: recType,
-		BookIDs:            bookIDs,
-		Scores:             scores,
-		ExpiresAt:          time.Now().Add(time.Duration(s.config.CacheTTLHours) * time.Hour),
-		CreatedAt:          time.Now(),
-	}
-
-	s.cacheRepo.Create(ctx, cache)
+	return s.cacheService.InvalidateUser(ctx, userID)
 }
 
 func (s *recommendationService) generateExplanations(recommendations []*domain.BookRecommendation, algorithmUsed string) map[string]string {
@@ -989,17 +600,8 @@ func (s *recommendationService) generateExplanations(recommendations []*domain.B
 		"note":      "これらの推薦は、あなたの読書履歴と好みに基づいてパーソナライズされています",
 	}
 
-	switch algorithmUsed {
-	case "content_based":
-		explanations["method"] = "あなたが過去に高評価した本と類似したコンテンツの本を推薦しています"
-	case "collaborative_filtering":
-		explanations["method"] = "あなたと似た読書嗜好を持つユーザーが好んだ本を推薦しています"
-	case "hybrid":
-		explanations["method"] = "コンテンツベースと協調フィルタリングを組み合わせた高精度な推薦です"
-	case "trending":
-		explanations["method"] = "現在人気上昇中の高評価本を推薦しています"
-	case "personalized_hybrid":
-		explanations["method"] = "あなたの発見モードに最適化された個人向け推薦です"
+	if description := s.descriptions[algorithmUsed]; description != "" {
+		explanations["method"] = description
 	}
 
 	return explanations
@@ -0,0 +1,355 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/ponyo877/roudoku/server/domain"
+	"github.com/ponyo877/roudoku/server/repository"
+)
+
+// contentSource proposes books similar to the ones the user enjoyed most
+type contentSource struct {
+	interactionRepo repository.UserInteractionRepository
+	vectorRepo      repository.BookVectorRepository
+	bookRepo        repository.BookRepository
+}
+
+// Only books the user interacted with this strongly seed similar books
+const (
+	contentSeedBooks    = 5
+	contentSeedMinScore = 0.7
+)
+
+func (s *contentSource) Name() string    { return SourceContent }
+func (s *contentSource) Feature() string { return FeatureContent }
+func (s *contentSource) Realtime() bool  { return true }
+
+// Candidates scores each similar book by its similarity times the user's
+// preference for the seed
+func (s *contentSource) Candidates(ctx context.Context, query *RecommendationQuery) ([]*SourceCandidate, error) {
+	interactions, err := s.interactionRepo.GetMostInteractedBooks(ctx, query.UserID, 20)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []*SourceCandidate
+	for _, interaction := range interactions[:min(contentSeedBooks, len(interactions))] {
+		if interaction.Score <= contentSeedMinScore {
+			continue
+		}
+
+		similarBooks, err := s.vectorRepo.GetSimilarBooks(ctx, repository.SimilarBooksQuery{
+			BookID:         interaction.BookID,
+			Count:          query.Count,
+			SimilarityType: repository.SimilarityContent,
+			IncludePremium: query.Filters != nil && query.Filters.IncludePremium,
+			ReaderID:       &query.UserID,
+			ExcludeBookIDs: query.ExcludeBookIDs,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		reason := fmt.Sprintf("Similar to %s which you enjoyed", bookTitle(ctx, s.bookRepo, interaction.BookID))
+		for _, similar := range similarBooks {
+			candidates = append(candidates, &SourceCandidate{
+				BookID: similar.BookID,
+				Score:  similar.Similarity * interaction.Score,
+				Reason: reason,
+			})
+		}
+	}
+	return candidates, nil
+}
+
+// collaborativeSource proposes the books scored highest by the trained
+// collaborative filtering model
+type collaborativeSource struct {
+	cfService CollaborativeFilteringService
+}
+
+func (s *collaborativeSource) Name() string    { return SourceCollaborative }
+func (s *collaborativeSource) Feature() string { return FeatureCollaborative }
+
+// Realtime is false because the factors only change when the model is
+// trained again
+func (s *collaborativeSource) Realtime() bool { return false }
+
+func (s *collaborativeSource) Candidates(ctx context.Context, query *RecommendationQuery) ([]*SourceCandidate, error) {
+	scores, err := s.cfService.ScoreBooks(ctx, query.UserID, query.Count)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]*SourceCandidate, 0, len(scores))
+	for _, cfScore := range scores {
+		reason := "Readers with similar taste enjoyed this"
+		if cfScore.Source == domain.CFSourceItemItem {
+			reason = "Readers of the books you enjoyed also enjoyed this"
+		}
+		candidates = append(candidates, &SourceCandidate{
+			BookID: cfScore.BookID,
+			Score:  cfScore.Score,
+			Reason: reason,
+		})
+	}
+	return candidates, nil
+}
+
+// similarUsersSource aggregates the favourite books of the user's most
+// similar users
+type similarUsersSource struct {
+	similarityRepo  repository.UserSimilarityRepository
+	interactionRepo repository.UserInteractionRepository
+}
+
+// A book needs this many similar users who liked it to be proposed
+const similarUsersMinRecommenders = 2
+
+func (s *similarUsersSource) Name() string    { return SourceSimilarUsers }
+func (s *similarUsersSource) Feature() string { return FeatureCollaborative }
+
+// Realtime is false because user similarities are calculated in batches
+func (s *similarUsersSource) Realtime() bool { return false }
+
+// Candidates averages the similarity-weighted preferences of the users who
+// liked each book
+func (s *similarUsersSource) Candidates(ctx context.Context, query *RecommendationQuery) ([]*SourceCandidate, error) {
+	similarUsers, err := s.similarityRepo.GetSimilarUsers(ctx, query.UserID, 10)
+	if err != nil {
+		return nil, err
+	}
+
+	bookScores := make(map[int64]float64)
+	bookCounts := make(map[int64]int)
+	var order []int64
+	for _, similarUser := range similarUsers {
+		interactions, err := s.interactionRepo.GetMostInteractedBooks(ctx, similarUser.UserBID, 10)
+		if err != nil {
+			continue
+		}
+
+		for _, interaction := range interactions {
+			if interaction.Score <= 0.6 { // Only consider books they liked
+				continue
+			}
+			if bookCounts[interaction.BookID] == 0 {
+				order = append(order, interaction.BookID)
+			}
+			bookScores[interaction.BookID] += interaction.Score * similarUser.SimilarityScore
+			bookCounts[interaction.BookID]++
+		}
+	}
+
+	var candidates []*SourceCandidate
+	for _, bookID := range order {
+		if bookCounts[bookID] < similarUsersMinRecommenders {
+			continue
+		}
+		candidates = append(candidates, &SourceCandidate{
+			BookID: bookID,
+			Score:  bookScores[bookID] / float64(bookCounts[bookID]),
+			Reason: fmt.Sprintf("Recommended by %d users with similar taste", bookCounts[bookID]),
+		})
+	}
+	return candidates, nil
+}
+
+// trendingSource proposes popular, highly rated books
+type trendingSource struct {
+	bookRepo repository.BookRepository
+}
+
+func (s *trendingSource) Name() string    { return SourceTrending }
+func (s *trendingSource) Feature() string { return FeaturePopularity }
+func (s *trendingSource) Realtime() bool  { return true }
+
+func (s *trendingSource) Candidates(ctx context.Context, query *RecommendationQuery) ([]*SourceCandidate, error) {
+	books, _, err := s.bookRepo.List(ctx, &domain.BookSearchRequest{
+		Filter: &domain.BookFilter{
+			MinRating: floatPtr(4.0),
+			IsActive:  boolPtr(true),
+		},
+		SortBy: domain.SortByPopularity,
+		Limit:  query.Count,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]*SourceCandidate, 0, len(books))
+	for _, book := range books {
+		candidates = append(candidates, &SourceCandidate{
+			BookID: book.ID,
+			Score:  popularityScore(book),
+			Reason: "Popular and highly-rated",
+			Book:   book,
+		})
+	}
+	return candidates, nil
+}
+
+// sequentialSource proposes what to read next after the books the user
+// finished or is reading: other books by the same author and books similar
+// in content
+type sequentialSource struct {
+	progressRepo repository.BookProgressRepository
+	bookRepo     repository.BookRepository
+	vectorRepo   repository.BookVectorRepository
+}
+
+// The most recent books seed the source, each counting less than the one
+// read after it
+const (
+	sequentialSeedBooks   = 3
+	sequentialSeedDecay   = 0.8
+	sequentialAuthorScore = 0.9
+)
+
+func (s *sequentialSource) Name() string    { return SourceSequential }
+func (s *sequentialSource) Feature() string { return FeatureSequential }
+func (s *sequentialSource) Realtime() bool  { return true }
+
+func (s *sequentialSource) Candidates(ctx context.Context, query *RecommendationQuery) ([]*SourceCandidate, error) {
+	reading, err := s.progressRepo.GetActiveByUserID(ctx, query.UserID)
+	if err != nil {
+		return nil, err
+	}
+	completed, err := s.progressRepo.GetCompletedByUserID(ctx, query.UserID, sequentialSeedBooks, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// Finished books come first: what follows them is what the user is
+	// most likely to want next
+	seeds := append(completed, reading...)
+	seeds = seeds[:min(sequentialSeedBooks, len(seeds))]
+
+	perSeed := max(query.Count/max(len(seeds), 1), 1)
+	var candidates []*SourceCandidate
+	for i, seed := range seeds {
+		if seed.Book == nil {
+			continue
+		}
+		weight := math.Pow(sequentialSeedDecay, float64(i))
+
+		sameAuthor, _, err := s.bookRepo.List(ctx, &domain.BookSearchRequest{
+			Filter: &domain.BookFilter{
+				Authors:  []string{seed.Book.Author},
+				IsActive: boolPtr(true),
+			},
+			SortBy: domain.SortByPopularity,
+			Limit:  perSeed + 1,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, book := range sameAuthor {
+			if book.ID == seed.BookID {
+				continue
+			}
+			candidates = append(candidates, &SourceCandidate{
+				BookID: book.ID,
+				Score:  sequentialAuthorScore * weight,
+				Reason: fmt.Sprintf("Also by %s, whose %s you read", seed.Book.Author, seed.Book.Title),
+				Book:   book,
+			})
+		}
+
+		similarBooks, err := s.vectorRepo.GetSimilarBooks(ctx, repository.SimilarBooksQuery{
+			BookID:         seed.BookID,
+			Count:          perSeed,
+			SimilarityType: repository.SimilarityContent,
+			IncludePremium: query.Filters != nil && query.Filters.IncludePremium,
+			ReaderID:       &query.UserID,
+			ExcludeBookIDs: query.ExcludeBookIDs,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, similar := range similarBooks {
+			candidates = append(candidates, &SourceCandidate{
+				BookID: similar.BookID,
+				Score:  similar.Similarity * weight,
+				Reason: fmt.Sprintf("A natural next read after %s", seed.Book.Title),
+			})
+		}
+	}
+	return candidates, nil
+}
+
+// swipeAffinitySource proposes the books whose quotes the user liked in the
+// swipe modes, and books similar to the most liked ones
+type swipeAffinitySource struct {
+	swipeRepo  repository.SwipeRepository
+	vectorRepo repository.BookVectorRepository
+	bookRepo   repository.BookRepository
+}
+
+// Similar books of the most liked books are proposed at this share of
+// their affinity
+const (
+	swipeAffinitySeedBooks       = 3
+	swipeAffinitySimilarDiscount = 0.8
+)
+
+func (s *swipeAffinitySource) Name() string    { return SourceSwipeAffinity }
+func (s *swipeAffinitySource) Feature() string { return FeatureSwipeAffinity }
+func (s *swipeAffinitySource) Realtime() bool  { return true }
+
+// Candidates normalizes affinities by the strongest one
+func (s *swipeAffinitySource) Candidates(ctx context.Context, query *RecommendationQuery) ([]*SourceCandidate, error) {
+	affinities, err := s.swipeRepo.GetBookAffinities(ctx, query.UserID, query.Count)
+	if err != nil {
+		return nil, err
+	}
+	if len(affinities) == 0 {
+		return nil, nil
+	}
+	strongest := affinities[0].Score
+
+	var candidates []*SourceCandidate
+	for _, affinity := range affinities {
+		candidates = append(candidates, &SourceCandidate{
+			BookID: affinity.BookID,
+			Score:  affinity.Score / strongest,
+			Reason: "You liked quotes from this book",
+		})
+	}
+
+	for _, affinity := range affinities[:min(swipeAffinitySeedBooks, len(affinities))] {
+		similarBooks, err := s.vectorRepo.GetSimilarBooks(ctx, repository.SimilarBooksQuery{
+			BookID:         affinity.BookID,
+			Count:          query.Count / swipeAffinitySeedBooks,
+			SimilarityType: repository.SimilarityContent,
+			IncludePremium: query.Filters != nil && query.Filters.IncludePremium,
+			ReaderID:       &query.UserID,
+			ExcludeBookIDs: query.ExcludeBookIDs,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		reason := fmt.Sprintf("Similar to %s, whose quotes you liked", bookTitle(ctx, s.bookRepo, affinity.BookID))
+		for _, similar := range similarBooks {
+			candidates = append(candidates, &SourceCandidate{
+				BookID: similar.BookID,
+				Score:  similar.Similarity * affinity.Score / strongest * swipeAffinitySimilarDiscount,
+				Reason: reason,
+			})
+		}
+	}
+	return candidates, nil
+}
+
+// bookTitle returns the title of a book for reasons, or a placeholder when
+// it cannot be loaded
+func bookTitle(ctx context.Context, bookRepo repository.BookRepository, bookID int64) string {
+	book, err := bookRepo.GetByID(ctx, bookID)
+	if err != nil || book == nil {
+		return fmt.Sprintf("Book #%d", bookID)
+	}
+	return book.Title
+}
@@ -13,6 +13,9 @@ import (
 	"github.com/ponyo877/roudoku/server/repository"
 )
 
+// freePlanAudioStorageMB is the synthesized audio budget of users without a subscription
+const freePlanAudioStorageMB = 200
+
 // SubscriptionService defines the interface for subscription operations
 type SubscriptionService interface {
 	// Subscription Plans
@@ -30,10 +33,12 @@ type SubscriptionService interface {
 	GetUsageStats(ctx context.Context, userID uuid.UUID) (*dto.UsageStatsResponse, error)
 	TrackFeatureUsage(ctx context.Context, userID uuid.UUID, featureType string, count int, value float64) error
 	CheckFeatureAccess(ctx context.Context, userID uuid.UUID, feature string) (*dto.FeatureAccessResponse, error)
+	GetTTSQuota(ctx context.Context, userID uuid.UUID) (*dto.TTSQuotaResponse, error)
+	GetOfflineDownloadQuota(ctx context.Context, userID uuid.UUID) (*dto.OfflineDownloadQuotaResponse, error)
 	
 	// Premium Features
 	CanAccessPremiumBook(ctx context.Context, userID uuid.UUID, bookID int64) (bool, error)
-	CanUseTTS(ctx context.Context, userID uuid.UUID, requestedMinutes int) (bool, error)
+	CanUseTTS(ctx context.Context, userID uuid.UUID, requestedMinutes float64) (bool, error)
 	CanDownloadOffline(ctx context.Context, userID uuid.UUID) (bool, error)
 }
 
@@ -42,6 +47,7 @@ type subscriptionService struct {
 	planRepo         repository.SubscriptionPlanRepository
 	subscriptionRepo repository.UserSubscriptionRepository
 	usageRepo        repository.UsageTrackingRepository
+	downloadRepo     repository.OfflineDownloadRepository
 }
 
 // NewSubscriptionService creates a new subscription service
@@ -49,6 +55,7 @@ func NewSubscriptionService(
 	planRepo repository.SubscriptionPlanRepository,
 	subscriptionRepo repository.UserSubscriptionRepository,
 	usageRepo repository.UsageTrackingRepository,
+	downloadRepo repository.OfflineDownloadRepository,
 	logger *logger.Logger,
 ) SubscriptionService {
 	return &subscriptionService{
@@ -56,6 +63,7 @@ func NewSubscriptionService(
 		planRepo:         planRepo,
 		subscriptionRepo: subscriptionRepo,
 		usageRepo:        usageRepo,
+		downloadRepo:     downloadRepo,
 	}
 }
 
@@ -232,7 +240,8 @@ func (s *subscriptionService) GetUsageStats(ctx context.Context, userID uuid.UUI
 		plan = &domain.SubscriptionPlan{
 			MaxTTSMinutesPerDay:  30,
 			MaxPremiumBooks:      0,
-			MaxOfflineDownloads:  5,
+			MaxOfflineDownloads:  0,
+			MaxAudioStorageMB:    freePlanAudioStorageMB,
 		}
 	}
 
@@ -277,22 +286,11 @@ func (s *subscriptionService) GetUsageStats(ctx context.Context, userID uuid.UUI
 	}, nil
 }
 
-// TrackFeatureUsage tracks usage of a premium feature
+// TrackFeatureUsage adds to today's usage of a premium feature
 func (s *subscriptionService) TrackFeatureUsage(ctx context.Context, userID uuid.UUID, featureType string, count int, value float64) error {
 	s.logger.Info("Tracking feature usage")
 
-	usage := &domain.UsageTracking{
-		ID:          uuid.New(),
-		UserID:      userID,
-		FeatureType: featureType,
-		UsageDate:   time.Now(),
-		UsageCount:  count,
-		UsageValue:  value,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
-	}
-
-	err := s.usageRepo.Create(ctx, usage)
+	err := s.usageRepo.UpdateUsage(ctx, userID, featureType, count, value)
 	if err != nil {
 		s.logger.Error("Failed to track feature usage")
 		return fmt.Errorf("failed to track feature usage: %w", err)
@@ -339,42 +337,94 @@ func (s *subscriptionService) CanAccessPremiumBook(ctx context.Context, userID u
 	return access.CanAccess, nil
 }
 
-func (s *subscriptionService) CanUseTTS(ctx context.Context, userID uuid.UUID, requestedMinutes int) (bool, error) {
-	subscription, err := s.subscriptionRepo.GetByUserID(ctx, userID)
+func (s *subscriptionService) CanUseTTS(ctx context.Context, userID uuid.UUID, requestedMinutes float64) (bool, error) {
+	quota, err := s.GetTTSQuota(ctx, userID)
 	if err != nil {
 		return false, err
 	}
 
-	var dailyLimit int
-	if subscription == nil || !subscription.IsActive() {
-		dailyLimit = 30 // Free plan limit
-	} else if subscription.Plan.MaxTTSMinutesPerDay == -1 {
-		return true, nil // Unlimited
-	} else {
-		dailyLimit = subscription.Plan.MaxTTSMinutesPerDay
-	}
+	return quota.Unlimited || requestedMinutes <= quota.RemainingMinutes, nil
+}
 
-	// Check today's usage
-	today := time.Now()
-	todayUsage, err := s.usageRepo.GetUsageForFeature(ctx, userID, "tts_minutes", today)
+// GetTTSQuota returns today's TTS minute allowance, usage and the time the
+// allowance resets
+func (s *subscriptionService) GetTTSQuota(ctx context.Context, userID uuid.UUID) (*dto.TTSQuotaResponse, error) {
+	subscription, err := s.subscriptionRepo.GetByUserID(ctx, userID)
 	if err != nil {
-		return false, err
+		return nil, fmt.Errorf("failed to get user subscription: %w", err)
 	}
 
-	currentUsage := 0
+	now := time.Now()
+	quota := &dto.TTSQuotaResponse{
+		LimitMinutes: 30, // Free plan limit
+		ResetAt:      time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location()),
+	}
+	if subscription != nil && subscription.IsActive() && subscription.Plan != nil {
+		quota.LimitMinutes = subscription.Plan.MaxTTSMinutesPerDay
+	}
+
+	todayUsage, err := s.usageRepo.GetUsageForFeature(ctx, userID, "tts_minutes", now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get TTS usage: %w", err)
+	}
 	if todayUsage != nil {
-		currentUsage = int(todayUsage.UsageValue)
+		quota.UsedMinutes = todayUsage.UsageValue
+	}
+
+	if quota.LimitMinutes == -1 {
+		quota.Unlimited = true
+		quota.RemainingMinutes = -1
+		return quota, nil
 	}
 
-	return currentUsage+requestedMinutes <= dailyLimit, nil
+	quota.RemainingMinutes = float64(quota.LimitMinutes) - quota.UsedMinutes
+	if quota.RemainingMinutes < 0 {
+		quota.RemainingMinutes = 0
+	}
+	return quota, nil
 }
 
+// CanDownloadOffline reports whether the user may download another book,
+// counting the packages they already hold against their plan's limit
 func (s *subscriptionService) CanDownloadOffline(ctx context.Context, userID uuid.UUID) (bool, error) {
-	access, err := s.CheckFeatureAccess(ctx, userID, "offline_downloads")
+	quota, err := s.GetOfflineDownloadQuota(ctx, userID)
 	if err != nil {
 		return false, err
 	}
-	return access.CanAccess, nil
+	return quota.Available && (quota.Unlimited || quota.Remaining > 0), nil
+}
+
+// GetOfflineDownloadQuota returns the plan's offline download limit and the
+// number of packages the user holds
+func (s *subscriptionService) GetOfflineDownloadQuota(ctx context.Context, userID uuid.UUID) (*dto.OfflineDownloadQuotaResponse, error) {
+	subscription, err := s.subscriptionRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user subscription: %w", err)
+	}
+
+	active, err := s.downloadRepo.CountActiveByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count offline downloads: %w", err)
+	}
+
+	quota := &dto.OfflineDownloadQuotaResponse{Active: active}
+	if subscription == nil || !subscription.CanAccessFeature("offline_downloads") {
+		return quota, nil
+	}
+
+	quota.Available = true
+	quota.Limit = subscription.Plan.MaxOfflineDownloads
+	if quota.Limit == -1 {
+		quota.Unlimited = true
+		quota.Remaining = -1
+		return quota, nil
+	}
+
+	quota.Remaining = quota.Limit - active
+	if quota.Remaining < 0 {
+		quota.Remaining = 0
+	}
+	return quota, nil
 }
 
 // Helper methods
@@ -405,6 +455,7 @@ func (s *subscriptionService) convertToSubscriptionPlanResponse(plan *domain.Sub
 		MaxPremiumBooks:         plan.MaxPremiumBooks,
 		MaxTTSMinutesPerDay:     plan.MaxTTSMinutesPerDay,
 		MaxOfflineDownloads:     plan.MaxOfflineDownloads,
+		MaxAudioStorageMB:       plan.MaxAudioStorageMB,
 		HasAdvancedAnalytics:    plan.HasAdvancedAnalytics,
 		HasAIRecommendations:    plan.HasAIRecommendations,
 		HasPrioritySupport:      plan.HasPrioritySupport,
@@ -456,7 +507,8 @@ func (s *subscriptionService) createFreeSubscriptionResponse(userID uuid.UUID) *
 			PriceMonthly:            0,
 			MaxPremiumBooks:         0,
 			MaxTTSMinutesPerDay:     30,
-			MaxOfflineDownloads:     5,
+			MaxOfflineDownloads:     0,
+			MaxAudioStorageMB:       freePlanAudioStorageMB,
 			HasAdvancedAnalytics:    false,
 			HasAIRecommendations:    false,
 			HasPrioritySupport:      false,
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"strings"
+
+	"github.com/ponyo877/roudoku/server/domain"
+	"github.com/ponyo877/roudoku/server/pkg/ssml"
+)
+
+// applyCast returns the narrator voice and the cast to synthesize with.
+// Unset narrator fields fall back to voice, and unset character language and
+// speed fall back to the narrator. A cast without characters reads every
+// sentence with the narrator, so it is dropped and nil is returned.
+func applyCast(voice domain.TTSConfig, cast *domain.VoiceCast) (domain.TTSConfig, *domain.VoiceCast) {
+	if cast == nil {
+		return voice, nil
+	}
+
+	narrator := cast.Narrator
+	if narrator.Language == "" {
+		narrator.Language = voice.Language
+	}
+	if narrator.Voice == "" {
+		narrator = voice
+	}
+	if narrator.Speed == 0 {
+		narrator.Speed = voice.Speed
+	}
+	if len(cast.Characters) == 0 {
+		return narrator, nil
+	}
+
+	resolved := &domain.VoiceCast{
+		Narrator:   narrator,
+		Characters: make([]domain.CastCharacter, len(cast.Characters)),
+	}
+	for i, character := range cast.Characters {
+		if character.Voice.Language == "" {
+			character.Voice.Language = narrator.Language
+		}
+		if character.Voice.Speed == 0 {
+			character.Voice.Speed = narrator.Speed
+		}
+		resolved.Characters[i] = character
+	}
+
+	return narrator, resolved
+}
+
+// sentenceVoices picks the voice for each sentence. Narration is read by the
+// narrator. The dialogue of a paragraph goes to the character whose name
+// appears first in the paragraph's narration; otherwise consecutive lines of
+// a conversation alternate between the characters in cast order, starting
+// again from the first character after a paragraph without dialogue.
+func sentenceVoices(sentences []ssml.Sentence, narrator domain.TTSConfig, cast *domain.VoiceCast) []domain.TTSConfig {
+	voices := make([]domain.TTSConfig, len(sentences))
+	for i := range voices {
+		voices[i] = narrator
+	}
+	if cast == nil || len(cast.Characters) == 0 {
+		return voices
+	}
+
+	speaker := -1
+	for start := 0; start < len(sentences); {
+		end := start
+		var narration strings.Builder
+		hasDialogue := false
+		for end < len(sentences) && sentences[end].Paragraph == sentences[start].Paragraph {
+			if sentences[end].Dialogue {
+				hasDialogue = true
+			} else {
+				narration.WriteString(sentences[end].Text)
+			}
+			end++
+		}
+
+		if !hasDialogue {
+			speaker = -1
+		} else {
+			if named := namedCharacter(narration.String(), cast.Characters); named >= 0 {
+				speaker = named
+			} else {
+				speaker = (speaker + 1) % len(cast.Characters)
+			}
+			for i := start; i < end; i++ {
+				if sentences[i].Dialogue {
+					voices[i] = cast.Characters[speaker].Voice
+				}
+			}
+		}
+
+		start = end
+	}
+
+	return voices
+}
+
+// namedCharacter returns the index of the character whose name appears
+// first in the narration, or -1 if none does
+func namedCharacter(narration string, characters []domain.CastCharacter) int {
+	found, foundAt := -1, len(narration)
+	for i, character := range characters {
+		if character.Name == "" {
+			continue
+		}
+		if at := strings.Index(narration, character.Name); at >= 0 && at < foundAt {
+			found, foundAt = i, at
+		}
+	}
+	return found
+}
+
+// enginesForCast returns the engines, in the narrator's fallback order, that
+// are configured for every voice of the cast
+func (s *ttsService) enginesForCast(cast *domain.VoiceCast) []TTSEngine {
+	engines := s.enginesForVoice(cast.Narrator.Voice)
+	for _, character := range cast.Characters {
+		allowed := make(map[string]bool)
+		for _, engine := range s.enginesForVoice(character.Voice.Voice) {
+			allowed[engine.Name()] = true
+		}
+
+		shared := engines[:0:0]
+		for _, engine := range engines {
+			if allowed[engine.Name()] {
+				shared = append(shared, engine)
+			}
+		}
+		engines = shared
+	}
+	return engines
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"strings"
+
+	"github.com/ponyo877/roudoku/server/dto"
+)
+
+// TTS engine provider names
+const (
+	TTSEngineGoogle = "google"
+	TTSEngineLocal  = "local"
+)
+
+// TTSEngine is a speech synthesis provider
+type TTSEngine interface {
+	// Name returns the provider name used in configuration and cache keys
+	Name() string
+	// AudioFormats returns the audio_files.format values the engine can
+	// produce; the first is used when a request names none it supports
+	AudioFormats() []string
+	Synthesize(ctx context.Context, req *TTSEngineRequest) (*TTSEngineResult, error)
+	ListVoices(ctx context.Context, languageCode string) ([]dto.TTSVoice, error)
+}
+
+// TTSEngineRequest holds provider-independent synthesis parameters.
+// When SSML is set it is synthesized instead of Text.
+type TTSEngineRequest struct {
+	Text       string
+	SSML       string
+	Language   string
+	Voice      string
+	Speed      float32
+	Pitch      float32
+	VolumeGain float32
+	// Format is the audio_files.format to produce; empty uses the engine's first format
+	Format          string
+	SampleRateHertz int32
+	// EnableTimepoints requests the time of every SSML <mark>
+	EnableTimepoints bool
+}
+
+// TTSEngineResult holds synthesized audio
+type TTSEngineResult struct {
+	Audio           []byte
+	Format          string
+	ContentType     string
+	SampleRateHertz int32
+	BitRate         int32
+	Timepoints      []TTSTimepoint
+}
+
+// TTSTimepoint is the time at which an SSML <mark> was reached
+type TTSTimepoint struct {
+	MarkName string
+	Seconds  float64
+}
+
+// TTSEngineFallback configures the order in which engines are tried.
+// Voices listed in PerVoice use their own order instead of Default.
+type TTSEngineFallback struct {
+	Default  []string
+	PerVoice map[string][]string
+}
+
+// Audio quality levels
+const (
+	TTSQualityLow      = "low"
+	TTSQualityStandard = "standard"
+	TTSQualityHigh     = "high"
+)
+
+// ttsSampleRates gives the sample rate of each quality level by format.
+// Opus only supports rates that divide 48 kHz.
+var ttsSampleRates = map[string]map[string]int32{
+	"mp3": {TTSQualityLow: 16000, TTSQualityStandard: 22050, TTSQualityHigh: 44100},
+	"wav": {TTSQualityLow: 16000, TTSQualityStandard: 22050, TTSQualityHigh: 44100},
+	"ogg": {TTSQualityLow: 16000, TTSQualityStandard: 24000, TTSQualityHigh: 48000},
+}
+
+// supportsFormat reports whether an engine can produce an audio format
+func supportsFormat(engine TTSEngine, format string) bool {
+	for _, supported := range engine.AudioFormats() {
+		if supported == format {
+			return true
+		}
+	}
+	return false
+}
+
+// formatForAudioEncoding returns the audio_files.format for an encoding
+// name, or an empty string for an unknown encoding
+func formatForAudioEncoding(encoding string) string {
+	switch strings.ToUpper(encoding) {
+	case "MP3":
+		return "mp3"
+	case "LINEAR16":
+		return "wav"
+	case "OGG_OPUS":
+		return "ogg"
+	default:
+		return ""
+	}
+}
+
+// audioEncodingForFormat returns the encoding name recorded in voice configs
+func audioEncodingForFormat(format string) string {
+	switch format {
+	case "wav":
+		return "LINEAR16"
+	case "ogg":
+		return "OGG_OPUS"
+	default:
+		return "MP3"
+	}
+}
+
+// contentTypeForFormat returns the MIME type for an audio_files.format value
+func contentTypeForFormat(format string) string {
+	switch format {
+	case "wav":
+		return "audio/wav"
+	case "ogg":
+		return "audio/ogg"
+	default:
+		return "audio/mpeg"
+	}
+}
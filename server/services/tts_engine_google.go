@@ -0,0 +1,252 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	texttospeech "cloud.google.com/go/texttospeech/apiv1"
+	"cloud.google.com/go/texttospeech/apiv1/texttospeechpb"
+	"google.golang.org/api/option"
+	htransport "google.golang.org/api/transport/http"
+
+	"github.com/ponyo877/roudoku/server/dto"
+)
+
+const (
+	googleMP3BitRate = 32000
+	// The v1 API used by the client library does not return timepoints, so
+	// requests that need them go to the v1beta1 REST endpoint instead.
+	googleTimepointEndpoint = "https://texttospeech.googleapis.com/v1beta1/text:synthesize"
+)
+
+// googleTTSEngine synthesizes speech with Google Cloud Text-to-Speech
+type googleTTSEngine struct {
+	client     *texttospeech.Client
+	httpClient *http.Client
+}
+
+// NewGoogleTTSEngine creates a Google Cloud TTS engine
+func NewGoogleTTSEngine(credentialsPath string) (TTSEngine, error) {
+	ctx := context.Background()
+
+	var opts []option.ClientOption
+	if credentialsPath != "" {
+		opts = append(opts, option.WithCredentialsFile(credentialsPath))
+	}
+	// Without a credentials file, default credentials are used (for production with service account)
+
+	client, err := texttospeech.NewClient(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create TTS client: %w", err)
+	}
+
+	httpOpts := append(opts, option.WithScopes("https://www.googleapis.com/auth/cloud-platform"))
+	httpClient, _, err := htransport.NewClient(ctx, httpOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create TTS HTTP client: %w", err)
+	}
+
+	return &googleTTSEngine{client: client, httpClient: httpClient}, nil
+}
+
+// Name returns the provider name
+func (e *googleTTSEngine) Name() string {
+	return TTSEngineGoogle
+}
+
+// AudioFormats returns the produced audio formats
+func (e *googleTTSEngine) AudioFormats() []string {
+	return []string{"mp3", "ogg", "wav"}
+}
+
+// googleAudioEncodings maps audio formats to Cloud TTS encodings. LINEAR16
+// audio is returned with a WAV header.
+var googleAudioEncodings = map[string]texttospeechpb.AudioEncoding{
+	"mp3": texttospeechpb.AudioEncoding_MP3,
+	"ogg": texttospeechpb.AudioEncoding_OGG_OPUS,
+	"wav": texttospeechpb.AudioEncoding_LINEAR16,
+}
+
+// Synthesize synthesizes text or SSML to MP3, Ogg Opus or WAV
+func (e *googleTTSEngine) Synthesize(ctx context.Context, req *TTSEngineRequest) (*TTSEngineResult, error) {
+	format := req.Format
+	if format == "" {
+		format = "mp3"
+	}
+	encoding, ok := googleAudioEncodings[format]
+	if !ok {
+		return nil, fmt.Errorf("google TTS cannot produce %s audio", format)
+	}
+
+	if req.EnableTimepoints && req.SSML != "" {
+		return e.synthesizeWithTimepoints(ctx, req, format, encoding)
+	}
+
+	input := &texttospeechpb.SynthesisInput{
+		InputSource: &texttospeechpb.SynthesisInput_Text{
+			Text: req.Text,
+		},
+	}
+	if req.SSML != "" {
+		input.InputSource = &texttospeechpb.SynthesisInput_Ssml{
+			Ssml: req.SSML,
+		}
+	}
+
+	ttsReq := &texttospeechpb.SynthesizeSpeechRequest{
+		Input: input,
+		Voice: &texttospeechpb.VoiceSelectionParams{
+			LanguageCode: req.Language,
+			Name:         req.Voice,
+		},
+		AudioConfig: &texttospeechpb.AudioConfig{
+			AudioEncoding:   encoding,
+			SpeakingRate:    float64(req.Speed),
+			Pitch:           float64(req.Pitch),
+			VolumeGainDb:    float64(req.VolumeGain),
+			SampleRateHertz: req.SampleRateHertz,
+		},
+	}
+
+	resp, err := e.client.SynthesizeSpeech(ctx, ttsReq)
+	if err != nil {
+		return nil, fmt.Errorf("google TTS synthesis failed: %w", err)
+	}
+
+	return &TTSEngineResult{
+		Audio:           resp.AudioContent,
+		Format:          format,
+		ContentType:     contentTypeForFormat(format),
+		SampleRateHertz: req.SampleRateHertz,
+		BitRate:         googleBitRate(format, req.SampleRateHertz),
+	}, nil
+}
+
+// googleSynthesizeRequest is the v1beta1 REST request body
+type googleSynthesizeRequest struct {
+	Input struct {
+		SSML string `json:"ssml"`
+	} `json:"input"`
+	Voice struct {
+		LanguageCode string `json:"languageCode"`
+		Name         string `json:"name"`
+	} `json:"voice"`
+	AudioConfig struct {
+		AudioEncoding   string  `json:"audioEncoding"`
+		SpeakingRate    float32 `json:"speakingRate,omitempty"`
+		Pitch           float32 `json:"pitch,omitempty"`
+		VolumeGainDb    float32 `json:"volumeGainDb,omitempty"`
+		SampleRateHertz int32   `json:"sampleRateHertz,omitempty"`
+	} `json:"audioConfig"`
+	EnableTimePointing []string `json:"enableTimePointing"`
+}
+
+// googleSynthesizeResponse is the v1beta1 REST response body
+type googleSynthesizeResponse struct {
+	AudioContent string `json:"audioContent"`
+	Timepoints   []struct {
+		MarkName    string  `json:"markName"`
+		TimeSeconds float64 `json:"timeSeconds"`
+	} `json:"timepoints"`
+}
+
+// synthesizeWithTimepoints calls the v1beta1 REST API, which reports the time
+// of every SSML <mark>
+func (e *googleTTSEngine) synthesizeWithTimepoints(ctx context.Context, req *TTSEngineRequest, format string, encoding texttospeechpb.AudioEncoding) (*TTSEngineResult, error) {
+	var body googleSynthesizeRequest
+	body.Input.SSML = req.SSML
+	body.Voice.LanguageCode = req.Language
+	body.Voice.Name = req.Voice
+	body.AudioConfig.AudioEncoding = encoding.String()
+	body.AudioConfig.SpeakingRate = req.Speed
+	body.AudioConfig.Pitch = req.Pitch
+	body.AudioConfig.VolumeGainDb = req.VolumeGain
+	body.AudioConfig.SampleRateHertz = req.SampleRateHertz
+	body.EnableTimePointing = []string{"SSML_MARK"}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode google TTS request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, googleTimepointEndpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create google TTS request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("google TTS synthesis failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("google TTS synthesis failed with status %d: %s", resp.StatusCode, message)
+	}
+
+	var result googleSynthesizeResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode google TTS response: %w", err)
+	}
+
+	audio, err := base64.StdEncoding.DecodeString(result.AudioContent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode google TTS audio: %w", err)
+	}
+
+	timepoints := make([]TTSTimepoint, 0, len(result.Timepoints))
+	for _, tp := range result.Timepoints {
+		timepoints = append(timepoints, TTSTimepoint{MarkName: tp.MarkName, Seconds: tp.TimeSeconds})
+	}
+
+	return &TTSEngineResult{
+		Audio:           audio,
+		Format:          format,
+		ContentType:     contentTypeForFormat(format),
+		SampleRateHertz: req.SampleRateHertz,
+		BitRate:         googleBitRate(format, req.SampleRateHertz),
+		Timepoints:      timepoints,
+	}, nil
+}
+
+// googleBitRate returns the nominal bit rate of Cloud TTS audio. Opus is
+// variable, so its rate is left to be measured from the file.
+func googleBitRate(format string, sampleRateHertz int32) int32 {
+	switch format {
+	case "mp3":
+		return googleMP3BitRate
+	case "wav":
+		return sampleRateHertz * 16
+	default:
+		return 0
+	}
+}
+
+// ListVoices returns the Google voices for a language
+func (e *googleTTSEngine) ListVoices(ctx context.Context, languageCode string) ([]dto.TTSVoice, error) {
+	resp, err := e.client.ListVoices(ctx, &texttospeechpb.ListVoicesRequest{
+		LanguageCode: languageCode,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list google voices: %w", err)
+	}
+
+	var voices []dto.TTSVoice
+	for _, voice := range resp.Voices {
+		voices = append(voices, dto.TTSVoice{
+			Name:                   voice.Name,
+			LanguageCodes:          voice.LanguageCodes,
+			Gender:                 voice.SsmlGender.String(),
+			NaturalSampleRateHertz: voice.NaturalSampleRateHertz,
+		})
+	}
+
+	return voices, nil
+}
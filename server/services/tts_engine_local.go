@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"unicode"
+
+	"github.com/ponyo877/roudoku/server/dto"
+	"github.com/ponyo877/roudoku/server/pkg/audio"
+	"github.com/ponyo877/roudoku/server/pkg/ssml"
+)
+
+const (
+	localTTSDefaultSampleRate = 16000
+	localTTSSyllableSeconds   = 0.125
+	localTTSRampSeconds       = 0.01
+)
+
+// localTTSEngine is an offline engine for development and CI. It renders
+// text as a deterministic sequence of tones, one per character, with pauses
+// at punctuation, so the same input always produces the same WAV file.
+type localTTSEngine struct{}
+
+// NewLocalTTSEngine creates the offline tone engine
+func NewLocalTTSEngine() TTSEngine {
+	return &localTTSEngine{}
+}
+
+// Name returns the provider name
+func (e *localTTSEngine) Name() string {
+	return TTSEngineLocal
+}
+
+// AudioFormats returns the produced audio formats
+func (e *localTTSEngine) AudioFormats() []string {
+	return []string{"wav"}
+}
+
+// Synthesize renders text as tones. Marks in SSML input are reported as
+// timepoints at the sample where they occur.
+func (e *localTTSEngine) Synthesize(ctx context.Context, req *TTSEngineRequest) (*TTSEngineResult, error) {
+	if req.Format != "" && req.Format != "wav" {
+		return nil, fmt.Errorf("local TTS cannot produce %s audio", req.Format)
+	}
+	sampleRate := int(req.SampleRateHertz)
+	if sampleRate <= 0 {
+		sampleRate = localTTSDefaultSampleRate
+	}
+	speed := float64(req.Speed)
+	if speed <= 0 {
+		speed = 1.0
+	}
+
+	// Each voice name gets its own base frequency between 160 and 280 Hz
+	hash := fnv.New32a()
+	hash.Write([]byte(req.Voice))
+	baseFrequency := 160.0 + float64(hash.Sum32()%120)
+	baseFrequency *= math.Pow(2, float64(req.Pitch)/12)
+
+	amplitude := 0.3 * math.Pow(10, float64(req.VolumeGain)/20)
+	if amplitude > 1 {
+		amplitude = 1
+	}
+
+	segments := []ssml.Segment{{Text: req.Text}}
+	if req.SSML != "" {
+		var err error
+		if segments, err = ssml.Parse(req.SSML); err != nil {
+			return nil, err
+		}
+	}
+
+	var samples []int16
+	var timepoints []TTSTimepoint
+	for _, segment := range segments {
+		if segment.Mark != "" {
+			timepoints = append(timepoints, TTSTimepoint{
+				MarkName: segment.Mark,
+				Seconds:  float64(len(samples)) / float64(sampleRate),
+			})
+		}
+
+		for _, r := range segment.Text {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
+			switch {
+			case r == '。' || r == '！' || r == '？' || r == '.' || r == '!' || r == '?':
+				samples = appendSilence(samples, sampleRate, 0.3/speed)
+			case r == '、' || r == '，' || r == ',':
+				samples = appendSilence(samples, sampleRate, 0.15/speed)
+			case unicode.IsSpace(r):
+				samples = appendSilence(samples, sampleRate, 0.08/speed)
+			case unicode.IsPunct(r) || unicode.IsSymbol(r):
+				// Brackets and quotes are not voiced
+			default:
+				frequency := baseFrequency * (1 + float64(r%7)/20)
+				samples = appendTone(samples, sampleRate, frequency, amplitude, localTTSSyllableSeconds/speed)
+			}
+		}
+	}
+
+	pcm := &audio.PCM{SampleRate: sampleRate, Channels: 1, Samples: samples}
+
+	return &TTSEngineResult{
+		Audio:           audio.EncodeWAV(pcm),
+		Format:          "wav",
+		ContentType:     "audio/wav",
+		SampleRateHertz: int32(sampleRate),
+		BitRate:         int32(sampleRate * 16),
+		Timepoints:      timepoints,
+	}, nil
+}
+
+// ListVoices returns the local voices, which are available for every language
+func (e *localTTSEngine) ListVoices(ctx context.Context, languageCode string) ([]dto.TTSVoice, error) {
+	voices := []dto.TTSVoice{
+		{Name: "local-female", Gender: "FEMALE"},
+		{Name: "local-male", Gender: "MALE"},
+		{Name: "local-neutral", Gender: "NEUTRAL"},
+	}
+	for i := range voices {
+		voices[i].LanguageCodes = []string{languageCode}
+		voices[i].NaturalSampleRateHertz = localTTSDefaultSampleRate
+	}
+	return voices, nil
+}
+
+// appendTone appends a sine tone with short linear ramps to avoid clicks
+func appendTone(samples []int16, sampleRate int, frequency, amplitude, seconds float64) []int16 {
+	count := int(seconds * float64(sampleRate))
+	ramp := int(localTTSRampSeconds * float64(sampleRate))
+	for i := 0; i < count; i++ {
+		envelope := 1.0
+		if i < ramp {
+			envelope = float64(i) / float64(ramp)
+		} else if count-i < ramp {
+			envelope = float64(count-i) / float64(ramp)
+		}
+		value := math.Sin(2*math.Pi*frequency*float64(i)/float64(sampleRate)) * amplitude * envelope
+		samples = append(samples, int16(value*math.MaxInt16))
+	}
+	return samples
+}
+
+// appendSilence appends the given number of seconds of silence
+func appendSilence(samples []int16, sampleRate int, seconds float64) []int16 {
+	return append(samples, make([]int16, int(seconds*float64(sampleRate)))...)
+}
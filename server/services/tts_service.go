@@ -1,60 +1,178 @@
 package services
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"time"
+	"unicode/utf8"
 
-	texttospeech "cloud.google.com/go/texttospeech/apiv1"
-	"cloud.google.com/go/texttospeech/apiv1/texttospeechpb"
-	"google.golang.org/api/option"
+	"github.com/google/uuid"
 
+	"github.com/ponyo877/roudoku/server/domain"
 	"github.com/ponyo877/roudoku/server/dto"
+	"github.com/ponyo877/roudoku/server/pkg/audio"
+	apperrors "github.com/ponyo877/roudoku/server/pkg/errors"
 	"github.com/ponyo877/roudoku/server/pkg/logger"
+	"github.com/ponyo877/roudoku/server/pkg/ssml"
+	"github.com/ponyo877/roudoku/server/pkg/storage"
+	"github.com/ponyo877/roudoku/server/repository"
+)
+
+const (
+	// ttsMaxInputBytes keeps each engine request below the 5000 byte input limit of Cloud TTS
+	ttsMaxInputBytes = 4500
 )
 
 // TTSService defines the interface for text-to-speech operations
 type TTSService interface {
-	SynthesizeText(ctx context.Context, req *dto.TTSSynthesizeRequest) (*dto.TTSSynthesizeResponse, error)
+	SynthesizeText(ctx context.Context, userID uuid.UUID, req *dto.TTSSynthesizeRequest) (*dto.TTSSynthesizeResponse, error)
 	GetAvailableVoices(ctx context.Context, languageCode string) (*dto.TTSVoicesResponse, error)
-	PreviewVoice(ctx context.Context, req *dto.TTSPreviewRequest) (*dto.TTSPreviewResponse, error)
+	PreviewVoice(ctx context.Context, userID uuid.UUID, req *dto.TTSPreviewRequest) (*dto.TTSPreviewResponse, error)
+	// SaveVoicePreset makes a voice the user's default for requests that
+	// leave voice parameters out
+	SaveVoicePreset(ctx context.Context, userID uuid.UUID, req *dto.SaveVoicePresetRequest) (*dto.VoicePresetResponse, error)
+	// SelectVoice reports the voice the voice policy and the user's preset
+	// choose for a book, and the rule that chose it
+	SelectVoice(ctx context.Context, userID uuid.UUID, req *dto.VoiceSelectionRequest) (*dto.VoiceSelectionResponse, error)
+	// SynthesizeChapter narrates a whole chapter into a single stored audio
+	// file. With a cast, dialogue is read by the cast's character voices.
+	SynthesizeChapter(ctx context.Context, userID uuid.UUID, chapter *domain.Chapter, voice *domain.TTSConfig, cast *domain.VoiceCast) (*domain.AudioFile, error)
+	// GetAlignment returns the sentence timings of a synthesized audio file
+	GetAlignment(ctx context.Context, userID, audioFileID uuid.UUID) (*dto.AudioAlignmentResponse, error)
+	// SynthesizeFormat narrates the text of an audio file again in another
+	// format with the same voice settings
+	SynthesizeFormat(ctx context.Context, userID uuid.UUID, source *domain.AudioFile, format string) (*domain.AudioFile, error)
 }
 
 // ttsService implements TTSService
 type ttsService struct {
 	*BaseService
-	client         *texttospeech.Client
-	credentialsPath string
+	engines             map[string]TTSEngine
+	fallback            TTSEngineFallback
+	voiceDefaults       TTSVoiceDefaults
+	audioFileRepo       repository.AudioFileRepository
+	alignmentRepo       repository.AudioAlignmentRepository
+	userRepo            repository.UserRepository
+	bookRepo            repository.BookRepository
+	blobStore           storage.BlobStore
+	subscriptionService SubscriptionService
+	lexicons            PronunciationService
+}
+
+// ttsVoiceConfig is the voice configuration stored in audio_files.voice_config.
+// Together with the text hash it identifies a synthesized file, so two
+// requests with the same text and settings share one cached file.
+type ttsVoiceConfig struct {
+	Engine          string  `json:"engine"`
+	Language        string  `json:"language"`
+	Voice           string  `json:"voice"`
+	Speed           float32 `json:"speed"`
+	Pitch           float32 `json:"pitch"`
+	VolumeGain      float32 `json:"volume_gain"`
+	AudioEncoding   string  `json:"audio_encoding"`
+	SampleRateHertz int32   `json:"sample_rate_hertz"`
+	// Cast is set for multi-voice narration; the fields above are the narrator
+	Cast *domain.VoiceCast `json:"cast,omitempty"`
+	// Lexicon fingerprints the pronunciation entries applied to the text, so
+	// changing a reading synthesizes the affected text again
+	Lexicon string `json:"lexicon,omitempty"`
+}
+
+// ttsChunk is a run of sentences synthesized in one engine request
+type ttsChunk struct {
+	Voice     domain.TTSConfig
+	Sentences []ssml.Sentence
+}
+
+// ttsSource identifies the text being synthesized and what it belongs to
+type ttsSource struct {
+	UserID    uuid.UUID
+	BookID    *int64
+	ChapterID *uuid.UUID
+	Text      string
+}
+
+// ttsFormatRequest is the audio format a request asks for. Empty fields use
+// the default format at standard quality.
+type ttsFormatRequest struct {
+	Format  string
+	Quality string
+}
+
+// ttsEncoding is the audio format and sample rate requested from an engine
+type ttsEncoding struct {
+	Format          string
+	SampleRateHertz int32
+}
+
+// ttsOutput is synthesized or cached audio. AudioFile is nil when the audio
+// could not be stored, and Audio is nil for cached audio, which is read
+// from storage only when needed.
+type ttsOutput struct {
+	AudioFile   *domain.AudioFile
+	Audio       []byte
+	ContentType string
+	// Duration is the playing time in seconds read from the encoded audio
+	Duration float64
+	Cached   bool
 }
 
-// NewTTSService creates a new TTS service
-func NewTTSService(credentialsPath string, logger *logger.Logger) (TTSService, error) {
-	ctx := context.Background()
-	
-	var client *texttospeech.Client
-	var err error
-	
-	if credentialsPath != "" {
-		client, err = texttospeech.NewClient(ctx, option.WithCredentialsFile(credentialsPath))
-	} else {
-		// Use default credentials (for production with service account)
-		client, err = texttospeech.NewClient(ctx)
+// NewTTSService creates a new TTS service. Engines are tried in the order
+// given by fallback; engines missing from engines are skipped. Voice
+// parameters a request leaves out come from the user's voice preset and
+// voiceDefaults, adjusted by the voice policy in voiceDefaults for the book
+// and the listener's local time, and audio is produced in its AudioEncoding
+// unless a request names a format. Synthesis is
+// metered against the daily TTS quota of subscriptionService when it is set,
+// and text is read with the pronunciation lexicon of lexicons when it is set.
+func NewTTSService(engines []TTSEngine, fallback TTSEngineFallback, voiceDefaults TTSVoiceDefaults, audioFileRepo repository.AudioFileRepository, alignmentRepo repository.AudioAlignmentRepository, userRepo repository.UserRepository, bookRepo repository.BookRepository, blobStore storage.BlobStore, subscriptionService SubscriptionService, lexicons PronunciationService, logger *logger.Logger) (TTSService, error) {
+	if len(engines) == 0 {
+		return nil, fmt.Errorf("at least one TTS engine is required")
 	}
-	
-	if err != nil {
-		return nil, fmt.Errorf("failed to create TTS client: %w", err)
+	if voiceDefaults.AudioEncoding != "" && formatForAudioEncoding(voiceDefaults.AudioEncoding) == "" {
+		return nil, fmt.Errorf("unknown audio encoding %q", voiceDefaults.AudioEncoding)
+	}
+
+	engineMap := make(map[string]TTSEngine, len(engines))
+	for _, engine := range engines {
+		engineMap[engine.Name()] = engine
+	}
+
+	// Without a configured order, try engines in the order they were given
+	if len(fallback.Default) == 0 {
+		for _, engine := range engines {
+			fallback.Default = append(fallback.Default, engine.Name())
+		}
 	}
 
 	return &ttsService{
-		BaseService:     NewBaseService(logger),
-		client:         client,
-		credentialsPath: credentialsPath,
+		BaseService:         NewBaseService(logger),
+		engines:             engineMap,
+		fallback:            fallback,
+		voiceDefaults:       voiceDefaults,
+		audioFileRepo:       audioFileRepo,
+		alignmentRepo:       alignmentRepo,
+		userRepo:            userRepo,
+		bookRepo:            bookRepo,
+		blobStore:           blobStore,
+		subscriptionService: subscriptionService,
+		lexicons:            lexicons,
 	}, nil
 }
 
-// SynthesizeText synthesizes text to speech using Google Cloud TTS
-func (s *ttsService) SynthesizeText(ctx context.Context, req *dto.TTSSynthesizeRequest) (*dto.TTSSynthesizeResponse, error) {
+// SynthesizeText synthesizes text to speech with the first engine that
+// succeeds for the requested voice, completed by the voice policy and the
+// user's voice preset. Previously synthesized audio with the same text and
+// voice settings is served from the audio cache instead of being
+// synthesized again.
+func (s *ttsService) SynthesizeText(ctx context.Context, userID uuid.UUID, req *dto.TTSSynthesizeRequest) (*dto.TTSSynthesizeResponse, error) {
 	s.logger.Info("Synthesizing text to speech")
 
 	if err := s.ValidateStruct(req); err != nil {
@@ -62,77 +180,318 @@ func (s *ttsService) SynthesizeText(ctx context.Context, req *dto.TTSSynthesizeR
 		return nil, fmt.Errorf("validation failed: %w", err)
 	}
 
-	// Build the TTS request
-	ttsReq := &texttospeechpb.SynthesizeSpeechRequest{
-		Input: &texttospeechpb.SynthesisInput{
-			InputSource: &texttospeechpb.SynthesisInput_Text{
-				Text: req.Text,
-			},
-		},
-		Voice: &texttospeechpb.VoiceSelectionParams{
-			LanguageCode: req.Language,
-			Name:         req.Voice,
-		},
-		AudioConfig: &texttospeechpb.AudioConfig{
-			AudioEncoding:   texttospeechpb.AudioEncoding_MP3,
-			SpeakingRate:    float64(req.Speed),
-			Pitch:           float64(req.Pitch),
-			VolumeGainDb:    float64(req.VolumeGain),
-			SampleRateHertz: 22050,
-		},
-	}
-
-	// Call Google Cloud TTS
-	resp, err := s.client.SynthesizeSpeech(ctx, ttsReq)
-	if err != nil {
-		s.logger.Error("TTS synthesis failed")
-		return nil, fmt.Errorf("TTS synthesis failed: %w", err)
-	}
-
-	// Encode audio data to base64
-	audioContent := base64.StdEncoding.EncodeToString(resp.AudioContent)
-
-	return &dto.TTSSynthesizeResponse{
-		AudioContent: audioContent,
-		ContentType:  "audio/mpeg",
-		Duration:     s.estimateDuration(req.Text, req.Speed),
-		Language:     req.Language,
-		Voice:        req.Voice,
-		CreatedAt:    time.Now(),
-	}, nil
+	choice, err := s.resolveVoice(ctx, userID, ttsVoiceRequest{
+		Language:   req.Language,
+		Voice:      req.Voice,
+		Speed:      req.Speed,
+		Pitch:      req.Pitch,
+		VolumeGain: req.VolumeGain,
+		BookID:     req.BookID,
+		Timezone:   req.Timezone,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	source := ttsSource{UserID: userID, BookID: req.BookID, Text: req.Text}
+	format := ttsFormatRequest{Format: req.Format, Quality: req.Quality}
+	output, err := s.synthesize(ctx, source, choice.Voice, nil, format)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.buildSynthesizeResponse(ctx, choice, output, req.IncludeAudio)
 }
 
-// GetAvailableVoices returns available voices for the specified language
-func (s *ttsService) GetAvailableVoices(ctx context.Context, languageCode string) (*dto.TTSVoicesResponse, error) {
-	s.logger.Info("Getting available voices")
+// SynthesizeChapter narrates a chapter, splitting it into engine-sized
+// requests and joining the results into one audio file
+func (s *ttsService) SynthesizeChapter(ctx context.Context, userID uuid.UUID, chapter *domain.Chapter, voice *domain.TTSConfig, cast *domain.VoiceCast) (*domain.AudioFile, error) {
+	s.logger.Info("Synthesizing chapter to speech")
+
+	source := ttsSource{
+		UserID:    userID,
+		BookID:    &chapter.BookID,
+		ChapterID: &chapter.ID,
+		Text:      chapter.Content,
+	}
+
+	output, err := s.synthesize(ctx, source, *voice, cast, ttsFormatRequest{})
+	if err != nil {
+		return nil, err
+	}
+	if output.AudioFile == nil {
+		return nil, fmt.Errorf("failed to store chapter audio")
+	}
 
-	req := &texttospeechpb.ListVoicesRequest{
-		LanguageCode: languageCode,
+	return output.AudioFile, nil
+}
+
+// SynthesizeFormat reads the voice settings stored with an audio file and
+// synthesizes its text in the given format, serving it from the cache when
+// that format has been synthesized before
+func (s *ttsService) SynthesizeFormat(ctx context.Context, userID uuid.UUID, source *domain.AudioFile, format string) (*domain.AudioFile, error) {
+	var config ttsVoiceConfig
+	if err := json.Unmarshal([]byte(source.VoiceConfig), &config); err != nil {
+		return nil, fmt.Errorf("failed to parse voice config: %w", err)
 	}
 
-	resp, err := s.client.ListVoices(ctx, req)
+	voice := domain.TTSConfig{
+		Language:   config.Language,
+		Voice:      config.Voice,
+		Speed:      config.Speed,
+		Pitch:      config.Pitch,
+		VolumeGain: config.VolumeGain,
+	}
+	output, err := s.synthesize(ctx, ttsSource{
+		UserID:    userID,
+		BookID:    source.BookID,
+		ChapterID: source.ChapterID,
+		Text:      source.TextContent,
+	}, voice, config.Cast, ttsFormatRequest{Format: format})
 	if err != nil {
-		s.logger.Error("Failed to get voices")
-		return nil, fmt.Errorf("failed to get voices: %w", err)
+		return nil, err
+	}
+	if output.AudioFile == nil {
+		return nil, fmt.Errorf("failed to store %s audio", format)
 	}
 
-	var voices []dto.TTSVoice
-	for _, voice := range resp.Voices {
-		voices = append(voices, dto.TTSVoice{
-			Name:         voice.Name,
-			LanguageCodes: voice.LanguageCodes,
-			Gender:       voice.SsmlGender.String(),
-			NaturalSampleRateHertz: voice.NaturalSampleRateHertz,
+	return output.AudioFile, nil
+}
+
+// synthesize returns cached audio for the source text when available and
+// otherwise synthesizes it with the engines configured for the voice that
+// can produce the requested format. When cast is set, dialogue in 「」 is
+// read by the cast's character voices. Only new synthesis is checked
+// against and counted towards the user's daily TTS quota; cache hits are free.
+func (s *ttsService) synthesize(ctx context.Context, source ttsSource, voice domain.TTSConfig, cast *domain.VoiceCast, format ttsFormatRequest) (*ttsOutput, error) {
+	voice, cast = applyCast(voice, cast)
+	configured := s.enginesForVoice(voice.Voice)
+	if cast != nil {
+		configured = s.enginesForCast(cast)
+	}
+	if len(configured) == 0 {
+		return nil, fmt.Errorf("no TTS engine configured for voice %s", voice.Voice)
+	}
+
+	var engines []TTSEngine
+	var encodings []ttsEncoding
+	for _, engine := range configured {
+		if encoding, ok := s.encodingFor(engine, format); ok {
+			engines = append(engines, engine)
+			encodings = append(encodings, encoding)
+		}
+	}
+	if len(engines) == 0 {
+		return nil, apperrors.BadRequest(fmt.Sprintf("No TTS engine for voice %s can produce %s audio", voice.Voice, format.Format), nil)
+	}
+	textHash := hashText(source.Text)
+
+	lexicon, err := s.lexiconFor(ctx, source.BookID)
+	if err != nil {
+		return nil, err
+	}
+	lexiconKey := lexicon.Fingerprint(source.Text)
+
+	// Prefer a cached result from the highest priority engine
+	voiceConfigs := make([]string, len(engines))
+	for i, engine := range engines {
+		voiceConfigJSON, err := json.Marshal(newTTSVoiceConfig(engine, encodings[i], voice, cast, lexiconKey))
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode voice config: %w", err)
+		}
+		voiceConfigs[i] = string(voiceConfigJSON)
+
+		if cached := s.lookupCachedAudio(ctx, source.UserID, textHash, voiceConfigs[i]); cached != nil {
+			cached = s.claimCachedAudio(ctx, source, cached)
+			if cached == nil {
+				continue
+			}
+			s.logger.Debug("Serving synthesized audio from cache")
+			return &ttsOutput{
+				AudioFile:   cached,
+				ContentType: contentTypeForFormat(cached.Format),
+				Duration:    cached.DurationSeconds,
+				Cached:      true,
+			}, nil
+		}
+	}
+
+	if err := s.checkQuota(ctx, source.UserID, estimateNarrationMinutes(source.Text, voice.Speed)); err != nil {
+		return nil, err
+	}
+
+	sentences := ssml.SplitSentences(source.Text, ttsMaxInputBytes/2)
+	if cast != nil {
+		sentences = ssml.SplitDialogue(sentences)
+	}
+	chunks := groupSentences(sentences, sentenceVoices(sentences, voice, cast), lexicon, ttsMaxInputBytes)
+
+	var lastErr error
+	for i, engine := range engines {
+		result, duration, err := s.synthesizeChunks(ctx, engine, encodings[i], chunks, lexicon)
+		if err != nil {
+			s.logger.WithError(err).Warn("TTS engine failed, trying next engine")
+			lastErr = err
+			continue
+		}
+
+		info, err := audio.Probe(result.Format, result.Audio)
+		if err != nil {
+			s.logger.WithError(err).Warn("Failed to probe synthesized audio, using engine metadata")
+			info = &audio.Info{
+				Duration:   duration,
+				SampleRate: int(result.SampleRateHertz),
+				BitRate:    int(result.BitRate),
+			}
+		}
+
+		s.recordUsage(ctx, source.UserID, info.Duration)
+
+		audioFile, err := s.storeAudio(ctx, source, textHash, voiceConfigs[i], result, info)
+		if err != nil {
+			// The caller still gets the audio; only the cache entry is lost.
+			s.logger.Error("Failed to store synthesized audio in cache")
+		} else if duration > 0 {
+			s.storeAlignment(ctx, buildAlignment(audioFile.ID, sentences, result.Timepoints, duration))
+		}
+
+		return &ttsOutput{
+			AudioFile:   audioFile,
+			Audio:       result.Audio,
+			ContentType: result.ContentType,
+			Duration:    info.Duration,
+		}, nil
+	}
+
+	s.logger.Error("TTS synthesis failed")
+	return nil, fmt.Errorf("TTS synthesis failed: %w", lastErr)
+}
+
+// synthesizeChunks synthesizes each chunk of sentences in its voice with one
+// engine in the given encoding, reading surface forms in lexicon as their readings, and joins the
+// audio. Timepoints in the result are relative to the
+// start of the joined audio. The returned duration is zero when it could not
+// be measured.
+func (s *ttsService) synthesizeChunks(ctx context.Context, engine TTSEngine, encoding ttsEncoding, chunks []ttsChunk, lexicon *ssml.Lexicon) (*TTSEngineResult, float64, error) {
+	var result *TTSEngineResult
+	var timepoints []TTSTimepoint
+	var offset float64
+	measured := true
+	parts := make([][]byte, 0, len(chunks))
+
+	for _, chunk := range chunks {
+		chunkResult, err := engine.Synthesize(ctx, &TTSEngineRequest{
+			SSML:             ssml.BuildMarked(chunk.Sentences, lexicon),
+			Language:         chunk.Voice.Language,
+			Voice:            chunk.Voice.Voice,
+			Speed:            chunk.Voice.Speed,
+			Pitch:            chunk.Voice.Pitch,
+			VolumeGain:       chunk.Voice.VolumeGain,
+			Format:           encoding.Format,
+			SampleRateHertz:  encoding.SampleRateHertz,
+			EnableTimepoints: true,
 		})
+		if err != nil {
+			return nil, 0, err
+		}
+		if result == nil {
+			result = chunkResult
+		}
+		parts = append(parts, chunkResult.Audio)
+
+		for _, tp := range chunkResult.Timepoints {
+			timepoints = append(timepoints, TTSTimepoint{MarkName: tp.MarkName, Seconds: offset + tp.Seconds})
+		}
+
+		chunkDuration, err := audio.Duration(chunkResult.Format, chunkResult.Audio)
+		if err != nil {
+			s.logger.WithError(err).Warn("Failed to measure synthesized audio duration")
+			measured = false
+		}
+		offset += chunkDuration
 	}
 
-	return &dto.TTSVoicesResponse{
-		Voices: voices,
-	}, nil
+	if result == nil {
+		return nil, 0, fmt.Errorf("no text to synthesize")
+	}
+
+	joined, err := audio.Concat(result.Format, parts)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to join synthesized audio: %w", err)
+	}
+	result.Audio = joined
+	result.Timepoints = timepoints
+
+	if !measured {
+		return result, 0, nil
+	}
+	return result, offset, nil
+}
+
+// GetAlignment returns the sentence timings of a synthesized audio file
+func (s *ttsService) GetAlignment(ctx context.Context, userID, audioFileID uuid.UUID) (*dto.AudioAlignmentResponse, error) {
+	accessible, err := s.audioFileRepo.IsAccessibleBy(ctx, audioFileID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check audio file access: %w", err)
+	}
+	if !accessible {
+		return nil, apperrors.NotFound("Audio alignment not found")
+	}
+
+	alignment, err := s.alignmentRepo.GetByAudioFileID(ctx, audioFileID)
+	if err != nil {
+		s.logger.Error("Failed to get audio alignment")
+		return nil, fmt.Errorf("failed to get audio alignment: %w", err)
+	}
+	if alignment == nil {
+		return nil, apperrors.NotFound("Audio alignment not found")
+	}
+
+	response := &dto.AudioAlignmentResponse{
+		AudioFileID: alignment.AudioFileID,
+		DurationMs:  alignment.DurationMs,
+		Segments:    make([]dto.AudioAlignmentSegment, 0, len(alignment.Segments)),
+	}
+	for _, segment := range alignment.Segments {
+		response.Segments = append(response.Segments, dto.AudioAlignmentSegment{
+			StartMs:         segment.StartMs,
+			EndMs:           segment.EndMs,
+			Sentence:        segment.Sentence,
+			Paragraph:       segment.Paragraph,
+			ParagraphOffset: segment.ParagraphOffset,
+			CharOffset:      segment.CharOffset,
+			CharLength:      segment.CharLength,
+		})
+	}
+
+	return response, nil
+}
+
+// GetAvailableVoices returns available voices for the specified language
+// from the first engine in the default order that can list them
+func (s *ttsService) GetAvailableVoices(ctx context.Context, languageCode string) (*dto.TTSVoicesResponse, error) {
+	s.logger.Info("Getting available voices")
+
+	var lastErr error
+	for _, engine := range s.enginesForVoice("") {
+		voices, err := engine.ListVoices(ctx, languageCode)
+		if err != nil {
+			s.logger.WithError(err).Warn("Failed to get voices from TTS engine, trying next engine")
+			lastErr = err
+			continue
+		}
+
+		return &dto.TTSVoicesResponse{
+			Voices: voices,
+		}, nil
+	}
+
+	s.logger.Error("Failed to get voices")
+	return nil, fmt.Errorf("failed to get voices: %w", lastErr)
 }
 
 // PreviewVoice generates a short preview of the voice
-func (s *ttsService) PreviewVoice(ctx context.Context, req *dto.TTSPreviewRequest) (*dto.TTSPreviewResponse, error) {
+func (s *ttsService) PreviewVoice(ctx context.Context, userID uuid.UUID, req *dto.TTSPreviewRequest) (*dto.TTSPreviewResponse, error) {
 	s.logger.Info("Generating voice preview")
 
 	// Use a standard preview text
@@ -141,33 +500,433 @@ func (s *ttsService) PreviewVoice(ctx context.Context, req *dto.TTSPreviewReques
 		previewText = req.PreviewText
 	}
 
-	synthesizeReq := &dto.TTSSynthesizeRequest{
-		Text:       previewText,
+	choice, err := s.resolveVoice(ctx, userID, ttsVoiceRequest{
 		Language:   req.Language,
 		Voice:      req.Voice,
 		Speed:      req.Speed,
 		Pitch:      req.Pitch,
 		VolumeGain: req.VolumeGain,
+	})
+	if err != nil {
+		return nil, err
 	}
+	voice := choice.Voice
 
-	result, err := s.SynthesizeText(ctx, synthesizeReq)
+	format := ttsFormatRequest{Format: req.Format, Quality: req.Quality}
+	output, err := s.synthesize(ctx, ttsSource{UserID: userID, Text: previewText}, voice, nil, format)
 	if err != nil {
 		return nil, fmt.Errorf("preview generation failed: %w", err)
 	}
+	audio, err := s.outputAudio(ctx, output)
+	if err != nil {
+		return nil, err
+	}
 
 	return &dto.TTSPreviewResponse{
-		AudioContent: result.AudioContent,
-		ContentType:  result.ContentType,
-		Duration:     result.Duration,
+		AudioContent: base64.StdEncoding.EncodeToString(audio),
+		ContentType:  output.ContentType,
+		Duration:     int(math.Ceil(output.Duration)),
 		PreviewText:  previewText,
+		Language:     voice.Language,
+		Voice:        voice.Voice,
+		Speed:        voice.Speed,
+		Pitch:        voice.Pitch,
+		VoiceRule:    voiceRuleResponse(choice.Rule),
 	}, nil
 }
 
-// estimateDuration estimates audio duration based on text length and speed
-func (s *ttsService) estimateDuration(text string, speed float32) int {
-	// Rough estimation: 150 words per minute for Japanese at normal speed
-	wordsPerMinute := 150.0 * float64(speed)
-	wordCount := float64(len([]rune(text))) / 2.0 // Rough Japanese word count estimation
-	durationMinutes := wordCount / wordsPerMinute
-	return int(durationMinutes * 60) // Return duration in seconds
-}
\ No newline at end of file
+// lexiconFor returns the pronunciation lexicon for a book, or nil when no
+// lexicon is configured
+func (s *ttsService) lexiconFor(ctx context.Context, bookID *int64) (*ssml.Lexicon, error) {
+	if s.lexicons == nil {
+		return nil, nil
+	}
+
+	lexicon, err := s.lexicons.LexiconFor(ctx, bookID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load pronunciation lexicon: %w", err)
+	}
+	return lexicon, nil
+}
+
+// ttsCharactersPerMinute is the typical narration rate of Japanese text at
+// normal speed, used to estimate how many minutes synthesis will use
+const ttsCharactersPerMinute = 350
+
+// estimateNarrationMinutes estimates the playing time of text read at speed
+func estimateNarrationMinutes(text string, speed float32) float64 {
+	if speed <= 0 {
+		speed = 1
+	}
+	return float64(utf8.RuneCountInString(text)) / ttsCharactersPerMinute / float64(speed)
+}
+
+// checkQuota rejects synthesis whose estimated minutes exceed what is left
+// of the user's daily TTS minutes
+func (s *ttsService) checkQuota(ctx context.Context, userID uuid.UUID, estimatedMinutes float64) error {
+	if s.subscriptionService == nil {
+		return nil
+	}
+
+	allowed, err := s.subscriptionService.CanUseTTS(ctx, userID, estimatedMinutes)
+	if err != nil {
+		return fmt.Errorf("failed to check TTS quota: %w", err)
+	}
+	if allowed {
+		return nil
+	}
+
+	quota, err := s.subscriptionService.GetTTSQuota(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to check TTS quota: %w", err)
+	}
+	return apperrors.QuotaExceeded("Not enough daily text-to-speech minutes are left for this text", map[string]interface{}{
+		"limit_minutes":       quota.LimitMinutes,
+		"used_minutes":        quota.UsedMinutes,
+		"remaining_minutes":   quota.RemainingMinutes,
+		"requested_minutes":   math.Round(estimatedMinutes*100) / 100,
+		"reset_at":            quota.ResetAt,
+		"retry_after_seconds": int(math.Ceil(time.Until(quota.ResetAt).Seconds())),
+	})
+}
+
+// recordUsage counts the playing time of newly synthesized audio towards the
+// user's TTS quota. The audio has already been produced, so failures are
+// only logged.
+func (s *ttsService) recordUsage(ctx context.Context, userID uuid.UUID, durationSeconds float64) {
+	if s.subscriptionService == nil {
+		return
+	}
+
+	if err := s.subscriptionService.TrackFeatureUsage(ctx, userID, "tts_minutes", 1, durationSeconds/60); err != nil {
+		s.logger.WithError(err).Error("Failed to record TTS usage")
+	}
+}
+
+// lookupCachedAudio returns a completed audio file when the same text has
+// already been synthesized with the same voice config, the user's own
+// record first. A record whose file has gone missing is treated as a cache
+// miss.
+func (s *ttsService) lookupCachedAudio(ctx context.Context, userID uuid.UUID, textHash, voiceConfig string) *domain.AudioFile {
+	audioFile, err := s.audioFileRepo.GetByCacheKey(ctx, userID, textHash, voiceConfig)
+	if err != nil {
+		s.logger.Error("Failed to look up audio cache")
+		return nil
+	}
+	if audioFile == nil {
+		return nil
+	}
+
+	if _, err := s.blobStore.Stat(ctx, audioFile.FilePath); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			s.logger.Warn("Cached audio file is missing, synthesizing again")
+		} else {
+			s.logger.Error("Failed to read cached audio file")
+		}
+		return nil
+	}
+
+	return audioFile
+}
+
+// outputAudio returns the content of synthesized or cached audio
+func (s *ttsService) outputAudio(ctx context.Context, output *ttsOutput) ([]byte, error) {
+	if output.Audio != nil {
+		return output.Audio, nil
+	}
+	audio, err := storage.ReadAll(ctx, s.blobStore, output.AudioFile.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cached audio: %w", err)
+	}
+	return audio, nil
+}
+
+// claimCachedAudio records audio another user synthesized as the user's own,
+// sharing the same blob and alignment, so the user may stream it and it
+// counts towards their storage budget. It is only called when the user has
+// no record of the audio yet, and a record created concurrently is reused.
+// The shared record is returned when the user's record cannot be created,
+// and nil when the shared record was removed meanwhile, since its blob is
+// then deleted.
+func (s *ttsService) claimCachedAudio(ctx context.Context, source ttsSource, cached *domain.AudioFile) *domain.AudioFile {
+	if cached.UserID == source.UserID {
+		return cached
+	}
+
+	now := time.Now()
+	claimed := *cached
+	claimed.ID = uuid.New()
+	claimed.UserID = source.UserID
+	claimed.PlayCount = 0
+	claimed.LastPlayedAt = nil
+	claimed.CreatedAt = now
+	claimed.UpdatedAt = now
+	if source.BookID != nil {
+		claimed.BookID = source.BookID
+		claimed.ChapterID = source.ChapterID
+	}
+	var removed bool
+	err := s.audioFileRepo.WithFilePathLock(ctx, cached.FilePath, func() error {
+		current, err := s.audioFileRepo.GetByID(ctx, cached.ID)
+		if err != nil {
+			return err
+		}
+		if current == nil {
+			removed = true
+			return nil
+		}
+		return s.audioFileRepo.Create(ctx, &claimed)
+	})
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to record cached audio for user")
+		return cached
+	}
+	if removed {
+		return nil
+	}
+
+	alignment, err := s.alignmentRepo.GetByAudioFileID(ctx, cached.ID)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to get cached audio alignment")
+	} else if alignment != nil {
+		copied := *alignment
+		copied.AudioFileID = claimed.ID
+		copied.CreatedAt = now
+		s.storeAlignment(ctx, &copied)
+	}
+
+	return &claimed
+}
+
+// storeAudio writes synthesized audio to the blob store and records its
+// metadata, as probed from the encoded audio, in audio_files.
+func (s *ttsService) storeAudio(ctx context.Context, source ttsSource, textHash, voiceConfig string, result *TTSEngineResult, info *audio.Info) (*domain.AudioFile, error) {
+	filePath := fmt.Sprintf("tts/%s.%s", cacheKey(textHash, voiceConfig), result.Format)
+	putOpts := storage.PutOptions{
+		ContentType: result.ContentType,
+		Metadata:    map[string]string{"text_hash": textHash},
+	}
+
+	now := time.Now()
+	audioFile := &domain.AudioFile{
+		ID:              uuid.New(),
+		UserID:          source.UserID,
+		BookID:          source.BookID,
+		ChapterID:       source.ChapterID,
+		TextContent:     source.Text,
+		TextHash:        textHash,
+		VoiceConfig:     voiceConfig,
+		FilePath:        filePath,
+		FileSizeBytes:   int64(len(result.Audio)),
+		DurationSeconds: info.Duration,
+		Format:          result.Format,
+		SampleRate:      info.SampleRate,
+		BitRate:         info.BitRate,
+		Status:          "completed",
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+
+	err := s.audioFileRepo.WithFilePathLock(ctx, filePath, func() error {
+		if _, err := s.blobStore.Put(ctx, filePath, bytes.NewReader(result.Audio), putOpts); err != nil {
+			return fmt.Errorf("failed to write audio file: %w", err)
+		}
+		if err := s.audioFileRepo.Create(ctx, audioFile); err != nil {
+			return fmt.Errorf("failed to record audio file: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return audioFile, nil
+}
+
+// buildSynthesizeResponse converts synthesized or cached audio into the API
+// response. Stored audio is referred to by its file ID, and its content is
+// only included when asked for.
+func (s *ttsService) buildSynthesizeResponse(ctx context.Context, choice *ttsVoiceChoice, output *ttsOutput, includeAudio bool) (*dto.TTSSynthesizeResponse, error) {
+	response := &dto.TTSSynthesizeResponse{
+		ContentType: output.ContentType,
+		Duration:    int(math.Ceil(output.Duration)),
+		Language:    choice.Voice.Language,
+		Voice:       choice.Voice.Voice,
+		Speed:       choice.Voice.Speed,
+		Pitch:       choice.Voice.Pitch,
+		VoiceRule:   voiceRuleResponse(choice.Rule),
+		Cached:      output.Cached,
+		CreatedAt:   time.Now(),
+	}
+
+	if output.AudioFile != nil {
+		response.AudioFileID = &output.AudioFile.ID
+		response.CreatedAt = output.AudioFile.CreatedAt
+	}
+	if includeAudio || output.AudioFile == nil {
+		audio, err := s.outputAudio(ctx, output)
+		if err != nil {
+			return nil, err
+		}
+		response.AudioContent = base64.StdEncoding.EncodeToString(audio)
+	}
+
+	return response, nil
+}
+
+// enginesForVoice returns the engines to try for a voice, in fallback order
+func (s *ttsService) enginesForVoice(voice string) []TTSEngine {
+	order, ok := s.fallback.PerVoice[voice]
+	if !ok {
+		order = s.fallback.Default
+	}
+
+	var engines []TTSEngine
+	for _, name := range order {
+		if engine, ok := s.engines[name]; ok {
+			engines = append(engines, engine)
+		}
+	}
+	return engines
+}
+
+// encodingFor returns the encoding to request from an engine. A format named
+// by the request rules out engines that cannot produce it; otherwise the
+// default format is used, or the engine's own when it cannot produce that.
+func (s *ttsService) encodingFor(engine TTSEngine, req ttsFormatRequest) (ttsEncoding, bool) {
+	format := req.Format
+	if format == "" {
+		format = formatForAudioEncoding(s.voiceDefaults.AudioEncoding)
+		if format == "" || !supportsFormat(engine, format) {
+			format = engine.AudioFormats()[0]
+		}
+	}
+	if !supportsFormat(engine, format) {
+		return ttsEncoding{}, false
+	}
+
+	quality := req.Quality
+	if quality == "" {
+		quality = TTSQualityStandard
+	}
+	sampleRate, ok := ttsSampleRates[format][quality]
+	if !ok {
+		return ttsEncoding{}, false
+	}
+
+	return ttsEncoding{Format: format, SampleRateHertz: sampleRate}, true
+}
+
+// newTTSVoiceConfig builds the cache key voice config for an engine
+func newTTSVoiceConfig(engine TTSEngine, encoding ttsEncoding, voice domain.TTSConfig, cast *domain.VoiceCast, lexiconKey string) ttsVoiceConfig {
+	return ttsVoiceConfig{
+		Engine:          engine.Name(),
+		Language:        voice.Language,
+		Voice:           voice.Voice,
+		Speed:           voice.Speed,
+		Pitch:           voice.Pitch,
+		VolumeGain:      voice.VolumeGain,
+		AudioEncoding:   audioEncodingForFormat(encoding.Format),
+		SampleRateHertz: encoding.SampleRateHertz,
+		Cast:            cast,
+		Lexicon:         lexiconKey,
+	}
+}
+
+// groupSentences packs consecutive sentences read by the same voice into
+// chunks whose SSML, with lexicon applied, stays within maxBytes. voices
+// holds the voice of each sentence.
+func groupSentences(sentences []ssml.Sentence, voices []domain.TTSConfig, lexicon *ssml.Lexicon, maxBytes int) []ttsChunk {
+	var chunks []ttsChunk
+	var current *ttsChunk
+	size := ssml.DocumentOverhead
+
+	for i, sentence := range sentences {
+		sentenceSize := ssml.MarkedSize(sentence, lexicon)
+		if current != nil && (current.Voice != voices[i] || size+sentenceSize > maxBytes) {
+			chunks = append(chunks, *current)
+			current = nil
+			size = ssml.DocumentOverhead
+		}
+		if current == nil {
+			current = &ttsChunk{Voice: voices[i]}
+		}
+		current.Sentences = append(current.Sentences, sentence)
+		size += sentenceSize
+	}
+	if current != nil {
+		chunks = append(chunks, *current)
+	}
+
+	return chunks
+}
+
+// buildAlignment converts sentence mark timepoints into playback intervals.
+// A sentence whose mark was not reported starts where the previous one did.
+func buildAlignment(audioFileID uuid.UUID, sentences []ssml.Sentence, timepoints []TTSTimepoint, durationSeconds float64) *domain.AudioAlignment {
+	starts := make(map[int]float64, len(timepoints))
+	for _, tp := range timepoints {
+		if index, ok := ssml.ParseMarkName(tp.MarkName); ok {
+			starts[index] = tp.Seconds
+		}
+	}
+
+	durationMs := int(durationSeconds * 1000)
+	segments := make([]domain.AlignmentSegment, len(sentences))
+	previousStart := 0
+	for i, sentence := range sentences {
+		startMs := previousStart
+		if seconds, ok := starts[sentence.Index]; ok {
+			startMs = int(seconds * 1000)
+		}
+		if startMs < previousStart {
+			startMs = previousStart
+		}
+		previousStart = startMs
+
+		segments[i] = domain.AlignmentSegment{
+			StartMs:         startMs,
+			Sentence:        sentence.Index,
+			Paragraph:       sentence.Paragraph,
+			ParagraphOffset: sentence.ParagraphOffset,
+			CharOffset:      sentence.Offset,
+			CharLength:      sentence.Length,
+		}
+		if i > 0 {
+			segments[i-1].EndMs = startMs
+		}
+	}
+	if len(segments) > 0 {
+		last := &segments[len(segments)-1]
+		last.EndMs = durationMs
+		if last.EndMs < last.StartMs {
+			last.EndMs = last.StartMs
+		}
+	}
+
+	return &domain.AudioAlignment{
+		AudioFileID: audioFileID,
+		DurationMs:  durationMs,
+		Segments:    segments,
+		CreatedAt:   time.Now(),
+	}
+}
+
+// storeAlignment saves sentence timings; failures only lose read-along highlighting
+func (s *ttsService) storeAlignment(ctx context.Context, alignment *domain.AudioAlignment) {
+	if err := s.alignmentRepo.Upsert(ctx, alignment); err != nil {
+		s.logger.WithError(err).Error("Failed to store audio alignment")
+	}
+}
+
+// hashText returns the SHA-256 hex digest used as audio_files.text_hash
+func hashText(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// cacheKey derives the storage key for a text hash and voice config pair
+func cacheKey(textHash, voiceConfig string) string {
+	sum := sha256.Sum256([]byte(textHash + ":" + voiceConfig))
+	return hex.EncodeToString(sum[:])
+}
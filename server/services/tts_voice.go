@@ -0,0 +1,331 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ponyo877/roudoku/server/domain"
+	"github.com/ponyo877/roudoku/server/dto"
+	apperrors "github.com/ponyo877/roudoku/server/pkg/errors"
+	"github.com/ponyo877/roudoku/server/pkg/voicepolicy"
+)
+
+// presetPitchSemitones is the pitch shift of a preset pitch of 0 or 1.
+// A preset pitch of 0.5 keeps the voice's natural pitch.
+const presetPitchSemitones = 10
+
+// TTSVoiceDefaults resolves the voice of requests that leave it out
+type TTSVoiceDefaults struct {
+	Language string
+	Voice    string
+	// GenderVoices maps a language and a preset gender (male, female,
+	// neutral) to the voice that reads it, e.g. {"ja-JP": {"male": "ja-JP-Wavenet-C"}}
+	GenderVoices map[string]map[string]string
+	// Policy adjusts the voice by book and time of day; nil disables it
+	Policy *voicepolicy.Policy
+	// AudioEncoding (MP3, LINEAR16 or OGG_OPUS) is produced for requests
+	// that name no format; engines that cannot produce it use their own
+	AudioEncoding string
+}
+
+// ttsVoiceRequest holds the voice parameters of a request. Empty and nil
+// fields are resolved from the voice policy and the user's voice preset.
+type ttsVoiceRequest struct {
+	Language   string
+	Voice      string
+	Speed      *float32
+	Pitch      *float32
+	VolumeGain float32
+	BookID     *int64
+	// Timezone is the listener's IANA zone; the policy's zone when empty
+	Timezone string
+}
+
+// ttsVoiceChoice is a resolved voice and how it was chosen
+type ttsVoiceChoice struct {
+	Voice domain.TTSConfig
+	// Rule is the voice policy rule that was applied, if any
+	Rule      *voicepolicy.Rule
+	LocalTime time.Time
+}
+
+// resolveVoice fills the parameters missing from req. They start from the
+// user's voice preset: a voice saved in the preset is used for its own
+// language; other languages get the voice configured for the preset gender,
+// and then the default voice. The voice policy then adjusts them for the
+// book and the listener's local time. Parameters set in req always win.
+func (s *ttsService) resolveVoice(ctx context.Context, userID uuid.UUID, req ttsVoiceRequest) (*ttsVoiceChoice, error) {
+	preset := s.voicePreset(ctx, userID)
+
+	location, err := s.listenerLocation(req.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	choice := &ttsVoiceChoice{
+		Voice: domain.TTSConfig{
+			Language:   req.Language,
+			Speed:      1.0,
+			VolumeGain: req.VolumeGain,
+		},
+		LocalTime: time.Now().In(location),
+	}
+	voice := &choice.Voice
+
+	if voice.Language == "" {
+		voice.Language = s.voiceDefaults.Language
+		if preset != nil && preset.Voice != "" && preset.Language != "" {
+			voice.Language = preset.Language
+		}
+	}
+	if voice.Language == "" {
+		return nil, apperrors.Validation("language is required")
+	}
+
+	gender := ""
+	if preset != nil {
+		gender = preset.Gender
+		if preset.Voice != "" && preset.Language == voice.Language {
+			voice.Voice = preset.Voice
+		} else {
+			voice.Voice = s.voiceDefaults.GenderVoices[voice.Language][preset.Gender]
+		}
+		voice.Speed = float32(preset.Speed)
+		voice.Pitch = presetPitchToSemitones(preset.Pitch)
+	}
+	if voice.Voice == "" {
+		voice.Voice = s.voiceDefaults.Voice
+	}
+
+	if s.voiceDefaults.Policy != nil && (req.Voice == "" || req.Speed == nil || req.Pitch == nil) {
+		policyContext, err := s.voicePolicyContext(ctx, req.BookID, voice.Language, gender, choice.LocalTime)
+		if err != nil {
+			return nil, err
+		}
+
+		decision := s.voiceDefaults.Policy.Select(policyContext, voicepolicy.Voice{
+			Voice: voice.Voice,
+			Speed: voice.Speed,
+			Pitch: voice.Pitch,
+		})
+		voice.Voice = decision.Voice.Voice
+		voice.Speed = decision.Voice.Speed
+		voice.Pitch = decision.Voice.Pitch
+		choice.Rule = decision.Rule
+	}
+
+	if req.Voice != "" {
+		voice.Voice = req.Voice
+	}
+	if req.Speed != nil {
+		voice.Speed = *req.Speed
+	}
+	if req.Pitch != nil {
+		voice.Pitch = *req.Pitch
+	}
+	if voice.Voice == "" {
+		return nil, apperrors.Validation("voice is required")
+	}
+
+	return choice, nil
+}
+
+// SelectVoice reports the voice a request without voice parameters would
+// get, and the voice policy rule that chose it
+func (s *ttsService) SelectVoice(ctx context.Context, userID uuid.UUID, req *dto.VoiceSelectionRequest) (*dto.VoiceSelectionResponse, error) {
+	if err := s.ValidateStruct(req); err != nil {
+		return nil, err
+	}
+
+	choice, err := s.resolveVoice(ctx, userID, ttsVoiceRequest{
+		Language: req.Language,
+		BookID:   req.BookID,
+		Timezone: req.Timezone,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.VoiceSelectionResponse{
+		Language:  choice.Voice.Language,
+		Voice:     choice.Voice.Voice,
+		Speed:     choice.Voice.Speed,
+		Pitch:     choice.Voice.Pitch,
+		VoiceRule: voiceRuleResponse(choice.Rule),
+		LocalTime: choice.LocalTime,
+	}, nil
+}
+
+// voicePolicyContext describes a request to the voice policy. Book fields
+// are left empty when the text is not part of a book.
+func (s *ttsService) voicePolicyContext(ctx context.Context, bookID *int64, language, gender string, localTime time.Time) (voicepolicy.Context, error) {
+	policyContext := voicepolicy.Context{
+		Language:  language,
+		Gender:    gender,
+		LocalTime: localTime,
+	}
+	if bookID == nil || s.bookRepo == nil {
+		return policyContext, nil
+	}
+
+	book, err := s.bookRepo.GetByID(ctx, *bookID)
+	if err != nil {
+		return voicepolicy.Context{}, err
+	}
+	if book.Genre != nil {
+		policyContext.Genre = *book.Genre
+	}
+	if book.Epoch != nil {
+		policyContext.Epoch = *book.Epoch
+	}
+	policyContext.DifficultyLevel = book.DifficultyLevel
+
+	return policyContext, nil
+}
+
+// listenerLocation returns the listener's time zone, defaulting to the
+// voice policy's zone and then the server's
+func (s *ttsService) listenerLocation(timezone string) (*time.Location, error) {
+	if timezone != "" {
+		location, err := time.LoadLocation(timezone)
+		if err != nil {
+			return nil, apperrors.BadRequest(fmt.Sprintf("Unknown timezone %s", timezone), err)
+		}
+		return location, nil
+	}
+	if s.voiceDefaults.Policy != nil {
+		return s.voiceDefaults.Policy.Location(), nil
+	}
+	return time.Local, nil
+}
+
+// voicePreset returns the user's voice preset, or nil when the user has none
+// or it cannot be read. Requests still succeed with the default voice.
+func (s *ttsService) voicePreset(ctx context.Context, userID uuid.UUID) *domain.VoicePreset {
+	if s.userRepo == nil {
+		return nil
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		s.logger.WithError(err).Warn("Failed to get voice preset, using the default voice")
+		return nil
+	}
+	// Every saved preset has a speed; a zero value means none was ever stored
+	if user == nil || user.VoicePreset.Speed == 0 {
+		return nil
+	}
+	return &user.VoicePreset
+}
+
+// SaveVoicePreset stores a voice, typically one just previewed, as the
+// user's voice preset. The preset gender is taken from the voice list so
+// that other languages get a voice of the same gender.
+func (s *ttsService) SaveVoicePreset(ctx context.Context, userID uuid.UUID, req *dto.SaveVoicePresetRequest) (*dto.VoicePresetResponse, error) {
+	if err := s.ValidateStruct(req); err != nil {
+		return nil, err
+	}
+	if s.userRepo == nil {
+		return nil, fmt.Errorf("voice presets are not configured")
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	gender, err := s.voiceGender(ctx, req.Language, req.Voice)
+	if err != nil {
+		return nil, err
+	}
+	if gender == "" {
+		gender = user.VoicePreset.Gender
+	}
+
+	preset := domain.VoicePreset{
+		Gender:   gender,
+		Pitch:    0.5,
+		Speed:    1.0,
+		Language: req.Language,
+		Voice:    req.Voice,
+	}
+	if req.Speed != nil {
+		preset.Speed = float64(*req.Speed)
+	}
+	if req.Pitch != nil {
+		preset.Pitch = semitonesToPresetPitch(*req.Pitch)
+	}
+
+	user.VoicePreset = preset
+	user.UpdatedAt = time.Now()
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		s.logger.Error("Failed to save voice preset")
+		return nil, fmt.Errorf("failed to save voice preset: %w", err)
+	}
+
+	return &dto.VoicePresetResponse{
+		Gender:   preset.Gender,
+		Pitch:    preset.Pitch,
+		Speed:    preset.Speed,
+		Language: preset.Language,
+		Voice:    preset.Voice,
+	}, nil
+}
+
+// voiceGender returns the preset gender of a voice. It is empty when no
+// engine can list voices; an engine that lists voices without this one
+// rejects it.
+func (s *ttsService) voiceGender(ctx context.Context, language, name string) (string, error) {
+	for _, engine := range s.enginesForVoice(name) {
+		voices, err := engine.ListVoices(ctx, language)
+		if err != nil {
+			s.logger.WithError(err).Warn("Failed to get voices from TTS engine, trying next engine")
+			continue
+		}
+
+		for _, voice := range voices {
+			if voice.Name == name {
+				return presetGender(voice.Gender), nil
+			}
+		}
+		return "", apperrors.BadRequest(fmt.Sprintf("Voice %s is not available for %s", name, language), nil)
+	}
+
+	s.logger.Warn("Could not determine voice gender, keeping the preset gender")
+	return "", nil
+}
+
+// presetGender maps an engine's SSML gender to a preset gender
+func presetGender(ssmlGender string) string {
+	switch strings.ToUpper(ssmlGender) {
+	case "MALE":
+		return "male"
+	case "FEMALE":
+		return "female"
+	default:
+		return "neutral"
+	}
+}
+
+func presetPitchToSemitones(pitch float64) float32 {
+	return float32((pitch - 0.5) * 2 * presetPitchSemitones)
+}
+
+func voiceRuleResponse(rule *voicepolicy.Rule) *dto.VoiceRule {
+	if rule == nil {
+		return nil
+	}
+	return &dto.VoiceRule{
+		Name:        rule.Name,
+		Description: rule.Description,
+	}
+}
+
+func semitonesToPresetPitch(semitones float32) float64 {
+	pitch := 0.5 + float64(semitones)/(2*presetPitchSemitones)
+	return math.Min(math.Max(pitch, 0), 1)
+}
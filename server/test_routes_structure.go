@@ -23,8 +23,17 @@ func (m *MockBookRepository) Create(ctx context.Context, book *domain.Book) erro
 func (m *MockBookRepository) GetByID(ctx context.Context, id int64) (*domain.Book, error) {
 	return &domain.Book{ID: id, Title: "Test Book", Author: "Test Author", IsActive: true}, nil
 }
+func (m *MockBookRepository) GetByIDs(ctx context.Context, ids []int64) ([]*domain.Book, error) {
+	books := make([]*domain.Book, 0, len(ids))
+	for _, id := range ids {
+		book, _ := m.GetByID(ctx, id)
+		books = append(books, book)
+	}
+	return books, nil
+}
 func (m *MockBookRepository) Update(ctx context.Context, book *domain.Book) error { return nil }
 func (m *MockBookRepository) Delete(ctx context.Context, id int64) error { return nil }
+func (m *MockBookRepository) UpdateEmbedding(ctx context.Context, bookID int64, embedding []float64) error { return nil }
 func (m *MockBookRepository) List(ctx context.Context, req *domain.BookSearchRequest) ([]*domain.Book, int, error) {
 	return []*domain.Book{{ID: 1, Title: "Test Book", Author: "Test Author", IsActive: true}}, 1, nil
 }